@@ -25,6 +25,6 @@ func main() {
 		Date:    buildDate,
 		Commit:  gitCommit,
 	}); err != nil {
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
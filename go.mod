@@ -3,13 +3,13 @@ module github.com/idvoretskyi/voice-transcriber
 go 1.25.10
 
 require (
+	cloud.google.com/go/auth v0.20.0
 	github.com/spf13/cobra v1.10.2
 	google.golang.org/genai v1.54.0
 )
 
 require (
 	cloud.google.com/go v0.123.0 // indirect
-	cloud.google.com/go/auth v0.20.0 // indirect
 	cloud.google.com/go/compute/metadata v0.9.0 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
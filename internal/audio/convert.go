@@ -0,0 +1,248 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// convertFrames is the number of input frames a converter processes at once.
+const convertFrames = 4096
+
+// Convert returns a reader that yields the PCM read from r, in format from,
+// converted to format to: samples are rescaled to the target bit depth,
+// channels are mixed down to mono by averaging or copied across when
+// upmixing, and the sample rate is changed with a windowed-sinc resampler.
+// When the formats are equal r is returned as is.
+func Convert(r io.Reader, from, to Format) (io.Reader, error) {
+	if err := from.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := to.Validate(); err != nil {
+		return nil, err
+	}
+
+	if from == to {
+		return r, nil
+	}
+
+	c := &converter{
+		r:    r,
+		from: from,
+		to:   to,
+		in:   make([]byte, convertFrames*from.FrameSize()),
+	}
+
+	if from.SampleRate != to.SampleRate {
+		c.rs = newResampler(from.SampleRate, to.SampleRate, to.Channels)
+	}
+
+	return c, nil
+}
+
+// converter is the reader returned by Convert.
+type converter struct {
+	r        io.Reader
+	from, to Format
+	rs       *resampler // nil when the sample rates match
+
+	in      []byte // input buffer
+	partial int    // bytes of an incomplete frame at the start of in
+	samples []float64
+	encoded []byte
+	out     bytes.Buffer
+	err     error
+}
+
+func (c *converter) Read(p []byte) (int, error) {
+	for c.out.Len() == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		c.fill()
+	}
+
+	return c.out.Read(p) //nolint:wrapcheck // bytes.Buffer only fails when empty
+}
+
+// fill converts the next block of input into c.out.
+func (c *converter) fill() {
+	n, err := io.ReadAtLeast(c.r, c.in[c.partial:], 1)
+	n += c.partial
+
+	frameSize := c.from.FrameSize()
+	whole := n - n%frameSize
+
+	c.samples = c.mix(c.samples[:0], c.in[:whole])
+	c.partial = copy(c.in, c.in[whole:n])
+
+	eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+
+	samples := c.samples
+	if c.rs != nil {
+		samples = c.rs.process(samples, eof)
+	}
+
+	c.encoded = c.encoded[:0]
+	for _, v := range samples {
+		c.encoded = appendSample(c.encoded, c.to, v)
+	}
+
+	c.out.Write(c.encoded)
+
+	switch {
+	case eof:
+		c.err = io.EOF
+	case err != nil:
+		c.err = fmt.Errorf("reading PCM: %w", err)
+	}
+}
+
+// mix decodes whole frames from data, remaps them to the output channel
+// count and appends the interleaved samples to dst.
+func (c *converter) mix(dst []float64, data []byte) []float64 {
+	sampleSize := c.from.SampleSize()
+	inCh, outCh := c.from.Channels, c.to.Channels
+
+	for frame := 0; frame+c.from.FrameSize() <= len(data); frame += c.from.FrameSize() {
+		sample := func(ch int) float64 {
+			offset := frame + ch*sampleSize
+
+			return decodeSample(c.from, data[offset:offset+sampleSize])
+		}
+
+		switch {
+		case outCh == 1 && inCh > 1:
+			var sum float64
+			for ch := range inCh {
+				sum += sample(ch)
+			}
+
+			dst = append(dst, sum/float64(inCh))
+		default:
+			// Matching layouts copy channels across; extra output
+			// channels repeat the last input channel.
+			for ch := range outCh {
+				dst = append(dst, sample(min(ch, inCh-1)))
+			}
+		}
+	}
+
+	return dst
+}
+
+// ExtractChannel returns a reader that yields channel ch (zero-based) of the
+// PCM read from r as mono audio in the same sample format.
+func ExtractChannel(r io.Reader, f Format, ch int) (io.Reader, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	if ch < 0 || ch >= f.Channels {
+		return nil, fmt.Errorf("channel %d out of range: input has %d channels", ch+1, f.Channels)
+	}
+
+	if f.Channels == 1 {
+		return r, nil
+	}
+
+	return &channelReader{r: r, f: f, ch: ch, in: make([]byte, convertFrames*f.FrameSize())}, nil
+}
+
+// channelReader is the reader returned by ExtractChannel.
+type channelReader struct {
+	r  io.Reader
+	f  Format
+	ch int
+
+	in      []byte
+	partial int
+	out     bytes.Buffer
+	err     error
+}
+
+func (c *channelReader) Read(p []byte) (int, error) {
+	for c.out.Len() == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		n, err := io.ReadAtLeast(c.r, c.in[c.partial:], 1)
+		n += c.partial
+
+		frameSize, sampleSize := c.f.FrameSize(), c.f.SampleSize()
+		whole := n - n%frameSize
+
+		for frame := 0; frame < whole; frame += frameSize {
+			offset := frame + c.ch*sampleSize
+			c.out.Write(c.in[offset : offset+sampleSize])
+		}
+
+		c.partial = copy(c.in, c.in[whole:n])
+
+		switch {
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			c.err = io.EOF
+		case err != nil:
+			c.err = fmt.Errorf("reading PCM: %w", err)
+		}
+	}
+
+	return c.out.Read(p) //nolint:wrapcheck // bytes.Buffer only fails when empty
+}
+
+// decodeSample converts one little-endian sample to the range [-1, 1).
+func decodeSample(f Format, b []byte) float64 {
+	switch {
+	case f.Float && f.BitDepth == 32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case f.Float:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case f.BitDepth == 8:
+		return (float64(b[0]) - 128) / 128
+	case f.BitDepth == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) // #nosec G115 -- reinterpreting bits
+	case f.BitDepth == 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16 // #nosec G115 -- sign extension
+
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) // #nosec G115 -- reinterpreting bits
+	}
+}
+
+// appendSample appends v to dst as one little-endian sample, clipping
+// values outside [-1, 1).
+func appendSample(dst []byte, f Format, v float64) []byte {
+	if f.Float {
+		if f.BitDepth == 32 {
+			return binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(v)))
+		}
+
+		return binary.LittleEndian.AppendUint64(dst, math.Float64bits(v))
+	}
+
+	scale := float64(int64(1) << (f.BitDepth - 1))
+	q := int64(max(-scale, min(scale-1, math.Round(v*scale))))
+
+	switch f.BitDepth {
+	case 8:
+		return append(dst, byte(q+128)) // #nosec G115 -- clipped to [0, 255]
+	case 16:
+		return binary.LittleEndian.AppendUint16(dst, uint16(q)) // #nosec G115 -- two's complement
+	case 24:
+		return append(dst, byte(q), byte(q>>8), byte(q>>16)) // #nosec G115 -- two's complement
+	default:
+		return binary.LittleEndian.AppendUint32(dst, uint32(q)) // #nosec G115 -- two's complement
+	}
+}
@@ -0,0 +1,221 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// s16 encodes samples as 16-bit little-endian PCM.
+func s16(samples ...int16) []byte {
+	out := make([]byte, 0, 2*len(samples))
+	for _, s := range samples {
+		out = binary.LittleEndian.AppendUint16(out, uint16(s))
+	}
+
+	return out
+}
+
+// samples16 decodes 16-bit little-endian PCM.
+func samples16(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+	}
+
+	return out
+}
+
+// sine returns n frames of a mono float64 sine wave at freq Hz.
+func sine(freq float64, rate, n int) []byte {
+	out := make([]byte, 0, 8*n)
+	for i := range n {
+		v := 0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		out = binary.LittleEndian.AppendUint64(out, math.Float64bits(v))
+	}
+
+	return out
+}
+
+func convert(t *testing.T, in []byte, from, to audio.Format) []byte {
+	t.Helper()
+
+	r, err := audio.Convert(iotest.HalfReader(bytes.NewReader(in)), from, to)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading converted audio: %v", err)
+	}
+
+	return out
+}
+
+func TestConvertSampleFormats(t *testing.T) {
+	t.Parallel()
+
+	mono16 := audio.Format{SampleRate: 8000, Channels: 1, BitDepth: 16}
+	want := []int16{0, 16384, -16384, 32767, -32768}
+
+	tests := []struct {
+		name string
+		from audio.Format
+		in   []byte
+	}{
+		{"u8", audio.Format{SampleRate: 8000, Channels: 1, BitDepth: 8}, []byte{128, 192, 64, 255, 0}},
+		{"s16", mono16, s16(want...)},
+		{"s24", audio.Format{SampleRate: 8000, Channels: 1, BitDepth: 24}, []byte{
+			0, 0, 0, 0, 0, 0x40, 0, 0, 0xC0, 0xFF, 0xFF, 0x7F, 0, 0, 0x80,
+		}},
+		{"f32", audio.Format{SampleRate: 8000, Channels: 1, BitDepth: 32, Float: true}, func() []byte {
+			var out []byte
+			for _, v := range []float32{0, 0.5, -0.5, 1.5, -1} {
+				out = binary.LittleEndian.AppendUint32(out, math.Float32bits(v))
+			}
+
+			return out
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := samples16(convert(t, tt.in, tt.from, mono16))
+			if len(got) != len(want) {
+				t.Fatalf("got %d samples, want %d", len(got), len(want))
+			}
+
+			for i := range want {
+				// 8-bit input cannot represent the extremes exactly.
+				if diff := int(got[i]) - int(want[i]); diff < -256 || diff > 256 {
+					t.Errorf("sample %d = %d, want %d", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvertChannels(t *testing.T) {
+	t.Parallel()
+
+	stereo := audio.Format{SampleRate: 16000, Channels: 2, BitDepth: 16}
+
+	if got := samples16(convert(t, s16(100, 300, -200, 0), stereo, audio.Speech)); !equal(got, []int16{200, -100}) {
+		t.Errorf("downmix = %v, want [200 -100]", got)
+	}
+
+	if got := samples16(convert(t, s16(7, -7), audio.Speech, stereo)); !equal(got, []int16{7, 7, -7, -7}) {
+		t.Errorf("upmix = %v, want [7 7 -7 -7]", got)
+	}
+}
+
+func TestConvertResample(t *testing.T) {
+	t.Parallel()
+
+	const (
+		inRate = 48000
+		frames = inRate / 2
+	)
+
+	from := audio.Format{SampleRate: inRate, Channels: 1, BitDepth: 64, Float: true}
+
+	tests := []struct {
+		name    string
+		freq    float64
+		maxPeak float64
+		minPeak float64
+	}{
+		// A 440 Hz tone passes through at its original level.
+		{name: "passband", freq: 440, minPeak: 0.45, maxPeak: 0.55},
+		// A 12 kHz tone is above the 8 kHz output Nyquist and must not alias.
+		{name: "stopband", freq: 12000, maxPeak: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			out := samples16(convert(t, sine(tt.freq, inRate, frames), from, audio.Speech))
+
+			if len(out) < frames/3-1 || len(out) > frames/3+1 {
+				t.Fatalf("got %d frames, want about %d", len(out), frames/3)
+			}
+
+			// Ignore the filter's ramp at either edge.
+			var peak float64
+			for _, s := range out[100 : len(out)-100] {
+				peak = max(peak, math.Abs(float64(s)/32768))
+			}
+
+			if peak < tt.minPeak || peak > tt.maxPeak {
+				t.Errorf("peak = %.3f, want between %.3f and %.3f", peak, tt.minPeak, tt.maxPeak)
+			}
+		})
+	}
+}
+
+func TestConvertSameFormat(t *testing.T) {
+	t.Parallel()
+
+	in := bytes.NewReader(s16(1, 2, 3))
+
+	r, err := audio.Convert(in, audio.Speech, audio.Speech)
+	if err != nil || r != io.Reader(in) {
+		t.Errorf("Convert() = %v, %v; want the input reader back", r, err)
+	}
+
+	if _, err := audio.Convert(in, audio.Format{SampleRate: 8000, Channels: 1, BitDepth: 12}, audio.Speech); err == nil {
+		t.Error("expected an error for 12-bit input")
+	}
+}
+
+func TestExtractChannel(t *testing.T) {
+	t.Parallel()
+
+	stereo := audio.Format{SampleRate: 16000, Channels: 2, BitDepth: 16}
+
+	r, err := audio.ExtractChannel(iotest.OneByteReader(bytes.NewReader(s16(1, -1, 2, -2, 3, -3))), stereo, 1)
+	if err != nil {
+		t.Fatalf("ExtractChannel() error = %v", err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := samples16(out); !equal(got, []int16{-1, -2, -3}) {
+		t.Errorf("right channel = %v, want [-1 -2 -3]", got)
+	}
+
+	if _, err := audio.ExtractChannel(bytes.NewReader(nil), stereo, 2); err == nil {
+		t.Error("expected an error for channel 3 of a stereo input")
+	}
+}
+
+func equal(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
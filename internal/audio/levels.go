@@ -0,0 +1,174 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// MinLevel is the lowest level Analyze reports, in dBFS; digital silence
+	// measures this rather than minus infinity.
+	MinLevel = -120.0
+
+	// levelBlock is the length of the blocks of audio whose levels are
+	// compared to find the noise floor and speech.
+	levelBlock = 20 * time.Millisecond
+	// levelBins is the number of histogram bins per dB.
+	levelBins = 2
+	// noisePercentile and signalPercentile pick the block levels taken as
+	// the noise floor and the level of speech.
+	noisePercentile  = 0.10
+	signalPercentile = 0.95
+	// speechMargin is how far above the noise floor a block must be to count
+	// as speech.
+	speechMargin = 10.0
+)
+
+// Levels describes the loudness of a stream of PCM audio.
+type Levels struct {
+	Duration time.Duration
+	// RMS and Peak are the overall root-mean-square and peak levels in dBFS.
+	RMS  float64
+	Peak float64
+	// Clipping is the fraction of samples at full scale.
+	Clipping float64
+	// NoiseFloor is the level in dBFS of the quietest tenth of 20 ms blocks,
+	// and SNR how far in dB the loudest blocks rise above it: an estimate of
+	// the signal-to-noise ratio of speech.
+	NoiseFloor float64
+	SNR        float64
+	// Speech is the length of the blocks loud enough to be speech and
+	// SpeechRatio its share of Duration.
+	Speech      time.Duration
+	SpeechRatio float64
+}
+
+// Analyze measures the PCM audio read from r, in format f. Blocks of 20 ms
+// count as speech when they are at least 10 dB above the noise floor and no
+// quieter than silence, in dBFS: an energy estimate that does not tell
+// speech from music or other loud sounds.
+func Analyze(r io.Reader, f Format, silence float64) (Levels, error) {
+	if err := f.Validate(); err != nil {
+		return Levels{}, err
+	}
+
+	blockLen := max(1, f.Frames(levelBlock))
+	buf := make([]byte, blockLen*int64(f.FrameSize()))
+	clip := clipLevel(f)
+
+	// histogram counts the blocks by level.
+	var (
+		histogram        [-MinLevel*levelBins + 1]int64
+		blocks, frames   int64
+		samples, clipped int64
+		sumSquares, peak float64
+	)
+
+	sampleSize := f.SampleSize()
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		n -= n % f.FrameSize()
+
+		if n > 0 {
+			var blockSquares float64
+
+			for i := 0; i < n; i += sampleSize {
+				v := math.Abs(decodeSample(f, buf[i:]))
+				blockSquares += v * v
+				peak = max(peak, v)
+
+				if v >= clip {
+					clipped++
+				}
+			}
+
+			count := int64(n / sampleSize)
+			samples += count
+			sumSquares += blockSquares
+			frames += int64(n / f.FrameSize())
+			blocks++
+
+			histogram[levelBin(decibels(blockSquares/float64(count)))]++
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return Levels{}, fmt.Errorf("reading PCM: %w", err)
+		}
+	}
+
+	if samples == 0 {
+		return Levels{RMS: MinLevel, Peak: MinLevel, NoiseFloor: MinLevel}, nil
+	}
+
+	levels := Levels{
+		Duration:   f.Duration(frames),
+		RMS:        decibels(sumSquares / float64(samples)),
+		Peak:       decibels(peak * peak),
+		Clipping:   float64(clipped) / float64(samples),
+		NoiseFloor: percentile(histogram[:], blocks, noisePercentile),
+	}
+	levels.SNR = percentile(histogram[:], blocks, signalPercentile) - levels.NoiseFloor
+
+	threshold := max(levels.NoiseFloor+speechMargin, silence)
+
+	var speech int64
+	for bin := levelBin(threshold); bin < len(histogram); bin++ {
+		speech += histogram[bin]
+	}
+
+	levels.SpeechRatio = float64(speech) / float64(blocks)
+	levels.Speech = min(levels.Duration, f.Duration(speech*blockLen))
+
+	return levels, nil
+}
+
+// clipLevel returns the magnitude at which a sample in format f is at full
+// scale: the largest positive integer value, or 1 for floating point.
+func clipLevel(f Format) float64 {
+	if f.Float {
+		return 1
+	}
+
+	return 1 - 1/float64(int64(1)<<(f.BitDepth-1))
+}
+
+// decibels converts a mean square amplitude to dBFS, no lower than MinLevel.
+func decibels(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return MinLevel
+	}
+
+	return max(MinLevel, 10*math.Log10(meanSquare))
+}
+
+// levelBin returns the histogram bin of a level in dBFS.
+func levelBin(db float64) int {
+	return int(math.Round((min(0, max(MinLevel, db)) - MinLevel) * levelBins))
+}
+
+// percentile returns the level below which the fraction p of the total
+// blocks in histogram lie.
+func percentile(histogram []int64, total int64, p float64) float64 {
+	target := int64(math.Ceil(p * float64(total)))
+
+	var seen int64
+
+	for bin, n := range histogram {
+		if seen += n; seen >= target {
+			return MinLevel + float64(bin)/levelBins
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,113 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// pcm16 encodes samples in [-1, 1] as 16-bit PCM, clipping at full scale.
+func pcm16(samples []float64) []byte {
+	out := make([]byte, 0, 2*len(samples))
+
+	for _, v := range samples {
+		q := int16(max(math.MinInt16, min(math.MaxInt16, math.Round(v*(1<<15)))))
+		out = binary.LittleEndian.AppendUint16(out, uint16(q)) // #nosec G115 -- two's complement
+	}
+
+	return out
+}
+
+// recording returns 3 s of faint noise followed by 1 s of a tone of the
+// given amplitude at 16 kHz.
+func recording(amplitude float64) []float64 {
+	rng := rand.New(rand.NewPCG(1, 2)) // #nosec G404 -- reproducible test noise
+
+	samples := make([]float64, 0, 4*16000)
+	for range 3 * 16000 {
+		samples = append(samples, 0.001*(2*rng.Float64()-1))
+	}
+
+	for i := range 16000 {
+		samples = append(samples, amplitude*math.Sin(2*math.Pi*440*float64(i)/16000))
+	}
+
+	return samples
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	levels, err := audio.Analyze(bytes.NewReader(pcm16(recording(0.1))), audio.Speech, -45)
+	if err != nil {
+		t.Fatalf("Analyze() unexpected error: %v", err)
+	}
+
+	if levels.Duration != 4*time.Second {
+		t.Errorf("Duration = %v, want 4s", levels.Duration)
+	}
+
+	// The tone peaks at -20 dBFS; the noise is some 40 dB quieter.
+	if math.Abs(levels.Peak+20) > 0.1 {
+		t.Errorf("Peak = %.2f dBFS, want -20", levels.Peak)
+	}
+
+	if levels.NoiseFloor > -60 || levels.SNR < 35 {
+		t.Errorf("NoiseFloor = %.1f dBFS, SNR = %.1f dB; want a floor below -60 and SNR above 35",
+			levels.NoiseFloor, levels.SNR)
+	}
+
+	if math.Abs(levels.SpeechRatio-0.25) > 0.01 || levels.Speech != time.Second {
+		t.Errorf("speech = %v (%.3f), want 1s (0.25)", levels.Speech, levels.SpeechRatio)
+	}
+
+	if levels.Clipping != 0 {
+		t.Errorf("Clipping = %v, want 0", levels.Clipping)
+	}
+}
+
+func TestAnalyzeClipping(t *testing.T) {
+	t.Parallel()
+
+	// A tone twice full scale is flattened at both ends of every cycle.
+	levels, err := audio.Analyze(bytes.NewReader(pcm16(recording(2))), audio.Speech, -45)
+	if err != nil {
+		t.Fatalf("Analyze() unexpected error: %v", err)
+	}
+
+	// Two thirds of each cycle exceeds full scale, over a quarter of the
+	// recording.
+	if math.Abs(levels.Clipping-0.25*2/3) > 0.01 || levels.Peak != 0 {
+		t.Errorf("Clipping = %.3f, Peak = %.2f; want 0.167 and 0 dBFS", levels.Clipping, levels.Peak)
+	}
+}
+
+func TestAnalyzeSilence(t *testing.T) {
+	t.Parallel()
+
+	for _, pcm := range [][]byte{nil, make([]byte, 32000)} {
+		levels, err := audio.Analyze(bytes.NewReader(pcm), audio.Speech, -45)
+		if err != nil {
+			t.Fatalf("Analyze() unexpected error: %v", err)
+		}
+
+		if levels.RMS != audio.MinLevel || levels.Peak != audio.MinLevel || levels.Speech != 0 ||
+			levels.SpeechRatio != 0 || levels.SNR != 0 {
+			t.Errorf("Analyze(%d bytes of silence) = %+v", len(pcm), levels)
+		}
+	}
+
+	if _, err := audio.Analyze(bytes.NewReader(nil), audio.Format{}, -45); err == nil {
+		t.Error("Analyze() accepted an invalid format")
+	}
+}
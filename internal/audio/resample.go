@@ -0,0 +1,117 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio
+
+import "math"
+
+const (
+	// zeroCrossings is the number of sinc lobes on each side of the filter
+	// at the narrower of the two rates.
+	zeroCrossings = 8
+	// tableResolution is the number of kernel samples per input frame in
+	// the precomputed filter table.
+	tableResolution = 128
+)
+
+// resampler changes the sample rate of interleaved float samples with a
+// Hann-windowed sinc filter. When downsampling the filter is widened so its
+// cutoff sits at the output Nyquist frequency, which keeps aliasing out of
+// the band speech lives in.
+type resampler struct {
+	channels int
+	step     float64 // input frames per output frame
+	half     int     // filter half-width in input frames
+	table    []float64
+
+	buf  []float64 // buffered input frames, the first at index base
+	base int64
+	next float64 // input position of the next output frame
+}
+
+// newResampler returns a resampler from inRate to outRate for interleaved
+// audio with the given number of channels.
+func newResampler(inRate, outRate, channels int) *resampler {
+	cutoff := min(1, float64(outRate)/float64(inRate))
+	half := int(math.Ceil(zeroCrossings / cutoff))
+
+	table := make([]float64, half*tableResolution+1)
+	for i := range table {
+		x := float64(i) / tableResolution
+		window := 0.5 + 0.5*math.Cos(math.Pi*x/float64(half))
+		table[i] = cutoff * sinc(cutoff*x) * window
+	}
+
+	return &resampler{
+		channels: channels,
+		step:     float64(inRate) / float64(outRate),
+		half:     half,
+		table:    table,
+	}
+}
+
+// process appends in to the buffered input and returns every output sample
+// that can be computed from it. With flush set the input is complete: the
+// remaining output is produced with the signal taken as silent after it.
+func (r *resampler) process(in []float64, flush bool) []float64 {
+	r.buf = append(r.buf, in...)
+	end := r.base + int64(len(r.buf)/r.channels)
+
+	var out []float64
+
+	for {
+		center := int64(math.Floor(r.next))
+		if flush && r.next >= float64(end) || !flush && center+int64(r.half) >= end {
+			break
+		}
+
+		for ch := range r.channels {
+			var acc float64
+
+			for k := center - int64(r.half) + 1; k <= center+int64(r.half); k++ {
+				if k >= r.base && k < end {
+					acc += r.kernel(r.next-float64(k)) * r.buf[int(k-r.base)*r.channels+ch]
+				}
+			}
+
+			out = append(out, acc)
+		}
+
+		r.next += r.step
+	}
+
+	// Drop the frames no later output frame reaches back to.
+	if drop := int64(math.Floor(r.next)) - int64(r.half) + 1 - r.base; drop > 0 {
+		drop = min(drop, end-r.base)
+		r.buf = r.buf[:copy(r.buf, r.buf[int(drop)*r.channels:])]
+		r.base += drop
+	}
+
+	return out
+}
+
+// kernel returns the filter response at distance x input frames, linearly
+// interpolated from the table.
+func (r *resampler) kernel(x float64) float64 {
+	pos := math.Abs(x) * tableResolution
+
+	i := int(pos)
+	if i >= len(r.table)-1 {
+		return 0
+	}
+
+	frac := pos - float64(i)
+
+	return r.table[i] + frac*(r.table[i+1]-r.table[i])
+}
+
+// sinc is the normalised sinc function sin(πx)/(πx).
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
@@ -0,0 +1,90 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Range is a span of audio from Start up to End. An End of zero means the
+// range runs to the end of the stream.
+type Range struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Slice returns a reader that yields only the audio between start and end of
+// the PCM read from r; an end of zero reads to the end of the stream.
+func Slice(r io.Reader, f Format, start, end time.Duration) io.Reader {
+	return Select(r, f, []Range{{Start: start, End: end}})
+}
+
+// Select returns a reader that yields only the audio within ranges, which
+// must be in order and must not overlap, concatenated. Everything else read
+// from r is discarded. Range bounds are rounded down to whole frames.
+func Select(r io.Reader, f Format, ranges []Range) io.Reader {
+	frameSize := int64(f.FrameSize())
+	spans := make([]byteSpan, 0, len(ranges))
+
+	for _, rg := range ranges {
+		span := byteSpan{start: f.Frames(rg.Start) * frameSize, end: -1}
+		if rg.End > 0 {
+			span.end = f.Frames(rg.End) * frameSize
+		}
+
+		spans = append(spans, span)
+	}
+
+	return &selectReader{r: r, spans: spans}
+}
+
+// byteSpan is a Range converted to byte offsets; an end of -1 is open.
+type byteSpan struct {
+	start, end int64
+}
+
+// selectReader is the reader returned by Select.
+type selectReader struct {
+	r     io.Reader
+	spans []byteSpan
+	pos   int64
+}
+
+func (s *selectReader) Read(p []byte) (int, error) {
+	for len(s.spans) > 0 && s.spans[0].end >= 0 && s.pos >= s.spans[0].end {
+		s.spans = s.spans[1:]
+	}
+
+	if len(s.spans) == 0 {
+		return 0, io.EOF
+	}
+
+	span := s.spans[0]
+
+	if s.pos < span.start {
+		n, err := io.CopyN(io.Discard, s.r, span.start-s.pos)
+		s.pos += n
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return 0, io.EOF
+		case err != nil:
+			return 0, fmt.Errorf("skipping PCM: %w", err)
+		}
+	}
+
+	if span.end >= 0 {
+		p = p[:min(int64(len(p)), span.end-s.pos)]
+	}
+
+	n, err := s.r.Read(p)
+	s.pos += int64(n)
+
+	return n, err //nolint:wrapcheck // pass the underlying reader's io.EOF through
+}
@@ -0,0 +1,72 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	// Ten frames per second, so every frame is 100ms.
+	format := audio.Format{SampleRate: 10, Channels: 1, BitDepth: 16}
+	in := s16(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	tests := []struct {
+		name   string
+		ranges []audio.Range
+		want   []int16
+	}{
+		{"slice", []audio.Range{{Start: 200 * time.Millisecond, End: 500 * time.Millisecond}}, []int16{2, 3, 4}},
+		{"open end", []audio.Range{{Start: 700 * time.Millisecond}}, []int16{7, 8, 9}},
+		{"several", []audio.Range{
+			{Start: 0, End: 200 * time.Millisecond},
+			{Start: 500 * time.Millisecond, End: 600 * time.Millisecond},
+			{Start: 800 * time.Millisecond},
+		}, []int16{0, 1, 5, 8, 9}},
+		{"past the end", []audio.Range{{Start: 2 * time.Second}}, []int16{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := audio.Select(iotest.HalfReader(bytes.NewReader(in)), format, tt.ranges)
+
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+
+			if got := samples16(out); !equal(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	t.Parallel()
+
+	format := audio.Format{SampleRate: 4, Channels: 2, BitDepth: 16}
+	in := s16(0, 0, 1, 1, 2, 2, 3, 3)
+
+	out, err := io.ReadAll(audio.Slice(bytes.NewReader(in), format, 250*time.Millisecond, 750*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := samples16(out); !equal(got, []int16{1, 1, 2, 2}) {
+		t.Errorf("Slice() = %v, want [1 1 2 2]", got)
+	}
+}
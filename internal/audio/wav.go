@@ -0,0 +1,281 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+// Package audio reads, converts and writes uncompressed PCM audio in pure Go,
+// so WAV and raw PCM inputs can be processed without FFmpeg.
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// HeaderSize is the size of the canonical WAV header written by WriteHeader.
+const HeaderSize = 44
+
+// streamedSize is the placeholder data size of a WAV stream whose length is
+// not known when its header is written, as used by FFmpeg on pipes.
+const streamedSize = 0xFFFFFFFF
+
+// WAVE format tags.
+const (
+	tagPCM        = 0x0001
+	tagFloat      = 0x0003
+	tagExtensible = 0xFFFE
+)
+
+var (
+	// ErrNotWAV is returned for input that is not a RIFF/WAVE file.
+	ErrNotWAV = errors.New("not a WAV file")
+	// ErrUnsupported is returned for WAV encodings other than integer PCM
+	// (8, 16, 24 or 32 bits) and IEEE float (32 or 64 bits).
+	ErrUnsupported = errors.New("unsupported WAV encoding")
+)
+
+// Format describes interleaved, little-endian PCM audio.
+type Format struct {
+	SampleRate int
+	Channels   int
+	// BitDepth is the size of one sample in bits.
+	BitDepth int
+	// Float marks IEEE floating-point samples; otherwise samples are
+	// integers, unsigned for 8 bits and signed for wider ones.
+	Float bool
+}
+
+// Speech is 16 kHz mono 16-bit PCM, the format speech is transcribed in.
+var Speech = Format{SampleRate: 16000, Channels: 1, BitDepth: 16}
+
+// String formats f as e.g. "16000 Hz, 1 ch, s16".
+func (f Format) String() string {
+	kind := "s"
+
+	switch {
+	case f.Float:
+		kind = "f"
+	case f.BitDepth == 8:
+		kind = "u"
+	}
+
+	return fmt.Sprintf("%d Hz, %d ch, %s%d", f.SampleRate, f.Channels, kind, f.BitDepth)
+}
+
+// Validate reports whether f is a format this package can process.
+func (f Format) Validate() error {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupported, f)
+	}
+
+	switch {
+	case f.Float && (f.BitDepth == 32 || f.BitDepth == 64):
+	case !f.Float && (f.BitDepth == 8 || f.BitDepth == 16 || f.BitDepth == 24 || f.BitDepth == 32):
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, f)
+	}
+
+	return nil
+}
+
+// SampleSize returns the number of bytes per sample.
+func (f Format) SampleSize() int { return f.BitDepth / 8 }
+
+// FrameSize returns the number of bytes per sample frame (one sample for
+// every channel).
+func (f Format) FrameSize() int { return f.Channels * f.SampleSize() }
+
+// ByteRate returns the number of bytes per second of audio.
+func (f Format) ByteRate() int { return f.SampleRate * f.FrameSize() }
+
+// Frames converts a duration to a whole number of sample frames.
+func (f Format) Frames(d time.Duration) int64 {
+	return int64(d) * int64(f.SampleRate) / int64(time.Second)
+}
+
+// Duration converts a number of sample frames to a duration.
+func (f Format) Duration(frames int64) time.Duration {
+	return time.Duration(frames * int64(time.Second) / int64(f.SampleRate))
+}
+
+// Header is the parsed header of a WAV file.
+type Header struct {
+	Format Format
+	// DataSize is the size of the sample data the header declares. Streams
+	// written to a pipe may declare a placeholder, so treat it as an upper
+	// bound and read until EOF.
+	DataSize int64
+}
+
+// Duration returns the playback length the header declares, or zero when
+// its data size is a streaming placeholder.
+func (h Header) Duration() time.Duration {
+	if h.DataSize >= streamedSize || h.DataSize == 0 {
+		return 0
+	}
+
+	return h.Format.Duration(h.DataSize / int64(h.Format.FrameSize()))
+}
+
+// ReadHeader consumes RIFF/WAVE chunks from r up to the start of the sample
+// data and returns the header. Chunks other than "fmt " and "data" (such as
+// LIST metadata) are skipped.
+func ReadHeader(r io.Reader) (Header, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return Header{}, fmt.Errorf("reading WAV header: %w", err)
+	}
+
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return Header{}, ErrNotWAV
+	}
+
+	var (
+		format Format
+		seen   bool
+	)
+
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return Header{}, fmt.Errorf("reading WAV chunk header: %w", err)
+		}
+
+		id, size := string(hdr[0:4]), int64(binary.LittleEndian.Uint32(hdr[4:8]))
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size+size&1)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Header{}, fmt.Errorf("reading WAV fmt chunk: %w", err)
+			}
+
+			f, err := parseFmtChunk(body)
+			if err != nil {
+				return Header{}, err
+			}
+
+			format, seen = f, true
+		case "data":
+			if !seen {
+				return Header{}, fmt.Errorf("%w: data chunk before fmt chunk", ErrNotWAV)
+			}
+
+			return Header{Format: format, DataSize: size}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size&1); err != nil {
+				return Header{}, fmt.Errorf("skipping WAV %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// parseFmtChunk decodes the body of a "fmt " chunk.
+func parseFmtChunk(body []byte) (Format, error) {
+	if len(body) < 16 {
+		return Format{}, fmt.Errorf("%w: short fmt chunk", ErrNotWAV)
+	}
+
+	tag := binary.LittleEndian.Uint16(body[0:2])
+
+	// WAVE_FORMAT_EXTENSIBLE carries the real tag at the start of its
+	// sub-format GUID.
+	if tag == tagExtensible && len(body) >= 26 {
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	f := Format{
+		Channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+		BitDepth:   int(binary.LittleEndian.Uint16(body[14:16])),
+		Float:      tag == tagFloat,
+	}
+
+	if tag != tagPCM && tag != tagFloat {
+		return Format{}, fmt.Errorf("%w: format tag %#04x", ErrUnsupported, tag)
+	}
+
+	if err := f.Validate(); err != nil {
+		return Format{}, err
+	}
+
+	return f, nil
+}
+
+// WriteHeader writes a canonical 44-byte WAV header for dataSize bytes of
+// audio in format f. A negative dataSize writes the placeholder used for
+// streams of unknown length.
+func WriteHeader(w io.Writer, f Format, dataSize int64) error {
+	var buf [HeaderSize]byte
+
+	riffSize, size := uint32(streamedSize), uint32(streamedSize)
+	if dataSize >= 0 && dataSize < streamedSize-36 {
+		riffSize, size = uint32(36+dataSize), uint32(dataSize) // #nosec G115 -- bounds checked above
+	}
+
+	tag := uint16(tagPCM)
+	if f.Float {
+		tag = tagFloat
+	}
+
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], riffSize)
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], tag)
+	binary.LittleEndian.PutUint16(buf[22:24], uint16(f.Channels))    // #nosec G115
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(f.SampleRate))  // #nosec G115
+	binary.LittleEndian.PutUint32(buf[28:32], uint32(f.ByteRate()))  // #nosec G115
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(f.FrameSize())) // #nosec G115
+	binary.LittleEndian.PutUint16(buf[34:36], uint16(f.BitDepth))    // #nosec G115
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], size)
+
+	if _, err := w.Write(buf[:]); err != nil {
+		return fmt.Errorf("writing WAV header: %w", err)
+	}
+
+	return nil
+}
+
+// Encode wraps pcm in a canonical WAV header.
+func Encode(f Format, pcm []byte) []byte {
+	var buf bytes.Buffer
+
+	buf.Grow(HeaderSize + len(pcm))
+	_ = WriteHeader(&buf, f, int64(len(pcm))) // writes to a bytes.Buffer cannot fail
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
+
+// NewStream returns a WAV stream of unknown length: a header with a
+// placeholder size followed by the PCM read from pcm.
+func NewStream(f Format, pcm io.Reader) io.Reader {
+	var header bytes.Buffer
+
+	_ = WriteHeader(&header, f, -1) // writes to a bytes.Buffer cannot fail
+
+	return io.MultiReader(&header, pcm)
+}
+
+// ParseDuration returns the playback length of the complete WAV file in
+// data. A data size larger than the payload, as streamed WAVs declare, is
+// clamped to it. It reports false when data is not a readable WAV file.
+func ParseDuration(data []byte) (time.Duration, bool) {
+	r := bytes.NewReader(data)
+
+	h, err := ReadHeader(r)
+	if err != nil {
+		return 0, false
+	}
+
+	size := min(h.DataSize, int64(r.Len()))
+
+	return h.Format.Duration(size / int64(h.Format.FrameSize())), true
+}
@@ -0,0 +1,186 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// extensibleWAV builds a WAVE_FORMAT_EXTENSIBLE file with a LIST chunk
+// before the data, as written by many recorders.
+func extensibleWAV(t *testing.T, channels, rate, bits int, subTag uint16, pcm []byte) []byte {
+	t.Helper()
+
+	var fmtBody bytes.Buffer
+
+	for _, v := range []any{
+		uint16(0xFFFE), uint16(channels), uint32(rate), uint32(rate * channels * bits / 8),
+		uint16(channels * bits / 8), uint16(bits), uint16(22), uint16(bits), uint32(3), subTag,
+	} {
+		_ = binary.Write(&fmtBody, binary.LittleEndian, v)
+	}
+
+	fmtBody.Write(make([]byte, 14)) // rest of the sub-format GUID
+
+	var body bytes.Buffer
+
+	body.WriteString("WAVE")
+	chunk := func(id string, data []byte) {
+		body.WriteString(id)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(data)))
+		body.Write(data)
+
+		if len(data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	chunk("fmt ", fmtBody.Bytes())
+	chunk("LIST", []byte("INFOisft\x03\x00\x00\x00abc"))
+	chunk("data", pcm)
+
+	var out bytes.Buffer
+
+	out.WriteString("RIFF")
+	_ = binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+
+	return out.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data []byte
+		want audio.Format
+	}{
+		{
+			name: "canonical",
+			data: audio.Encode(audio.Speech, make([]byte, 32000)),
+			want: audio.Speech,
+		},
+		{
+			name: "float",
+			data: audio.Encode(audio.Format{SampleRate: 48000, Channels: 2, BitDepth: 32, Float: true}, make([]byte, 8)),
+			want: audio.Format{SampleRate: 48000, Channels: 2, BitDepth: 32, Float: true},
+		},
+		{
+			name: "extensible 24-bit with LIST chunk",
+			data: extensibleWAV(t, 2, 44100, 24, 1, make([]byte, 6*44100)),
+			want: audio.Format{SampleRate: 44100, Channels: 2, BitDepth: 24},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(tt.data)
+
+			h, err := audio.ReadHeader(r)
+			if err != nil {
+				t.Fatalf("ReadHeader() error = %v", err)
+			}
+
+			if h.Format != tt.want {
+				t.Errorf("Format = %v, want %v", h.Format, tt.want)
+			}
+
+			if int64(r.Len()) != h.DataSize {
+				t.Errorf("reader left at %d bytes before the end, data size %d", r.Len(), h.DataSize)
+			}
+		})
+	}
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := audio.ReadHeader(bytes.NewReader([]byte("ID3\x04not a wav file"))); !errors.Is(err, audio.ErrNotWAV) {
+		t.Errorf("MP3 input: error = %v, want ErrNotWAV", err)
+	}
+
+	alaw := extensibleWAV(t, 1, 8000, 8, 6, make([]byte, 16))
+	if _, err := audio.ReadHeader(bytes.NewReader(alaw)); !errors.Is(err, audio.ErrUnsupported) {
+		t.Errorf("A-law input: error = %v, want ErrUnsupported", err)
+	}
+
+	truncated := audio.Encode(audio.Speech, nil)[:30]
+	if _, err := audio.ReadHeader(bytes.NewReader(truncated)); err == nil {
+		t.Error("truncated header: expected an error")
+	}
+}
+
+func TestHeaderDuration(t *testing.T) {
+	t.Parallel()
+
+	h := audio.Header{Format: audio.Speech, DataSize: 48000}
+	if got := h.Duration(); got != 1500*time.Millisecond {
+		t.Errorf("Duration() = %v, want 1.5s", got)
+	}
+
+	h.DataSize = 0xFFFFFFFF
+	if got := h.Duration(); got != 0 {
+		t.Errorf("streamed Duration() = %v, want 0", got)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	data := audio.Encode(audio.Speech, make([]byte, 64000))
+
+	if got, ok := audio.ParseDuration(data); !ok || got != 2*time.Second {
+		t.Errorf("ParseDuration() = %v, %v; want 2s, true", got, ok)
+	}
+
+	// A streamed header declares more data than there is.
+	var streamed bytes.Buffer
+
+	_ = audio.WriteHeader(&streamed, audio.Speech, -1)
+	streamed.Write(make([]byte, 16000))
+
+	if got, ok := audio.ParseDuration(streamed.Bytes()); !ok || got != 500*time.Millisecond {
+		t.Errorf("streamed ParseDuration() = %v, %v; want 500ms, true", got, ok)
+	}
+
+	if _, ok := audio.ParseDuration([]byte("not audio")); ok {
+		t.Error("ParseDuration() of non-WAV data reported ok")
+	}
+}
+
+func TestNewStream(t *testing.T) {
+	t.Parallel()
+
+	pcm := []byte{1, 2, 3, 4}
+
+	var out bytes.Buffer
+	if _, err := out.ReadFrom(audio.NewStream(audio.Speech, bytes.NewReader(pcm))); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(out.Bytes())
+
+	h, err := audio.ReadHeader(r)
+	if err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+
+	if h.Format != audio.Speech || h.Duration() != 0 {
+		t.Errorf("header = %+v, want Speech with a streaming size", h)
+	}
+
+	if rest := out.Bytes()[audio.HeaderSize:]; !bytes.Equal(rest, pcm) {
+		t.Errorf("payload = %v, want %v", rest, pcm)
+	}
+}
@@ -0,0 +1,436 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+// Package cache stores transcription results on disk under content-derived
+// keys, so that transcribing the same audio with the same settings again
+// does not call the model.
+//
+// Each entry is a JSON file named after its key. A link is an entry that
+// holds only the key of another, which reading the link reads instead;
+// links are not listed and are removed with their target. Reading and
+// adding entries takes a shared lock on the cache directory and replaces
+// files atomically, so any number of processes can use one cache at once;
+// pruning and clearing take an exclusive lock.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// lockName is the file in the cache directory that is locked.
+	lockName = ".lock"
+	// tempPrefix starts the names of entries still being written.
+	tempPrefix = ".tmp-"
+	// entryExt is the extension of entry files.
+	entryExt = ".json"
+)
+
+// keyRe matches a valid key: lower-case hex, as produced by a hash.
+var keyRe = regexp.MustCompile(`^[0-9a-f]{16,128}$`)
+
+// Cache is a directory of cached values.
+type Cache struct {
+	dir string
+}
+
+// Entry describes a cached value.
+type Entry struct {
+	Key string `json:"key"`
+	// Input names the media the value was made from and Model the model
+	// that made it, for listings.
+	Input   string    `json:"input,omitempty"`
+	Model   string    `json:"model,omitempty"`
+	Created time.Time `json:"created"`
+	// Target, for a link, is the key of the entry it leads to.
+	Target string `json:"target,omitempty"`
+	// Size is the size of the entry on disk and Used when it was last read
+	// or written.
+	Size int64     `json:"-"`
+	Used time.Time `json:"-"`
+}
+
+// record is the content of an entry file.
+type record struct {
+	Entry
+
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// DefaultDir returns the cache directory used when none is configured:
+// voice-transcriber in the user's cache directory, such as ~/.cache on
+// Linux.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the user cache directory: %w", err)
+	}
+
+	return filepath.Join(dir, "voice-transcriber"), nil
+}
+
+// Open returns the cache in dir, creating the directory if needed.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory of c.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// path returns the file of the entry with key, spread over subdirectories
+// named after its first two characters.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+entryExt)
+}
+
+// Get decodes the value cached under key into v and marks the entry used.
+// A link is followed to its target. It reports false when there is no
+// such entry.
+func (c *Cache) Get(key string, v any) (bool, error) {
+	if !keyRe.MatchString(key) {
+		return false, fmt.Errorf("invalid cache key %q", key)
+	}
+
+	unlock, err := c.lock(false)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	rec, ok, err := c.read(key)
+	if !ok || err != nil {
+		return false, err
+	}
+
+	if rec.Target != "" {
+		if !keyRe.MatchString(rec.Target) {
+			return false, fmt.Errorf("decoding cache entry %s: invalid target %q", key, rec.Target)
+		}
+
+		if rec, ok, err = c.read(rec.Target); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	if err := json.Unmarshal(rec.Value, v); err != nil {
+		return false, fmt.Errorf("decoding cache entry %s: %w", rec.Key, err)
+	}
+
+	return true, nil
+}
+
+// read reads the entry with key and marks it used. It reports false when
+// there is no such entry.
+func (c *Cache) read(key string) (record, bool, error) {
+	path := c.path(key)
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path is built from a validated hex key
+	if errors.Is(err, fs.ErrNotExist) {
+		return record{}, false, nil
+	} else if err != nil {
+		return record{}, false, fmt.Errorf("reading cache entry: %w", err)
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return record{}, false, fmt.Errorf("decoding cache entry %s: %w", key, err)
+	}
+
+	// The modification time records the last use for pruning; failing to
+	// update it only makes the entry look older.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return rec, true, nil
+}
+
+// Put stores v under e.Key, replacing any entry with the same key. Created
+// is set when zero.
+func (c *Cache) Put(e Entry, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	e.Target = ""
+
+	return c.write(e, value)
+}
+
+// Link stores under e.Key a link to the entry with key target, replacing
+// any entry with the same key. Created is set when zero.
+func (c *Cache) Link(e Entry, target string) error {
+	if !keyRe.MatchString(target) {
+		return fmt.Errorf("invalid cache key %q", target)
+	}
+
+	e.Target = target
+
+	return c.write(e, nil)
+}
+
+// write stores the entry e with value.
+func (c *Cache) write(e Entry, value json.RawMessage) error {
+	if !keyRe.MatchString(e.Key) {
+		return fmt.Errorf("invalid cache key %q", e.Key)
+	}
+
+	if e.Created.IsZero() {
+		e.Created = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(record{Entry: e, Value: value})
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	unlock, err := c.lock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := c.path(e.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	return writeAtomic(path, data)
+}
+
+// writeAtomic writes data to a temporary file beside path and renames it
+// over path, so readers never see a partial entry.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// List returns every entry but links, most recently used first.
+func (c *Cache) List() ([]Entry, error) {
+	unlock, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, _, err := c.entries(false)
+
+	return entries, err
+}
+
+// Prune removes entries not used within maxAge, when it is positive, and
+// then the least recently used ones until the rest take at most maxSize
+// bytes, when it is positive, along with the links to them. It returns the
+// removed entries, not counting links.
+func (c *Cache) Prune(maxAge time.Duration, maxSize int64) ([]Entry, error) {
+	unlock, err := c.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, links, err := c.entries(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		removed []Entry
+		total   int64
+	)
+
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	// Entries are listed most recently used first, so the oldest go first.
+	kept := len(entries)
+
+	for ; kept > 0; kept-- {
+		e := entries[kept-1]
+
+		tooOld := maxAge > 0 && time.Since(e.Used) > maxAge
+		if !tooOld && (maxSize <= 0 || total <= maxSize) {
+			break
+		}
+
+		if err := c.remove(e.Key); err != nil {
+			return removed, err
+		}
+
+		total -= e.Size
+		removed = append(removed, e)
+	}
+
+	return removed, c.removeLinks(links, entries[:kept])
+}
+
+// Clear removes every entry and returns how many there were, not counting
+// links.
+func (c *Cache) Clear() (int, error) {
+	unlock, err := c.lock(true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, links, err := c.entries(true)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, e := range entries {
+		if err := c.remove(e.Key); err != nil {
+			return i, err
+		}
+	}
+
+	return len(entries), c.removeLinks(links, nil)
+}
+
+// removeLinks removes the links whose target is not among entries.
+func (c *Cache) removeLinks(links, entries []Entry) error {
+	kept := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		kept[e.Key] = true
+	}
+
+	for _, l := range links {
+		if kept[l.Target] {
+			continue
+		}
+
+		if err := c.remove(l.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove deletes the entry with key.
+func (c *Cache) remove(key string) error {
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// entries reads the descriptions of every entry and, apart, of every link,
+// most recently used first. Unreadable entries are skipped. With sweep
+// set, which needs the exclusive lock, it also removes temporary files left
+// behind by interrupted writes: no running Put can still own them.
+func (c *Cache) entries(sweep bool) (entries, links []Entry, err error) {
+	err = filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if sweep && strings.HasPrefix(d.Name(), tempPrefix) {
+			_ = os.Remove(path)
+
+			return nil
+		}
+
+		if d.IsDir() || !strings.HasSuffix(d.Name(), entryExt) {
+			return nil
+		}
+
+		switch e, ok := readEntry(path); {
+		case !ok:
+		case e.Target != "":
+			links = append(links, e)
+		default:
+			entries = append(entries, e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing cache entries: %w", err)
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int { return b.Used.Compare(a.Used) })
+
+	return entries, links, nil
+}
+
+// readEntry reads the description of the entry file at path.
+func readEntry(path string) (Entry, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path was found by walking the cache directory
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || !keyRe.MatchString(e.Key) ||
+		filepath.Base(path) != e.Key+entryExt {
+		return Entry{}, false
+	}
+
+	e.Size, e.Used = info.Size(), info.ModTime()
+
+	return e, true
+}
+
+// lock takes the lock on the cache directory, shared or exclusive, and
+// returns the function that releases it.
+func (c *Cache) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(c.dir, lockName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening cache lock: %w", err)
+	}
+
+	if err := lockFile(f, exclusive); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("locking cache: %w", err)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
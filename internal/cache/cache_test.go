@@ -0,0 +1,201 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
+)
+
+// testKey returns a valid key made of c.
+func testKey(c byte) string {
+	return strings.Repeat(string(c), 64)
+}
+
+type value struct {
+	Text  string
+	Words int
+}
+
+func TestPutGet(t *testing.T) {
+	t.Parallel()
+
+	c, err := cache.Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	var got value
+
+	if ok, err := c.Get(testKey('a'), &got); ok || err != nil {
+		t.Fatalf("Get() on an empty cache = %v, %v; want a miss", ok, err)
+	}
+
+	want := value{Text: "Добрий вечір.", Words: 2}
+	if err := c.Put(cache.Entry{Key: testKey('a'), Input: "talk.mp4", Model: "m"}, want); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if ok, err := c.Get(testKey('a'), &got); !ok || err != nil || got != want {
+		t.Fatalf("Get() = %+v, %v, %v; want %+v", got, ok, err, want)
+	}
+
+	entries, err := c.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %+v, %v; want one entry", entries, err)
+	}
+
+	if e := entries[0]; e.Key != testKey('a') || e.Input != "talk.mp4" || e.Model != "m" ||
+		e.Size == 0 || e.Created.IsZero() {
+		t.Errorf("entry = %+v", e)
+	}
+
+	if _, err := c.Get("../../etc/passwd", &got); err == nil {
+		t.Error("Get() accepted a key that is not hex")
+	}
+}
+
+func TestLink(t *testing.T) {
+	t.Parallel()
+
+	c, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	want := value{Text: "linked", Words: 1}
+	if err := c.Put(cache.Entry{Key: testKey('a')}, want); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	for _, k := range []byte("bc") {
+		if err := c.Link(cache.Entry{Key: testKey(k)}, testKey('a')); err != nil {
+			t.Fatalf("Link() unexpected error: %v", err)
+		}
+	}
+
+	var got value
+	if ok, err := c.Get(testKey('b'), &got); !ok || err != nil || got != want {
+		t.Fatalf("Get(link) = %+v, %v, %v; want %+v", got, ok, err, want)
+	}
+
+	if entries, _ := c.List(); len(entries) != 1 || entries[0].Key != testKey('a') {
+		t.Errorf("List() = %+v; want the target alone", entries)
+	}
+
+	// Pruning the target takes its links with it.
+	if removed, err := c.Prune(0, 1); err != nil || len(removed) != 1 {
+		t.Fatalf("Prune() = %+v, %v; want the target removed", removed, err)
+	}
+
+	if err := c.Put(cache.Entry{Key: testKey('a')}, want); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if ok, err := c.Get(testKey('c'), &got); ok || err != nil {
+		t.Errorf("Get(pruned link) = %v, %v; want a miss", ok, err)
+	}
+}
+
+func TestPruneAndClear(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	// Entries a, b and c were last used 3, 2 and 1 days ago.
+	for i, k := range []byte("abc") {
+		if err := c.Put(cache.Entry{Key: testKey(k)}, value{Text: strings.Repeat("x", 100)}); err != nil {
+			t.Fatalf("Put() unexpected error: %v", err)
+		}
+
+		used := time.Now().Add(-time.Duration(3-i) * 24 * time.Hour)
+		path := filepath.Join(dir, testKey(k)[:2], testKey(k)+".json")
+
+		if err := os.Chtimes(path, used, used); err != nil {
+			t.Fatalf("Chtimes() unexpected error: %v", err)
+		}
+	}
+
+	// A write interrupted by a crash leaves its temporary file behind.
+	stale := filepath.Join(dir, "aa", ".tmp-123")
+	if err := os.WriteFile(stale, []byte("{"), 0o600); err != nil {
+		t.Fatalf("writing stale file: %v", err)
+	}
+
+	removed, err := c.Prune(60*time.Hour, 0)
+	if err != nil || len(removed) != 1 || removed[0].Key != testKey('a') {
+		t.Fatalf("Prune(60h) = %+v, %v; want entry a removed", removed, err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file survived pruning: %v", err)
+	}
+
+	entries, _ := c.List()
+	if len(entries) != 2 || entries[0].Key != testKey('c') {
+		t.Fatalf("List() after pruning = %+v; want c, then b", entries)
+	}
+
+	removed, err = c.Prune(0, entries[0].Size)
+	if err != nil || len(removed) != 1 || removed[0].Key != testKey('b') {
+		t.Fatalf("Prune(size of one) = %+v, %v; want entry b removed", removed, err)
+	}
+
+	n, err := c.Clear()
+	if err != nil || n != 1 {
+		t.Fatalf("Clear() = %d, %v; want 1", n, err)
+	}
+
+	if entries, _ := c.List(); len(entries) != 0 {
+		t.Errorf("List() after Clear() = %+v; want none", entries)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	c, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Go(func() {
+			k := testKey("0123"[i%4])
+
+			if err := c.Put(cache.Entry{Key: k}, value{Words: i % 4}); err != nil {
+				t.Errorf("Put() unexpected error: %v", err)
+			}
+
+			// A concurrent prune may have removed the entry again.
+			var got value
+			if ok, err := c.Get(k, &got); err != nil || (ok && got.Words != i%4) {
+				t.Errorf("Get() = %+v, %v, %v", got, ok, err)
+			}
+
+			if i == 5 {
+				if _, err := c.Prune(0, 1); err != nil {
+					t.Errorf("Prune() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,18 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build !unix
+
+package cache
+
+import "os"
+
+// lockFile does nothing where flock is unavailable. Entries are still
+// replaced atomically, so concurrent runs at worst repeat work, but pruning
+// may then race with a run adding an entry.
+func lockFile(*os.File, bool) error { return nil }
+
+// unlockFile does nothing where flock is unavailable.
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,538 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// defaultJobs is the number of inputs of a batch transcribed at once: while
+// one is decoded by FFmpeg, another can wait on Gemini.
+const defaultJobs = 2
+
+// ErrPartialFailure is returned when a batch finished but some of its
+// inputs failed.
+var ErrPartialFailure = errors.New("some inputs failed")
+
+// batchOptions holds the transcribe flags that control several inputs.
+type batchOptions struct {
+	// Jobs is the number of inputs transcribed at once.
+	Jobs int
+	// Include and Exclude are file name patterns, as matched by
+	// filepath.Match, that pick the files found in directories and globs.
+	// Without Include, media files are picked.
+	Include []string
+	Exclude []string
+	// Manifest is where the manifest of a new batch is saved, and Resume
+	// the manifest of a batch to resume instead. RetryFailed resumes only
+	// the items that failed.
+	Manifest    string
+	Resume      string
+	RetryFailed bool
+}
+
+// validate checks the batch options against the other transcribe
+// arguments.
+func (o batchOptions) validate(args []string, outputFile, name string) error {
+	switch {
+	case o.Jobs < 1:
+		return fmt.Errorf("--jobs must be at least 1")
+	case o.RetryFailed && o.Resume == "":
+		return fmt.Errorf("--retry-failed needs --resume")
+	case o.Resume == "":
+		return nil
+	case len(args) > 0:
+		return fmt.Errorf("--resume takes its inputs from the manifest; drop the other arguments")
+	case outputFile != "" || name != "" || o.Manifest != "":
+		return fmt.Errorf("--resume cannot be combined with -o, --name or --manifest: the manifest records the outputs")
+	default:
+		return nil
+	}
+}
+
+// batchItem is one input of a batch: its transcript path, how far it has
+// got and, once attempted, its outcome. Items are saved in the manifest.
+type batchItem struct {
+	Input  string    `json:"input"`
+	Output string    `json:"output"`
+	State  itemState `json:"state"`
+	// Outputs lists the files written for the input: transcripts, exported
+	// subtitles and subtitle comparisons.
+	Outputs  []string   `json:"outputs,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Attempts int        `json:"attempts,omitempty"`
+	Started  time.Time  `json:"started,omitzero"`
+	Finished time.Time  `json:"finished,omitzero"`
+	Usage    *itemUsage `json:"usage,omitempty"`
+}
+
+// status describes the outcome of the item in a word.
+func (b *batchItem) status() string {
+	switch b.State {
+	case stateDone:
+		return "done"
+	case stateFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// elapsed returns how long the last attempt at the item took, or 0 if it
+// has not finished.
+func (b *batchItem) elapsed() time.Duration {
+	if b.Finished.IsZero() {
+		return 0
+	}
+
+	return b.Finished.Sub(b.Started)
+}
+
+// planRun works out what a transcribe run does: a single input to
+// transcribe, or a batch recorded in a manifest with the items to
+// transcribe now. A batch is either made from the arguments or resumed.
+func planRun(
+	args []string, outputFile, name string, opts batchOptions,
+) (m *manifest, todo []*batchItem, single string, err error) {
+	if opts.Resume != "" {
+		if m, err = loadManifest(opts.Resume); err != nil {
+			return nil, nil, "", err
+		}
+
+		return m, m.resumable(opts.RetryFailed), "", nil
+	}
+
+	inputs, isBatch, err := expandInputs(args, opts)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if !isBatch {
+		if opts.Manifest != "" {
+			return nil, nil, "", fmt.Errorf("--manifest is only written for a batch of inputs")
+		}
+
+		return nil, nil, inputs[0], nil
+	}
+
+	items, err := planBatch(inputs, outputFile, name)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return newManifest(manifestPath(opts.Manifest, outputFile), items), items, "", nil
+}
+
+// expandInputs turns the transcribe arguments into a list of inputs:
+// directories are searched recursively and arguments that name no file but
+// contain glob metacharacters are expanded, both keeping the files opts
+// picks; anything else is taken as given. It reports a batch when there
+// are several inputs or any came from a directory or glob.
+func expandInputs(args []string, opts batchOptions) ([]string, bool, error) {
+	if err := opts.checkPatterns(); err != nil {
+		return nil, false, err
+	}
+
+	var (
+		inputs []string
+		batch  bool
+		seen   = make(map[string]bool)
+	)
+
+	add := func(input string) {
+		if !seen[input] {
+			seen[input] = true
+			inputs = append(inputs, input)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == transcriber.StdinPath || transcriber.IsURL(arg) || transcriber.IsStorageURI(arg) {
+			add(arg)
+
+			continue
+		}
+
+		info, err := os.Stat(arg)
+
+		switch {
+		case err == nil && info.IsDir():
+			batch = true
+
+			if err := walkMedia(arg, opts, add); err != nil {
+				return nil, false, err
+			}
+		case err != nil && strings.ContainsAny(arg, "*?["):
+			batch = true
+
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid glob %q: %w", arg, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, false, fmt.Errorf("no files match %q", arg)
+			}
+
+			// As in a shell, only a pattern that starts with a dot
+			// matches hidden files.
+			dotted := strings.HasPrefix(filepath.Base(arg), ".")
+
+			for _, match := range matches {
+				if !dotted && strings.HasPrefix(filepath.Base(match), ".") {
+					continue
+				}
+
+				if err := walkMedia(match, opts, add); err != nil {
+					return nil, false, err
+				}
+			}
+		default:
+			// Missing files are reported when they are transcribed.
+			add(filepath.Clean(arg))
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, false, fmt.Errorf("no media files found in %s", strings.Join(args, ", "))
+	}
+
+	return inputs, batch || len(inputs) > 1, nil
+}
+
+// walkMedia calls add for every file under root, itself a file or a
+// directory, that opts picks. Hidden files and directories are skipped.
+func walkMedia(root string, opts batchOptions, add func(string)) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		hidden := path != root && strings.HasPrefix(d.Name(), ".")
+
+		switch {
+		case d.IsDir() && hidden:
+			return filepath.SkipDir
+		case d.IsDir() || hidden:
+			return nil
+		}
+
+		// Symbolic links are followed to the files they name.
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return nil //nolint:nilerr // broken links and special files are not inputs
+		}
+
+		if opts.picks(path) {
+			add(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("searching %s: %w", root, err)
+	}
+
+	return nil
+}
+
+// checkPatterns reports an error for the first malformed file name pattern.
+func (o batchOptions) checkPatterns() error {
+	for _, pattern := range append(slices.Clone(o.Include), o.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file name pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// picks reports whether the file at path is taken from a directory or glob.
+func (o batchOptions) picks(path string) bool {
+	name := filepath.Base(path)
+
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	switch {
+	case matches(o.Exclude):
+		return false
+	case len(o.Include) > 0:
+		return matches(o.Include)
+	default:
+		return transcriber.IsMediaFile(path)
+	}
+}
+
+// planBatch returns the items of a batch of inputs, each with its transcript
+// path under outputDir, or under output/ when outputDir is empty. Inputs
+// that would overwrite each other's transcripts are rejected up front.
+func planBatch(inputs []string, outputDir, name string) ([]*batchItem, error) {
+	if name != "" {
+		return nil, fmt.Errorf("--name cannot be used with several inputs")
+	}
+
+	if outputDir == "" {
+		outputDir = "output"
+	}
+
+	items := make([]*batchItem, 0, len(inputs))
+	owners := make(map[string]string, len(inputs))
+
+	for _, input := range inputs {
+		if input == transcriber.StdinPath {
+			return nil, fmt.Errorf("standard input cannot be transcribed with other inputs")
+		}
+
+		base := transcriber.InputFileName(input)
+		output := outputPathIn(outputDir, strings.TrimSuffix(base, filepath.Ext(base)))
+
+		if other, ok := owners[output]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s; transcribe them separately",
+				other, input, output)
+		}
+
+		owners[output] = input
+		items = append(items, &batchItem{Input: input, Output: output, State: statePending})
+	}
+
+	return items, nil
+}
+
+// runBatch transcribes the todo items of m with up to jobs workers sharing
+// t, so their requests share its rate limits. The manifest is saved before
+// the first item starts and whenever an item changes state. A failed item
+// does not stop the others; cancelling ctx stops taking new ones. Progress
+// and a summary table are written to w. It returns ErrPartialFailure when
+// some items of the manifest are not done, and another error when none are
+// or the batch was interrupted.
+func runBatch(
+	ctx context.Context, cfg *config.Config, t *transcriber.Transcriber, logger *slog.Logger, m *manifest,
+	todo []*batchItem, jobs int, w io.Writer,
+) error {
+	if err := m.save(); err != nil {
+		return err
+	}
+
+	// Per-input summaries would interleave; the table replaces them.
+	itemCfg := *cfg
+	itemCfg.Quiet = true
+
+	var (
+		mu       sync.Mutex
+		finished int
+		wg       sync.WaitGroup
+		work     = make(chan *batchItem)
+	)
+
+	// update changes an item under mu and, if fn reports a change, saves
+	// the manifest. A manifest that cannot be saved is reported but does
+	// not stop the batch.
+	update := func(fn func() bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !fn() {
+			return
+		}
+
+		if err := m.save(); err != nil {
+			logger.WarnContext(ctx, "cannot save batch manifest", slog.Any("error", err))
+		}
+	}
+
+	for range max(1, min(jobs, len(todo))) {
+		wg.Go(func() {
+			for item := range work {
+				update(func() bool {
+					item.State, item.Error, item.Attempts = stateExtracting, "", item.Attempts+1
+					item.Started, item.Finished = time.Now(), time.Time{}
+
+					return true
+				})
+
+				// Extraction goes on while chunks are transcribed, and is
+				// reported as often as FFmpeg reports, so an item only
+				// moves forward: from extracting to transcribing.
+				itemCtx := transcriber.WithProgress(ctx, func(p transcriber.Progress) {
+					if stateOf(p.Stage) != stateTranscribing {
+						return
+					}
+
+					update(func() bool {
+						changed := item.State != stateTranscribing
+						item.State = stateTranscribing
+
+						return changed
+					})
+				})
+
+				results, err := transcribeInput(itemCtx, &itemCfg, t, item.Input, item.Output)
+
+				update(func() bool {
+					item.Finished = time.Now()
+
+					if err != nil {
+						item.State, item.Error = stateFailed, err.Error()
+					} else {
+						item.State, item.Outputs, item.Usage = stateDone, itemOutputs(cfg, item.Output, results), usageOf(results)
+					}
+
+					finished++
+
+					if !cfg.Quiet {
+						reportItem(w, item, finished, len(todo))
+					}
+
+					return true
+				})
+			}
+		})
+	}
+
+feed:
+	for _, item := range todo {
+		select {
+		case work <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	if !cfg.Quiet {
+		if err := writeBatchSummary(w, m.Items); err != nil {
+			return err
+		}
+	}
+
+	done := 0
+
+	for _, item := range m.Items {
+		if item.State == stateDone {
+			done++
+		}
+	}
+
+	total := len(m.Items)
+
+	if done < total && !cfg.Quiet {
+		fmt.Fprintf(w, "Resume with: voice-transcriber transcribe --resume %s\n", m.path)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf("batch interrupted with %d of %d inputs done: %w", done, total, ctx.Err())
+	case done == total:
+		return nil
+	case done == 0:
+		return fmt.Errorf("none of %d inputs were transcribed", total)
+	default:
+		return fmt.Errorf("%w: %d of %d not done", ErrPartialFailure, total-done, total)
+	}
+}
+
+// itemOutputs returns the files written for an input whose transcript path
+// is transcriptPath, given its transcription results.
+func itemOutputs(cfg *config.Config, transcriptPath string, results []*transcriber.TranscriptionResult) []string {
+	if cfg.Subtitles == config.SubtitlesExport {
+		return []string{subtitleExportPath(cfg, transcriptPath)}
+	}
+
+	outputs := make([]string, 0, len(results))
+
+	for _, result := range results {
+		path := transcriptPath
+		if cfg.AllAudioStreams {
+			path = streamOutputPath(transcriptPath, result.Stream)
+		}
+
+		outputs = append(outputs, path)
+
+		if result.SubtitleReport != nil {
+			outputs = append(outputs, subtitleDiffPath(path))
+		}
+	}
+
+	return outputs
+}
+
+// reportItem writes a line about the item that has just finished, the
+// done-th of total.
+func reportItem(w io.Writer, item *batchItem, done, total int) {
+	if item.State == stateFailed {
+		fmt.Fprintf(w, "[%d/%d] %s failed: %s\n", done, total, item.Input, item.Error)
+
+		return
+	}
+
+	fmt.Fprintf(w, "[%d/%d] %s: %d words in %v, saved to %s\n",
+		done, total, item.Input, item.Usage.Words, item.elapsed().Round(time.Second), item.Output)
+}
+
+// writeBatchSummary writes a table of the outcome of every item to w.
+func writeBatchSummary(w io.Writer, items []*batchItem) error {
+	counts := make(map[string]int)
+
+	fmt.Fprintf(w, "\nBatch completed:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INPUT\tSTATUS\tWORDS\tTIME\tOUTPUT")
+
+	for _, item := range items {
+		status := item.status()
+		counts[status]++
+
+		detail, words, elapsed := item.Output, "-", "-"
+
+		switch status {
+		case "done":
+			elapsed = item.elapsed().Round(time.Second).String()
+
+			if item.Usage != nil {
+				words = fmt.Sprint(item.Usage.Words)
+			}
+		case "failed":
+			// FFmpeg errors carry its log; the first line says what failed.
+			detail, _, _ = strings.Cut(item.Error, "\n")
+			elapsed = item.elapsed().Round(time.Second).String()
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.Input, status, words, elapsed, detail)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing batch summary: %w", err)
+	}
+
+	fmt.Fprintf(w, "%d done, %d failed, %d skipped\n", counts["done"], counts["failed"], counts["skipped"])
+	fmt.Fprintln(w, strings.Repeat("-", outputSeparatorWidth))
+
+	return nil
+}
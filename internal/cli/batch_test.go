@@ -0,0 +1,185 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+)
+
+func TestExpandInputs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"a.mp3", "b.mkv", "notes.txt", ".hidden.mp3", "sub/c.wav", "sub/skip.wav", ".git/d.mp3",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte("placeholder"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+
+		return paths
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		opts      cli.BatchOptions
+		want      []string
+		wantBatch bool
+		wantErr   bool
+	}{
+		{name: "single file", args: in("a.mp3"), want: in("a.mp3")},
+		{name: "missing file", args: in("gone.mp3"), want: in("gone.mp3")},
+		{name: "url", args: []string{"https://example.com/talk.mp3"}, want: []string{"https://example.com/talk.mp3"}},
+		{
+			name:      "directory",
+			args:      in(""),
+			want:      in("a.mp3", "b.mkv", "sub/c.wav", "sub/skip.wav"),
+			wantBatch: true,
+		},
+		{
+			name:      "directory with patterns",
+			args:      in(""),
+			opts:      cli.BatchOptions{Include: []string{"*.wav", "*.txt"}, Exclude: []string{"skip.*"}},
+			want:      in("notes.txt", "sub/c.wav"),
+			wantBatch: true,
+		},
+		{name: "glob", args: in("*.m*"), want: in("a.mp3", "b.mkv"), wantBatch: true},
+		{name: "hidden glob", args: in(".*.mp3"), want: in(".hidden.mp3"), wantBatch: true},
+		{name: "glob of one", args: in("sub/c.*"), want: in("sub/c.wav"), wantBatch: true},
+		{name: "duplicates", args: in("a.mp3", "a.mp3", "b.mkv"), want: in("a.mp3", "b.mkv"), wantBatch: true},
+		{name: "glob without matches", args: in("*.flac"), wantErr: true},
+		{name: "directory without media", args: in(""), opts: cli.BatchOptions{Include: []string{"*.flac"}}, wantErr: true},
+		{name: "invalid pattern", args: in("a.mp3"), opts: cli.BatchOptions{Exclude: []string{"["}}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, batch, err := cli.ExpandInputs(tc.args, tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ExpandInputs() = %v; want an error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ExpandInputs() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tc.want) || batch != tc.wantBatch {
+				t.Errorf("ExpandInputs() = %v, %v; want %v, %v", got, batch, tc.want, tc.wantBatch)
+			}
+		})
+	}
+}
+
+func TestPlanBatch(t *testing.T) {
+	t.Parallel()
+
+	items, err := cli.PlanBatch([]string{"talks/day1.mp4", "https://example.com/day2.mp3?dl=1"}, "out", "")
+	if err != nil {
+		t.Fatalf("PlanBatch() unexpected error: %v", err)
+	}
+
+	want := []string{filepath.Join("out", "day1", "day1.txt"), filepath.Join("out", "day2", "day2.txt")}
+	for i, item := range items {
+		if item.Output != want[i] {
+			t.Errorf("PlanBatch() output %d = %s; want %s", i, item.Output, want[i])
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		inputs []string
+		flag   string
+	}{
+		{name: "collision", inputs: []string{"a/talk.mp4", "b/talk.mkv"}},
+		{name: "name flag", inputs: []string{"a.mp4", "b.mp4"}, flag: "talk"},
+		{name: "standard input", inputs: []string{"a.mp4", "-"}},
+	} {
+		if _, err := cli.PlanBatch(tc.inputs, "", tc.flag); err == nil {
+			t.Errorf("PlanBatch(%s) succeeded; want an error", tc.name)
+		}
+	}
+}
+
+func TestWriteBatchSummary(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	items := []*cli.BatchItem{
+		{
+			Input: "a.mp3", Output: "output/a/a.txt", State: "done",
+			Started: start, Finished: start.Add(3 * time.Second), Usage: &cli.ItemUsage{Words: 120},
+		},
+		{Input: "b.mkv", Output: "output/b/b.txt", State: "failed", Error: "ffmpeg failed\nlog", Started: start},
+		{Input: "c.wav", Output: "output/c/c.txt", State: "pending"},
+	}
+
+	var buf bytes.Buffer
+	if err := cli.WriteBatchSummary(&buf, items); err != nil {
+		t.Fatalf("WriteBatchSummary() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{
+		"a.mp3  done     120    3s    output/a/a.txt",
+		"b.mkv  failed   -      0s    ffmpeg failed\n",
+		"c.wav  skipped  -      -     output/c/c.txt",
+		"1 done, 1 failed, 1 skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: nil, want: 0},
+		{err: errors.New("boom"), want: cli.ExitFailure},
+		{err: fmt.Errorf("%w: 1 of 3", cli.ErrPartialFailure), want: cli.ExitPartialFailure},
+	}
+
+	for _, tc := range tests {
+		if got := cli.ExitCode(tc.err); got != tc.want {
+			t.Errorf("ExitCode(%v) = %d; want %d", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// shortKeyLength is how much of a cache key is listed.
+const shortKeyLength = 12
+
+// newCacheCmd constructs the cache subcommand and its list, prune and clear
+// subcommands.
+func newCacheCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "List, prune or clear cached transcripts",
+		Long: `Transcripts are cached under a hash of the audio sent to Gemini and of
+the model, prompt, language and other settings, so transcribing the same
+audio the same way again costs nothing. The cache lives in the user cache
+directory (e.g. ~/.cache/voice-transcriber) unless --cache-dir names
+another.`,
+	}
+
+	var olderThan, maxSize string
+
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached transcripts not used recently, or beyond a total size",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runCachePrune(cfg, cmd.OutOrStdout(), olderThan, maxSize)
+		},
+	}
+
+	prune.Flags().StringVar(&olderThan, "older-than", "",
+		"Remove transcripts not used for this long (e.g. 30d, 12h)")
+	prune.Flags().StringVar(&maxSize, "max-size", "",
+		"Then remove the least recently used transcripts until the cache fits in this size (e.g. 500M, 2G)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List cached transcripts, most recently used first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runCacheList(cfg, cmd.OutOrStdout())
+		},
+	}, prune, &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every cached transcript",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runCacheClear(cfg, cmd.OutOrStdout())
+		},
+	})
+
+	return cmd
+}
+
+// openCache opens the cache configured by cfg.
+func openCache(cfg *config.Config) (*cache.Cache, error) {
+	dir := cfg.CacheDir
+	if dir == "" {
+		var err error
+
+		if dir, err = cache.DefaultDir(); err != nil {
+			return nil, fmt.Errorf("opening cache: %w", err)
+		}
+	}
+
+	c, err := cache.Open(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening cache: %w", err)
+	}
+
+	return c, nil
+}
+
+// runCacheList writes the cached transcripts to w as a table.
+func runCacheList(cfg *config.Config, w io.Writer) error {
+	c, err := openCache(cfg)
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return fmt.Errorf("reading cache: %w", err)
+	}
+
+	fmt.Fprintf(w, "Cache: %s\n", c.Dir())
+
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No cached transcripts.")
+
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tSIZE\tLAST USED\tMODEL\tINPUT")
+
+	var total int64
+
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Key[:shortKeyLength], gemini.FormatBytes(e.Size),
+			e.Used.Local().Format("2006-01-02 15:04"), e.Model, e.Input)
+
+		total += e.Size
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing cache listing: %w", err)
+	}
+
+	fmt.Fprintf(w, "%d transcripts, %s\n", len(entries), gemini.FormatBytes(total))
+
+	return nil
+}
+
+// runCachePrune removes cached transcripts not used within olderThan, then
+// the least recently used ones beyond maxSize, and reports what it freed.
+func runCachePrune(cfg *config.Config, w io.Writer, olderThan, maxSize string) error {
+	if olderThan == "" && maxSize == "" {
+		return fmt.Errorf("prune needs --older-than, --max-size or both")
+	}
+
+	var (
+		age  time.Duration
+		size int64
+		err  error
+	)
+
+	if olderThan != "" {
+		if age, err = parseAge(olderThan); err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+
+	if maxSize != "" {
+		if size, err = parseSize(maxSize); err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+
+	c, err := openCache(cfg)
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Prune(age, size)
+	if err != nil {
+		return fmt.Errorf("pruning cache: %w", err)
+	}
+
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+	}
+
+	fmt.Fprintf(w, "Removed %d cached transcripts, %s.\n", len(removed), gemini.FormatBytes(freed))
+
+	return nil
+}
+
+// runCacheClear removes every cached transcript.
+func runCacheClear(cfg *config.Config, w io.Writer) error {
+	c, err := openCache(cfg)
+	if err != nil {
+		return err
+	}
+
+	n, err := c.Clear()
+	if err != nil {
+		return fmt.Errorf("clearing cache: %w", err)
+	}
+
+	fmt.Fprintf(w, "Removed %d cached transcripts from %s.\n", n, c.Dir())
+
+	return nil
+}
+
+// parseAge parses a duration as accepted by time.ParseDuration or a whole
+// number of days such as "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%q is not a positive number of days", s)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a positive duration such as 30d or 12h", s)
+	}
+
+	return d, nil
+}
+
+// parseSize parses a size in bytes with an optional binary unit: K, M, G or
+// T, optionally followed by "iB" or "B", so 500M, 500MB and 500MiB all mean
+// 500 MiB.
+func parseSize(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	upper := strings.ToUpper(num)
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "B"), "I")
+
+	shift := 0
+	if i := len(upper) - 1; i >= 0 {
+		if p := strings.IndexByte("KMGT", upper[i]); p >= 0 {
+			shift = 10 * (p + 1)
+			upper = upper[:i]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || n <= 0 || n*float64(int64(1)<<shift) >= 1<<62 {
+		return 0, fmt.Errorf("%q is not a positive size such as 500M or 2G", num)
+	}
+
+	return int64(n * float64(int64(1)<<shift)), nil
+}
@@ -0,0 +1,110 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func TestParseAge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "0d"},
+		{in: "-1h"},
+		{in: "soon"},
+	}
+
+	for _, tc := range tests {
+		got, err := cli.ParseAge(tc.in)
+		if (err != nil) != (tc.want == 0) || got != tc.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{in: "1024", want: 1024},
+		{in: "500M", want: 500 << 20},
+		{in: "500MB", want: 500 << 20},
+		{in: "500MiB", want: 500 << 20},
+		{in: "1.5g", want: 3 << 29},
+		{in: "2T", want: 2 << 40},
+		{in: "0"},
+		{in: "-5M"},
+		{in: "lots"},
+	}
+
+	for _, tc := range tests {
+		got, err := cli.ParseSize(tc.in)
+		if (err != nil) != (tc.want == 0) || got != tc.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestCacheCommands(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{CacheDir: t.TempDir()}
+
+	c, err := cache.Open(cfg.CacheDir)
+	if err != nil {
+		t.Fatalf("cache.Open() unexpected error: %v", err)
+	}
+
+	key := strings.Repeat("ab", 32)
+	if err := c.Put(cache.Entry{Key: key, Input: "talk.mp4", Model: "gemini-test"}, "transcript"); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+
+	if err := cli.RunCacheList(cfg, &out); err != nil {
+		t.Fatalf("RunCacheList() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{key[:12], "talk.mp4", "gemini-test", "1 transcripts"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("listing %q lacks %q", out.String(), want)
+		}
+	}
+
+	if err := cli.RunCachePrune(cfg, &out, "", ""); err == nil {
+		t.Error("RunCachePrune() without limits succeeded; want an error")
+	}
+
+	out.Reset()
+
+	if err := cli.RunCachePrune(cfg, &out, "30d", ""); err != nil || !strings.Contains(out.String(), "Removed 0") {
+		t.Errorf("RunCachePrune(30d) = %q, %v; want the fresh entry kept", out.String(), err)
+	}
+
+	out.Reset()
+
+	if err := cli.RunCacheClear(cfg, &out); err != nil || !strings.Contains(out.String(), "Removed 1") {
+		t.Errorf("RunCacheClear() = %q, %v; want one entry removed", out.String(), err)
+	}
+}
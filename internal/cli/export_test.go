@@ -11,3 +11,75 @@ var SanitizeFilename = sanitizeFilename
 
 // ResolveOutputPath exposes resolveOutputPath for black-box tests.
 var ResolveOutputPath = resolveOutputPath
+
+// DefaultOutputPath exposes defaultOutputPath for black-box tests.
+var DefaultOutputPath = defaultOutputPath
+
+// WriteMediaInfo exposes writeMediaInfo for black-box tests.
+var WriteMediaInfo = writeMediaInfo
+
+// StreamOutputPath exposes streamOutputPath for black-box tests.
+var StreamOutputPath = streamOutputPath
+
+// SubtitleDiffPath exposes subtitleDiffPath for black-box tests.
+var SubtitleDiffPath = subtitleDiffPath
+
+// ParseAge exposes parseAge for black-box tests.
+var ParseAge = parseAge
+
+// ParseSize exposes parseSize for black-box tests.
+var ParseSize = parseSize
+
+// RunCacheList exposes runCacheList for black-box tests.
+var RunCacheList = runCacheList
+
+// RunCachePrune exposes runCachePrune for black-box tests.
+var RunCachePrune = runCachePrune
+
+// RunCacheClear exposes runCacheClear for black-box tests.
+var RunCacheClear = runCacheClear
+
+// BatchOptions exposes batchOptions for black-box tests.
+type BatchOptions = batchOptions
+
+// BatchItem exposes batchItem for black-box tests.
+type BatchItem = batchItem
+
+// ExpandInputs exposes expandInputs for black-box tests.
+var ExpandInputs = expandInputs
+
+// PlanBatch exposes planBatch for black-box tests.
+var PlanBatch = planBatch
+
+// WriteBatchSummary exposes writeBatchSummary for black-box tests.
+var WriteBatchSummary = writeBatchSummary
+
+// ItemUsage exposes itemUsage for black-box tests.
+type ItemUsage = itemUsage
+
+// Manifest exposes manifest for black-box tests.
+type Manifest = manifest
+
+// NewManifest exposes newManifest for black-box tests.
+var NewManifest = newManifest
+
+// LoadManifest exposes loadManifest for black-box tests.
+var LoadManifest = loadManifest
+
+// SaveManifest exposes manifest.save for black-box tests.
+func SaveManifest(m *Manifest) error { return m.save() }
+
+// Resumable exposes manifest.resumable for black-box tests.
+func Resumable(m *Manifest, retryFailed bool) []*BatchItem { return m.resumable(retryFailed) }
+
+// MoveFile exposes moveFile for black-box tests.
+var MoveFile = moveFile
+
+// MovedDir exposes movedDir for black-box tests.
+var MovedDir = movedDir
+
+// ClaimOutputPath exposes claimOutputPath for black-box tests.
+var ClaimOutputPath = claimOutputPath
+
+// DescribeProgress exposes describeProgress for black-box tests.
+var DescribeProgress = describeProgress
@@ -0,0 +1,125 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// newInfoCmd constructs the info subcommand.
+func newInfoCmd(cfg *config.Config) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "info [media-file]",
+		Short: "Show the container, duration and streams of a media file",
+		Long: `Inspect a media file with ffprobe and print its container, duration,
+size and streams, including codecs, sample rates, channel layouts and
+language tags. Use - to inspect standard input. Requires ffprobe (installed
+with FFmpeg).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInfo(cmd.Context(), cfg, cmd.OutOrStdout(), args[0], asJSON)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the details as JSON")
+
+	return cmd
+}
+
+// runInfo inspects mediaFile with the ffprobe configured by cfg and writes
+// the report to w.
+func runInfo(ctx context.Context, cfg *config.Config, w io.Writer, mediaFile string, asJSON bool) error {
+	info, err := transcriber.Inspect(ctx, cfg, newLogger(cfg, os.Stderr), mediaFile)
+	if err != nil {
+		return fmt.Errorf("inspecting %s: %w", mediaFile, err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(info); err != nil {
+			return fmt.Errorf("writing media info: %w", err)
+		}
+
+		return nil
+	}
+
+	writeMediaInfo(w, info)
+
+	return nil
+}
+
+// writeMediaInfo prints info as human-readable text.
+func writeMediaInfo(w io.Writer, info *transcriber.MediaInfo) {
+	container := info.Container
+	if info.ContainerName != "" {
+		container += " (" + info.ContainerName + ")"
+	}
+
+	fmt.Fprintf(w, "File:      %s\n", info.Path)
+	fmt.Fprintf(w, "Container: %s\n", container)
+	fmt.Fprintf(w, "Duration:  %s\n", transcriber.FormatTimestamp(info.Duration))
+	fmt.Fprintf(w, "Size:      %s\n", gemini.FormatBytes(info.Size))
+
+	if info.BitRate > 0 {
+		fmt.Fprintf(w, "Bit rate:  %d kb/s\n", info.BitRate/1000)
+	}
+
+	fmt.Fprintf(w, "Streams:\n")
+
+	for _, s := range info.Streams {
+		fmt.Fprintf(w, "  #%d %-8s %s\n", s.Index, s.Type, describeStream(s))
+	}
+}
+
+// describeStream summarises the codec parameters and tags of one stream.
+func describeStream(s transcriber.StreamInfo) string {
+	parts := []string{s.Codec}
+
+	switch s.Type {
+	case "audio":
+		layout := s.ChannelLayout
+		if layout == "" {
+			layout = fmt.Sprintf("%d channels", s.Channels)
+		}
+
+		parts = append(parts, fmt.Sprintf("%d Hz, %s", s.SampleRate, layout))
+	case "video":
+		if s.AttachedPicture {
+			parts = append(parts, "cover art")
+		} else if s.Width > 0 {
+			parts = append(parts, fmt.Sprintf("%dx%d", s.Width, s.Height))
+		}
+	}
+
+	if s.Language != "" {
+		parts = append(parts, "["+s.Language+"]")
+	}
+
+	if s.Title != "" {
+		parts = append(parts, fmt.Sprintf("%q", s.Title))
+	}
+
+	if s.Default {
+		parts = append(parts, "(default)")
+	}
+
+	return strings.Join(parts, "  ")
+}
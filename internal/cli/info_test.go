@@ -0,0 +1,53 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestWriteMediaInfo(t *testing.T) {
+	t.Parallel()
+
+	info := &transcriber.MediaInfo{
+		Path:          "talk.mkv",
+		Container:     "matroska,webm",
+		ContainerName: "Matroska / WebM",
+		Duration:      90*time.Minute + 1500*time.Millisecond,
+		Size:          3 << 30,
+		BitRate:       4_500_000,
+		Streams: []transcriber.StreamInfo{
+			{Index: 0, Type: "video", Codec: "h264", Width: 1920, Height: 1080, Default: true},
+			{Index: 1, Type: "audio", Codec: "opus", SampleRate: 48000, ChannelLayout: "stereo", Language: "ukr"},
+			{Index: 2, Type: "audio", Codec: "aac", SampleRate: 44100, Channels: 3, Title: "Director"},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	cli.WriteMediaInfo(&buf, info)
+
+	got := buf.String()
+	for _, want := range []string{
+		"Container: matroska,webm (Matroska / WebM)",
+		"Duration:  01:30:01.500",
+		"Size:      3.0 GiB",
+		"Bit rate:  4500 kb/s",
+		"#0 video    h264  1920x1080  (default)",
+		"#1 audio    opus  48000 Hz, stereo  [ukr]",
+		`#2 audio    aac  44100 Hz, 3 channels  "Director"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\ngot:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// manifestVersion is the version of the manifest format; manifests of
+// another version are not resumed.
+const manifestVersion = 1
+
+// manifestName is the file name of the manifest in the output directory of
+// a batch when --manifest does not name one.
+const manifestName = "manifest.json"
+
+// itemState is how far a batch item has got.
+type itemState string
+
+// The states of a batch item. An item is pending until a worker takes it,
+// then extracting and transcribing as the transcriber reports its stages,
+// and finally done or failed.
+const (
+	statePending      itemState = "pending"
+	stateExtracting   itemState = "extracting"
+	stateTranscribing itemState = "transcribing"
+	stateDone         itemState = "done"
+	stateFailed       itemState = "failed"
+)
+
+// stateOf returns the state of an item whose transcription has reached
+// stage: extracting until its audio is sent to the model, then transcribing.
+func stateOf(stage transcriber.Stage) itemState {
+	switch stage {
+	case transcriber.StageProbing, transcriber.StageExtracting:
+		return stateExtracting
+	default:
+		return stateTranscribing
+	}
+}
+
+// errInterrupted is recorded for items a manifest shows in progress when it
+// is resumed: the run that was transcribing them stopped without a word.
+var errInterrupted = errors.New("interrupted")
+
+// itemUsage is what transcribing one batch item consumed, summed over its
+// audio streams.
+type itemUsage struct {
+	Words       int   `json:"words"`
+	Requests    int   `json:"requests"`
+	UploadBytes int64 `json:"upload_bytes"`
+	gemini.Usage
+	// Cached reports that every transcript came from the cache.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// usageOf sums the usage of the results of one input.
+func usageOf(results []*transcriber.TranscriptionResult) *itemUsage {
+	u := &itemUsage{Cached: len(results) > 0}
+
+	for _, r := range results {
+		u.Words += r.WordCount
+		u.Requests += r.Chunks
+		u.UploadBytes += r.UploadSize
+		u.Usage = u.Usage.Add(r.Usage)
+		u.Cached = u.Cached && r.Cached
+	}
+
+	return u
+}
+
+// manifest is the persistent record of a batch, rewritten as its items
+// move from state to state so that an interrupted batch can be resumed.
+// It is not safe for concurrent use.
+type manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// Dir is the working directory of the batch, which relative inputs
+	// and outputs are relative to.
+	Dir   string       `json:"dir,omitempty"`
+	Items []*batchItem `json:"items"`
+
+	path string
+}
+
+// newManifest returns the manifest of a new batch of items, saved at path.
+func newManifest(path string, items []*batchItem) *manifest {
+	dir, _ := os.Getwd()
+
+	return &manifest{Version: manifestVersion, Created: time.Now(), Dir: dir, Items: items, path: path}
+}
+
+// manifestPath returns where the manifest of a batch is saved: at path when
+// --manifest gave one, otherwise in the output directory.
+func manifestPath(path, outputDir string) string {
+	switch {
+	case path != "":
+		return path
+	case outputDir != "":
+		return filepath.Join(outputDir, manifestName)
+	default:
+		return filepath.Join("output", manifestName)
+	}
+}
+
+// loadManifest reads the manifest at path. Items it shows in progress were
+// interrupted and are marked failed. Relative paths are resolved against
+// the directory of the batch when it differs from the working directory.
+func loadManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the user's --resume argument
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	m := &manifest{path: path}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("reading manifest %s: %w", path, err)
+	}
+
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest %s has version %d; this version of voice-transcriber reads version %d",
+			path, m.Version, manifestVersion)
+	}
+
+	for i, item := range m.Items {
+		if item == nil || item.Input == "" || item.Output == "" {
+			return nil, fmt.Errorf("manifest %s: item %d has no input or output", path, i+1)
+		}
+
+		m.resolve(item)
+
+		switch item.State {
+		case stateExtracting, stateTranscribing:
+			item.State, item.Error = stateFailed, errInterrupted.Error()
+		case statePending, stateDone, stateFailed:
+		default:
+			return nil, fmt.Errorf("manifest %s: item %d has unknown state %q", path, i+1, item.State)
+		}
+	}
+
+	return m, nil
+}
+
+// resolve makes the relative local paths of item relative to the working
+// directory rather than that of the batch.
+func (m *manifest) resolve(item *batchItem) {
+	if wd, err := os.Getwd(); m.Dir == "" || (err == nil && wd == m.Dir) {
+		return
+	}
+
+	local := func(path string) bool {
+		return !filepath.IsAbs(path) && !transcriber.IsURL(path) && !transcriber.IsStorageURI(path)
+	}
+
+	if local(item.Input) {
+		item.Input = filepath.Join(m.Dir, item.Input)
+	}
+
+	if local(item.Output) {
+		item.Output = filepath.Join(m.Dir, item.Output)
+	}
+
+	for i, output := range item.Outputs {
+		if local(output) {
+			item.Outputs[i] = filepath.Join(m.Dir, output)
+		}
+	}
+}
+
+// resumable returns the items of m that a resumed batch transcribes: those
+// not done, or with retryFailed only the failed ones.
+func (m *manifest) resumable(retryFailed bool) []*batchItem {
+	var todo []*batchItem
+
+	for _, item := range m.Items {
+		if item.State == stateFailed || (item.State == statePending && !retryFailed) {
+			todo = append(todo, item)
+		}
+	}
+
+	return todo
+}
+
+// save writes the manifest to its path atomically: a crash leaves either
+// the previous version or this one.
+func (m *manifest) save() error {
+	m.Updated = time.Now()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o750); err != nil {
+		return fmt.Errorf("creating manifest directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(m.path), "."+filepath.Base(m.path)+"-*")
+	if err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if serr := f.Sync(); err == nil {
+		err = serr
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), m.path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
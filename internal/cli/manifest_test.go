@@ -0,0 +1,93 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+)
+
+func TestManifestResume(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "out", "manifest.json")
+	m := cli.NewManifest(path, []*cli.BatchItem{
+		{Input: "a.mp3", Output: "out/a/a.txt", State: "done", Usage: &cli.ItemUsage{Words: 10}},
+		{Input: "b.mp3", Output: "out/b/b.txt", State: "failed", Error: "quota exceeded", Attempts: 1},
+		{Input: "c.mp3", Output: "out/c/c.txt", State: "transcribing", Attempts: 1},
+		{Input: "d.mp3", Output: "out/d/d.txt", State: "pending"},
+	})
+
+	if err := cli.SaveManifest(m); err != nil {
+		t.Fatalf("saving manifest: %v", err)
+	}
+
+	loaded, err := cli.LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest() unexpected error: %v", err)
+	}
+
+	if c := loaded.Items[2]; c.State != "failed" || c.Error != "interrupted" {
+		t.Errorf("item in progress loaded as %s %q; want failed, interrupted", c.State, c.Error)
+	}
+
+	if a := loaded.Items[0]; a.Usage == nil || a.Usage.Words != 10 {
+		t.Errorf("done item usage = %+v; want 10 words", a.Usage)
+	}
+
+	inputs := func(items []*cli.BatchItem) string {
+		names := make([]string, len(items))
+		for i, item := range items {
+			names[i] = item.Input
+		}
+
+		return strings.Join(names, " ")
+	}
+
+	if got := inputs(cli.Resumable(loaded, false)); got != "b.mp3 c.mp3 d.mp3" {
+		t.Errorf("resumed inputs = %s; want every input not done", got)
+	}
+
+	if got := inputs(cli.Resumable(loaded, true)); got != "b.mp3 c.mp3" {
+		t.Errorf("inputs retried = %s; want the failed and interrupted ones", got)
+	}
+
+	// Saving leaves no temporary files behind.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("manifest directory holds %v, %v; want only the manifest", entries, err)
+	}
+}
+
+func TestLoadManifestRejects(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"garbage":       "not json",
+		"version":       `{"version": 2, "items": []}`,
+		"unknown state": `{"version": 1, "items": [{"input": "a.mp3", "output": "a.txt", "state": "paused"}]}`,
+		"no output":     `{"version": 1, "items": [{"input": "a.mp3", "state": "done"}]}`,
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := cli.LoadManifest(path); err == nil {
+			t.Errorf("LoadManifest(%s) succeeded; want an error", name)
+		}
+	}
+
+	if _, err := cli.LoadManifest(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadManifest(missing) succeeded; want an error")
+	}
+}
@@ -0,0 +1,323 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+const (
+	// progressRedraw is how often status lines are redrawn on a terminal.
+	progressRedraw = 200 * time.Millisecond
+	// progressLogInterval is how often the progress of every input is
+	// logged when stderr is not a terminal.
+	progressLogInterval = 30 * time.Second
+	// progressBarWidth is the number of cells in a progress bar.
+	progressBarWidth = 20
+)
+
+// spinner is drawn before the status of an input, one frame per redraw.
+var spinner = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// inputProgress is what a progressDisplay knows of one input.
+type inputProgress struct {
+	last    transcriber.Progress
+	started time.Time
+	// extracted is the percentage of the last FFmpeg pass, or -1.
+	extracted float64
+}
+
+// progressDisplay shows the progress events of a Transcriber: on a
+// terminal as a status line per input, redrawn in place below the other
+// output, and elsewhere as a log line per input every progressLogInterval.
+type progressDisplay struct {
+	// out is where status lines are drawn; live is set when they are, and
+	// quiet when nothing is shown.
+	out   io.Writer
+	live  bool
+	quiet bool
+	width int
+
+	mu     sync.Mutex
+	inputs map[string]*inputProgress
+	order  []string
+	// drawn is the number of status lines on screen, frame the spinner frame.
+	drawn int
+	frame int
+}
+
+// newProgressDisplay returns the display of progress for cfg: live status
+// lines when stderr is a terminal, unless logging is quiet or verbose, and
+// log lines otherwise.
+func newProgressDisplay(cfg *config.Config) *progressDisplay {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	return &progressDisplay{
+		out:    os.Stderr,
+		live:   !cfg.Quiet && !cfg.Verbose && isTerminal(os.Stderr) && os.Getenv("TERM") != "dumb",
+		quiet:  cfg.Quiet,
+		width:  width,
+		inputs: make(map[string]*inputProgress),
+	}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// start shows the progress of t until the returned function is called,
+// logging it to logger unless the display is live.
+func (d *progressDisplay) start(ctx context.Context, t *transcriber.Transcriber, logger *slog.Logger) (stop func()) {
+	if d.quiet {
+		return func() {}
+	}
+
+	unsubscribe := t.Subscribe(d.update)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		interval := progressLogInterval
+		if d.live {
+			interval = progressRedraw
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if d.live {
+					d.redraw()
+				} else {
+					d.log(ctx, logger)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		close(done)
+		<-stopped
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.clear()
+	}
+}
+
+// update records an event. An input leaves the display once it is being
+// written or done, since what the command prints about it comes next.
+func (d *progressDisplay) update(p transcriber.Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	in, ok := d.inputs[p.Input]
+
+	if p.Stage == transcriber.StageWriting || p.Stage == transcriber.StageDone {
+		if ok {
+			delete(d.inputs, p.Input)
+
+			for i, input := range d.order {
+				if input == p.Input {
+					d.order = append(d.order[:i], d.order[i+1:]...)
+
+					break
+				}
+			}
+
+			d.clear()
+			d.draw()
+		}
+
+		return
+	}
+
+	if !ok {
+		in = &inputProgress{started: p.Time, extracted: -1}
+		d.inputs[p.Input] = in
+		d.order = append(d.order, p.Input)
+	}
+
+	if percent, ok := p.Percent(); ok {
+		in.extracted = percent
+	}
+
+	in.last = p
+}
+
+// redraw draws the status lines again with the next spinner frame.
+func (d *progressDisplay) redraw() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.frame++
+	d.clear()
+	d.draw()
+}
+
+// clear erases the status lines, leaving the cursor where the first was.
+// The caller holds mu.
+func (d *progressDisplay) clear() {
+	if d.drawn == 0 {
+		return
+	}
+
+	_, _ = io.WriteString(d.out, "\r\x1b[K"+strings.Repeat("\x1b[A\x1b[K", d.drawn-1))
+	d.drawn = 0
+}
+
+// draw writes a status line for every input, without a final newline so
+// that the next clear can erase them all. The caller holds mu.
+func (d *progressDisplay) draw() {
+	if !d.live || len(d.order) == 0 {
+		return
+	}
+
+	now := time.Now()
+	lines := make([]string, 0, len(d.order))
+
+	for _, input := range d.order {
+		in := d.inputs[input]
+		status, fraction := describeProgress(in.last, in.extracted)
+
+		bar := ""
+		if fraction >= 0 {
+			bar = fmt.Sprintf("%s %3.0f%%  ", progressBar(fraction), 100*fraction)
+		}
+
+		line := fmt.Sprintf("%c %s  %s%s  %v", spinner[d.frame%len(spinner)], transcriber.InputFileName(input),
+			bar, status, now.Sub(in.started).Round(time.Second))
+
+		lines = append(lines, truncate(line, d.width-1))
+	}
+
+	_, _ = io.WriteString(d.out, strings.Join(lines, "\n"))
+	d.drawn = len(lines)
+}
+
+// log logs the progress of every input.
+func (d *progressDisplay) log(ctx context.Context, logger *slog.Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, input := range d.order {
+		in := d.inputs[input]
+		status, fraction := describeProgress(in.last, in.extracted)
+
+		attrs := []any{slog.String("input", input), slog.String("status", status)}
+		if fraction >= 0 {
+			attrs = append(attrs, slog.String("progress", fmt.Sprintf("%.0f%%", 100*fraction)))
+		}
+
+		attrs = append(attrs, slog.Duration("elapsed", time.Since(in.started).Round(time.Second)))
+
+		logger.InfoContext(ctx, "progress", attrs...)
+	}
+}
+
+// wrap returns a writer to w that moves the status lines out of the way of
+// what is written, for output to the terminal they are drawn on.
+func (d *progressDisplay) wrap(w io.Writer) io.Writer {
+	if !d.live {
+		return w
+	}
+
+	return writerFunc(func(b []byte) (int, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.clear()
+		n, err := w.Write(b)
+		d.draw()
+
+		return n, err //nolint:wrapcheck // the writer is transparent
+	})
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) { return f(b) }
+
+// describeProgress returns the status of an input whose last event is p and
+// whose last FFmpeg pass is extracted percent done, or -1 when unknown, and
+// how far it has got from 0 to 1: the share of chunks transcribed when
+// there are several, otherwise of the FFmpeg pass, or -1 when unknown.
+func describeProgress(p transcriber.Progress, extracted float64) (string, float64) {
+	var status string
+
+	switch p.Stage {
+	case transcriber.StageExtracting:
+		status = "extracting audio"
+	case transcriber.StageUploading:
+		status = "uploading"
+	case transcriber.StageWaiting:
+		status = "waiting on the model"
+	case transcriber.StageTranscribed:
+		status = "transcribed"
+	default:
+		status = string(p.Stage)
+	}
+
+	switch {
+	case p.Chunks > 1:
+		if p.Chunk > 0 {
+			status += fmt.Sprintf(" (chunk %d)", p.Chunk)
+		}
+
+		return fmt.Sprintf("%s, %d/%d chunks done", status, p.Transcribed, p.Chunks),
+			float64(p.Transcribed) / float64(p.Chunks)
+	case p.Chunks == 0 && p.Transcribed > 0:
+		return fmt.Sprintf("%s, %d chunks done", status, p.Transcribed), -1
+	case p.Stage == transcriber.StageExtracting && extracted >= 0:
+		return status, extracted / 100
+	default:
+		return status, -1
+	}
+}
+
+// progressBar draws a bar filled to fraction, from 0 to 1.
+func progressBar(fraction float64) string {
+	filled := int(fraction*progressBarWidth + 0.5)
+	filled = max(0, min(progressBarWidth, filled))
+
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled) + "]"
+}
+
+// truncate shortens s to at most width characters.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	return string([]rune(s)[:max(0, width)])
+}
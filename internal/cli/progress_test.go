@@ -0,0 +1,76 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestDescribeProgress(t *testing.T) {
+	t.Parallel()
+
+	half := &transcriber.FFmpegProgress{Processed: time.Minute, Total: 2 * time.Minute}
+
+	tests := []struct {
+		name         string
+		p            transcriber.Progress
+		extracted    float64
+		wantStatus   string
+		wantFraction float64
+	}{
+		{
+			name:       "probing",
+			p:          transcriber.Progress{Stage: transcriber.StageProbing},
+			extracted:  -1,
+			wantStatus: "probing", wantFraction: -1,
+		},
+		{
+			name:       "extracting",
+			p:          transcriber.Progress{Stage: transcriber.StageExtracting, FFmpeg: half},
+			extracted:  50,
+			wantStatus: "extracting audio", wantFraction: 0.5,
+		},
+		{
+			name:       "single request",
+			p:          transcriber.Progress{Stage: transcriber.StageWaiting, Chunk: 1, Chunks: 1},
+			extracted:  100,
+			wantStatus: "waiting on the model", wantFraction: -1,
+		},
+		{
+			name:       "chunks",
+			p:          transcriber.Progress{Stage: transcriber.StageUploading, Chunk: 3, Chunks: 8, Transcribed: 2},
+			extracted:  40,
+			wantStatus: "uploading (chunk 3), 2/8 chunks done", wantFraction: 0.25,
+		},
+		{
+			name:       "extracting while chunks are transcribed",
+			p:          transcriber.Progress{Stage: transcriber.StageExtracting, FFmpeg: half, Chunks: 4, Transcribed: 1},
+			extracted:  50,
+			wantStatus: "extracting audio, 1/4 chunks done", wantFraction: 0.25,
+		},
+		{
+			name:       "unknown number of chunks",
+			p:          transcriber.Progress{Stage: transcriber.StageTranscribed, Chunk: 5, Transcribed: 5},
+			extracted:  -1,
+			wantStatus: "transcribed, 5 chunks done", wantFraction: -1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			status, fraction := cli.DescribeProgress(tc.p, tc.extracted)
+			if status != tc.wantStatus || fraction != tc.wantFraction {
+				t.Errorf("DescribeProgress() = %q, %v; want %q, %v", status, fraction, tc.wantStatus, tc.wantFraction)
+			}
+		})
+	}
+}
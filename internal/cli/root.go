@@ -7,10 +7,16 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -20,6 +26,26 @@ import (
 
 const appName = "Voice Transcriber"
 
+// Long-audio chunking defaults.
+const (
+	defaultChunkDuration    = 10 * time.Minute
+	defaultChunkOverlap     = 2 * time.Second
+	defaultChunkParallelism = 4
+)
+
+// Decoded audio format defaults: 16 kHz mono is what Gemini resamples
+// speech to anyway.
+const (
+	defaultSampleRate = config.DefaultSampleRate
+	defaultChannels   = config.DefaultChannels
+)
+
+// Silence trimming defaults.
+const (
+	defaultSilenceThreshold   = config.DefaultSilenceThreshold
+	defaultSilenceMinDuration = config.DefaultSilenceMinDuration
+)
+
 // NewRootCmd builds and returns the root Cobra command with all subcommands
 // wired in. cfg is the shared configuration that persistent flags write into.
 // info carries build-time version metadata; empty fields fall back to defaults.
@@ -32,14 +58,18 @@ Language is detected automatically from the audio by default.
 
 Features:
 • Automatic language detection (default) or specify with --language
-• Accepts both video files (mp4, mkv, mov, ...) and audio files (wav, mp3, flac, ...)
-• No Google Cloud Storage required — audio sent inline to Gemini
-• FFmpeg used only for video-to-audio extraction
+• Accepts both video files (mp4, mkv, mov, ...) and audio files (wav, mp3, flac, ...),
+  from disk, standard input, http(s) URLs or Cloud Storage
+• Local audio is sent inline to Gemini; gs:// audio is read by Gemini in place
+• FFmpeg extracts audio from video and decodes audio for chunking, cutting,
+  silence trimming, enhancement and formats Gemini does not accept
+• Long recordings are chunked at pauses and transcribed in parallel
+• Cached transcripts, resumable batches and a watch mode for drop folders
 • Cost-efficient: default model ~$0.03/hr of audio
-• Single binary - no extra runtime dependencies
+• Single binary - no runtime dependencies beyond FFmpeg
 
 Prerequisites:
-• FFmpeg installed (brew install ffmpeg / apt install ffmpeg) — for video files only
+• FFmpeg installed (brew install ffmpeg / apt install ffmpeg) — for video and processed audio
 • Google Cloud authentication (gcloud auth application-default login)
 • Vertex AI API enabled (gcloud services enable aiplatform.googleapis.com)
 • GCP project configured (gcloud config set project YOUR_PROJECT_ID)
@@ -51,6 +81,14 @@ Examples:
   voice-transcriber transcribe input/video.mp4 --verbose
   voice-transcriber transcribe input/video.mp4 --model gemini-3-flash-preview
   voice-transcriber transcribe input/video.mp4 --language uk
+  voice-transcriber transcribe input/movie.mkv --audio-stream lang:ukr
+  voice-transcriber transcribe input/movie.mkv --subtitles context --subtitle-stream lang:ukr
+  voice-transcriber transcribe input/session.mp4 --start 42:00 --end 1:15:00
+  voice-transcriber transcribe input/field.wav --enhance noisy-field
+  voice-transcriber transcribe input/call.wav --split-channels --channel-names left=Agent,right=Customer
+  voice-transcriber watch input/
+  voice-transcriber info input/video.mp4 --json
+  voice-transcriber cache prune --older-than 30d
   voice-transcriber version`,
 		SilenceUsage: true,
 		// Validate config flags before any subcommand runs.
@@ -68,8 +106,97 @@ Examples:
 		"Gemini model to use for transcription (e.g. gemini-3.1-flash-lite-preview, gemini-3-flash-preview)")
 	rootCmd.PersistentFlags().StringVar(&cfg.GCPLocation, "location", gemini.DefaultLocation,
 		"Vertex AI location (e.g. global, us-central1, europe-west4); Gemini 3.x models require global")
+	rootCmd.PersistentFlags().StringVar(&cfg.AudioStream, "audio-stream", "",
+		"Audio track to transcribe: a stream index from 'info' or lang:<tag> (e.g. 2, lang:ukr)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.AllAudioStreams, "all-audio-streams", false,
+		"Transcribe every audio track into its own output, using each track's language tag as a hint")
+	rootCmd.PersistentFlags().StringVar(&cfg.Subtitles, "subtitles", "",
+		"Use an embedded subtitle track: export (save it instead of transcribing), context (give it to the model) "+
+			"or diff (compare it with the transcript)")
+	rootCmd.PersistentFlags().StringVar(&cfg.SubtitleStream, "subtitle-stream", "",
+		"Subtitle track for --subtitles: a stream index from 'info' or lang:<tag> (default: the default text track)")
+	rootCmd.PersistentFlags().StringVar(&cfg.SubtitleFormat, "subtitle-format", "",
+		"Format of subtitles exported with --subtitles export: srt or vtt (default srt)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.SplitChannels, "split-channels", false,
+		"Transcribe each audio channel separately and merge them into a speaker-attributed transcript")
+	rootCmd.PersistentFlags().StringVar(&cfg.ChannelNames, "channel-names", "",
+		"Speaker names for --split-channels, e.g. left=Agent,right=Customer (channels: left, right or 1, 2, ...)")
+	rootCmd.PersistentFlags().StringVar(&cfg.Start, "start", "",
+		"Transcribe from this point of the input (HH:MM:SS.mmm or seconds)")
+	rootCmd.PersistentFlags().StringVar(&cfg.End, "end", "",
+		"Transcribe up to this point of the input (HH:MM:SS.mmm or seconds)")
+	rootCmd.PersistentFlags().StringVar(&cfg.RangesFile, "ranges", "",
+		"File listing time ranges to transcribe, one 'START END' per line")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Timestamps, "timestamps", false,
+		"Prefix each transcript segment with its start time (HH:MM:SS.mmm)")
+	rootCmd.PersistentFlags().DurationVar(&cfg.ChunkDuration, "chunk-duration", defaultChunkDuration,
+		"Split audio longer than this into chunks cut at pauses; longer gs:// audio is downloaded to split it (0 disables chunking)")
+	rootCmd.PersistentFlags().DurationVar(&cfg.ChunkOverlap, "chunk-overlap", defaultChunkOverlap,
+		"Audio shared between neighbouring chunks, de-duplicated when merging")
+	rootCmd.PersistentFlags().IntVar(&cfg.ChunkParallelism, "chunk-parallel", defaultChunkParallelism,
+		"Maximum number of chunks transcribed at once")
+	rootCmd.PersistentFlags().BoolVar(&cfg.TrimSilence, "trim-silence", false,
+		"Remove long silences with FFmpeg before upload (timestamps still match the original)")
+	rootCmd.PersistentFlags().Float64Var(&cfg.SilenceThreshold, "silence-threshold", defaultSilenceThreshold,
+		"Level in dBFS below which audio counts as silence for --trim-silence")
+	rootCmd.PersistentFlags().DurationVar(&cfg.SilenceMinDuration, "min-silence", defaultSilenceMinDuration,
+		"Shortest silence removed by --trim-silence")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Analyze, "analyze", false,
+		"Measure levels, clipping, noise and speech in the audio before upload and report them")
+	rootCmd.PersistentFlags().Float64Var(&cfg.MinSpeechRatio, "min-speech-ratio", 0,
+		"Least share of the audio that must be speech, e.g. 0.1 (implies --analyze)")
+	rootCmd.PersistentFlags().DurationVar(&cfg.MinSpeech, "min-speech", 0,
+		"Least total speech the audio must contain, e.g. 10s (implies --analyze)")
+	rootCmd.PersistentFlags().Float64Var(&cfg.MaxClipping, "max-clipping", 0,
+		"Largest share of samples that may be clipped, e.g. 0.01 (implies --analyze)")
+	rootCmd.PersistentFlags().Float64Var(&cfg.MinSNR, "min-snr", 0,
+		"Lowest estimated signal-to-noise ratio of speech in dB, e.g. 15 (implies --analyze)")
+	rootCmd.PersistentFlags().StringVar(&cfg.QualityAction, "quality-action", "",
+		"What to do with audio that fails a quality threshold: abort (default; nothing is uploaded) or warn")
+	rootCmd.PersistentFlags().StringVar(&cfg.UploadCodec, "upload-codec", "wav",
+		"Encoding for audio sent to Gemini: "+strings.Join(config.UploadCodecs, ", ")+
+			" (flac is lossless; opus and mp3 are smallest)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.TranscodeAudio, "transcode-audio", false,
+		"Also re-encode native audio inputs with --upload-codec instead of sending them as-is")
+	rootCmd.PersistentFlags().StringVar(&cfg.Enhance, "enhance", "",
+		"Clean up audio while decoding with a preset: "+strings.Join(config.EnhancePresets, ", "))
+	rootCmd.PersistentFlags().StringVar(&cfg.AudioFilter, "audio-filter", "",
+		"Custom FFmpeg -af filter chain applied while decoding, after any --enhance preset")
+	rootCmd.PersistentFlags().IntVar(&cfg.SampleRate, "sample-rate", defaultSampleRate,
+		"Sample rate in Hz of decoded audio")
+	rootCmd.PersistentFlags().IntVar(&cfg.Channels, "channels", defaultChannels,
+		"Channel count of decoded audio (1 or 2)")
+	rootCmd.PersistentFlags().IntVar(&cfg.RequestsPerMinute, "rpm", 0,
+		"Client-side limit on Gemini requests per minute (0 = model default)")
+	rootCmd.PersistentFlags().IntVar(&cfg.TokensPerMinute, "tpm", 0,
+		"Client-side limit on estimated input tokens per minute (0 = model default)")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxConcurrent, "max-concurrent", 0,
+		"Maximum Gemini requests in flight at once (0 = model default)")
+	rootCmd.PersistentFlags().StringVar(&cfg.DebugDir, "debug-dir", "",
+		"Write a redacted JSON record of every Gemini request and response to this directory")
+	rootCmd.PersistentFlags().BoolVar(&cfg.DebugAudio, "debug-audio", false,
+		"Also write the audio bytes of each request to --debug-dir")
+	rootCmd.PersistentFlags().StringVar(&cfg.TempDir, "tmp-dir", "",
+		"Directory for temporary files such as downloaded inputs and staged audio (default: the system temporary directory)")
+	rootCmd.PersistentFlags().StringVar(&cfg.CacheDir, "cache-dir", "",
+		"Directory of cached transcripts (default: voice-transcriber in the user cache directory)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.NoCache, "no-cache", false,
+		"Neither read nor write cached transcripts")
+	rootCmd.PersistentFlags().BoolVar(&cfg.RefreshCache, "refresh", false,
+		"Transcribe again even when a cached transcript exists, and cache the new one")
+	rootCmd.PersistentFlags().StringVar(&cfg.FFmpegPath, "ffmpeg", "",
+		"Path to the ffmpeg binary (default: ffmpeg on PATH)")
+	rootCmd.PersistentFlags().StringVar(&cfg.FFprobePath, "ffprobe", "",
+		"Path to the ffprobe binary (default: ffprobe on PATH)")
+	rootCmd.PersistentFlags().StringVar(&cfg.FFmpegInputArgs, "ffmpeg-input-args", "",
+		`Extra FFmpeg options placed before the input, e.g. "-hwaccel auto -analyzeduration 10M"`)
+	rootCmd.PersistentFlags().StringVar(&cfg.FFmpegOutputArgs, "ffmpeg-output-args", "",
+		`Extra FFmpeg options placed before the decoded output, e.g. "-threads 2"`)
 
 	rootCmd.AddCommand(newTranscribeCmd(cfg))
+	rootCmd.AddCommand(newWatchCmd(cfg))
+	rootCmd.AddCommand(newInfoCmd(cfg))
+	rootCmd.AddCommand(newCacheCmd(cfg))
 	rootCmd.AddCommand(newVersionCmd(info))
 
 	return rootCmd
@@ -77,24 +204,57 @@ Examples:
 
 // Execute builds the command tree with the provided version info and runs it.
 // This is called by main.main().
+// SIGINT and SIGTERM cancel the command's context, so that it can stop
+// FFmpeg and remove its temporary files before exiting; a second signal
+// terminates the process immediately.
 func Execute(info VersionInfo) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	cfg := config.FromEnv()
 	root := NewRootCmd(cfg, info)
 	// Wire the runtime version into Cobra's built-in --version flag.
 	root.Version = info.withDefaults().Version
 
-	if err := root.Execute(); err != nil {
+	if err := root.ExecuteContext(ctx); err != nil {
 		return fmt.Errorf("executing root command: %w", err)
 	}
 
 	return nil
 }
 
-// newLogger returns a slog.Logger appropriate for the current config:
+// Exit codes of the command besides 0, for success.
+const (
+	// ExitFailure reports that the command failed.
+	ExitFailure = 1
+	// ExitPartialFailure reports that a batch finished but some of its
+	// inputs failed.
+	ExitPartialFailure = 2
+)
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrPartialFailure):
+		return ExitPartialFailure
+	default:
+		return ExitFailure
+	}
+}
+
+// newLogger returns a slog.Logger appropriate for the current config,
+// writing to w, which is stderr or wraps it:
 //   - Quiet: all output discarded
-//   - Verbose: Debug level to stderr
-//   - Default: Info level to stderr (shows progress during long transcriptions)
-func newLogger(cfg *config.Config) *slog.Logger {
+//   - Verbose: Debug level
+//   - Default: Info level (shows progress during long transcriptions)
+func newLogger(cfg *config.Config, w io.Writer) *slog.Logger {
 	if cfg.Quiet {
 		return slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
@@ -104,5 +264,5 @@ func newLogger(cfg *config.Config) *slog.Logger {
 		level = slog.LevelDebug
 	}
 
-	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
 }
@@ -6,16 +6,21 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
 	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
 )
 
@@ -29,65 +34,204 @@ var sanitizeRe = regexp.MustCompile(`[^\p{L}\p{N}_\-.]`)
 // newTranscribeCmd constructs the transcribe subcommand.
 // cfg is the shared config populated by persistent flags on the root command.
 func newTranscribeCmd(cfg *config.Config) *cobra.Command {
-	var outputFile string
+	var (
+		outputFile, name string
+		batch            = batchOptions{Jobs: defaultJobs}
+	)
 
 	cmd := &cobra.Command{
-		Use:   "transcribe [media-file]",
-		Short: "Transcribe a video or audio file to text",
-		Long: `Transcribe a video or audio file to text using Google Gemini.
+		Use:   "transcribe [media-file | directory | glob | url | -]...",
+		Short: "Transcribe video or audio files to text",
+		Long: `Transcribe one or more video or audio files to text using Google Gemini.
 
 Language is detected automatically from the audio by default.
 Use --language to specify an ISO 639-1 code (e.g. uk, en, de).
 
 Supported input formats:
-  Video: mp4, mkv, mov, avi, wmv, flv, ts, mpeg, 3gp (audio extracted via FFmpeg)
-  Audio: wav, mp3, flac, ogg, m4a, aac, pcm, webm (sent directly to Gemini, no FFmpeg needed)`,
-		Args: cobra.ExactArgs(1),
-		RunE: func(_ *cobra.Command, args []string) error {
-			return runTranscribe(cfg, args[0], outputFile)
+  Video: mp4, m4v, mkv, mov, avi, wmv, flv, ts, mts, m2ts, mpeg, mpg, vob, 3gp
+         (audio extracted via FFmpeg)
+  Audio: wav, mp3, flac, ogg, opus, oga, m4a, aac, pcm, webm
+         (accepted by Gemini as they are; FFmpeg decodes them when needed
+         for chunking, --start/--end, --trim-silence or --enhance)
+  Other: aiff, aif, aifc, amr, wma
+         (audio Gemini does not accept; decoded by FFmpeg and uploaded in
+         --upload-codec)
+
+The input may also be an http(s) URL: files are downloaded first, while HLS
+and DASH playlists are streamed by FFmpeg. Audio in Cloud Storage given as a
+gs://bucket/object URI is read by Gemini in place; other objects are
+downloaded.
+
+Use - to read the media from standard input; named pipes and process
+substitutions are read the same way:
+  ffmpeg -i rtsp://camera/stream -t 600 -f wav - | voice-transcriber transcribe - --name camera
+  voice-transcriber transcribe <(curl -s https://example.com/talk.mp3) --input-format mp3
+
+Files with embedded text subtitles can use them with --subtitles: export
+saves the track next to the transcript path without calling Gemini, context
+gives it to the model as a reference for names and terms, and diff writes a
+report of where the subtitles and the new transcript disagree to
+<transcript>.subtitles-diff.txt.
+
+Several inputs, directories and quoted globs are transcribed as a batch by
+--jobs workers, so that one input is decoded while another waits on Gemini.
+Directories are searched recursively for media files, or for the files
+matching --include; --exclude drops files by name. A failed input does not
+stop the batch: a summary table lists every input, and the exit code is 2
+when some inputs failed and 1 when all did.
+  voice-transcriber transcribe input/ --include '*.mp4' --exclude 'draft-*'
+  voice-transcriber transcribe 'archive/2024-*.wav' talk.mp3 -o transcripts
+
+Each batch keeps a manifest of its inputs, their state, outputs, errors and
+usage: manifest.json in the output directory unless --manifest names
+another. --resume continues an interrupted batch from its manifest,
+skipping the inputs already done; pass it the same transcription flags as
+the original run. --retry-failed transcribes only the inputs that failed.
+  voice-transcriber transcribe --resume output/manifest.json
+  voice-transcriber transcribe --resume output/manifest.json --retry-failed`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if batch.Resume != "" {
+				return nil
+			}
+
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTranscribe(cmd.Context(), cfg, args, outputFile, name, batch)
 		},
 	}
 
 	cmd.Flags().StringVarP(&outputFile, "output", "o", "",
-		"Output file path (default: creates directory based on media filename)")
+		"Output file path, or output directory for a batch (default: creates directory based on media filename)")
+	cmd.Flags().StringVar(&name, "name", "",
+		"Name the default output directory and file after this instead of the media filename (default 'stdin' for -)")
+	cmd.Flags().StringVar(&cfg.InputFormat, "input-format", "",
+		"Format of the input as a file extension (e.g. mp3, wav, mp4), overriding its name and content")
+	cmd.Flags().IntVarP(&batch.Jobs, "jobs", "j", defaultJobs,
+		"Number of inputs of a batch transcribed at once")
+	cmd.Flags().StringSliceVar(&batch.Include, "include", nil,
+		"Only take files whose name matches one of these patterns from directories and globs (e.g. '*.mp4')")
+	cmd.Flags().StringSliceVar(&batch.Exclude, "exclude", nil,
+		"Skip files whose name matches one of these patterns in directories and globs")
+	cmd.Flags().StringVar(&batch.Manifest, "manifest", "",
+		"Where to save the manifest of a batch (default: manifest.json in the output directory)")
+	cmd.Flags().StringVar(&batch.Resume, "resume", "",
+		"Resume the batch recorded in this manifest, skipping inputs already done")
+	cmd.Flags().BoolVar(&batch.RetryFailed, "retry-failed", false,
+		"With --resume, transcribe only the inputs that failed")
 
 	return cmd
 }
 
 // runTranscribe is the extracted body of the transcribe RunE, making it testable.
-func runTranscribe(cfg *config.Config, mediaFile, outputFile string) error {
-	ctx := context.Background()
-	logger := newLogger(cfg)
+// A single input is transcribed directly; several, or any directory or glob,
+// make a batch. Temporary files are removed before it returns, also when
+// ctx is cancelled.
+func runTranscribe(
+	ctx context.Context, cfg *config.Config, args []string, outputFile, name string, batch batchOptions,
+) error {
+	if err := batch.validate(args, outputFile, name); err != nil {
+		return err
+	}
+
+	m, todo, mediaFile, err := planRun(args, outputFile, name, batch)
+	if err != nil {
+		return err
+	}
+
+	display := newProgressDisplay(cfg)
+	logger := newLogger(cfg, display.wrap(os.Stderr))
 
 	t, err := transcriber.New(ctx, cfg, logger)
 	if err != nil {
 		return fmt.Errorf("initialization failed: %w", err)
 	}
 
+	defer func() {
+		if err := t.Close(); err != nil {
+			logger.WarnContext(ctx, "failed to remove temporary files", slog.Any("error", err))
+		}
+	}()
+
+	defer display.start(ctx, t, logger)()
+
+	if m != nil {
+		return runBatch(ctx, cfg, t, logger, m, todo, batch.Jobs, display.wrap(os.Stdout))
+	}
+
+	// Determine output path.
+	transcriptPath := resolveOutputPath(outputFile, mediaFile)
+	if outputFile == "" && name != "" {
+		transcriptPath = defaultOutputPath(name)
+	}
+
+	_, err = transcribeInput(ctx, cfg, t, mediaFile, transcriptPath)
+
+	return err
+}
+
+// transcribeInput transcribes mediaFile, or exports its subtitles, and
+// saves the output at transcriptPath. It returns the transcription results:
+// one per audio stream with --all-audio-streams, none for an export. The
+// writing and done stages are reported to the subscribers of t.
+func transcribeInput(
+	ctx context.Context, cfg *config.Config, t *transcriber.Transcriber, mediaFile, transcriptPath string,
+) (results []*transcriber.TranscriptionResult, err error) {
+	defer func() {
+		t.Report(ctx, transcriber.Progress{Input: mediaFile, Stage: transcriber.StageDone, Err: err})
+	}()
+
+	if cfg.Subtitles == config.SubtitlesExport {
+		return nil, exportSubtitles(ctx, cfg, t, mediaFile, transcriptPath)
+	}
+
+	if cfg.AllAudioStreams {
+		results, err = t.TranscribeAllAudioStreams(ctx, mediaFile)
+		if err != nil {
+			return nil, fmt.Errorf("transcription failed: %w", err)
+		}
+
+		t.Report(ctx, transcriber.Progress{Input: mediaFile, Stage: transcriber.StageWriting})
+
+		for _, result := range results {
+			if err := saveResult(cfg, result, streamOutputPath(transcriptPath, result.Stream)); err != nil {
+				return results, err
+			}
+		}
+
+		return results, nil
+	}
+
 	result, err := t.TranscribeLocalFile(ctx, mediaFile)
 	if err != nil {
-		return fmt.Errorf("transcription failed: %w", err)
+		return nil, fmt.Errorf("transcription failed: %w", err)
 	}
 
+	t.Report(ctx, transcriber.Progress{Input: mediaFile, Stage: transcriber.StageWriting})
+
+	return []*transcriber.TranscriptionResult{result}, saveResult(cfg, result, transcriptPath)
+}
+
+// saveResult prints the transcription summary and writes the transcript to
+// transcriptPath, creating its directory as needed.
+func saveResult(cfg *config.Config, result *transcriber.TranscriptionResult, transcriptPath string) error {
 	if !cfg.Quiet {
-		fmt.Printf("\nTranscription completed:\n")
-		fmt.Printf("   Words: %d\n", result.WordCount)
-		fmt.Printf("   Characters: %d\n", len(result.Text))
-		fmt.Printf("   Processing time: %v\n", result.ProcessingTime)
-		fmt.Println(strings.Repeat("-", outputSeparatorWidth))
+		printSummary(result)
 	}
 
-	// Determine output path.
-	transcriptPath := resolveOutputPath(outputFile, mediaFile)
-
 	// Ensure the directory for the output file exists.
 	outputDir := filepath.Dir(transcriptPath)
 	if err := os.MkdirAll(outputDir, 0o750); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	text := result.Text
+	if cfg.Timestamps {
+		text = result.TimestampedText()
+	}
+
 	// Save transcript with secure file permissions (0600 = rw-------)
-	if err := os.WriteFile(transcriptPath, []byte(result.Text), 0o600); err != nil {
+	if err := os.WriteFile(transcriptPath, []byte(text), 0o600); err != nil {
 		return fmt.Errorf("failed to save transcript: %w", err)
 	}
 
@@ -95,9 +239,139 @@ func runTranscribe(cfg *config.Config, mediaFile, outputFile string) error {
 		fmt.Printf("Transcript saved to: %s\n", transcriptPath)
 	}
 
+	if result.SubtitleReport != nil {
+		return saveSubtitleReport(cfg, result, subtitleDiffPath(transcriptPath))
+	}
+
+	return nil
+}
+
+// exportSubtitles writes the embedded subtitle track of mediaFile next to
+// transcriptPath, with the extension of the configured subtitle format,
+// without transcribing the media.
+func exportSubtitles(
+	ctx context.Context, cfg *config.Config, t *transcriber.Transcriber, mediaFile, transcriptPath string,
+) error {
+	track, err := t.ExtractSubtitles(ctx, mediaFile)
+	if err != nil {
+		return fmt.Errorf("subtitle export failed: %w", err)
+	}
+
+	t.Report(ctx, transcriber.Progress{Input: mediaFile, Stage: transcriber.StageWriting})
+
+	path := subtitleExportPath(cfg, transcriptPath)
+
+	var buf bytes.Buffer
+	if err := subtitle.Write(&buf, subtitleFormat(cfg), track.Cues); err != nil {
+		return fmt.Errorf("subtitle export failed: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to save subtitles: %w", err)
+	}
+
+	if !cfg.Quiet {
+		fmt.Printf("Subtitles from stream #%d %s (%d cues) saved to: %s\n",
+			track.Stream.Index, track.Stream.Language, len(track.Cues), path)
+	}
+
+	return nil
+}
+
+// subtitleFormat returns the configured format of exported subtitles.
+func subtitleFormat(cfg *config.Config) string {
+	if cfg.SubtitleFormat == "" {
+		return subtitle.SRT
+	}
+
+	return cfg.SubtitleFormat
+}
+
+// subtitleExportPath returns where subtitles exported for transcriptPath
+// are saved: beside it, with the extension of their format.
+func subtitleExportPath(cfg *config.Config, transcriptPath string) string {
+	return strings.TrimSuffix(transcriptPath, filepath.Ext(transcriptPath)) + "." + subtitleFormat(cfg)
+}
+
+// saveSubtitleReport writes the comparison of the subtitle track with the
+// transcript to path.
+func saveSubtitleReport(cfg *config.Config, result *transcriber.TranscriptionResult, path string) error {
+	var buf bytes.Buffer
+	if err := result.SubtitleReport.WriteText(&buf); err != nil {
+		return fmt.Errorf("failed to save subtitle comparison: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to save subtitle comparison: %w", err)
+	}
+
+	if !cfg.Quiet {
+		fmt.Printf("Subtitle comparison saved to: %s\n", path)
+	}
+
 	return nil
 }
 
+// subtitleDiffPath derives the path of the subtitle comparison from the
+// transcript path: talk.txt becomes talk.subtitles-diff.txt.
+func subtitleDiffPath(transcriptPath string) string {
+	return strings.TrimSuffix(transcriptPath, filepath.Ext(transcriptPath)) + ".subtitles-diff.txt"
+}
+
+// printSummary prints the statistics of one transcription.
+func printSummary(result *transcriber.TranscriptionResult) {
+	fmt.Printf("\nTranscription completed:\n")
+
+	if s := result.Stream; s != nil {
+		fmt.Printf("   Audio stream: #%d %s\n", s.Index, s.Language)
+	}
+
+	if result.Cached {
+		fmt.Printf("   Source: cached transcript (nothing uploaded)\n")
+	}
+
+	fmt.Printf("   Words: %d\n", result.WordCount)
+	fmt.Printf("   Characters: %d\n", len(result.Text))
+
+	if result.Chunks > 1 {
+		fmt.Printf("   Chunks: %d\n", result.Chunks)
+	}
+
+	if result.SourceDuration > 0 {
+		fmt.Printf("   Silence removed: %v of %v (%.0f%%)\n",
+			result.SilenceRemoved.Round(time.Second), result.SourceDuration.Round(time.Second),
+			100*result.SilenceRemoved.Seconds()/result.SourceDuration.Seconds())
+	}
+
+	if q := result.Quality; q != nil {
+		fmt.Printf("   Audio: %v, RMS %.1f dBFS, peak %.1f dBFS, clipped %.2f%%, SNR ~%.0f dB\n",
+			q.Duration.Round(time.Second), q.RMS, q.Peak, 100*q.Clipping, q.SNR)
+		fmt.Printf("   Speech: %v (%.0f%%)\n", q.Speech.Round(time.Second), 100*q.SpeechRatio)
+
+		for _, problem := range q.Problems {
+			fmt.Printf("   Warning: %s\n", problem)
+		}
+	}
+
+	if r := result.SubtitleReport; r != nil {
+		fmt.Printf("   Subtitle agreement: %.1f%% (stream #%d, %d cues)\n",
+			100*r.Agreement(), result.Subtitles.Stream.Index, len(r.Cues))
+	}
+
+	if result.InputSize > 0 && result.UploadSize > 0 {
+		fmt.Printf("   Uploaded: %s (input %s, %.0f%%)\n",
+			gemini.FormatBytes(result.UploadSize), gemini.FormatBytes(result.InputSize),
+			100*float64(result.UploadSize)/float64(result.InputSize))
+	}
+
+	fmt.Printf("   Processing time: %v\n", result.ProcessingTime)
+	fmt.Println(strings.Repeat("-", outputSeparatorWidth))
+}
+
 // sanitizeFilename removes special characters and replaces spaces with underscores
 // to create a safe filename for use in the filesystem.
 // Preserves Unicode letters (including multilingual scripts) for internationalized filenames.
@@ -114,16 +388,51 @@ func sanitizeFilename(filename string) string {
 
 // resolveOutputPath returns the final transcript output path.
 // If outputFile is non-empty it is returned unchanged; otherwise a default
-// path of output/<sanitized-name>/<sanitized-name>.txt is derived from mediaFile.
+// path of output/<sanitized-name>/<sanitized-name>.txt is derived from the file
+// name of mediaFile, which may be a URL, or from "stdin" when the media is read
+// from standard input.
 func resolveOutputPath(outputFile, mediaFile string) string {
 	if outputFile != "" {
 		return outputFile
 	}
 
-	mediaBaseName := filepath.Base(mediaFile)
+	if mediaFile == transcriber.StdinPath {
+		return defaultOutputPath("stdin")
+	}
+
+	mediaBaseName := transcriber.InputFileName(mediaFile)
 	mediaNameWithoutExt := strings.TrimSuffix(mediaBaseName, filepath.Ext(mediaBaseName))
-	sanitizedName := sanitizeFilename(mediaNameWithoutExt)
-	outputSubDir := filepath.Join("output", sanitizedName)
+
+	return defaultOutputPath(mediaNameWithoutExt)
+}
+
+// defaultOutputPath returns output/<sanitized-name>/<sanitized-name>.txt.
+func defaultOutputPath(name string) string {
+	return outputPathIn("output", name)
+}
+
+// outputPathIn returns <dir>/<sanitized-name>/<sanitized-name>.txt.
+func outputPathIn(dir, name string) string {
+	sanitizedName := sanitizeFilename(name)
+	outputSubDir := filepath.Join(dir, sanitizedName)
 
 	return filepath.Join(outputSubDir, sanitizedName+".txt")
 }
+
+// streamOutputPath derives the transcript path of one audio stream from the
+// path of the whole file: talk.txt becomes talk.track2-ukr.txt for stream 2
+// tagged "ukr".
+func streamOutputPath(transcriptPath string, stream *transcriber.StreamInfo) string {
+	if stream == nil {
+		return transcriptPath
+	}
+
+	suffix := fmt.Sprintf(".track%d", stream.Index)
+	if stream.Language != "" {
+		suffix += "-" + sanitizeFilename(stream.Language)
+	}
+
+	ext := filepath.Ext(transcriptPath)
+
+	return strings.TrimSuffix(transcriptPath, ext) + suffix + ext
+}
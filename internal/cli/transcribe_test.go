@@ -12,6 +12,7 @@ import (
 
 	"github.com/idvoretskyi/voice-transcriber/internal/cli"
 	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -185,6 +186,24 @@ func TestResolveOutputPath(t *testing.T) {
 			mediaFile:  "!!!.mp4",
 			want:       "output/transcript/transcript.txt",
 		},
+		{
+			name:       "URL named after its last path segment",
+			outputFile: "",
+			mediaFile:  "https://files.example.com/calls/2025/weekly%20sync.mp3?token=abc",
+			want:       "output/weekly_sync/weekly_sync.txt",
+		},
+		{
+			name:       "Cloud Storage object named after the object",
+			outputFile: "",
+			mediaFile:  "gs://archive/2025/interviews/ivanna.mp3",
+			want:       "output/ivanna/ivanna.txt",
+		},
+		{
+			name:       "standard input named stdin",
+			outputFile: "",
+			mediaFile:  "-",
+			want:       "output/stdin/stdin.txt",
+		},
 	}
 
 	for _, tc := range tests {
@@ -199,3 +218,43 @@ func TestResolveOutputPath(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultOutputPath(t *testing.T) {
+	t.Parallel()
+
+	// A --name is used whole: dots are not taken for an extension.
+	got := cli.DefaultOutputPath("standup 2025.10.18")
+	if want := "output/standup_2025.10.18/standup_2025.10.18.txt"; got != want {
+		t.Errorf("DefaultOutputPath() = %q; want %q", got, want)
+	}
+}
+
+func TestStreamOutputPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path   string
+		stream *transcriber.StreamInfo
+		want   string
+	}{
+		{path: "output/talk/talk.txt", stream: &transcriber.StreamInfo{Index: 2, Language: "ukr"},
+			want: "output/talk/talk.track2-ukr.txt"},
+		{path: "out.txt", stream: &transcriber.StreamInfo{Index: 1}, want: "out.track1.txt"},
+		{path: "transcript", stream: &transcriber.StreamInfo{Index: 3, Language: "eng"}, want: "transcript.track3-eng"},
+		{path: "out.txt", stream: nil, want: "out.txt"},
+	}
+
+	for _, tc := range tests {
+		if got := cli.StreamOutputPath(tc.path, tc.stream); got != tc.want {
+			t.Errorf("StreamOutputPath(%q, %+v) = %q; want %q", tc.path, tc.stream, got, tc.want)
+		}
+	}
+}
+
+func TestSubtitleDiffPath(t *testing.T) {
+	t.Parallel()
+
+	if got, want := cli.SubtitleDiffPath("output/talk/talk.txt"), "output/talk/talk.subtitles-diff.txt"; got != want {
+		t.Errorf("SubtitleDiffPath() = %q; want %q", got, want)
+	}
+}
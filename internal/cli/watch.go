@@ -0,0 +1,360 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+	"github.com/idvoretskyi/voice-transcriber/internal/watch"
+)
+
+// defaultWatchDir is the directory watched when none is given.
+const defaultWatchDir = "input"
+
+// watchOptions holds the flags of the watch subcommand.
+type watchOptions struct {
+	// OutputDir is where transcripts are written.
+	OutputDir string
+	// DoneDir and FailedDir receive the files transcribed and those that
+	// failed; when empty, done/ and failed/ in the file's own directory.
+	DoneDir   string
+	FailedDir string
+	// Watch configures how files are found; Pick chooses among them and
+	// how many are transcribed at once.
+	Watch watch.Options
+	Pick  batchOptions
+}
+
+// newWatchCmd constructs the watch subcommand.
+// cfg is the shared config populated by persistent flags on the root command.
+func newWatchCmd(cfg *config.Config) *cobra.Command {
+	opts := watchOptions{Pick: batchOptions{Jobs: defaultJobs}}
+
+	cmd := &cobra.Command{
+		Use:   "watch [directory]...",
+		Short: "Transcribe media files as they appear in directories",
+		Long: `Watch one or more directories, input/ by default, and transcribe every
+media file that appears in them, including those already there.
+
+A file is transcribed once its size and modification time have not changed
+for --settle, so recordings still being copied or written are left alone.
+Changes are picked up with inotify on Linux; elsewhere, or with --poll for
+network file systems, the directories are scanned every --poll-interval.
+Subdirectories and hidden files are ignored.
+
+Transcripts are written to output/<name>/<name>.txt, or under -o; a name
+already taken is numbered, as in output/<name>-1/, rather than overwritten.
+The file is then moved to done/ beside it, or to failed/ if transcription
+failed; --done-dir and --failed-dir name other directories. Up to --jobs
+files are transcribed at once.
+
+SIGINT or SIGTERM stops watching: files being transcribed are left where
+they are and picked up again on the next start.
+
+Examples:
+  voice-transcriber watch
+  voice-transcriber watch /srv/dictaphone /srv/calls -o /srv/transcripts --include '*.wav'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{defaultWatchDir}
+			}
+
+			return runWatch(cmd.Context(), cfg, args, opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.OutputDir, "output", "o", "output", "Directory transcripts are written to")
+	cmd.Flags().StringVar(&opts.DoneDir, "done-dir", "",
+		"Directory transcribed files are moved to (default: done/ in the watched directory)")
+	cmd.Flags().StringVar(&opts.FailedDir, "failed-dir", "",
+		"Directory files that failed are moved to (default: failed/ in the watched directory)")
+	cmd.Flags().DurationVar(&opts.Watch.Settle, "settle", watch.DefaultSettle,
+		"How long a file must stay unchanged before it is transcribed")
+	cmd.Flags().DurationVar(&opts.Watch.Interval, "poll-interval", watch.DefaultInterval,
+		"How often directories are scanned when polling")
+	cmd.Flags().BoolVar(&opts.Watch.Poll, "poll", false,
+		"Scan directories every --poll-interval instead of using inotify, e.g. for network file systems")
+	cmd.Flags().IntVarP(&opts.Pick.Jobs, "jobs", "j", defaultJobs, "Number of files transcribed at once")
+	cmd.Flags().StringSliceVar(&opts.Pick.Include, "include", nil,
+		"Only transcribe files whose name matches one of these patterns (e.g. '*.wav'; default: media files)")
+	cmd.Flags().StringSliceVar(&opts.Pick.Exclude, "exclude", nil,
+		"Ignore files whose name matches one of these patterns")
+
+	return cmd
+}
+
+// runWatch transcribes the files that appear in dirs until ctx is done,
+// writing a line about each to w. It returns nil when stopped by ctx.
+func runWatch(ctx context.Context, cfg *config.Config, dirs []string, opts watchOptions, w io.Writer) error {
+	if opts.Pick.Jobs < 1 {
+		return fmt.Errorf("--jobs must be at least 1")
+	}
+
+	if err := opts.Pick.checkPatterns(); err != nil {
+		return err
+	}
+
+	display := newProgressDisplay(cfg)
+	logger := newLogger(cfg, display.wrap(os.Stderr))
+	w = display.wrap(w)
+
+	watcher, err := watch.New(dirs, opts.Watch, logger)
+	if err != nil {
+		return fmt.Errorf("starting watch: %w", err)
+	}
+
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			logger.WarnContext(ctx, "failed to stop watching", slog.Any("error", err))
+		}
+	}()
+
+	t, err := transcriber.New(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+
+	defer func() {
+		if err := t.Close(); err != nil {
+			logger.WarnContext(ctx, "failed to remove temporary files", slog.Any("error", err))
+		}
+	}()
+
+	defer display.start(ctx, t, logger)()
+
+	// Per-file summaries would interleave; one line per file replaces them.
+	itemCfg := *cfg
+	itemCfg.Quiet = true
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		work   = make(chan string)
+		counts = make(map[string]int)
+	)
+
+	// report writes a line about a file. Workers call it holding mu, which
+	// also serialises moves and output directories so that two files of
+	// the same name get distinct targets.
+	report := func(format string, args ...any) {
+		if !cfg.Quiet {
+			fmt.Fprintf(w, format+"\n", args...)
+		}
+	}
+
+	for range opts.Pick.Jobs {
+		wg.Go(func() {
+			for path := range work {
+				outcome := processWatched(ctx, &itemCfg, t, path, opts, &mu, report)
+
+				mu.Lock()
+				counts[outcome]++
+				mu.Unlock()
+			}
+		})
+	}
+
+	mode := "inotify"
+	if !watcher.Notifying() {
+		mode = "polling every " + opts.Watch.Interval.String()
+	}
+
+	report("Watching %s (%s); transcripts go to %s. Press Ctrl-C to stop.",
+		strings.Join(dirs, ", "), mode, opts.OutputDir)
+
+	watcher.Run(ctx, func(path string) {
+		if !opts.Pick.picks(path) {
+			logger.DebugContext(ctx, "ignoring file", slog.String("path", path))
+
+			return
+		}
+
+		select {
+		case work <- path:
+		case <-ctx.Done():
+		}
+	})
+
+	close(work)
+	wg.Wait()
+
+	report("Stopped watching: %d transcribed, %d failed, %d left in place.",
+		counts["done"], counts["failed"], counts["interrupted"])
+
+	return nil
+}
+
+// processWatched transcribes the watched file at path and moves it to the
+// done or failed directory, writing a line about it with report under mu.
+// A file interrupted by ctx is left in place. It returns the outcome:
+// done, failed or interrupted.
+func processWatched(
+	ctx context.Context, cfg *config.Config, t *transcriber.Transcriber, path string, opts watchOptions,
+	mu *sync.Mutex, report func(string, ...any),
+) string {
+	start := time.Now()
+	base := filepath.Base(path)
+
+	mu.Lock()
+	output, err := claimOutputPath(opts.OutputDir, strings.TrimSuffix(base, filepath.Ext(base)))
+	mu.Unlock()
+
+	var results []*transcriber.TranscriptionResult
+	if err == nil {
+		results, err = transcribeInput(ctx, cfg, t, path, output)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ctx.Err() != nil {
+		report("%s: interrupted; left in place", path)
+
+		return "interrupted"
+	}
+
+	outcome, dest := "done", movedDir(path, opts.DoneDir, "done")
+	if err != nil {
+		outcome, dest = "failed", movedDir(path, opts.FailedDir, "failed")
+	}
+
+	where := "left in place"
+	if moved, merr := moveFile(path, dest); merr != nil {
+		report("%s: %v", path, merr)
+	} else {
+		where = "moved to " + moved
+	}
+
+	if err != nil {
+		if output != "" {
+			// Frees the claimed name unless something was written there.
+			_ = os.Remove(filepath.Dir(output))
+		}
+
+		msg, _, _ := strings.Cut(err.Error(), "\n")
+		report("%s failed: %s; %s", path, msg, where)
+
+		return outcome
+	}
+
+	report("%s: %d words in %v, saved to %s; %s",
+		path, usageOf(results).Words, time.Since(start).Round(time.Second), output, where)
+
+	return outcome
+}
+
+// claimOutputPath creates the directory for the transcript of a watched
+// file called name in dir and returns the transcript's path, numbering the
+// directory as moveFile numbers files if a transcript of that name exists,
+// so that files dropped again or into another watched directory never
+// overwrite an earlier transcript. Callers hold the watch lock.
+func claimOutputPath(dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("creating output directory: %w", err)
+	}
+
+	name = sanitizeFilename(name)
+	claimed := name
+
+	for n := 1; ; n++ {
+		err := os.Mkdir(filepath.Join(dir, claimed), 0o750)
+		if err == nil {
+			return outputPathIn(dir, claimed), nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("creating output directory: %w", err)
+		}
+
+		claimed = fmt.Sprintf("%s-%d", name, n)
+	}
+}
+
+// movedDir returns the directory a watched file at path is moved to: dir,
+// or the named subdirectory of the file's own directory when dir is empty.
+func movedDir(path, dir, name string) string {
+	if dir != "" {
+		return dir
+	}
+
+	return filepath.Join(filepath.Dir(path), name)
+}
+
+// moveFile moves the file at path into dir, numbering its name if dir
+// already holds a file of that name, and returns its new path. Files are
+// copied across file systems.
+func moveFile(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("moving %s: %w", path, err)
+	}
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	target := filepath.Join(dir, base)
+
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		target = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext))
+	}
+
+	err := os.Rename(path, target)
+	if errors.Is(err, syscall.EXDEV) {
+		err = copyFile(path, target)
+		if err == nil {
+			err = os.Remove(path)
+		}
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("moving %s: %w", path, err)
+	}
+
+	return target, nil
+}
+
+// copyFile copies the file at src to a new file at dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) // #nosec G304 -- src is a file in a directory the user asked to watch
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec G304 -- dst is in a chosen directory
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(dst)
+
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cli"
+)
+
+func TestMovedDir(t *testing.T) {
+	t.Parallel()
+
+	got, want := cli.MovedDir(filepath.Join("input", "a.wav"), "", "done"), filepath.Join("input", "done")
+	if got != want {
+		t.Errorf("MovedDir() = %s; want %s", got, want)
+	}
+
+	if got := cli.MovedDir(filepath.Join("input", "a.wav"), "archive", "done"); got != "archive" {
+		t.Errorf("MovedDir() with a directory = %s; want archive", got)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	done := filepath.Join(dir, "done")
+
+	for i, want := range []string{"talk.mp3", "talk-1.mp3", "talk-2.mp3"} {
+		path := filepath.Join(dir, "talk.mp3")
+		if err := os.WriteFile(path, []byte{byte(i)}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		moved, err := cli.MoveFile(path, done)
+		if err != nil {
+			t.Fatalf("MoveFile() unexpected error: %v", err)
+		}
+
+		if moved != filepath.Join(done, want) {
+			t.Errorf("move %d went to %s; want %s", i+1, moved, want)
+		}
+
+		if data, err := os.ReadFile(moved); err != nil || len(data) != 1 || data[0] != byte(i) {
+			t.Errorf("moved file holds %v, %v; want the content of move %d", data, err, i+1)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after the move", path)
+		}
+	}
+}
+
+func TestClaimOutputPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for _, want := range []string{"my_talk", "my_talk-1", "my_talk-2"} {
+		got, err := cli.ClaimOutputPath(dir, "my talk")
+		if err != nil {
+			t.Fatalf("ClaimOutputPath() unexpected error: %v", err)
+		}
+
+		if path := filepath.Join(dir, want, want+".txt"); got != path {
+			t.Errorf("ClaimOutputPath() = %s; want %s", got, path)
+		}
+
+		if info, err := os.Stat(filepath.Dir(got)); err != nil || !info.IsDir() {
+			t.Errorf("output directory %s not created: %v", filepath.Dir(got), err)
+		}
+	}
+}
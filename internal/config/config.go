@@ -7,15 +7,22 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // iso639Re matches exactly two lowercase ASCII letters (ISO 639-1 code).
 var iso639Re = regexp.MustCompile(`^[a-z]{2}$`)
 
+// inputFormatRe matches the accepted values of Config.InputFormat.
+var inputFormatRe = regexp.MustCompile(`^[a-z0-9]{1,8}$`)
+
 // NormalizeLanguage normalizes and validates a language string.
 // It trims and lowercases the input, then returns:
 //   - code="", auto=true  when input is empty or "auto" (automatic detection)
@@ -34,6 +41,203 @@ func NormalizeLanguage(language string) (code string, auto bool) {
 	return lang, false
 }
 
+// languageTagRe matches a two- or three-letter ISO 639 language tag.
+var languageTagRe = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// iso639Part2 maps ISO 639-2 codes, as found in media stream language tags,
+// to their ISO 639-1 equivalents. Both bibliographic and terminological
+// variants are listed where they differ.
+var iso639Part2 = map[string]string{
+	"ara": "ar", "bel": "be", "bul": "bg", "ces": "cs", "cze": "cs", "dan": "da",
+	"deu": "de", "ger": "de", "ell": "el", "gre": "el", "eng": "en", "spa": "es",
+	"est": "et", "fin": "fi", "fra": "fr", "fre": "fr", "heb": "he", "hin": "hi",
+	"hrv": "hr", "hun": "hu", "ind": "id", "ita": "it", "jpn": "ja", "kat": "ka",
+	"geo": "ka", "kaz": "kk", "kor": "ko", "lit": "lt", "lav": "lv", "nld": "nl",
+	"dut": "nl", "nor": "no", "pol": "pl", "por": "pt", "ron": "ro", "rum": "ro",
+	"rus": "ru", "slk": "sk", "slo": "sk", "slv": "sl", "srp": "sr", "swe": "sv",
+	"tha": "th", "tur": "tr", "ukr": "uk", "vie": "vi", "zho": "zh", "chi": "zh",
+}
+
+// LanguageFromTag converts a media language tag ("ukr", "uk", "ENG") to an
+// ISO 639-1 code usable as --language. It reports false for unknown or
+// undetermined ("und") tags.
+func LanguageFromTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	if iso639Re.MatchString(tag) {
+		return tag, true
+	}
+
+	code, ok := iso639Part2[tag]
+
+	return code, ok
+}
+
+// AudioStreamSelector identifies one audio track of a multi-track input.
+type AudioStreamSelector struct {
+	// Index is the stream index as listed by the info command, or -1 when
+	// selecting by language.
+	Index int
+	// Language is a two- or three-letter language tag (e.g. "ukr").
+	Language string
+}
+
+// ParseAudioStream parses an --audio-stream value: a stream index ("1") or
+// a language tag prefixed with "lang:" ("lang:ukr").
+func ParseAudioStream(s string) (AudioStreamSelector, error) {
+	s = strings.TrimSpace(s)
+
+	if lang, ok := strings.CutPrefix(s, "lang:"); ok {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if !languageTagRe.MatchString(lang) {
+			return AudioStreamSelector{}, fmt.Errorf("invalid language tag %q: must be two or three letters", lang)
+		}
+
+		return AudioStreamSelector{Index: -1, Language: lang}, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return AudioStreamSelector{}, fmt.Errorf("invalid audio stream %q: must be a stream index or lang:<tag>", s)
+	}
+
+	return AudioStreamSelector{Index: index}, nil
+}
+
+// ParseChannelNames parses a --channel-names value such as
+// "left=Agent,right=Customer" into speaker names keyed by zero-based channel
+// index. Channels are given as "left", "right" or a one-based number.
+func ParseChannelNames(s string) (map[int]string, error) {
+	names := make(map[int]string)
+
+	for entry := range strings.SplitSeq(s, ",") {
+		key, name, ok := strings.Cut(entry, "=")
+		key, name = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(name)
+
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid entry %q: want channel=name", strings.TrimSpace(entry))
+		}
+
+		var channel int
+
+		switch key {
+		case "left":
+			channel = 0
+		case "right":
+			channel = 1
+		default:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid channel %q: must be left, right or a number from 1", key)
+			}
+
+			channel = n - 1
+		}
+
+		if _, dup := names[channel]; dup {
+			return nil, fmt.Errorf("channel %q named twice", key)
+		}
+
+		names[channel] = name
+	}
+
+	return names, nil
+}
+
+// ParseTimestamp parses a media timestamp written either as plain seconds
+// ("90", "90.5") or as a clock value ("1:30", "00:01:30.500"). Clock values
+// accept one to three colon-separated fields with optional fractional seconds;
+// a comma is accepted as the decimal separator (as used in SRT files).
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return 0, fmt.Errorf("empty timestamp")
+	}
+
+	fields := strings.Split(s, ":")
+	if len(fields) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q: too many fields", s)
+	}
+
+	var total float64
+
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || v < 0 || (i < len(fields)-1 && strings.Contains(f, ".")) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+
+		total = total*60 + v
+	}
+
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// UploadCodecs lists the accepted values of Config.UploadCodec.
+var UploadCodecs = []string{"wav", "flac", "opus", "mp3"}
+
+// EnhancePresets lists the accepted values of Config.Enhance.
+var EnhancePresets = []string{"voice", "phone", "noisy-field", "lecture-hall"}
+
+// Accepted values of Config.Subtitles.
+const (
+	// SubtitlesExport skips transcription and exports the subtitle track.
+	SubtitlesExport = "export"
+	// SubtitlesContext gives the subtitle track to the model as a reference.
+	SubtitlesContext = "context"
+	// SubtitlesDiff compares the subtitle track with the new transcript.
+	SubtitlesDiff = "diff"
+)
+
+// SubtitleModes lists the accepted values of Config.Subtitles.
+var SubtitleModes = []string{SubtitlesExport, SubtitlesContext, SubtitlesDiff}
+
+// SubtitleFormats lists the accepted values of Config.SubtitleFormat.
+var SubtitleFormats = []string{"srt", "vtt"}
+
+// Accepted values of Config.QualityAction.
+const (
+	// QualityAbort refuses to transcribe audio that fails a threshold.
+	QualityAbort = "abort"
+	// QualityWarn logs a warning and transcribes the audio anyway.
+	QualityWarn = "warn"
+)
+
+// QualityActions lists the accepted values of Config.QualityAction.
+var QualityActions = []string{QualityAbort, QualityWarn}
+
+// DefaultSilenceThreshold is the level in dBFS below which silence
+// trimming and audio analysis count audio as silence when
+// Config.SilenceThreshold is zero.
+const DefaultSilenceThreshold = -45.0
+
+// DefaultSilenceMinDuration is the shortest silence trimmed when
+// Config.SilenceMinDuration is zero.
+const DefaultSilenceMinDuration = 3 * time.Second
+
+// Accepted ranges of Config.SampleRate and Config.Channels.
+const (
+	MinSampleRate = 8000
+	MaxSampleRate = 48000
+	MaxChannels   = 2
+)
+
+// Decoded audio format used when Config.SampleRate or Config.Channels is
+// zero.
+const (
+	DefaultSampleRate = 16000
+	DefaultChannels   = 1
+)
+
+// MaxInlineSize is the largest audio payload sent inline in a Gemini
+// request, and so the largest chunk, overlap included.
+const MaxInlineSize = 20 << 20
+
+// MinChunkDuration is the shortest accepted Config.ChunkDuration other than
+// zero. Shorter windows leave too little audio between cut points for the
+// chunker to advance, and would cost a request every few seconds anyway.
+const MinChunkDuration = 10 * time.Second
+
 // Config holds application configuration.
 type Config struct {
 	Verbose bool
@@ -47,9 +251,128 @@ type Config struct {
 	GeminiModel string // e.g., "gemini-3.1-flash-lite-preview", "gemini-3-flash-preview"
 	GCPLocation string // Vertex AI location, e.g., "global", "us-central1"
 
+	// InputFormat names the format of the input as a file extension, e.g.
+	// "mp3" or "mp4", overriding what its name and content suggest. It is
+	// how piped input, which has no name, is classified when its content is
+	// not recognised.
+	InputFormat string
+
+	// AudioStream selects one audio track of a multi-track input, as parsed
+	// by ParseAudioStream; empty lets FFmpeg pick the default track.
+	// AllAudioStreams instead transcribes every track into its own output.
+	AudioStream     string
+	AllAudioStreams bool
+
+	// Subtitles selects how an embedded subtitle track is used: one of
+	// SubtitleModes, or "" to ignore subtitles. SubtitleStream picks the
+	// track, as parsed by ParseAudioStream; empty prefers the default text
+	// track. SubtitleFormat is the file format exported subtitles are
+	// written in, one of SubtitleFormats; empty means SRT.
+	Subtitles      string
+	SubtitleStream string
+	SubtitleFormat string
+
+	// SplitChannels transcribes each audio channel separately and merges the
+	// results into one speaker-attributed transcript. ChannelNames names the
+	// speakers, as parsed by ParseChannelNames.
+	SplitChannels bool
+	ChannelNames  string
+
+	// Start and End limit transcription to one range of the input, as
+	// timestamps accepted by ParseTimestamp. RangesFile instead lists
+	// several ranges; see ParseRanges. Use TimeRanges to resolve them.
+	Start      string
+	End        string
+	RangesFile string
+
+	// Timestamps requests timed segments from the model and writes each
+	// segment of the transcript prefixed with its start time.
+	Timestamps bool
+
+	// Long-audio chunking. Audio longer than ChunkDuration is split at
+	// detected silences into windows of about ChunkDuration, overlapping by
+	// ChunkOverlap, and up to ChunkParallelism chunks are transcribed at once.
+	// A ChunkDuration of zero disables chunking.
+	ChunkDuration    time.Duration
+	ChunkOverlap     time.Duration
+	ChunkParallelism int
+
+	// TrimSilence removes silences of at least SilenceMinDuration quieter
+	// than SilenceThreshold (dBFS) before upload; zero values mean
+	// DefaultSilenceMinDuration and DefaultSilenceThreshold. Timestamps in
+	// the result still refer to the original media.
+	TrimSilence        bool
+	SilenceThreshold   float64
+	SilenceMinDuration time.Duration
+
+	// Analyze measures the decoded audio before upload: its levels,
+	// clipping, estimated signal-to-noise ratio and share of speech. It is
+	// implied by any of the thresholds, which are not checked when zero:
+	// MinSpeechRatio and MaxClipping are fractions, MinSNR is in dB.
+	// QualityAction says what happens when audio fails one, one of
+	// QualityActions; empty means QualityAbort. Analysis counts audio
+	// quieter than SilenceThreshold as silence, or quieter than
+	// DefaultSilenceThreshold when that is zero.
+	Analyze        bool
+	MinSpeechRatio float64
+	MinSpeech      time.Duration
+	MaxClipping    float64
+	MinSNR         float64
+	QualityAction  string
+
+	// UploadCodec selects how audio produced by FFmpeg is encoded for upload:
+	// one of UploadCodecs, or "" for WAV. TranscodeAudio also re-encodes
+	// native audio inputs, which are otherwise sent as-is.
+	UploadCodec    string
+	TranscodeAudio bool
+
+	// Enhance names one of EnhancePresets, a filter chain that cleans up
+	// noisy audio while decoding; AudioFilter is a custom FFmpeg -af chain
+	// applied after it. Either routes native audio through FFmpeg too.
+	Enhance     string
+	AudioFilter string
+
+	// SampleRate and Channels set the PCM format audio is decoded to;
+	// zero keeps the default of 16 kHz mono.
+	SampleRate int
+	Channels   int
+
 	// GCPProject is the Google Cloud project ID. Populated by FromEnv or
 	// resolved at runtime via gcloud when empty.
 	GCPProject string
+
+	// Client-side rate limits for the selected model. Zero keeps the
+	// built-in per-model default.
+	RequestsPerMinute int // maximum requests started per minute
+	TokensPerMinute   int // maximum estimated input tokens per minute
+	MaxConcurrent     int // maximum requests in flight at once
+
+	// DebugDir, when set, receives a JSON record of every backend call
+	// (prompt, generation config, audio metadata, response, usage, timing).
+	DebugDir string
+	// DebugAudio additionally writes the audio bytes of each call to DebugDir.
+	DebugAudio bool
+
+	// TempDir is where the per-run workspace for temporary files is
+	// created; empty means the system temporary directory.
+	TempDir string
+
+	// CacheDir holds cached transcription results; empty means the user's
+	// cache directory. NoCache neither reads nor writes the cache, while
+	// RefreshCache ignores cached results but stores the new ones.
+	CacheDir     string
+	NoCache      bool
+	RefreshCache bool
+
+	// FFmpegPath and FFprobePath name the binaries to run, as paths or as
+	// names looked up on PATH; empty means "ffmpeg" and "ffprobe".
+	FFmpegPath  string
+	FFprobePath string
+	// FFmpegInputArgs and FFmpegOutputArgs are extra FFmpeg options, split
+	// with SplitArgs, placed before the input of every pass that reads it
+	// and before the output of every pass that decodes it.
+	FFmpegInputArgs  string
+	FFmpegOutputArgs string
 }
 
 // FromEnv returns a Config pre-populated from well-known environment variables.
@@ -79,6 +402,86 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := c.validateInputFormat(); err != nil {
+		return err
+	}
+
+	if err := c.validateAudioStream(); err != nil {
+		return err
+	}
+
+	if err := c.validateSubtitles(); err != nil {
+		return err
+	}
+
+	if c.ChannelNames != "" {
+		if !c.SplitChannels {
+			return fmt.Errorf("--channel-names requires --split-channels")
+		}
+
+		if _, err := ParseChannelNames(c.ChannelNames); err != nil {
+			return fmt.Errorf("invalid --channel-names: %w", err)
+		}
+	}
+
+	if c.RangesFile != "" && (c.Start != "" || c.End != "") {
+		return fmt.Errorf("--ranges cannot be combined with --start or --end")
+	}
+
+	if _, err := c.TimeRanges(); err != nil {
+		return err
+	}
+
+	if c.RequestsPerMinute < 0 || c.TokensPerMinute < 0 || c.MaxConcurrent < 0 {
+		return fmt.Errorf("--rpm, --tpm and --max-concurrent must not be negative")
+	}
+
+	if err := c.validateChunking(); err != nil {
+		return err
+	}
+
+	if c.TrimSilence && (c.SilenceLevel() >= 0 || c.MinSilence() < time.Second) {
+		return fmt.Errorf("--silence-threshold must be negative dB and --min-silence at least 1s")
+	}
+
+	if err := c.validateQuality(); err != nil {
+		return err
+	}
+
+	if c.UploadCodec != "" && !slices.Contains(UploadCodecs, c.UploadCodec) {
+		return fmt.Errorf("invalid --upload-codec %q: must be one of %s",
+			c.UploadCodec, strings.Join(UploadCodecs, ", "))
+	}
+
+	if err := c.validateAudioFormat(); err != nil {
+		return err
+	}
+
+	if c.DebugAudio && c.DebugDir == "" {
+		return fmt.Errorf("--debug-audio requires --debug-dir")
+	}
+
+	if err := c.validateFFmpeg(); err != nil {
+		return err
+	}
+
+	if c.TempDir != "" {
+		// A missing directory is created when first needed.
+		if fi, err := os.Stat(c.TempDir); err == nil && !fi.IsDir() {
+			return fmt.Errorf("invalid --tmp-dir %q: not a directory", c.TempDir)
+		}
+	}
+
+	if c.NoCache && (c.RefreshCache || c.CacheDir != "") {
+		return fmt.Errorf("--no-cache cannot be combined with --refresh or --cache-dir")
+	}
+
+	if c.CacheDir != "" {
+		if fi, err := os.Stat(c.CacheDir); err == nil && !fi.IsDir() {
+			return fmt.Errorf("invalid --cache-dir %q: not a directory", c.CacheDir)
+		}
+	}
+
 	if trimmed := strings.TrimSpace(c.GeminiModel); c.GeminiModel != "" && trimmed == "" {
 		return fmt.Errorf("--model must not be blank")
 	} else if trimmed != "" {
@@ -87,3 +490,194 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// AnalyzeAudio reports whether audio is analysed before upload: when
+// Analyze or any quality threshold is set.
+func (c *Config) AnalyzeAudio() bool {
+	return c.Analyze || c.qualityThresholds()
+}
+
+// SilenceLevel returns the level in dBFS below which silence trimming and
+// audio analysis count audio as silence: SilenceThreshold, or
+// DefaultSilenceThreshold when it is zero.
+func (c *Config) SilenceLevel() float64 {
+	return cmp.Or(c.SilenceThreshold, DefaultSilenceThreshold)
+}
+
+// MinSilence returns the shortest silence trimmed: SilenceMinDuration, or
+// DefaultSilenceMinDuration when it is zero.
+func (c *Config) MinSilence() time.Duration {
+	return cmp.Or(c.SilenceMinDuration, DefaultSilenceMinDuration)
+}
+
+// qualityThresholds reports whether any quality threshold is set.
+func (c *Config) qualityThresholds() bool {
+	return c.MinSpeechRatio > 0 || c.MinSpeech > 0 || c.MaxClipping > 0 || c.MinSNR > 0
+}
+
+// validateQuality checks the audio analysis thresholds.
+func (c *Config) validateQuality() error {
+	if c.MinSpeechRatio < 0 || c.MinSpeechRatio > 1 || c.MaxClipping < 0 || c.MaxClipping > 1 {
+		return fmt.Errorf("--min-speech-ratio and --max-clipping must be between 0 and 1")
+	}
+
+	if c.MinSpeech < 0 || c.MinSNR < 0 {
+		return fmt.Errorf("--min-speech and --min-snr must not be negative")
+	}
+
+	if c.QualityAction != "" {
+		if !slices.Contains(QualityActions, c.QualityAction) {
+			return fmt.Errorf("invalid --quality-action %q: must be one of %s",
+				c.QualityAction, strings.Join(QualityActions, ", "))
+		}
+
+		if !c.qualityThresholds() {
+			return fmt.Errorf("--quality-action requires a threshold such as --min-speech-ratio")
+		}
+	}
+
+	if c.AnalyzeAudio() && c.SilenceThreshold > 0 {
+		return fmt.Errorf("--silence-threshold must be negative dB")
+	}
+
+	return nil
+}
+
+// validateAudioFormat checks the enhancement and decoding format flags.
+func (c *Config) validateAudioFormat() error {
+	if c.Enhance != "" && !slices.Contains(EnhancePresets, c.Enhance) {
+		return fmt.Errorf("invalid --enhance %q: must be one of %s",
+			c.Enhance, strings.Join(EnhancePresets, ", "))
+	}
+
+	if strings.ContainsAny(c.AudioFilter, "\n\r") {
+		return fmt.Errorf("invalid --audio-filter: must be a single line")
+	}
+
+	if c.SampleRate != 0 && (c.SampleRate < MinSampleRate || c.SampleRate > MaxSampleRate) {
+		return fmt.Errorf("invalid --sample-rate %d: must be between %d and %d",
+			c.SampleRate, MinSampleRate, MaxSampleRate)
+	}
+
+	if c.Channels < 0 || c.Channels > MaxChannels {
+		return fmt.Errorf("invalid --channels %d: must be 1 or %d", c.Channels, MaxChannels)
+	}
+
+	return nil
+}
+
+// validateInputFormat checks --input-format and normalises it to a
+// lower-case extension without the leading dot.
+func (c *Config) validateInputFormat() error {
+	if c.InputFormat == "" {
+		return nil
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(c.InputFormat)), ".")
+	if !inputFormatRe.MatchString(format) {
+		return fmt.Errorf("invalid --input-format %q: must be a file extension such as mp3, wav or mp4", c.InputFormat)
+	}
+
+	c.InputFormat = format
+
+	return nil
+}
+
+// validateAudioStream checks the audio track selection flags.
+func (c *Config) validateAudioStream() error {
+	if c.AudioStream == "" {
+		return nil
+	}
+
+	if c.AllAudioStreams {
+		return fmt.Errorf("--audio-stream and --all-audio-streams are mutually exclusive")
+	}
+
+	if _, err := ParseAudioStream(c.AudioStream); err != nil {
+		return fmt.Errorf("invalid --audio-stream: %w", err)
+	}
+
+	return nil
+}
+
+// validateSubtitles checks the subtitle track flags.
+func (c *Config) validateSubtitles() error {
+	if c.Subtitles == "" {
+		if c.SubtitleStream != "" {
+			return fmt.Errorf("--subtitle-stream requires --subtitles")
+		}
+
+		return nil
+	}
+
+	if !slices.Contains(SubtitleModes, c.Subtitles) {
+		return fmt.Errorf("invalid --subtitles %q: must be one of %s", c.Subtitles, strings.Join(SubtitleModes, ", "))
+	}
+
+	if c.AllAudioStreams {
+		return fmt.Errorf("--subtitles and --all-audio-streams are mutually exclusive")
+	}
+
+	if c.SubtitleStream != "" {
+		if _, err := ParseAudioStream(c.SubtitleStream); err != nil {
+			return fmt.Errorf("invalid --subtitle-stream: %w", err)
+		}
+	}
+
+	if c.SubtitleFormat != "" && !slices.Contains(SubtitleFormats, c.SubtitleFormat) {
+		return fmt.Errorf("invalid --subtitle-format %q: must be one of %s",
+			c.SubtitleFormat, strings.Join(SubtitleFormats, ", "))
+	}
+
+	return nil
+}
+
+// validateChunking checks the long-audio chunking settings.
+func (c *Config) validateChunking() error {
+	if c.ChunkDuration < 0 || c.ChunkOverlap < 0 || c.ChunkParallelism < 0 {
+		return fmt.Errorf("--chunk-duration, --chunk-overlap and --chunk-parallel must not be negative")
+	}
+
+	if c.ChunkDuration > 0 && c.ChunkDuration < MinChunkDuration {
+		return fmt.Errorf("--chunk-duration %v is too short (min %v, or 0 to disable chunking)",
+			c.ChunkDuration, MinChunkDuration)
+	}
+
+	if c.ChunkDuration > 0 && c.ChunkOverlap*4 > c.ChunkDuration {
+		return fmt.Errorf("--chunk-overlap %v is too large for --chunk-duration %v (max a quarter)",
+			c.ChunkOverlap, c.ChunkDuration)
+	}
+
+	// A chunk carries the overlap on both sides, and must still have room
+	// for audio of its own within the inline request limit.
+	if c.ChunkDuration > 0 && 2*c.overlapSize() >= MaxInlineSize {
+		return fmt.Errorf("--chunk-overlap %v is too large for %d Hz, %d channel audio (max %v)",
+			c.ChunkOverlap, c.sampleRate(), c.channels(), c.maxOverlap())
+	}
+
+	return nil
+}
+
+// sampleRate returns the sample rate audio is decoded to.
+func (c *Config) sampleRate() int {
+	return cmp.Or(c.SampleRate, DefaultSampleRate)
+}
+
+// channels returns the number of channels audio is decoded to.
+func (c *Config) channels() int {
+	return cmp.Or(c.Channels, DefaultChannels)
+}
+
+// overlapSize returns the size in bytes of ChunkOverlap of decoded 16-bit
+// PCM.
+func (c *Config) overlapSize() int64 {
+	return int64(c.ChunkOverlap.Seconds() * float64(c.sampleRate()*c.channels()*2))
+}
+
+// maxOverlap returns the longest ChunkOverlap whose two sides fit within
+// MaxInlineSize, rounded down to the second.
+func (c *Config) maxOverlap() time.Duration {
+	perSecond := int64(c.sampleRate() * c.channels() * 2)
+
+	return time.Duration((MaxInlineSize-1)/2/perSecond) * time.Second
+}
@@ -6,7 +6,10 @@
 package config_test
 
 import (
+	"maps"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/idvoretskyi/voice-transcriber/internal/config"
 )
@@ -74,6 +77,258 @@ func TestValidate(t *testing.T) {
 			cfg:     config.Config{Language: "ukr"},
 			wantErr: true,
 		},
+		{
+			name:    "positive rate limits are valid",
+			cfg:     config.Config{RequestsPerMinute: 10, TokensPerMinute: 1000, MaxConcurrent: 2},
+			wantErr: false,
+		},
+		{
+			name:    "debug audio without debug dir is invalid",
+			cfg:     config.Config{DebugAudio: true},
+			wantErr: true,
+		},
+		{
+			name:    "debug audio with debug dir is valid",
+			cfg:     config.Config{DebugDir: "debug", DebugAudio: true},
+			wantErr: false,
+		},
+		{
+			name:    "missing temporary directory is valid",
+			cfg:     config.Config{TempDir: "scratch/voice-transcriber"},
+			wantErr: false,
+		},
+		{
+			name:    "temporary directory that is a file is invalid",
+			cfg:     config.Config{TempDir: "config.go"},
+			wantErr: true,
+		},
+		{
+			name:    "refreshing a custom cache directory is valid",
+			cfg:     config.Config{CacheDir: "cache", RefreshCache: true},
+			wantErr: false,
+		},
+		{
+			name:    "refresh without the cache is invalid",
+			cfg:     config.Config{NoCache: true, RefreshCache: true},
+			wantErr: true,
+		},
+		{
+			name:    "cache directory that is a file is invalid",
+			cfg:     config.Config{CacheDir: "config.go"},
+			wantErr: true,
+		},
+		{
+			name:    "speech ratio threshold with a warning is valid",
+			cfg:     config.Config{MinSpeechRatio: 0.2, QualityAction: "warn", SilenceThreshold: -45},
+			wantErr: false,
+		},
+		{
+			name:    "speech ratio above one is invalid",
+			cfg:     config.Config{MinSpeechRatio: 1.5, SilenceThreshold: -45},
+			wantErr: true,
+		},
+		{
+			name:    "quality action without a threshold is invalid",
+			cfg:     config.Config{Analyze: true, QualityAction: "warn", SilenceThreshold: -45},
+			wantErr: true,
+		},
+		{
+			name:    "analysis without a silence threshold uses the default",
+			cfg:     config.Config{Analyze: true},
+			wantErr: false,
+		},
+		{
+			name:    "analysis with a positive silence threshold is invalid",
+			cfg:     config.Config{Analyze: true, SilenceThreshold: 10},
+			wantErr: true,
+		},
+		{
+			name:    "subtitle context from a chosen track is valid",
+			cfg:     config.Config{Subtitles: "context", SubtitleStream: "lang:eng"},
+			wantErr: false,
+		},
+		{
+			name:    "subtitle export as WebVTT is valid",
+			cfg:     config.Config{Subtitles: "export", SubtitleFormat: "vtt"},
+			wantErr: false,
+		},
+		{
+			name:    "unknown subtitle mode is invalid",
+			cfg:     config.Config{Subtitles: "burn"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown subtitle format is invalid",
+			cfg:     config.Config{Subtitles: "export", SubtitleFormat: "ass"},
+			wantErr: true,
+		},
+		{
+			name:    "subtitle stream without a subtitle mode is invalid",
+			cfg:     config.Config{SubtitleStream: "2"},
+			wantErr: true,
+		},
+		{
+			name:    "subtitles with all audio streams is invalid",
+			cfg:     config.Config{Subtitles: "diff", AllAudioStreams: true},
+			wantErr: true,
+		},
+		{
+			name:    "extra FFmpeg options are valid",
+			cfg:     config.Config{FFmpegInputArgs: "-hwaccel auto -analyzeduration 10M", FFmpegOutputArgs: "-threads 2"},
+			wantErr: false,
+		},
+		{
+			name:    "FFmpeg options that do not start with an option are invalid",
+			cfg:     config.Config{FFmpegOutputArgs: "2 -threads"},
+			wantErr: true,
+		},
+		{
+			name:    "FFmpeg options adding an input are invalid",
+			cfg:     config.Config{FFmpegInputArgs: "-f lavfi -i anullsrc"},
+			wantErr: true,
+		},
+		{
+			name:    "FFmpeg options with an unterminated quote are invalid",
+			cfg:     config.Config{FFmpegInputArgs: `-user_agent "voice`},
+			wantErr: true,
+		},
+		{
+			name:    "chunking with small overlap is valid",
+			cfg:     config.Config{ChunkDuration: 10 * time.Minute, ChunkOverlap: 2 * time.Second},
+			wantErr: false,
+		},
+		{
+			name:    "chunk overlap larger than a quarter window is invalid",
+			cfg:     config.Config{ChunkDuration: 10 * time.Second, ChunkOverlap: 5 * time.Second},
+			wantErr: true,
+		},
+		{
+			name: "chunk overlap filling the request limit on both sides is invalid",
+			cfg: config.Config{
+				ChunkDuration: 10 * time.Minute, ChunkOverlap: time.Minute, SampleRate: 48000, Channels: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "same chunk overlap at the default format is valid",
+			cfg:     config.Config{ChunkDuration: 10 * time.Minute, ChunkOverlap: time.Minute},
+			wantErr: false,
+		},
+		{
+			name:    "tiny chunk duration is invalid",
+			cfg:     config.Config{ChunkDuration: time.Millisecond},
+			wantErr: true,
+		},
+		{
+			name:    "minimum chunk duration is valid",
+			cfg:     config.Config{ChunkDuration: config.MinChunkDuration},
+			wantErr: false,
+		},
+		{
+			name:    "zero chunk duration disables chunking",
+			cfg:     config.Config{ChunkDuration: 0, ChunkOverlap: 2 * time.Second},
+			wantErr: false,
+		},
+		{
+			name:    "negative chunk duration is invalid",
+			cfg:     config.Config{ChunkDuration: -time.Second},
+			wantErr: true,
+		},
+		{
+			name:    "silence trimming with defaults is valid",
+			cfg:     config.Config{TrimSilence: true, SilenceThreshold: -45, SilenceMinDuration: 3 * time.Second},
+			wantErr: false,
+		},
+		{
+			name:    "silence trimming with positive threshold is invalid",
+			cfg:     config.Config{TrimSilence: true, SilenceThreshold: 10, SilenceMinDuration: 3 * time.Second},
+			wantErr: true,
+		},
+		{
+			name:    "silence trimming without a threshold or minimum uses the defaults",
+			cfg:     config.Config{TrimSilence: true},
+			wantErr: false,
+		},
+		{
+			name:    "known upload codec is valid",
+			cfg:     config.Config{UploadCodec: "opus"},
+			wantErr: false,
+		},
+		{
+			name:    "unknown upload codec is invalid",
+			cfg:     config.Config{UploadCodec: "aiff"},
+			wantErr: true,
+		},
+		{
+			name:    "input format given as an extension is valid",
+			cfg:     config.Config{InputFormat: ".MP3"},
+			wantErr: false,
+		},
+		{
+			name:    "input format with a path is invalid",
+			cfg:     config.Config{InputFormat: "../talk.mp3"},
+			wantErr: true,
+		},
+		{
+			name:    "audio stream by language is valid",
+			cfg:     config.Config{AudioStream: "lang:ukr"},
+			wantErr: false,
+		},
+		{
+			name:    "malformed audio stream is invalid",
+			cfg:     config.Config{AudioStream: "first"},
+			wantErr: true,
+		},
+		{
+			name:    "audio stream with all audio streams is invalid",
+			cfg:     config.Config{AudioStream: "1", AllAudioStreams: true},
+			wantErr: true,
+		},
+		{
+			name:    "named split channels are valid",
+			cfg:     config.Config{SplitChannels: true, ChannelNames: "left=Agent,right=Customer"},
+			wantErr: false,
+		},
+		{
+			name:    "channel names without split channels are invalid",
+			cfg:     config.Config{ChannelNames: "left=Agent"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed channel names are invalid",
+			cfg:     config.Config{SplitChannels: true, ChannelNames: "centre=Host"},
+			wantErr: true,
+		},
+		{
+			name:    "enhancement and format are valid",
+			cfg:     config.Config{Enhance: "phone", AudioFilter: "volume=2", SampleRate: 24000, Channels: 2},
+			wantErr: false,
+		},
+		{
+			name:    "unknown enhancement preset is invalid",
+			cfg:     config.Config{Enhance: "studio"},
+			wantErr: true,
+		},
+		{
+			name:    "multi-line audio filter is invalid",
+			cfg:     config.Config{AudioFilter: "volume=2\nvolume=3"},
+			wantErr: true,
+		},
+		{
+			name:    "sample rate out of range is invalid",
+			cfg:     config.Config{SampleRate: 96000},
+			wantErr: true,
+		},
+		{
+			name:    "too many channels is invalid",
+			cfg:     config.Config{Channels: 6},
+			wantErr: true,
+		},
+		{
+			name:    "negative rate limit is invalid",
+			cfg:     config.Config{RequestsPerMinute: -1},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -93,6 +348,33 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestSplitArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "  -threads   2 ", want: []string{"-threads", "2"}},
+		{in: `-headers "X-Team: audio" -user_agent 'voice transcriber'`,
+			want: []string{"-headers", "X-Team: audio", "-user_agent", "voice transcriber"}},
+		{in: `-metadata title=""`, want: []string{"-metadata", "title="}},
+		{in: `-af "volume=2" ''`, want: []string{"-af", "volume=2", ""}},
+	}
+
+	for _, tc := range tests {
+		got, err := config.SplitArgs(tc.in)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("SplitArgs(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+
+	if _, err := config.SplitArgs(`-vf 'scale`); err == nil {
+		t.Error("SplitArgs(unterminated quote) error = nil; want error")
+	}
+}
+
 func TestFromEnv(t *testing.T) {
 	// t.Setenv is incompatible with t.Parallel on subtests; run sequentially.
 	t.Run("GOOGLE_CLOUD_PROJECT is read", func(t *testing.T) {
@@ -115,3 +397,179 @@ func TestFromEnv(t *testing.T) {
 		}
 	})
 }
+
+func TestParseTimestamp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "90", want: 90 * time.Second},
+		{input: "90.5", want: 90500 * time.Millisecond},
+		{input: "1:30", want: 90 * time.Second},
+		{input: "00:01:30.250", want: 90250 * time.Millisecond},
+		{input: "01:02:03,004", want: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond},
+		{input: " 0:00:05 ", want: 5 * time.Second},
+		{input: "", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "1:2:3:4", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "1.5:00", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := config.ParseTimestamp(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseTimestamp(%q) = %v; want error", tc.input, got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseTimestamp(%q) unexpected error: %v", tc.input, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("ParseTimestamp(%q) = %v; want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAudioStream(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    config.AudioStreamSelector
+		wantErr bool
+	}{
+		{input: "0", want: config.AudioStreamSelector{Index: 0}},
+		{input: " 2 ", want: config.AudioStreamSelector{Index: 2}},
+		{input: "lang:ukr", want: config.AudioStreamSelector{Index: -1, Language: "ukr"}},
+		{input: "lang:EN", want: config.AudioStreamSelector{Index: -1, Language: "en"}},
+		{input: "", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "lang:", wantErr: true},
+		{input: "lang:english", wantErr: true},
+		{input: "ukr", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := config.ParseAudioStream(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseAudioStream(%q) = %+v; want error", tc.input, got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseAudioStream(%q) unexpected error: %v", tc.input, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("ParseAudioStream(%q) = %+v; want %+v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLanguageFromTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tag    string
+		want   string
+		wantOK bool
+	}{
+		{tag: "ukr", want: "uk", wantOK: true},
+		{tag: "uk", want: "uk", wantOK: true},
+		{tag: "GER", want: "de", wantOK: true},
+		{tag: "deu", want: "de", wantOK: true},
+		{tag: "und", wantOK: false},
+		{tag: "", wantOK: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := config.LanguageFromTag(tc.tag)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("LanguageFromTag(%q) = (%q, %v); want (%q, %v)", tc.tag, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestParseChannelNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    map[int]string
+		wantErr bool
+	}{
+		{input: "left=Agent,right=Customer", want: map[int]string{0: "Agent", 1: "Customer"}},
+		{input: " Right = Caller ", want: map[int]string{1: "Caller"}},
+		{input: "1=Host,3=Guest", want: map[int]string{0: "Host", 2: "Guest"}},
+		{input: "", wantErr: true},
+		{input: "left", wantErr: true},
+		{input: "left=", wantErr: true},
+		{input: "0=Host", wantErr: true},
+		{input: "left=A,1=B", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := config.ParseChannelNames(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseChannelNames(%q) = %v; want error", tc.input, got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseChannelNames(%q) unexpected error: %v", tc.input, err)
+			}
+
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("ParseChannelNames(%q) = %v; want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSilenceDefaults(t *testing.T) {
+	t.Parallel()
+
+	unset := &config.Config{}
+	if got := unset.SilenceLevel(); got != config.DefaultSilenceThreshold {
+		t.Errorf("SilenceLevel() unset = %v; want %v", got, config.DefaultSilenceThreshold)
+	}
+
+	if got := unset.MinSilence(); got != config.DefaultSilenceMinDuration {
+		t.Errorf("MinSilence() unset = %v; want %v", got, config.DefaultSilenceMinDuration)
+	}
+
+	set := &config.Config{SilenceThreshold: -30, SilenceMinDuration: 2 * time.Second}
+	if got := set.SilenceLevel(); got != -30 {
+		t.Errorf("SilenceLevel() = %v; want -30", got)
+	}
+
+	if got := set.MinSilence(); got != 2*time.Second {
+		t.Errorf("MinSilence() = %v; want 2s", got)
+	}
+}
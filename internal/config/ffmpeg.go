@@ -0,0 +1,98 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// FFmpegArgs returns the extra FFmpeg options of --ffmpeg-input-args and
+// --ffmpeg-output-args, split into arguments.
+func (c *Config) FFmpegArgs() (input, output []string, err error) {
+	if input, err = SplitArgs(c.FFmpegInputArgs); err != nil {
+		return nil, nil, fmt.Errorf("invalid --ffmpeg-input-args: %w", err)
+	}
+
+	if output, err = SplitArgs(c.FFmpegOutputArgs); err != nil {
+		return nil, nil, fmt.Errorf("invalid --ffmpeg-output-args: %w", err)
+	}
+
+	return input, output, nil
+}
+
+// SplitArgs splits s into arguments at white space, as a shell would. Single
+// or double quotes group text containing spaces and are removed; there are
+// no escapes.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		quote rune
+		inArg bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+
+			inArg = false
+		default:
+			arg.WriteRune(r)
+
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// validateFFmpeg checks the extra FFmpeg options. The binaries are checked
+// when first run.
+func (c *Config) validateFFmpeg() error {
+	input, output, err := c.FFmpegArgs()
+	if err != nil {
+		return err
+	}
+
+	if len(input) > 0 && !strings.HasPrefix(input[0], "-") {
+		return fmt.Errorf("invalid --ffmpeg-input-args %q: must start with an option such as -hwaccel",
+			c.FFmpegInputArgs)
+	}
+
+	if len(output) > 0 && !strings.HasPrefix(output[0], "-") {
+		return fmt.Errorf("invalid --ffmpeg-output-args %q: must start with an option such as -threads",
+			c.FFmpegOutputArgs)
+	}
+
+	// The input and output are chosen by the tool; extra ones would change
+	// what it reads or where the audio goes.
+	if slices.Contains(input, "-i") || slices.Contains(output, "-i") {
+		return fmt.Errorf("FFmpeg arguments must not add inputs with -i")
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package config
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TimeRange is a span of the input media to transcribe. An End of zero
+// means the range runs to the end of the media.
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// String formats the range as it is written in a ranges file.
+func (r TimeRange) String() string {
+	if r.End == 0 {
+		return r.Start.String() + "-"
+	}
+
+	return r.Start.String() + "-" + r.End.String()
+}
+
+// TimeRanges returns the ranges selected with --start/--end or --ranges, in
+// media order, or nil when the whole input is to be transcribed.
+func (c *Config) TimeRanges() ([]TimeRange, error) {
+	if c.RangesFile != "" {
+		return ReadRangesFile(c.RangesFile)
+	}
+
+	if c.Start == "" && c.End == "" {
+		return nil, nil
+	}
+
+	var (
+		r   TimeRange
+		err error
+	)
+
+	if c.Start != "" {
+		if r.Start, err = ParseTimestamp(c.Start); err != nil {
+			return nil, fmt.Errorf("invalid --start: %w", err)
+		}
+	}
+
+	if c.End != "" {
+		if r.End, err = ParseTimestamp(c.End); err != nil {
+			return nil, fmt.Errorf("invalid --end: %w", err)
+		}
+
+		if r.End <= r.Start {
+			return nil, fmt.Errorf("--end %s must be after --start %s", c.End, c.Start)
+		}
+	}
+
+	return []TimeRange{r}, nil
+}
+
+// ReadRangesFile reads a ranges file; see ParseRanges for the format.
+func ReadRangesFile(path string) ([]TimeRange, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("opening ranges file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	ranges, err := ParseRanges(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ranges, nil
+}
+
+// ParseRanges parses one time range per line, written as "START END" or
+// "START-END" with timestamps in any form accepted by ParseTimestamp.
+// Text after "#" is a comment and blank lines are ignored. The last range
+// may omit its end to run to the end of the media. Ranges are returned in
+// media order and must not overlap.
+func ParseRanges(r io.Reader) ([]TimeRange, error) {
+	var ranges []TimeRange
+
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+
+		tr, err := parseRangeLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		ranges = append(ranges, tr)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading ranges: %w", err)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no time ranges found")
+	}
+
+	slices.SortFunc(ranges, func(a, b TimeRange) int { return cmp.Compare(a.Start, b.Start) })
+
+	for i, tr := range ranges[:len(ranges)-1] {
+		if tr.End == 0 || tr.End > ranges[i+1].Start {
+			return nil, fmt.Errorf("range %s overlaps %s", tr, ranges[i+1])
+		}
+	}
+
+	return ranges, nil
+}
+
+// parseRangeLine parses "START END", "START-END" or "START" (to the end).
+func parseRangeLine(text string) (TimeRange, error) {
+	fields := strings.Fields(strings.Replace(text, "-", " ", 1))
+	if len(fields) > 2 {
+		return TimeRange{}, fmt.Errorf("invalid range %q: want START END", text)
+	}
+
+	start, err := ParseTimestamp(fields[0])
+	if err != nil {
+		return TimeRange{}, err
+	}
+
+	tr := TimeRange{Start: start}
+
+	if len(fields) == 2 {
+		if tr.End, err = ParseTimestamp(fields[1]); err != nil {
+			return TimeRange{}, err
+		}
+
+		if tr.End <= tr.Start {
+			return TimeRange{}, fmt.Errorf("invalid range %q: end must be after start", text)
+		}
+	}
+
+	return tr, nil
+}
@@ -0,0 +1,136 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func TestTimeRanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    []config.TimeRange
+		wantErr bool
+	}{
+		{name: "none", cfg: config.Config{}},
+		{
+			name: "start and end",
+			cfg:  config.Config{Start: "00:42:00", End: "4500"},
+			want: []config.TimeRange{{Start: 42 * time.Minute, End: 75 * time.Minute}},
+		},
+		{name: "start only", cfg: config.Config{Start: "90.5"}, want: []config.TimeRange{{Start: 90500 * time.Millisecond}}},
+		{name: "end only", cfg: config.Config{End: "1:00"}, want: []config.TimeRange{{End: time.Minute}}},
+		{name: "end before start", cfg: config.Config{Start: "2:00", End: "1:00"}, wantErr: true},
+		{name: "malformed start", cfg: config.Config{Start: "soon"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tc.cfg.TimeRanges()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("TimeRanges() = %v; want error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TimeRanges() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("TimeRanges() = %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	t.Parallel()
+
+	input := `# Excerpts from the panel
+01:20:00 01:30:30
+00:05:00-00:07:00
+
+  42:00 - 75:00
+2:00:00 # to the end
+`
+
+	got, err := config.ParseRanges(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseRanges() error = %v", err)
+	}
+
+	want := []config.TimeRange{
+		{Start: 5 * time.Minute, End: 7 * time.Minute},
+		{Start: 42 * time.Minute, End: 75 * time.Minute},
+		{Start: 80 * time.Minute, End: 90*time.Minute + 30*time.Second},
+		{Start: 2 * time.Hour},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ParseRanges() = %v; want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d = %v; want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseRangesErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"overlap":          "0:00 1:00\n0:30 2:00\n",
+		"open range first": "1:00\n2:00 3:00\n",
+		"end before start": "2:00 1:00\n",
+		"too many fields":  "0:00 1:00 2:00\n",
+		"bad timestamp":    "later 1:00\n",
+		"only comments":    "# nothing\n",
+	}
+
+	for name, input := range tests {
+		if got, err := config.ParseRanges(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: ParseRanges() = %v; want error", name, got)
+		}
+	}
+}
+
+func TestRangesFileValidation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ranges.txt")
+	if err := os.WriteFile(path, []byte("0:10 0:20\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&config.Config{RangesFile: path}).Validate(); err != nil {
+		t.Errorf("Validate() with ranges file = %v; want nil", err)
+	}
+
+	if err := (&config.Config{RangesFile: path, Start: "0:05"}).Validate(); err == nil {
+		t.Error("Validate() with --ranges and --start = nil; want error")
+	}
+
+	if err := (&config.Config{RangesFile: path + ".missing"}).Validate(); err == nil {
+		t.Error("Validate() with missing ranges file = nil; want error")
+	}
+}
@@ -0,0 +1,279 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package gemini
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// redacted replaces any secret value found in a debug dump.
+const redacted = "[REDACTED]"
+
+// captureHeader tags a request whose response body is kept for the debug
+// dump with the ID of its call. It is removed before the request is sent.
+const captureHeader = "X-Debug-Capture-Id"
+
+// secretPatterns match credentials that must never be written to a dump,
+// together with the replacement applied to each match.
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// JSON fields whose names indicate a credential.
+	{
+		re: regexp.MustCompile(
+			`(?i)("(?:access_token|refresh_token|id_token|client_secret|private_key|private_key_id|` +
+				`api_?key|authorization|x-goog-api-key|password|secret)"\s*:\s*)("(?:[^"\\]|\\.)*"|\[[^\]]*\])`),
+		repl: `${1}"` + redacted + `"`,
+	},
+	// Bearer tokens in headers or error messages.
+	{re: regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`), repl: "${1}" + redacted},
+	// Google OAuth2 access tokens.
+	{re: regexp.MustCompile(`ya29\.[A-Za-z0-9._-]+`), repl: redacted},
+	// Google API keys.
+	{re: regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`), repl: redacted},
+	// key=... query parameters in URLs.
+	{re: regexp.MustCompile(`([?&](?:key|access_token)=)[^&\s"]+`), repl: "${1}" + redacted},
+}
+
+// redactSecrets returns data with every recognised credential replaced.
+func redactSecrets(data []byte) []byte {
+	for _, p := range secretPatterns {
+		data = p.re.ReplaceAll(data, []byte(p.repl))
+	}
+
+	return data
+}
+
+// debugAudio describes the audio payload of a dumped request.
+type debugAudio struct {
+	SHA256   string `json:"sha256"`
+	Size     int    `json:"size"`
+	MIMEType string `json:"mimeType"`
+	// URI is the Cloud Storage object the backend read the audio from, for
+	// requests that referenced it instead of carrying it.
+	URI string `json:"uri,omitempty"`
+	// File is the name of the sibling file holding the audio bytes, set only
+	// when audio dumping is enabled.
+	File string `json:"file,omitempty"`
+}
+
+// debugTiming records how long each phase of a backend call took.
+type debugTiming struct {
+	Started   time.Time     `json:"started"`
+	QueueWait time.Duration `json:"queueWaitNs"`
+	Request   time.Duration `json:"requestNs"`
+}
+
+// debugRecord is the JSON document written for every backend call.
+type debugRecord struct {
+	Model            string                                      `json:"model"`
+	Location         string                                      `json:"location"`
+	Project          string                                      `json:"project"`
+	Prompt           string                                      `json:"prompt"`
+	GenerationConfig *genai.GenerateContentConfig                `json:"generationConfig"`
+	Audio            debugAudio                                  `json:"audio"`
+	Response         json.RawMessage                             `json:"response,omitempty"`
+	Usage            *genai.GenerateContentResponseUsageMetadata `json:"usage,omitempty"`
+	Timing           debugTiming                                 `json:"timing"`
+	Error            string                                      `json:"error,omitempty"`
+}
+
+// debugDumper writes one record per backend call into a directory.
+// It is safe for concurrent use.
+type debugDumper struct {
+	dir          string
+	includeAudio bool
+	seq          atomic.Int64
+	// responses records the raw response bodies of the calls dumped.
+	responses *responseRecorder
+}
+
+// newDebugDumper creates dir (if needed) and returns a dumper writing into it.
+func newDebugDumper(dir string, includeAudio bool) (*debugDumper, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating debug directory: %w", err)
+	}
+
+	return &debugDumper{
+		dir:          dir,
+		includeAudio: includeAudio,
+		responses:    newResponseRecorder(http.DefaultTransport),
+	}, nil
+}
+
+// responseRecorder is an http.RoundTripper that keeps the raw body of the
+// response to every request tagged with captureHeader, as the backend sent
+// it, until the call that made the request takes it. It is safe for
+// concurrent use.
+type responseRecorder struct {
+	base http.RoundTripper
+	seq  atomic.Int64
+
+	mu     sync.Mutex
+	bodies map[string]*bytes.Buffer
+}
+
+// newResponseRecorder returns a recorder sending requests through base.
+func newResponseRecorder(base http.RoundTripper) *responseRecorder {
+	return &responseRecorder{base: base, bodies: make(map[string]*bytes.Buffer)}
+}
+
+// capture returns a copy of cfg, which may be nil, that tags the request
+// of a call with a new ID, and that ID.
+func (r *responseRecorder) capture(cfg *genai.GenerateContentConfig) (*genai.GenerateContentConfig, string) {
+	id := strconv.FormatInt(r.seq.Add(1), 10)
+
+	r.mu.Lock()
+	r.bodies[id] = nil
+	r.mu.Unlock()
+
+	tagged := &genai.GenerateContentConfig{}
+	if cfg != nil {
+		*tagged = *cfg
+	}
+
+	tagged.HTTPOptions = &genai.HTTPOptions{Headers: http.Header{captureHeader: {id}}}
+
+	return tagged, id
+}
+
+// take returns the response body recorded for the call id, with secrets
+// redacted, and forgets it. It returns nil when no response arrived.
+func (r *responseRecorder) take(id string) []byte {
+	r.mu.Lock()
+	body := r.bodies[id]
+	delete(r.bodies, id)
+	r.mu.Unlock()
+
+	if body == nil {
+		return nil
+	}
+
+	return redactSecrets(body.Bytes())
+}
+
+// RoundTrip implements http.RoundTripper. A retried request replaces the
+// body recorded for its call.
+func (r *responseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(captureHeader)
+	if id == "" {
+		return r.base.RoundTrip(req) //nolint:wrapcheck // a transport passes errors through
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Del(captureHeader)
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // a transport passes errors through
+	}
+
+	body := &bytes.Buffer{}
+
+	r.mu.Lock()
+	if _, ok := r.bodies[id]; ok {
+		r.bodies[id] = body
+	}
+	r.mu.Unlock()
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, body), resp.Body}
+
+	return resp, nil
+}
+
+// rawResponse returns body as a debug record's response: the JSON as sent,
+// or a JSON string when the backend answered with something else, such as
+// an HTML error page.
+func rawResponse(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if json.Valid(body) {
+		return body
+	}
+
+	quoted, err := json.Marshal(string(body))
+	if err != nil {
+		return nil
+	}
+
+	return quoted
+}
+
+// newDebugAudio returns the metadata describing audioData, or the object at
+// uri when the audio was referenced rather than sent.
+func newDebugAudio(audioData []byte, mimeType, uri string) debugAudio {
+	sum := sha256.Sum256(audioData)
+
+	return debugAudio{
+		SHA256:   hex.EncodeToString(sum[:]),
+		Size:     len(audioData),
+		MIMEType: mimeType,
+		URI:      uri,
+	}
+}
+
+// write stores rec (and, when enabled, audioData) and returns the path of
+// the JSON record. Secrets are redacted before anything touches the disk.
+func (d *debugDumper) write(rec *debugRecord, audioData []byte) (string, error) {
+	base := fmt.Sprintf("%s-%04d-%s",
+		rec.Timing.Started.UTC().Format("20060102T150405.000Z"),
+		d.seq.Add(1),
+		rec.Audio.SHA256[:12],
+	)
+
+	// Referenced audio never passed through this process.
+	if d.includeAudio && rec.Audio.URI == "" {
+		rec.Audio.File = base + ".audio" + audioExtension(rec.Audio.MIMEType)
+
+		if err := os.WriteFile(filepath.Join(d.dir, rec.Audio.File), audioData, 0o600); err != nil {
+			return "", fmt.Errorf("writing debug audio: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding debug record: %w", err)
+	}
+
+	path := filepath.Join(d.dir, base+".json")
+	if err := os.WriteFile(path, redactSecrets(data), 0o600); err != nil {
+		return "", fmt.Errorf("writing debug record: %w", err)
+	}
+
+	return path, nil
+}
+
+// audioExtension returns a file extension for mimeType, e.g. ".wav".
+func audioExtension(mimeType string) string {
+	sub := strings.TrimPrefix(mimeType, "audio/")
+	if sub == "" || sub == mimeType || strings.ContainsAny(sub, `/\.`) {
+		return ".bin"
+	}
+
+	return "." + sub
+}
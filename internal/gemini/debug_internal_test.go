@@ -0,0 +1,95 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package gemini
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	t.Parallel()
+
+	// A field the SDK does not know, and a secret to redact.
+	const body = `{"candidates":[],"futureField":{"kept":true},"access_token":"ya29.secret"}`
+
+	var tagged bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagged = tagged || r.Header.Get(captureHeader) != ""
+
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	recorder := newResponseRecorder(http.DefaultTransport)
+	client := &http.Client{Transport: recorder}
+
+	given := &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+
+	cfg, id := recorder.capture(given)
+	if cfg.ResponseMIMEType != "application/json" || given.HTTPOptions != nil {
+		t.Errorf("capture() = %+v; want a tagged copy of the config", cfg)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+
+	req.Header = cfg.HTTPOptions.Headers
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if got, _ := io.ReadAll(resp.Body); string(got) != body {
+		t.Errorf("response body = %q; want it passed through unchanged", got)
+	}
+
+	_ = resp.Body.Close()
+
+	if tagged {
+		t.Error("the capture header reached the server; want it removed")
+	}
+
+	raw := rawResponse(recorder.take(id))
+
+	var dumped map[string]any
+	if err := json.Unmarshal(raw, &dumped); err != nil {
+		t.Fatalf("recorded response %q is not JSON: %v", raw, err)
+	}
+
+	if _, ok := dumped["futureField"]; !ok || strings.Contains(string(raw), "ya29.secret") {
+		t.Errorf("recorded response = %s; want the raw body with secrets redacted", raw)
+	}
+
+	if again := recorder.take(id); again != nil {
+		t.Errorf("second take() = %q; want nil", again)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	t.Parallel()
+
+	if got := rawResponse(nil); got != nil {
+		t.Errorf("rawResponse(nil) = %q; want nil", got)
+	}
+
+	const page = "<html>Bad Gateway</html>"
+
+	var got string
+	if err := json.Unmarshal(rawResponse([]byte(page)), &got); err != nil || got != page {
+		t.Errorf("rawResponse(HTML) = %q, %v; want the page as a JSON string", got, err)
+	}
+}
@@ -6,5 +6,46 @@
 // Package gemini exports internal symbols for testing.
 package gemini
 
+import "time"
+
 // BuildPrompt exposes buildPrompt for black-box tests.
 var BuildPrompt = buildPrompt
+
+// ReferenceInstructions exposes referenceInstructions for black-box tests.
+var ReferenceInstructions = referenceInstructions
+
+// ParseSegments exposes parseSegments for black-box tests.
+var ParseSegments = parseSegments
+
+// NewLimiterWithWindow returns a Limiter whose per-minute quotas apply over
+// window instead of a full minute, so tests run quickly.
+func NewLimiterWithWindow(limits Limits, window time.Duration) *Limiter {
+	l := NewLimiter(limits)
+	l.window = window
+
+	return l
+}
+
+// AudioPart exposes audioPart for black-box tests.
+var AudioPart = audioPart
+
+// ReadAudio exposes readAudio for black-box tests.
+var ReadAudio = readAudio
+
+// RedactSecrets exposes redactSecrets for black-box tests.
+var RedactSecrets = redactSecrets
+
+// WriteDebugDump writes a minimal debug record for audioData into dir and
+// returns the path of the JSON record.
+func WriteDebugDump(dir, prompt string, audioData []byte, mimeType string, includeAudio bool) (string, error) {
+	d, err := newDebugDumper(dir, includeAudio)
+	if err != nil {
+		return "", err
+	}
+
+	return d.write(&debugRecord{
+		Prompt: prompt,
+		Audio:  newDebugAudio(audioData, mimeType, ""),
+		Timing: debugTiming{Started: time.Now()},
+	}, audioData)
+}
@@ -0,0 +1,336 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+const (
+	// audioTokensPerSecond is the number of input tokens Gemini bills for each
+	// second of audio.
+	// See: https://cloud.google.com/vertex-ai/generative-ai/docs/multimodal/audio-understanding
+	audioTokensPerSecond = 32
+
+	// promptTokenOverhead is a generous allowance for the text prompt sent
+	// alongside the audio.
+	promptTokenOverhead = 256
+
+	// limiterWindow is the sliding window over which per-minute quotas apply.
+	limiterWindow = time.Minute
+)
+
+// Limits describes the client-side quota applied to requests for one model.
+// A zero field means "no limit" for that dimension.
+type Limits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	MaxConcurrent     int
+}
+
+// merge returns l with every non-zero field of override applied on top.
+func (l Limits) merge(override Limits) Limits {
+	if override.RequestsPerMinute != 0 {
+		l.RequestsPerMinute = override.RequestsPerMinute
+	}
+
+	if override.TokensPerMinute != 0 {
+		l.TokensPerMinute = override.TokensPerMinute
+	}
+
+	if override.MaxConcurrent != 0 {
+		l.MaxConcurrent = override.MaxConcurrent
+	}
+
+	return l
+}
+
+// defaultLimits applies to any model without an entry in modelLimits.
+// The values are deliberately conservative so a fresh project with the
+// default Vertex AI quota does not hit RESOURCE_EXHAUSTED errors.
+var defaultLimits = Limits{
+	RequestsPerMinute: 60,
+	TokensPerMinute:   1_000_000,
+	MaxConcurrent:     4,
+}
+
+// modelLimits holds per-model defaults, keyed by model ID prefix.
+// Overrides from the config (--rpm, --tpm, --max-concurrent) take precedence.
+var modelLimits = map[string]Limits{
+	"gemini-3.1-flash-lite": {RequestsPerMinute: 120, TokensPerMinute: 4_000_000, MaxConcurrent: 8},
+	"gemini-3-flash":        {RequestsPerMinute: 60, TokensPerMinute: 2_000_000, MaxConcurrent: 4},
+	"gemini-3-pro":          {RequestsPerMinute: 25, TokensPerMinute: 1_000_000, MaxConcurrent: 2},
+	"gemini-2.5-flash":      {RequestsPerMinute: 60, TokensPerMinute: 2_000_000, MaxConcurrent: 4},
+	"gemini-2.5-pro":        {RequestsPerMinute: 25, TokensPerMinute: 1_000_000, MaxConcurrent: 2},
+}
+
+// DefaultLimitsForModel returns the built-in client-side limits for model.
+// The longest matching prefix in the per-model table wins.
+func DefaultLimitsForModel(model string) Limits {
+	best, bestLen := defaultLimits, 0
+
+	for prefix, limits := range modelLimits {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = limits, len(prefix)
+		}
+	}
+
+	return best
+}
+
+// limiterRegistry holds one Limiter per model for the whole process, so that
+// every Transcriber (and every Service) talking to the same model shares a
+// single quota.
+var limiterRegistry = struct {
+	sync.Mutex
+	limiters map[string]*Limiter
+}{limiters: map[string]*Limiter{}}
+
+// SharedLimiter returns the process-wide Limiter for model, creating it from
+// the model defaults merged with override on first use. A non-zero override
+// on a later call updates the existing limiter in place.
+func SharedLimiter(model string, override Limits) *Limiter {
+	limiterRegistry.Lock()
+	defer limiterRegistry.Unlock()
+
+	if l, ok := limiterRegistry.limiters[model]; ok {
+		if override != (Limits{}) {
+			l.SetLimits(l.Limits().merge(override))
+		}
+
+		return l
+	}
+
+	l := NewLimiter(DefaultLimitsForModel(model).merge(override))
+	limiterRegistry.limiters[model] = l
+
+	return l
+}
+
+// limiterEntry records a request admitted within the current window.
+type limiterEntry struct {
+	at     time.Time
+	tokens int
+}
+
+// Limiter enforces requests-per-minute, tokens-per-minute and in-flight
+// request limits. It is safe for concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	limits   Limits
+	window   time.Duration
+	entries  []limiterEntry
+	inFlight int
+	// changed is closed and replaced whenever capacity may have been freed,
+	// waking every goroutine blocked in Acquire.
+	changed chan struct{}
+}
+
+// NewLimiter returns a Limiter enforcing limits.
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		window:  limiterWindow,
+		changed: make(chan struct{}),
+	}
+}
+
+// Limits returns the limits currently enforced.
+func (l *Limiter) Limits() Limits {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limits
+}
+
+// SetLimits replaces the enforced limits. Waiting callers re-evaluate
+// immediately.
+func (l *Limiter) SetLimits(limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits = limits
+	l.broadcastLocked()
+}
+
+// Acquire blocks until a request costing tokens input tokens may be sent,
+// or until ctx is done. On success the caller must invoke the returned
+// release function once the request has completed.
+func (l *Limiter) Acquire(ctx context.Context, tokens int) (func(), error) {
+	for {
+		l.mu.Lock()
+
+		now := time.Now()
+		l.pruneLocked(now)
+
+		wait, ok := l.admitLocked(now, tokens)
+		if ok {
+			l.entries = append(l.entries, limiterEntry{at: now, tokens: tokens})
+			l.inFlight++
+			l.mu.Unlock()
+
+			return l.releaseFunc(), nil
+		}
+
+		changed := l.changed
+		l.mu.Unlock()
+
+		if !sleepOrWake(ctx, wait, changed) {
+			return nil, fmt.Errorf("waiting for rate limiter: %w", ctx.Err())
+		}
+	}
+}
+
+// admitLocked reports whether a request of tokens may start now. When it may
+// not, it returns how long until the window frees enough capacity; zero means
+// "wait until an in-flight request finishes".
+func (l *Limiter) admitLocked(now time.Time, tokens int) (time.Duration, bool) {
+	if l.limits.MaxConcurrent > 0 && l.inFlight >= l.limits.MaxConcurrent {
+		return 0, false
+	}
+
+	if l.limits.RequestsPerMinute > 0 && len(l.entries) >= l.limits.RequestsPerMinute {
+		return l.entries[0].at.Add(l.window).Sub(now), false
+	}
+
+	if l.limits.TokensPerMinute > 0 && len(l.entries) > 0 {
+		used := 0
+		for _, e := range l.entries {
+			used += e.tokens
+		}
+
+		// A single request larger than the whole budget is admitted once the
+		// window is empty; otherwise it could never be sent.
+		if used+tokens > l.limits.TokensPerMinute {
+			freed := 0
+			for _, e := range l.entries {
+				freed += e.tokens
+				if used-freed+tokens <= l.limits.TokensPerMinute {
+					return e.at.Add(l.window).Sub(now), false
+				}
+			}
+
+			return l.entries[len(l.entries)-1].at.Add(l.window).Sub(now), false
+		}
+	}
+
+	return 0, true
+}
+
+// pruneLocked drops entries that have left the sliding window.
+func (l *Limiter) pruneLocked(now time.Time) {
+	cutoff := now.Add(-l.window)
+
+	i := 0
+	for i < len(l.entries) && !l.entries[i].at.After(cutoff) {
+		i++
+	}
+
+	if i > 0 {
+		l.entries = append(l.entries[:0], l.entries[i:]...)
+	}
+}
+
+// releaseFunc returns an idempotent function that frees one in-flight slot.
+func (l *Limiter) releaseFunc() func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.inFlight--
+			l.broadcastLocked()
+		})
+	}
+}
+
+// broadcastLocked wakes every goroutine waiting in Acquire.
+func (l *Limiter) broadcastLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// sleepOrWake waits for d (or indefinitely when d <= 0), for changed to be
+// closed, or for ctx to be done, whichever comes first. It returns false
+// when ctx is done.
+func sleepOrWake(ctx context.Context, d time.Duration, changed <-chan struct{}) bool {
+	var timeout <-chan time.Time
+
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-changed:
+		return true
+	case <-timeout:
+		return true
+	}
+}
+
+// EstimateTokens returns the approximate number of input tokens consumed by
+// a request carrying d of audio.
+func EstimateTokens(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+
+	return seconds*audioTokensPerSecond + promptTokenOverhead
+}
+
+// assumedBitrates maps compressed MIME types to a deliberately low bitrate
+// (bits per second) so duration, and therefore token usage, is overestimated
+// rather than underestimated.
+var assumedBitrates = map[string]int{
+	"audio/mp3":  64_000,
+	"audio/mpeg": 64_000,
+	"audio/aac":  64_000,
+	"audio/m4a":  64_000,
+	"audio/ogg":  32_000,
+	"audio/webm": 32_000,
+	"audio/flac": 256_000,
+}
+
+// pcmBytesPerSecond is the data rate of raw audio/pcm input, which is
+// assumed to be 16 kHz, 16-bit, mono.
+const pcmBytesPerSecond = 16000 * 2
+
+// EstimateAudioDuration returns the approximate playback length of data.
+// WAV durations are read from the RIFF header; other formats are estimated
+// from the payload size and a conservative bitrate, so the result errs on
+// the long side.
+func EstimateAudioDuration(data []byte, mimeType string) time.Duration {
+	if mimeType == "audio/wav" {
+		if d, ok := audio.ParseDuration(data); ok {
+			return d
+		}
+	}
+
+	return EstimateSizeDuration(int64(len(data)), mimeType)
+}
+
+// EstimateSizeDuration estimates the playback length of size bytes of audio
+// of the given type from a conservative bitrate, without looking at the
+// payload. Use it to plan work before audio is read.
+func EstimateSizeDuration(size int64, mimeType string) time.Duration {
+	bytesPerSecond := pcmBytesPerSecond
+	if bitrate, ok := assumedBitrates[mimeType]; ok {
+		bytesPerSecond = bitrate / 8
+	}
+
+	return time.Duration(float64(max(size, 0)) / float64(bytesPerSecond) * float64(time.Second))
+}
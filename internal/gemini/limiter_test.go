@@ -0,0 +1,231 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package gemini_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+func TestDefaultLimitsForModel(t *testing.T) {
+	t.Parallel()
+
+	lite := gemini.DefaultLimitsForModel("gemini-3.1-flash-lite-preview")
+	unknown := gemini.DefaultLimitsForModel("some-future-model")
+
+	if lite == (gemini.Limits{}) {
+		t.Fatal("DefaultLimitsForModel(default model) returned zero limits")
+	}
+
+	if unknown == (gemini.Limits{}) {
+		t.Fatal("DefaultLimitsForModel(unknown model) returned zero limits; want fallback")
+	}
+
+	if lite == unknown {
+		t.Errorf("flash-lite limits %+v equal fallback limits; want model-specific entry", lite)
+	}
+}
+
+func TestSharedLimiterIsPerModel(t *testing.T) {
+	t.Parallel()
+
+	a := gemini.SharedLimiter("test-shared-model-a", gemini.Limits{})
+	b := gemini.SharedLimiter("test-shared-model-a", gemini.Limits{})
+	c := gemini.SharedLimiter("test-shared-model-b", gemini.Limits{})
+
+	if a != b {
+		t.Error("SharedLimiter returned different limiters for the same model")
+	}
+
+	if a == c {
+		t.Error("SharedLimiter returned the same limiter for different models")
+	}
+
+	gemini.SharedLimiter("test-shared-model-a", gemini.Limits{RequestsPerMinute: 7})
+
+	if got := a.Limits().RequestsPerMinute; got != 7 {
+		t.Errorf("RequestsPerMinute after override = %d; want 7", got)
+	}
+}
+
+func TestLimiterMaxConcurrent(t *testing.T) {
+	t.Parallel()
+
+	l := gemini.NewLimiter(gemini.Limits{MaxConcurrent: 2})
+
+	var (
+		inFlight, peak atomic.Int32
+		wg             sync.WaitGroup
+	)
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			release, err := l.Acquire(context.Background(), 1)
+			if err != nil {
+				t.Errorf("Acquire() unexpected error: %v", err)
+
+				return
+			}
+			defer release()
+
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak in-flight = %d; want <= 2", got)
+	}
+}
+
+func TestLimiterRequestsPerWindow(t *testing.T) {
+	t.Parallel()
+
+	const window = 100 * time.Millisecond
+
+	l := gemini.NewLimiterWithWindow(gemini.Limits{RequestsPerMinute: 2}, window)
+	start := time.Now()
+
+	for range 3 {
+		release, err := l.Acquire(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Acquire() unexpected error: %v", err)
+		}
+
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < window {
+		t.Errorf("third request admitted after %v; want it delayed by at least %v", elapsed, window)
+	}
+}
+
+func TestLimiterTokensPerWindow(t *testing.T) {
+	t.Parallel()
+
+	const window = 100 * time.Millisecond
+
+	l := gemini.NewLimiterWithWindow(gemini.Limits{TokensPerMinute: 100}, window)
+
+	release, err := l.Acquire(context.Background(), 80)
+	if err != nil {
+		t.Fatalf("Acquire() unexpected error: %v", err)
+	}
+
+	release()
+
+	// An oversized request must still be admitted once the window is empty.
+	start := time.Now()
+
+	release, err = l.Acquire(context.Background(), 500)
+	if err != nil {
+		t.Fatalf("Acquire() oversized request unexpected error: %v", err)
+	}
+
+	release()
+
+	if elapsed := time.Since(start); elapsed < window/2 {
+		t.Errorf("oversized request admitted after %v; want it to wait for the window", elapsed)
+	}
+}
+
+func TestLimiterHonoursContext(t *testing.T) {
+	t.Parallel()
+
+	l := gemini.NewLimiter(gemini.Limits{MaxConcurrent: 1})
+
+	release, err := l.Acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Acquire() unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v; want context.DeadlineExceeded", err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	t.Parallel()
+
+	short := gemini.EstimateTokens(time.Second)
+	hour := gemini.EstimateTokens(time.Hour)
+
+	if hour <= short {
+		t.Errorf("EstimateTokens(1h) = %d; want more than EstimateTokens(1s) = %d", hour, short)
+	}
+
+	if hour < 3600*25 {
+		t.Errorf("EstimateTokens(1h) = %d; want at least 25 tokens per second", hour)
+	}
+}
+
+func TestEstimateAudioDuration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WAV duration is read from the header", func(t *testing.T) {
+		t.Parallel()
+
+		wav := makeWAV(16000, 1, 16000*2*3) // 3 seconds
+		if got := gemini.EstimateAudioDuration(wav, "audio/wav"); got != 3*time.Second {
+			t.Errorf("EstimateAudioDuration(wav) = %v; want 3s", got)
+		}
+	})
+
+	t.Run("compressed audio is estimated from size", func(t *testing.T) {
+		t.Parallel()
+
+		if got := gemini.EstimateAudioDuration(make([]byte, 8000), "audio/mp3"); got <= 0 {
+			t.Errorf("EstimateAudioDuration(mp3) = %v; want > 0", got)
+		}
+	})
+}
+
+// makeWAV returns a minimal 16-bit PCM WAV file with dataSize bytes of silence.
+func makeWAV(sampleRate, channels, dataSize int) []byte {
+	buf := make([]byte, 44+dataSize)
+	blockAlign := channels * 2
+
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+dataSize))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1)
+	binary.LittleEndian.PutUint16(buf[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(buf[28:32], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(buf[34:36], 16)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(dataSize))
+
+	return buf
+}
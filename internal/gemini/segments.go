@@ -0,0 +1,102 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// timestampInstructions is appended to the prompt when timed segments are
+// requested. The JSON shape itself is enforced by segmentSchema.
+const timestampInstructions = `
+Split the transcription into segments at natural pauses, one or two sentences each.
+For every segment give its start and end time as offsets from the beginning of
+this audio in HH:MM:SS.mmm format, and the text spoken in that segment.`
+
+// segmentSchema constrains the model's JSON output to a list of segments.
+var segmentSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"start": {Type: genai.TypeString, Description: "Segment start, HH:MM:SS.mmm"},
+			"end":   {Type: genai.TypeString, Description: "Segment end, HH:MM:SS.mmm"},
+			"text":  {Type: genai.TypeString, Description: "Verbatim transcription of the segment"},
+		},
+		Required:         []string{"start", "end", "text"},
+		PropertyOrdering: []string{"start", "end", "text"},
+	},
+}
+
+// timestampConfig returns the generation config used for timed requests.
+func timestampConfig() *genai.GenerateContentConfig {
+	return &genai.GenerateContentConfig{
+		AudioTimestamp:   true,
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   segmentSchema,
+	}
+}
+
+// rawSegment is one element of the model's JSON output.
+type rawSegment struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Text  string `json:"text"`
+}
+
+// parseSegments decodes the model's JSON segment list into a Transcript.
+// Segments with unparseable timestamps keep the previous segment's end as
+// their start so ordering is preserved; empty segments are dropped.
+func parseSegments(raw string) (*Transcript, error) {
+	var items []rawSegment
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, fmt.Errorf("decoding timed transcript: %w", err)
+	}
+
+	t := &Transcript{Segments: make([]Segment, 0, len(items))}
+	texts := make([]string, 0, len(items))
+
+	for _, item := range items {
+		text := strings.TrimSpace(item.Text)
+		if text == "" {
+			continue
+		}
+
+		var seg Segment
+
+		seg.Text = text
+
+		if len(t.Segments) > 0 {
+			seg.Start = t.Segments[len(t.Segments)-1].End
+		}
+
+		if start, err := config.ParseTimestamp(item.Start); err == nil {
+			seg.Start = start
+		}
+
+		seg.End = seg.Start
+		if end, err := config.ParseTimestamp(item.End); err == nil && end >= seg.Start {
+			seg.End = end
+		}
+
+		t.Segments = append(t.Segments, seg)
+		texts = append(texts, text)
+	}
+
+	if len(t.Segments) == 0 {
+		return nil, fmt.Errorf("gemini returned empty transcript")
+	}
+
+	t.Text = strings.Join(texts, "\n")
+
+	return t, nil
+}
@@ -7,10 +7,15 @@
 package gemini
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"strings"
+	"time"
 
 	"google.golang.org/genai"
 
@@ -29,20 +34,103 @@ const (
 
 	// roleUser is the Gemini content role for user turns.
 	roleUser = "user"
+
+	// MaxInlineSize is the largest audio payload sent inline in a request.
+	// Longer audio is split by the chunker, whose default window of 16 kHz
+	// mono WAV stays below it, or read by the backend from Cloud Storage.
+	MaxInlineSize = config.MaxInlineSize
 )
 
+// Request is a single audio payload to transcribe.
+type Request struct {
+	// Audio is read to EOF by TranscribeAudio. Inline requests carry the
+	// whole payload, so callers bound its size, e.g. by chunking; audio
+	// over MaxInlineSize is refused without being read past the limit.
+	Audio io.Reader
+	// Size is the length of Audio in bytes, or -1 when unknown.
+	Size     int64
+	MIMEType string
+	// Duration is the playback length of Audio. When zero it is estimated
+	// from the payload for rate limiting.
+	Duration time.Duration
+	// Timestamps asks the model for timed segments instead of plain text.
+	Timestamps bool
+	// Language overrides the configured --language for this request, e.g.
+	// with the language tag of the audio track being transcribed.
+	Language string
+	// FileURI, when set, is a gs:// URI of audio the backend reads from
+	// Cloud Storage itself; Audio is then ignored and Size is the size of
+	// the object.
+	FileURI string
+	// Reference is existing text for the audio, such as the lines of a
+	// subtitle track, given to the model to help with names and spelling.
+	Reference string
+	// Sent, when set, is called as the request goes to the model: once its
+	// audio has been read and any wait for the rate limiter is over.
+	Sent func()
+}
+
+// Segment is a timed span of transcribed speech. Start and End are offsets
+// from the beginning of the audio the segment was transcribed from.
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+	// Speaker names who spoke the segment when the caller knows it, e.g. the
+	// channel of a split stereo recording. The backend never sets it.
+	Speaker string
+}
+
+// Transcript is the backend's answer for one Request.
+type Transcript struct {
+	Text string
+	// Segments is populated only when the Request asked for timestamps.
+	Segments []Segment
+	// Usage is the tokens the request was billed for, as reported by the
+	// model, or the sum over every request a merged transcript came from.
+	Usage Usage
+}
+
+// Usage counts the tokens billed for one or more requests.
+type Usage struct {
+	// InputTokens counts the prompt and audio, OutputTokens the answer
+	// including any thinking.
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Add returns the sum of u and other.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{InputTokens: u.InputTokens + other.InputTokens, OutputTokens: u.OutputTokens + other.OutputTokens}
+}
+
+// usageOf returns the usage reported in resp, which may be nil.
+func usageOf(resp *genai.GenerateContentResponse) Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return Usage{}
+	}
+
+	m := resp.UsageMetadata
+
+	return Usage{
+		InputTokens:  int(m.PromptTokenCount),
+		OutputTokens: int(m.CandidatesTokenCount + m.ThoughtsTokenCount),
+	}
+}
+
 // AudioTranscriber is the interface for sending audio to a transcription backend.
 // It is satisfied by *Service and can be replaced in tests by a stub.
 type AudioTranscriber interface {
-	TranscribeAudio(ctx context.Context, audioData []byte, mimeType string) (string, error)
+	TranscribeAudio(ctx context.Context, req *Request) (*Transcript, error)
 }
 
 // buildPrompt returns the transcription prompt for the given language.
 // When language is "auto" or empty, Gemini detects the language automatically.
 // Otherwise language must be a two-letter ISO 639-1 code (e.g. "uk", "en", "de").
 // Inputs are normalized via config.NormalizeLanguage; invalid values fall back
-// to automatic detection.
-func buildPrompt(language string) string {
+// to automatic detection. With timestamps set, the prompt asks for timed
+// segments matching segmentSchema instead of plain text.
+func buildPrompt(language string, timestamps bool) string {
 	const suffix = `
 Output only the transcription text with no commentary, labels, or metadata.
 Preserve natural sentence structure and add punctuation where appropriate.
@@ -50,19 +138,69 @@ Do not translate, summarize, or modify the content in any way.`
 
 	code, auto := config.NormalizeLanguage(language)
 
+	prompt := "Transcribe the following audio recording verbatim in " + code + "."
 	if auto {
-		return "Transcribe the following audio recording verbatim in its original spoken language." + suffix
+		prompt = "Transcribe the following audio recording verbatim in its original spoken language."
+	}
+
+	prompt += suffix
+
+	if timestamps {
+		prompt += timestampInstructions
+	}
+
+	return prompt
+}
+
+// referenceInstructions returns the prompt section that hands the model
+// reference text for the audio, or "" when there is none.
+func referenceInstructions(reference string) string {
+	if strings.TrimSpace(reference) == "" {
+		return ""
+	}
+
+	return `
+
+Existing subtitles for this audio follow for reference. They may be
+incomplete, machine-generated or in another language. Use them to get names,
+terms and spellings right, but transcribe what is actually said: do not copy
+lines that are not spoken or translate the speech into their language.
+
+<subtitles>
+` + reference + `
+</subtitles>`
+}
+
+// Fingerprint describes what besides the audio shapes the answer of model
+// for language: every variant of the prompt and the generation settings of
+// timed requests. Callers caching transcripts include it in their keys, so
+// that a change to either invalidates what was cached before.
+func Fingerprint(model, language string) (string, error) {
+	settings, err := json.Marshal(timestampConfig())
+	if err != nil {
+		return "", fmt.Errorf("encoding generation settings: %w", err)
 	}
 
-	return "Transcribe the following audio recording verbatim in " + code + "." + suffix
+	return strings.Join([]string{
+		model,
+		buildPrompt(language, false),
+		buildPrompt(language, true),
+		referenceInstructions("{reference}"),
+		string(settings),
+	}, "\n"), nil
 }
 
 // Service handles Gemini transcription via Vertex AI.
 type Service struct {
 	client   *genai.Client
+	project  string
+	location string
 	model    string
 	language string
 	logger   *slog.Logger
+	limiter  *Limiter
+	// debug is nil unless --debug-dir is set.
+	debug *debugDumper
 }
 
 // NewService creates a new Gemini service and initializes the Vertex AI client.
@@ -83,68 +221,275 @@ func NewService(ctx context.Context, cfg *config.Config, projectID string, logge
 		model = DefaultModel
 	}
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+	clientConfig := &genai.ClientConfig{
 		Project:  projectID,
 		Location: location,
 		Backend:  genai.BackendVertexAI,
-	})
+	}
+
+	var (
+		debug *debugDumper
+		err   error
+	)
+
+	if cfg.DebugDir != "" {
+		if debug, err = newDebugDumper(cfg.DebugDir, cfg.DebugAudio); err != nil {
+			return nil, err
+		}
+
+		if err := recordResponses(ctx, clientConfig, debug.responses); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Vertex AI client: %w", err)
 	}
 
 	return &Service{
 		client:   client,
+		project:  projectID,
+		location: location,
 		model:    model,
 		language: cfg.Language,
 		logger:   logger,
+		limiter: SharedLimiter(model, Limits{
+			RequestsPerMinute: cfg.RequestsPerMinute,
+			TokensPerMinute:   cfg.TokensPerMinute,
+			MaxConcurrent:     cfg.MaxConcurrent,
+		}),
+		debug: debug,
 	}, nil
 }
 
 // TranscribeAudio sends audio bytes to Gemini and returns the transcript.
-// mimeType must be one of: audio/wav, audio/mp3, audio/flac, audio/ogg,
+// req.MIMEType must be one of: audio/wav, audio/mp3, audio/flac, audio/ogg,
 // audio/m4a, audio/aac, audio/webm, audio/pcm.
-func (s *Service) TranscribeAudio(ctx context.Context, audioData []byte, mimeType string) (string, error) {
-	s.logger.InfoContext(ctx, "sending audio to Gemini",
-		slog.String("model", s.model),
-		slog.String("size", formatBytes(len(audioData))),
-	)
+// Audio referenced by req.FileURI is not read at all.
+// The call blocks while the shared per-model rate limiter is saturated.
+func (s *Service) TranscribeAudio(ctx context.Context, req *Request) (*Transcript, error) {
+	var audioData []byte
+
+	if req.FileURI == "" {
+		var err error
+
+		if audioData, err = readAudio(req); err != nil {
+			return nil, err
+		}
+	}
+
+	mimeType := req.MIMEType
+
+	duration := req.Duration
+	if duration <= 0 && req.FileURI != "" {
+		duration = EstimateSizeDuration(req.Size, mimeType)
+	} else if duration <= 0 {
+		duration = EstimateAudioDuration(audioData, mimeType)
+	}
+
+	tokens := EstimateTokens(duration)
+
+	waitStart := time.Now()
+
+	release, err := s.limiter.Acquire(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if waited := time.Since(waitStart); waited > time.Second {
+		s.logger.InfoContext(ctx, "rate limiter delayed request", slog.Duration("waited", waited))
+	}
+
+	if req.FileURI != "" {
+		s.logger.InfoContext(ctx, "sending audio reference to Gemini",
+			slog.String("model", s.model),
+			slog.String("uri", req.FileURI),
+			slog.Int("estimated_tokens", tokens),
+		)
+	} else {
+		s.logger.InfoContext(ctx, "sending audio to Gemini",
+			slog.String("model", s.model),
+			slog.String("size", FormatBytes(int64(len(audioData)))),
+			slog.Int("estimated_tokens", tokens),
+		)
+	}
 
-	parts := []*genai.Part{
-		{Text: buildPrompt(s.language)},
-		{InlineData: &genai.Blob{MIMEType: mimeType, Data: audioData}},
+	language := s.language
+	if req.Language != "" {
+		language = req.Language
 	}
+
+	prompt := buildPrompt(language, req.Timestamps) + referenceInstructions(req.Reference)
+	parts := []*genai.Part{{Text: prompt}, audioPart(req, audioData)}
 	contents := []*genai.Content{{Role: roleUser, Parts: parts}}
 
-	resp, err := s.client.Models.GenerateContent(ctx, s.model, contents, nil)
+	var genConfig *genai.GenerateContentConfig
+	if req.Timestamps {
+		genConfig = timestampConfig()
+	}
+
+	if req.Sent != nil {
+		req.Sent()
+	}
+
+	// The dump records the configuration as given, not the copy tagged for
+	// capturing the response.
+	callConfig, captureID := genConfig, ""
+	if s.debug != nil {
+		callConfig, captureID = s.debug.responses.capture(genConfig)
+	}
+
+	requestStart := time.Now()
+	resp, err := s.client.Models.GenerateContent(ctx, s.model, contents, callConfig)
+
+	if s.debug != nil {
+		rec := &debugRecord{
+			Prompt:           prompt,
+			GenerationConfig: genConfig,
+			Audio:            newDebugAudio(audioData, mimeType, req.FileURI),
+			Response:         rawResponse(s.debug.responses.take(captureID)),
+			Timing: debugTiming{
+				Started:   waitStart,
+				QueueWait: requestStart.Sub(waitStart),
+				Request:   time.Since(requestStart),
+			},
+		}
+		if resp != nil {
+			rec.Usage = resp.UsageMetadata
+		}
+
+		s.dumpDebug(ctx, rec, audioData, err)
+	}
+
 	if err != nil {
-		return "", fmt.Errorf("gemini generation failed: %w", err)
+		return nil, fmt.Errorf("gemini generation failed: %w", err)
 	}
 
-	transcript := strings.TrimSpace(resp.Text())
-	if transcript == "" {
-		return "", fmt.Errorf("gemini returned empty transcript")
+	text := strings.TrimSpace(resp.Text())
+	if text == "" {
+		return nil, fmt.Errorf("gemini returned empty transcript")
+	}
+
+	transcript := &Transcript{Text: text}
+
+	if req.Timestamps {
+		if transcript, err = parseSegments(text); err != nil {
+			return nil, err
+		}
 	}
 
+	transcript.Usage = usageOf(resp)
+
 	s.logger.DebugContext(ctx, "transcription received",
-		slog.Int("characters", len(transcript)),
+		slog.Int("characters", len(transcript.Text)),
+		slog.Int("segments", len(transcript.Segments)),
 	)
 
 	return transcript, nil
 }
 
-// formatBytes returns a human-readable byte size string.
-func formatBytes(n int) string {
+// dumpDebug completes rec with service details and writes it to the debug
+// directory. Failures are logged but never fail the transcription.
+func (s *Service) dumpDebug(ctx context.Context, rec *debugRecord, audioData []byte, callErr error) {
+	rec.Model = s.model
+	rec.Location = s.location
+	rec.Project = s.project
+
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+
+	path, err := s.debug.write(rec, audioData)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to write debug dump", slog.Any("error", err))
+
+		return
+	}
+
+	s.logger.DebugContext(ctx, "debug dump written", slog.String("path", path))
+}
+
+// recordResponses makes the client configured by cc send its requests
+// through recorder. A client given its own HTTP client does not look up
+// credentials, so they are looked up here, together with the quota project
+// the default client would bill.
+func recordResponses(ctx context.Context, cc *genai.ClientConfig, recorder *responseRecorder) error {
+	cc.HTTPClient = &http.Client{Transport: recorder}
+
+	if err := cc.UseDefaultCredentials(); err != nil {
+		return fmt.Errorf("failed to find default credentials: %w", err)
+	}
+
+	quotaProject, err := cc.Credentials.QuotaProjectID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get quota project ID: %w", err)
+	}
+
+	if quotaProject != "" {
+		cc.HTTPOptions.Headers = http.Header{"X-Goog-User-Project": {quotaProject}}
+	}
+
+	return nil
+}
+
+// audioPart returns the content part carrying the audio of req: a reference
+// to req.FileURI, or audioData inline.
+func audioPart(req *Request, audioData []byte) *genai.Part {
+	if req.FileURI != "" {
+		return &genai.Part{FileData: &genai.FileData{FileURI: req.FileURI, MIMEType: req.MIMEType}}
+	}
+
+	return &genai.Part{InlineData: &genai.Blob{MIMEType: req.MIMEType, Data: audioData}}
+}
+
+// FormatBytes renders n as a human-readable size using binary units.
+func FormatBytes(n int64) string {
 	const unit = 1024
+
 	if n < unit {
 		return fmt.Sprintf("%d B", n)
 	}
 
-	div, exp := unit, 0
-
+	div, exp := int64(unit), 0
 	for v := n / unit; v >= unit; v /= unit {
 		div *= unit
 		exp++
 	}
 
-	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// readAudio reads the request payload into memory, refusing audio over
+// MaxInlineSize before it is read when its size is known, and otherwise
+// once the limit is passed.
+func readAudio(req *Request) ([]byte, error) {
+	if req.Size > MaxInlineSize {
+		return nil, inlineSizeError(FormatBytes(req.Size))
+	}
+
+	var buf bytes.Buffer
+
+	if req.Size > 0 {
+		buf.Grow(int(req.Size))
+	}
+
+	n, err := buf.ReadFrom(io.LimitReader(req.Audio, MaxInlineSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading audio: %w", err)
+	}
+
+	if n > MaxInlineSize {
+		return nil, inlineSizeError("more than " + FormatBytes(MaxInlineSize))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// inlineSizeError reports audio of the given size as too large to send
+// inline, saying how to send it instead.
+func inlineSizeError(size string) error {
+	return fmt.Errorf("audio of %s exceeds the %s inline request limit; split it with --chunk-duration, "+
+		"pick a smaller --upload-codec or transcribe it from a gs:// URI", size, FormatBytes(MaxInlineSize))
 }
@@ -6,8 +6,12 @@
 package gemini_test
 
 import (
+	"errors"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
 )
@@ -38,7 +42,7 @@ func TestBuildPrompt(t *testing.T) {
 	t.Run("auto returns original spoken language prompt", func(t *testing.T) {
 		t.Parallel()
 
-		p := gemini.BuildPrompt("auto")
+		p := gemini.BuildPrompt("auto", false)
 		if !strings.Contains(p, "original spoken language") {
 			t.Errorf("BuildPrompt(%q) = %q; want it to mention 'original spoken language'", "auto", p)
 		}
@@ -47,7 +51,7 @@ func TestBuildPrompt(t *testing.T) {
 	t.Run("empty string returns original spoken language prompt", func(t *testing.T) {
 		t.Parallel()
 
-		p := gemini.BuildPrompt("")
+		p := gemini.BuildPrompt("", false)
 		if !strings.Contains(p, "original spoken language") {
 			t.Errorf("BuildPrompt(%q) = %q; want it to mention 'original spoken language'", "", p)
 		}
@@ -56,7 +60,7 @@ func TestBuildPrompt(t *testing.T) {
 	t.Run("ISO code produces language-specific prompt", func(t *testing.T) {
 		t.Parallel()
 
-		p := gemini.BuildPrompt("uk")
+		p := gemini.BuildPrompt("uk", false)
 		if strings.Contains(p, "original spoken language") {
 			t.Errorf("BuildPrompt(%q) should not mention 'original spoken language'", "uk")
 		}
@@ -69,7 +73,7 @@ func TestBuildPrompt(t *testing.T) {
 	t.Run("uppercase ISO code is normalized to lowercase", func(t *testing.T) {
 		t.Parallel()
 
-		p := gemini.BuildPrompt("UK")
+		p := gemini.BuildPrompt("UK", false)
 		if strings.Contains(p, "original spoken language") {
 			t.Errorf("BuildPrompt(%q) should not fall back to auto-detection", "UK")
 		}
@@ -82,7 +86,7 @@ func TestBuildPrompt(t *testing.T) {
 	t.Run("whitespace around ISO code is trimmed", func(t *testing.T) {
 		t.Parallel()
 
-		p := gemini.BuildPrompt("  en  ")
+		p := gemini.BuildPrompt("  en  ", false)
 		if strings.Contains(p, "original spoken language") {
 			t.Errorf("BuildPrompt(%q) should not fall back to auto-detection", "  en  ")
 		}
@@ -96,7 +100,7 @@ func TestBuildPrompt(t *testing.T) {
 		t.Parallel()
 
 		for _, bad := range []string{"english", "123", "a", "uk-UA", "uk_UA", "u k"} {
-			p := gemini.BuildPrompt(bad)
+			p := gemini.BuildPrompt(bad, false)
 			if !strings.Contains(p, "original spoken language") {
 				t.Errorf("BuildPrompt(%q) = %q; want fallback to 'original spoken language' for invalid input", bad, p)
 			}
@@ -107,10 +111,194 @@ func TestBuildPrompt(t *testing.T) {
 		t.Parallel()
 
 		for _, lang := range []string{"auto", "", "uk", "en", "de"} {
-			p := gemini.BuildPrompt(lang)
+			p := gemini.BuildPrompt(lang, false)
 			if !strings.Contains(p, "Output only the transcription text") {
 				t.Errorf("BuildPrompt(%q) missing standard instructions", lang)
 			}
 		}
 	})
+	t.Run("timestamps request timed segments", func(t *testing.T) {
+		t.Parallel()
+
+		plain := gemini.BuildPrompt("uk", false)
+		timed := gemini.BuildPrompt("uk", true)
+
+		if !strings.HasPrefix(timed, plain) {
+			t.Errorf("timed prompt should extend the plain prompt\nplain: %q\ntimed: %q", plain, timed)
+		}
+
+		if !strings.Contains(timed, "HH:MM:SS.mmm") {
+			t.Errorf("BuildPrompt(%q, true) = %q; want timestamp format instructions", "uk", timed)
+		}
+	})
+}
+
+func TestReferenceInstructions(t *testing.T) {
+	t.Parallel()
+
+	if got := gemini.ReferenceInstructions(" \n"); got != "" {
+		t.Errorf("ReferenceInstructions(blank) = %q; want no section", got)
+	}
+
+	got := gemini.ReferenceInstructions("Welcome to Kyiv\nThanks, Oksana")
+	if !strings.Contains(got, "<subtitles>\nWelcome to Kyiv\nThanks, Oksana\n</subtitles>") {
+		t.Errorf("ReferenceInstructions() = %q; want the reference text delimited", got)
+	}
+
+	if !strings.Contains(got, "transcribe what is actually said") {
+		t.Errorf("ReferenceInstructions() = %q; want it to insist on the spoken words", got)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	fp := func(model, language string) string {
+		t.Helper()
+
+		s, err := gemini.Fingerprint(model, language)
+		if err != nil {
+			t.Fatalf("Fingerprint(%q, %q) unexpected error: %v", model, language, err)
+		}
+
+		return s
+	}
+
+	if fp("m", "uk") != fp("m", "UK") {
+		t.Error("Fingerprint() differs for equivalent language codes")
+	}
+
+	if fp("m", "uk") == fp("m", "en") || fp("m", "uk") == fp("n", "uk") || fp("m", "auto") == fp("m", "uk") {
+		t.Error("Fingerprint() does not tell models or languages apart")
+	}
+}
+
+func TestAudioPart(t *testing.T) {
+	t.Parallel()
+
+	inline := gemini.AudioPart(&gemini.Request{MIMEType: "audio/wav"}, []byte("RIFF"))
+	if inline.InlineData == nil || string(inline.InlineData.Data) != "RIFF" || inline.FileData != nil {
+		t.Errorf("AudioPart(inline) = %+v; want the audio inline", inline)
+	}
+
+	req := &gemini.Request{MIMEType: "audio/mp3", FileURI: "gs://archive/2025/talk.mp3"}
+
+	ref := gemini.AudioPart(req, nil)
+	if ref.FileData == nil || ref.InlineData != nil {
+		t.Fatalf("AudioPart(reference) = %+v; want file data", ref)
+	}
+
+	if ref.FileData.FileURI != req.FileURI || ref.FileData.MIMEType != req.MIMEType {
+		t.Errorf("FileData = %+v; want %s as %s", ref.FileData, req.FileURI, req.MIMEType)
+	}
+}
+
+func TestReadAudio(t *testing.T) {
+	t.Parallel()
+
+	data, err := gemini.ReadAudio(&gemini.Request{Audio: strings.NewReader("RIFF"), Size: 4})
+	if err != nil || string(data) != "RIFF" {
+		t.Errorf("ReadAudio() = %q, %v; want the audio", data, err)
+	}
+
+	// A declared size over the limit is refused before anything is read.
+	tooLarge := &gemini.Request{Audio: iotest.ErrReader(errors.New("read")), Size: gemini.MaxInlineSize + 1}
+	if _, err := gemini.ReadAudio(tooLarge); err == nil || !strings.Contains(err.Error(), "inline request limit") {
+		t.Errorf("ReadAudio(declared too large) error = %v; want the inline limit", err)
+	}
+
+	unknown := &gemini.Request{Audio: io.LimitReader(zeros{}, gemini.MaxInlineSize+100), Size: -1}
+	if _, err := gemini.ReadAudio(unknown); err == nil || !strings.Contains(err.Error(), "inline request limit") {
+		t.Errorf("ReadAudio(streamed too large) error = %v; want the inline limit", err)
+	}
+}
+
+// zeros is an endless stream of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	clear(b)
+
+	return len(b), nil
+}
+
+func TestParseSegments(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid segments", func(t *testing.T) {
+		t.Parallel()
+
+		raw := `[{"start":"00:00:01.500","end":"00:00:04.000","text":" Привіт. "},` +
+			`{"start":"00:00:04.000","end":"00:01:02.250","text":"Як справи?"},` +
+			`{"start":"00:01:03","end":"00:01:04","text":"  "}]`
+
+		got, err := gemini.ParseSegments(raw)
+		if err != nil {
+			t.Fatalf("ParseSegments() unexpected error: %v", err)
+		}
+
+		if len(got.Segments) != 2 {
+			t.Fatalf("len(Segments) = %d; want 2 (empty segment dropped)", len(got.Segments))
+		}
+
+		if got.Segments[0].Start != 1500*time.Millisecond || got.Segments[1].End != 62250*time.Millisecond {
+			t.Errorf("segment times = %+v; want 1.5s start and 1m2.25s end", got.Segments)
+		}
+
+		if got.Text != "Привіт.\nЯк справи?" {
+			t.Errorf("Text = %q; want segments joined by newlines", got.Text)
+		}
+	})
+
+	t.Run("bad timestamp keeps ordering", func(t *testing.T) {
+		t.Parallel()
+
+		raw := `[{"start":"00:00:02","end":"00:00:03","text":"a"},{"start":"??","end":"??","text":"b"}]`
+
+		got, err := gemini.ParseSegments(raw)
+		if err != nil {
+			t.Fatalf("ParseSegments() unexpected error: %v", err)
+		}
+
+		if got.Segments[1].Start != 3*time.Second {
+			t.Errorf("second segment start = %v; want previous end 3s", got.Segments[1].Start)
+		}
+	})
+
+	t.Run("invalid JSON is an error", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := gemini.ParseSegments("not json"); err == nil {
+			t.Error("ParseSegments(invalid) = nil error; want error")
+		}
+	})
+
+	t.Run("no segments is an error", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := gemini.ParseSegments("[]"); err == nil {
+			t.Error("ParseSegments([]) = nil error; want error")
+		}
+	})
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 250 * 1024 * 1024, want: "250.0 MiB"},
+		{n: 3 << 30, want: "3.0 GiB"},
+	}
+
+	for _, tc := range tests {
+		if got := gemini.FormatBytes(tc.n); got != tc.want {
+			t.Errorf("FormatBytes(%d) = %q; want %q", tc.n, got, tc.want)
+		}
+	}
 }
@@ -0,0 +1,289 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// alignSlack is how far outside every cue transcript speech may be and
+// still be matched to the nearest one, absorbing timing differences.
+const alignSlack = 2 * time.Second
+
+// Report is the comparison of a subtitle track with a transcript of the
+// same media.
+type Report struct {
+	// Cues lists every subtitle cue with the transcript heard during it.
+	Cues []CueComparison
+	// Missing lists transcript speech that no cue covers.
+	Missing []Cue
+	// SubtitleWords and TranscriptWords count the words on each side, and
+	// MatchedWords those found in both, in order, within the same cue.
+	SubtitleWords   int
+	TranscriptWords int
+	MatchedWords    int
+}
+
+// CueComparison is one subtitle cue and the transcript aligned with it.
+type CueComparison struct {
+	Cue Cue
+	// Transcript is the transcript text spoken during the cue.
+	Transcript string
+	// Matched is the number of words the cue shares with Transcript.
+	Matched int
+	// Differs reports whether the cue and Transcript differ in more than
+	// case and punctuation.
+	Differs bool
+}
+
+// Agreement returns the share of words the subtitles and the transcript
+// have in common, from 0 to 1.
+func (r *Report) Agreement() float64 {
+	total := r.SubtitleWords + r.TranscriptWords
+	if total == 0 {
+		return 1
+	}
+
+	return 2 * float64(r.MatchedWords) / float64(total)
+}
+
+// timedWord is a transcript word with the estimated time it was spoken.
+type timedWord struct {
+	Text string
+	At   time.Duration
+}
+
+// Compare aligns transcript, a list of timed segments, with the subtitle
+// cues by time and compares their words cue by cue. Each transcript word is
+// placed at an even share of its segment and matched to the cue spanning
+// it, or to the nearest cue within a couple of seconds; words further away
+// are reported as missing from the subtitles.
+func Compare(cues, transcript []Cue) *Report {
+	cues = sortedCues(cues)
+	report := &Report{Cues: make([]CueComparison, len(cues))}
+	heard := make([][]timedWord, len(cues))
+
+	var missing []timedWord
+
+	for _, w := range spreadWords(transcript) {
+		i := nearestCue(cues, w.At)
+		if i < 0 {
+			missing = append(missing, w)
+
+			continue
+		}
+
+		heard[i] = append(heard[i], w)
+	}
+
+	for i, cue := range cues {
+		cueWords := normalizeWords(strings.Fields(cue.PlainText()))
+		spoken := make([]string, len(heard[i]))
+
+		for j, w := range heard[i] {
+			spoken[j] = w.Text
+		}
+
+		spokenWords := normalizeWords(spoken)
+		matched := commonWords(cueWords, spokenWords)
+
+		report.Cues[i] = CueComparison{
+			Cue:        cue,
+			Transcript: strings.Join(spoken, " "),
+			Matched:    matched,
+			Differs:    matched != len(cueWords) || matched != len(spokenWords),
+		}
+		report.SubtitleWords += len(cueWords)
+		report.TranscriptWords += len(spokenWords)
+		report.MatchedWords += matched
+	}
+
+	for _, w := range missing {
+		report.TranscriptWords += len(normalizeWords([]string{w.Text}))
+	}
+
+	report.Missing = missingRuns(missing)
+
+	return report
+}
+
+// sortedCues returns the cues that have words, ordered by start time.
+// Cues holding only a music note or the like are dropped.
+func sortedCues(cues []Cue) []Cue {
+	out := make([]Cue, 0, len(cues))
+
+	for _, cue := range cues {
+		if len(normalizeWords(strings.Fields(cue.PlainText()))) > 0 {
+			out = append(out, cue)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+
+	return out
+}
+
+// spreadWords splits the transcript into words, spreading the words of each
+// segment evenly over its span.
+func spreadWords(transcript []Cue) []timedWord {
+	var words []timedWord
+
+	for _, seg := range transcript {
+		fields := strings.Fields(seg.PlainText())
+		span := max(seg.End-seg.Start, 0)
+
+		for i, f := range fields {
+			at := seg.Start + span*time.Duration(2*i+1)/time.Duration(2*len(fields))
+			words = append(words, timedWord{Text: f, At: at})
+		}
+	}
+
+	return words
+}
+
+// nearestCue returns the index of the cue spanning at, or of the nearest
+// cue within alignSlack of it, or -1.
+func nearestCue(cues []Cue, at time.Duration) int {
+	// First cue starting after at; the one before it may span at.
+	next := sort.Search(len(cues), func(i int) bool { return cues[i].Start > at })
+
+	best, bestDist := -1, alignSlack+1
+
+	// A cue may overlap the next one, so the one before the predecessor
+	// can still span at; the later cue wins a tie.
+	for i := max(next-2, 0); i < next; i++ {
+		if dist := max(at-cues[i].End, 0); dist <= bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	if next < len(cues) && cues[next].Start-at < bestDist {
+		best, bestDist = next, cues[next].Start-at
+	}
+
+	if bestDist > alignSlack {
+		return -1
+	}
+
+	return best
+}
+
+// commonWords returns the length of the longest common subsequence of the
+// normalised words a and b.
+func commonWords(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// normalizeWords lower-cases words and strips surrounding punctuation,
+// dropping those with nothing left, such as a lone dash or music note.
+func normalizeWords(words []string) []string {
+	out := make([]string, 0, len(words))
+
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+
+	return out
+}
+
+// missingRuns groups transcript words no cue covers into runs of speech,
+// starting a new run after a pause longer than alignSlack.
+func missingRuns(words []timedWord) []Cue {
+	var (
+		runs []Cue
+		text []string
+	)
+
+	for i, w := range words {
+		if i > 0 && w.At-words[i-1].At > alignSlack {
+			runs[len(runs)-1].Text = strings.Join(text, " ")
+			text = text[:0]
+		}
+
+		if len(text) == 0 {
+			runs = append(runs, Cue{Start: w.At})
+		}
+
+		runs[len(runs)-1].End = w.At
+		text = append(text, w.Text)
+	}
+
+	if len(runs) > 0 {
+		runs[len(runs)-1].Text = strings.Join(text, " ")
+	}
+
+	return runs
+}
+
+// WriteText writes the report for reading: a summary, then every cue that
+// differs from the transcript and every stretch of speech the subtitles
+// miss.
+func (r *Report) WriteText(w io.Writer) error {
+	var (
+		b       strings.Builder
+		differs int
+	)
+
+	for _, c := range r.Cues {
+		if c.Differs {
+			differs++
+		}
+	}
+
+	fmt.Fprintf(&b, "Word agreement: %.1f%%\n", 100*r.Agreement())
+	fmt.Fprintf(&b, "Subtitle words: %d, transcript words: %d, matched: %d\n",
+		r.SubtitleWords, r.TranscriptWords, r.MatchedWords)
+	fmt.Fprintf(&b, "Cues differing from the transcript: %d of %d\n", differs, len(r.Cues))
+	fmt.Fprintf(&b, "Stretches of speech missing from the subtitles: %d\n", len(r.Missing))
+
+	for _, c := range r.Cues {
+		if !c.Differs {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n[%s --> %s]\n- subtitles:  %s\n+ transcript: %s\n",
+			formatTime(c.Cue.Start, '.'), formatTime(c.Cue.End, '.'), c.Cue.PlainText(), c.Transcript)
+	}
+
+	if len(r.Missing) > 0 {
+		b.WriteString("\nSpeech missing from the subtitles:\n")
+
+		for _, m := range r.Missing {
+			fmt.Fprintf(&b, "[%s --> %s] %s\n", formatTime(m.Start, '.'), formatTime(m.End, '.'), m.Text)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing subtitle comparison: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package subtitle_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
+)
+
+// sec returns s seconds.
+func sec(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	cues := []subtitle.Cue{
+		{Start: sec(1), End: sec(3), Text: "<i>Good morning,</i> everyone."},
+		{Start: sec(3.5), End: sec(6), Text: "We will met at noon."},
+		{Start: sec(20), End: sec(22), Text: "♪"},
+	}
+	// One segment spans both cues; the last words come long after any cue.
+	transcript := []subtitle.Cue{
+		{Start: sec(1), End: sec(6), Text: "Good morning everyone. We'll meet at noon."},
+		{Start: sec(40), End: sec(42), Text: "Thanks for coming."},
+	}
+
+	r := subtitle.Compare(cues, transcript)
+
+	if r.SubtitleWords != 8 || r.TranscriptWords != 10 || r.MatchedWords != 5 {
+		t.Errorf("words = %d subtitle, %d transcript, %d matched; want 8, 10, 5",
+			r.SubtitleWords, r.TranscriptWords, r.MatchedWords)
+	}
+
+	if len(r.Cues) != 2 || r.Cues[0].Differs || !r.Cues[1].Differs {
+		t.Fatalf("cues = %+v; want the second of two text cues to differ", r.Cues)
+	}
+
+	if r.Cues[1].Transcript != "We'll meet at noon." {
+		t.Errorf("second cue transcript = %q; want %q", r.Cues[1].Transcript, "We'll meet at noon.")
+	}
+
+	if len(r.Missing) != 1 || r.Missing[0].Text != "Thanks for coming." || r.Missing[0].Start < sec(40) {
+		t.Errorf("missing = %+v; want the closing words at 40s", r.Missing)
+	}
+
+	var b strings.Builder
+
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Word agreement: 55.6%",
+		"Cues differing from the transcript: 1 of 2",
+		"[00:00:03.500 --> 00:00:06.000]\n- subtitles:  We will met at noon.\n+ transcript: We'll meet at noon.",
+		"] Thanks for coming.",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteText() = %q; want it to contain %q", b.String(), want)
+		}
+	}
+}
+
+func TestCompareIdentical(t *testing.T) {
+	t.Parallel()
+
+	cues := []subtitle.Cue{{Start: sec(0), End: sec(2), Text: "Hello"}, {Start: sec(2), End: sec(4), Text: "world"}}
+
+	r := subtitle.Compare(cues, cues)
+	if r.Agreement() != 1 || r.Cues[0].Differs || r.Cues[1].Differs || len(r.Missing) != 0 {
+		t.Errorf("Compare(cues, cues) = %+v; want full agreement", r)
+	}
+
+	if got := subtitle.Compare(nil, nil).Agreement(); got != 1 {
+		t.Errorf("Compare(nil, nil).Agreement() = %v; want 1", got)
+	}
+}
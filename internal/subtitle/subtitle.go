@@ -0,0 +1,178 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+// Package subtitle reads and writes SRT and WebVTT subtitles and compares
+// subtitle tracks with transcripts.
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subtitle file formats accepted by Write.
+const (
+	SRT = "srt"
+	VTT = "vtt"
+)
+
+var (
+	// timingRe matches the timing line of an SRT or WebVTT cue, ignoring any
+	// position settings after the end time. Hours are optional in WebVTT.
+	timingRe = regexp.MustCompile(
+		`^((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})\s+-->\s+((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})(?:\s|$)`)
+	// markupRe matches HTML-style tags and ASS override blocks such as {\an8}.
+	markupRe = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+)
+
+// Cue is one timed caption.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	// Text is the caption as written, with its line breaks and markup.
+	Text string
+}
+
+// PlainText returns the text of c on one line, without markup.
+func (c Cue) PlainText() string {
+	return strings.Join(strings.Fields(markupRe.ReplaceAllString(c.Text, " ")), " ")
+}
+
+// Parse reads SRT or WebVTT cues from r in the order they appear. Blocks
+// without a timing line, such as a WebVTT header, notes and styles, are
+// skipped, as are cues without text.
+func Parse(r io.Reader) ([]Cue, error) {
+	var (
+		cues  []Cue
+		block []string
+	)
+
+	flush := func() {
+		if cue, ok := parseBlock(block); ok {
+			cues = append(cues, cue)
+		}
+
+		block = block[:0]
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+
+	for first := true; sc.Scan(); first = false {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+
+			continue
+		}
+
+		block = append(block, line)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading subtitles: %w", err)
+	}
+
+	flush()
+
+	return cues, nil
+}
+
+// parseBlock parses one blank-line separated block: an optional cue number
+// or identifier, the timing line and the text.
+func parseBlock(block []string) (Cue, bool) {
+	for i, line := range block {
+		m := timingRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			// Only an identifier may come before the timing line.
+			if i > 0 {
+				return Cue{}, false
+			}
+
+			continue
+		}
+
+		start, okStart := parseTime(m[1])
+		end, okEnd := parseTime(m[2])
+		text := strings.TrimSpace(strings.Join(block[i+1:], "\n"))
+
+		if !okStart || !okEnd || text == "" {
+			return Cue{}, false
+		}
+
+		return Cue{Start: start, End: max(end, start), Text: text}, true
+	}
+
+	return Cue{}, false
+}
+
+// parseTime parses an SRT or WebVTT timestamp: [HH:]MM:SS,mmm or with a dot.
+func parseTime(s string) (time.Duration, bool) {
+	s = strings.Replace(s, ",", ".", 1)
+	clock, frac, _ := strings.Cut(s, ".")
+
+	var total time.Duration
+
+	for part := range strings.SplitSeq(clock, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+
+		total = total*60 + time.Duration(n)*time.Second
+	}
+
+	ms, err := strconv.Atoi((frac + "00")[:3])
+	if err != nil {
+		return 0, false
+	}
+
+	return total + time.Duration(ms)*time.Millisecond, true
+}
+
+// Write writes cues to w in format, SRT or VTT.
+func Write(w io.Writer, format string, cues []Cue) error {
+	var b strings.Builder
+
+	switch format {
+	case SRT:
+		for i, cue := range cues {
+			fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1,
+				formatTime(cue.Start, ','), formatTime(cue.End, ','), cue.Text)
+		}
+	case VTT:
+		b.WriteString("WEBVTT\n\n")
+
+		for _, cue := range cues {
+			// "-->" would end the cue text early.
+			text := strings.ReplaceAll(cue.Text, "-->", "->")
+			fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatTime(cue.Start, '.'), formatTime(cue.End, '.'), text)
+		}
+	default:
+		return fmt.Errorf("unknown subtitle format %q", format)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing subtitles: %w", err)
+	}
+
+	return nil
+}
+
+// formatTime formats d as HH:MM:SS followed by sep and milliseconds.
+func formatTime(d time.Duration, sep byte) string {
+	ms := max(d, 0).Milliseconds()
+
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, sep, ms%1000)
+}
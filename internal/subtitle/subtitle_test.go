@@ -0,0 +1,100 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package subtitle_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "srt",
+			in: "\ufeff1\r\n00:00:01,500 --> 00:00:03,000\r\n<i>Hello</i> there\r\n\r\n" +
+				"2\r\n00:00:03,200 --> 00:00:05,000\r\n{\\an8}Second\r\nline\r\n\r\n" +
+				"3\r\nbroken timing\r\ntext\r\n",
+		},
+		{
+			name: "vtt",
+			in: "WEBVTT - exported\n\nNOTE written by hand\n\n" +
+				"intro\n00:01.500 --> 00:03.000 align:start\n<i>Hello</i> there\n\n" +
+				"00:00:03.200 --> 00:00:05.000\n{\\an8}Second\nline\n",
+		},
+	}
+
+	want := []subtitle.Cue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "<i>Hello</i> there"},
+		{Start: 3200 * time.Millisecond, End: 5 * time.Second, Text: "{\\an8}Second\nline"},
+	}
+
+	for _, tc := range tests {
+		cues, err := subtitle.Parse(strings.NewReader(tc.in))
+		if err != nil || !slices.Equal(cues, want) {
+			t.Errorf("%s: Parse() = %q, %v; want %q", tc.name, cues, err, want)
+		}
+	}
+
+	if got := want[0].PlainText(); got != "Hello there" {
+		t.Errorf("PlainText() = %q; want %q", got, "Hello there")
+	}
+
+	if got := want[1].PlainText(); got != "Second line" {
+		t.Errorf("PlainText() = %q; want %q", got, "Second line")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	cues := []subtitle.Cue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "Hello\nthere"},
+		{Start: time.Hour + 2*time.Second, End: time.Hour + 4*time.Second, Text: "a --> b"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: subtitle.SRT,
+			want: "1\n00:00:01,500 --> 00:00:03,000\nHello\nthere\n\n" +
+				"2\n01:00:02,000 --> 01:00:04,000\na --> b\n\n",
+		},
+		{
+			format: subtitle.VTT,
+			want: "WEBVTT\n\n00:00:01.500 --> 00:00:03.000\nHello\nthere\n\n" +
+				"01:00:02.000 --> 01:00:04.000\na -> b\n\n",
+		},
+	}
+
+	for _, tc := range tests {
+		var b strings.Builder
+
+		if err := subtitle.Write(&b, tc.format, cues); err != nil || b.String() != tc.want {
+			t.Errorf("Write(%s) = %q, %v; want %q", tc.format, b.String(), err, tc.want)
+		}
+
+		// What is written parses back to the same cues, bar the escaped arrow.
+		back, err := subtitle.Parse(strings.NewReader(b.String()))
+		if err != nil || len(back) != len(cues) || back[0] != cues[0] {
+			t.Errorf("Parse(Write(%s)) = %q, %v; want %q", tc.format, back, err, cues)
+		}
+	}
+
+	if err := subtitle.Write(&strings.Builder{}, "ass", cues); err == nil {
+		t.Error("Write(ass) error = nil; want error")
+	}
+}
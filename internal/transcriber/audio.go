@@ -7,30 +7,28 @@ package transcriber
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
 )
 
 const (
-	// ffmpegTimeout is the maximum time allowed for a single FFmpeg extraction.
-	ffmpegTimeout = 30 * time.Minute
-
 	// maxFileSize is the maximum accepted input file size (10 GB).
 	maxFileSize = 10 * 1024 * 1024 * 1024
-
-	// ffmpegSampleRate is the PCM sample rate used for audio extraction.
-	ffmpegSampleRate = "16000"
-
-	// ffmpegChannels is the number of audio channels (mono) used for extraction.
-	ffmpegChannels = "1"
 )
 
+// defaultFormat is the PCM format audio is decoded to unless --sample-rate
+// or --channels say otherwise, and the assumed layout of raw .pcm inputs.
+var defaultFormat = audio.Speech
+
 // InputType represents the kind of media file provided by the user.
 type InputType int
 
@@ -39,6 +37,9 @@ const (
 	InputTypeAudio InputType = iota
 	// InputTypeVideo is a video file that requires FFmpeg audio extraction.
 	InputTypeVideo
+	// InputTypeTranscode is audio in a format Gemini does not accept, which
+	// FFmpeg decodes before upload.
+	InputTypeTranscode
 )
 
 // audioExtensions lists file extensions that Gemini accepts as native audio.
@@ -52,11 +53,46 @@ var audioExtensions = map[string]string{
 	".aac":  "audio/aac",
 	".pcm":  "audio/pcm",
 	".webm": "audio/webm",
+	".opus": "audio/ogg",
+	".oga":  "audio/ogg",
+}
+
+// transcodeExtensions lists audio file extensions Gemini does not accept,
+// which are decoded with FFmpeg and uploaded in --upload-codec.
+var transcodeExtensions = map[string]bool{
+	".aiff": true,
+	".aif":  true,
+	".aifc": true,
+	".amr":  true,
+	".wma":  true,
+}
+
+// videoExtensions lists the extensions of video files that are picked from
+// directories even when their content is not recognised.
+var videoExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mkv": true, ".mov": true, ".avi": true, ".wmv": true, ".flv": true,
+	".ts": true, ".mts": true, ".m2ts": true, ".mpeg": true, ".mpg": true, ".vob": true, ".3gp": true,
+}
+
+// IsMediaFile reports whether the regular file at path looks like media:
+// its content is a recognised audio or video container, or its extension
+// is that of a supported format. It is how inputs are picked from
+// directories.
+func IsMediaFile(path string) bool {
+	if _, ok := sniffFile(path); ok {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	_, native := audioExtensions[ext]
+
+	return native || transcodeExtensions[ext] || videoExtensions[ext]
 }
 
-// classifyInputFile determines whether the path is a native audio file or a
-// video file that needs FFmpeg extraction. It returns the InputType and, for
-// audio files, the MIME type string required by the Gemini API.
+// classifyInputFile determines from its extension whether the path is a
+// native audio file, audio that needs transcoding, or a video file that
+// needs FFmpeg extraction. It returns the InputType and, for native audio,
+// the MIME type string required by the Gemini API.
 func classifyInputFile(inputPath string) (InputType, string) {
 	ext := strings.ToLower(filepath.Ext(inputPath))
 
@@ -64,188 +100,346 @@ func classifyInputFile(inputPath string) (InputType, string) {
 		return InputTypeAudio, mimeType
 	}
 
+	if transcodeExtensions[ext] {
+		return InputTypeTranscode, ""
+	}
+
 	return InputTypeVideo, ""
 }
 
-// PreparedAudio holds the audio bytes and MIME type ready to send to Gemini.
-// Call Close() to remove any temporary file that was created during preparation.
-type PreparedAudio struct {
-	Data     []byte
+// mediaSource is a validated input file or pipe.
+type mediaSource struct {
+	Path string
+	Type InputType
+	// MIMEType is the Gemini MIME type of native audio; empty otherwise.
 	MIMEType string
-	// tempPath is the path of a temporary file to remove on Close, or empty
-	// when no temporary file was created (e.g. native audio input).
-	tempPath string
+	// Size is the size of the input file, or -1 for a pipe.
+	Size int64
+	// Container is the format recognised from the file's content, or ""
+	// when it was classified by extension.
+	Container string
+	// Info is what ffprobe reported, or nil when ffprobe is unavailable.
+	Info *MediaInfo
+	// Stream is the audio stream selected for transcription, when one was
+	// selected and ffprobe described it. StreamMap is the FFmpeg -map
+	// specifier that picks it; empty lets FFmpeg choose the default track.
+	Stream    *StreamInfo
+	StreamMap string
+	// Window is the part of the input selected with --start/--end or
+	// --ranges, cut with an input seek; a zero Length runs to the end. Gaps
+	// are the spans between selected ranges, relative to Window.Start.
+	Window timeSpan
+	Gaps   []timeSpan
+
+	// pipe is the stream a piped input is read from; nil for files.
+	pipe *pipeInput
+	// tempFile is the path of a downloaded copy of the input, removed when
+	// the source is closed.
+	tempFile string
+	// fileURI is the gs:// URI of audio the backend reads by reference;
+	// such a source is never opened locally. fileChecksum is a checksum of
+	// the object's content from its metadata, or empty.
+	fileURI      string
+	fileChecksum string
+	// input is the path or URL the source was opened from.
+	input string
+	// subtitles is the embedded subtitle track used with --subtitles
+	// context or diff, or nil.
+	subtitles *SubtitleTrack
 }
 
-// Close removes the temporary audio file if one was created during preparation.
-// It is safe to call Close on a PreparedAudio that has no temporary file.
-// Implements io.Closer.
-func (p *PreparedAudio) Close() error {
-	if p.tempPath == "" {
-		return nil
-	}
-
-	if err := os.Remove(p.tempPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove temp audio file %q: %w", p.tempPath, err)
-	}
-
-	return nil
-}
-
-// prepareAudio reads the input file, extracting audio via FFmpeg when the
-// input is a video, and returns a PreparedAudio ready to pass to the Gemini
-// service.
-func prepareAudio(ctx context.Context, inputPath string, logger *slog.Logger) (*PreparedAudio, error) {
+// openLocalSource validates inputPath and classifies it by content, falling
+// back to its extension when the content is not recognised. A named pipe is
+// opened and read as a stream.
+func openLocalSource(inputPath string) (*mediaSource, error) {
 	cleanPath, err := validateInputPath(inputPath)
 	if err != nil {
 		return nil, err
 	}
 
-	inputType, mimeType := classifyInputFile(cleanPath)
-
-	switch inputType {
-	case InputTypeAudio:
-		logger.InfoContext(ctx, "audio file detected, skipping FFmpeg extraction",
-			slog.String("mime", mimeType))
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("input file error: %w", err)
+	}
 
-		data, err := os.ReadFile(cleanPath) // #nosec G304 -- cleanPath validated above
+	if isPipe(info.Mode()) {
+		f, err := os.Open(cleanPath) // #nosec G304 -- cleanPath validated above
 		if err != nil {
-			return nil, fmt.Errorf("failed to read audio file: %w", err)
+			return nil, fmt.Errorf("input file error: %w", err)
 		}
 
-		return &PreparedAudio{
-			Data:     data,
-			MIMEType: mimeType,
-		}, nil
-
-	case InputTypeVideo:
-		audioPath, err := extractAudio(ctx, cleanPath, logger)
+		src, err := openPipe(cleanPath, f, f)
 		if err != nil {
+			_ = f.Close()
+
 			return nil, err
 		}
 
-		data, err := os.ReadFile(audioPath) // #nosec G304 -- audioPath from extractAudio
-		if err != nil {
-			if removeErr := os.Remove(audioPath); removeErr != nil && !os.IsNotExist(removeErr) {
-				logger.WarnContext(ctx, "failed to remove temp audio file after read error",
-					slog.String("path", audioPath), slog.Any("error", removeErr))
-			}
+		return src, nil
+	}
+
+	inputType, mimeType := classifyInputFile(cleanPath)
+	src := &mediaSource{Path: cleanPath, Type: inputType, MIMEType: mimeType, Size: info.Size()}
+
+	if sniffed, ok := sniffFile(cleanPath); ok {
+		src.classify(sniffed)
+	}
+
+	return src, nil
+}
+
+// classify applies what sniffing recognised. Raw PCM has no magic bytes, so
+// a .pcm input is only reclassified when it is clearly something else.
+func (s *mediaSource) classify(sniffed sniffResult) {
+	if s.MIMEType == "audio/pcm" && sniffed.FrameSync {
+		return
+	}
+
+	s.Type, s.MIMEType, s.Container = sniffed.Type, sniffed.MIMEType, sniffed.Container
+}
+
+// setFormat classifies s as the given format, a file extension without
+// the dot, whatever its name and content suggested.
+func (s *mediaSource) setFormat(format string) {
+	s.Type, s.MIMEType = classifyInputFile("." + format)
+	s.Container = format
+}
+
+// inputArgs returns the FFmpeg arguments that open the source. Raw PCM has
+// no header, so its layout must be spelled out. Piped input is fed to
+// FFmpeg's stdin.
+func (s *mediaSource) inputArgs() []string {
+	input := s.Path
+	if s.piped() {
+		input = "pipe:0"
+	}
+
+	if s.MIMEType == "audio/pcm" {
+		return append(append([]string{"-f", "s16le"}, formatArgs(defaultFormat)...), "-i", input)
+	}
+
+	return []string{"-i", input}
+}
+
+// decodeArgs returns the FFmpeg arguments that open the source and select
+// the chosen time window and audio stream.
+func (s *mediaSource) decodeArgs() []string {
+	args := append(s.seekArgs(), s.inputArgs()...)
+
+	return append(args, s.mapArgs()...)
+}
+
+// cut reports whether only part of the source was selected for decoding.
+func (s *mediaSource) cut() bool {
+	return s.Window != (timeSpan{}) || len(s.Gaps) > 0
+}
+
+// formatArgs returns the FFmpeg options that resample to format f.
+func formatArgs(f audio.Format) []string {
+	return []string{"-ar", strconv.Itoa(f.SampleRate), "-ac", strconv.Itoa(f.Channels)}
+}
+
+// PreparedAudio is a stream of audio ready to send to Gemini. It is either
+// the input file itself, read from disk, or PCM WAV decoded by FFmpeg or by
+// the audio package; in no case is the whole input held in memory.
+// Call Close() to release the file or FFmpeg process behind the stream.
+type PreparedAudio struct {
+	MIMEType string
+	// Size is the length of the stream in bytes, or -1 when it is not known
+	// in advance (decoded output, piped input).
+	Size int64
+
+	r io.ReadCloser
+	// native is true when the stream is the unmodified input file.
+	native bool
+	// source is the input the stream was prepared from.
+	source *mediaSource
+	// digest, when set, hashes what is read for the cache.
+	digest *audioDigest
+}
+
+// Read reads from the underlying file or FFmpeg output. Implements io.Reader.
+func (p *PreparedAudio) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+
+	if p.digest != nil {
+		p.digest.write(b[:n], errors.Is(err, io.EOF))
 
-			return nil, fmt.Errorf("failed to read extracted audio: %w", err)
+		if err != nil {
+			p.digest = nil
 		}
+	}
 
-		return &PreparedAudio{
-			Data:     data,
-			MIMEType: "audio/wav",
-			tempPath: audioPath,
-		}, nil
+	return n, err //nolint:wrapcheck // callers compare against io.EOF
+}
 
-	default:
-		// unreachable unless InputType is extended
-		return nil, fmt.Errorf("unsupported input type for %q", cleanPath)
+// Close releases the file or FFmpeg process behind the stream.
+// Implements io.Closer.
+func (p *PreparedAudio) Close() error {
+	if err := p.r.Close(); err != nil {
+		return fmt.Errorf("closing audio stream: %w", err)
 	}
+
+	return nil
 }
 
-// extractAudio extracts audio from a video file using FFmpeg.
-func extractAudio(ctx context.Context, videoPath string, logger *slog.Logger) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, ffmpegTimeout)
-	defer cancel()
+// pcmWAV reports whether p is a 16-bit PCM WAV stream that can be chunked
+// without decoding, and if so the format and duration its header declares
+// (zero when unknown). Native files are rewound after their header is
+// inspected.
+func (p *PreparedAudio) pcmWAV() (bool, audio.Format, time.Duration, error) {
+	if p.MIMEType != "audio/wav" {
+		return false, audio.Format{}, 0, nil
+	}
+
+	seeker, ok := p.r.(io.Seeker)
+	if !ok {
+		// Decoded output is always PCM WAV with a placeholder size.
+		return true, audio.Format{}, 0, nil
+	}
 
-	logger.InfoContext(ctx, "extracting audio from video", slog.String("path", videoPath))
+	format, dataSize, headerErr := readPCM16Header(p.r)
 
-	ffmpegPath, err := exec.LookPath("ffmpeg")
-	if err != nil {
-		return "", fmt.Errorf("ffmpeg not found; install ffmpeg first: %w", err)
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return false, audio.Format{}, 0, fmt.Errorf("rewinding audio file: %w", err)
 	}
 
-	audioPath, err := generateAudioPath(videoPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp audio file: %w", err)
+	if headerErr != nil {
+		return false, audio.Format{}, 0, nil
+	}
+
+	var duration time.Duration
+	if dataSize < p.Size {
+		duration = format.Duration(dataSize / int64(format.FrameSize()))
 	}
 
-	if err := runFFmpegCommand(ctx, ffmpegPath, videoPath, audioPath, logger); err != nil {
-		if removeErr := os.Remove(audioPath); removeErr != nil && !os.IsNotExist(removeErr) {
-			logger.WarnContext(ctx, "failed to remove temp audio file after ffmpeg error",
-				slog.String("path", audioPath), slog.Any("error", removeErr))
+	return true, format, duration, nil
+}
+
+// prepareOptions controls how an input is turned into a PreparedAudio.
+type prepareOptions struct {
+	// Filters is an FFmpeg audio filter chain applied while decoding, after
+	// the selected time ranges and channel have been cut out.
+	Filters []string
+	// Channel is the 1-based channel to decode, as mono; 0 keeps them all.
+	Channel int
+	// Transcode forces native audio to be decoded as well.
+	Transcode bool
+	// Format is the PCM format audio is decoded to.
+	Format audio.Format
+}
+
+// prepareAudio opens the input as an audio stream. Native audio is streamed
+// from disk as-is unless filters, cuts or transcoding require decoding;
+// everything else is decoded to PCM WAV in opts.Format.
+func (tc *toolchain) prepareAudio(ctx context.Context, src *mediaSource, opts prepareOptions) (*PreparedAudio, error) {
+	if src.Type == InputTypeAudio && src.StreamMap == "" && !src.cut() &&
+		len(opts.Filters) == 0 && opts.Channel == 0 && !opts.Transcode {
+		tc.logger.InfoContext(ctx, "audio file detected, skipping FFmpeg extraction",
+			slog.String("mime", src.MIMEType))
+
+		r, err := src.open()
+		if err != nil {
+			return nil, err
 		}
 
-		return "", err
+		return &PreparedAudio{MIMEType: src.MIMEType, Size: src.Size, r: r, native: true, source: src}, nil
 	}
 
-	logger.InfoContext(ctx, "audio extracted", slog.String("output", audioPath))
-
-	return audioPath, nil
+	return tc.decodeAudio(ctx, src, opts)
 }
 
-// validateInputPath validates and sanitizes any input media path (audio or video).
-func validateInputPath(inputPath string) (string, error) {
-	inputPath = filepath.Clean(inputPath)
+// decodeAudio decodes src, through the audio filters in opts, to a PCM WAV
+// stream in opts.Format. WAV and raw PCM that need no FFmpeg filters are
+// decoded in Go; everything else is decoded by FFmpeg on a pipe.
+func (tc *toolchain) decodeAudio(ctx context.Context, src *mediaSource, opts prepareOptions) (*PreparedAudio, error) {
+	if decodesNatively(src, opts) {
+		prepared, err := decodeNative(ctx, src, opts, tc.logger)
+		if err == nil || !errNativeUnsupported(err) {
+			return prepared, err
+		}
 
-	fileInfo, err := os.Stat(inputPath)
-	if err != nil {
-		return "", fmt.Errorf("input file error: %w", err)
+		tc.logger.DebugContext(ctx, "audio encoding not supported natively; trying FFmpeg", slog.Any("error", err))
 	}
 
-	if !fileInfo.Mode().IsRegular() {
-		return "", fmt.Errorf("not a regular file: %s", inputPath)
+	switch src.Type {
+	case InputTypeVideo:
+		tc.logger.InfoContext(ctx, "extracting audio from video", slog.String("path", src.Path))
+	case InputTypeTranscode:
+		tc.logger.InfoContext(ctx, "transcoding audio Gemini cannot read",
+			slog.String("path", src.Path),
+			slog.String("container", src.Container),
+		)
+	default:
+		tc.logger.InfoContext(ctx, "decoding audio with FFmpeg", slog.String("path", src.Path))
 	}
 
-	if fileInfo.Size() > maxFileSize {
-		return "", fmt.Errorf("file too large (>10GB): %s", inputPath)
+	stdin, err := src.ffmpegInput()
+	if err != nil {
+		return nil, err
 	}
 
-	return inputPath, nil
+	pass := ffmpegPass{Stage: "decode", Args: tc.decodeCommand(src, opts), Duration: src.duration()}
+
+	stream, err := tc.start(ctx, pass, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedAudio{MIMEType: "audio/wav", Size: -1, r: stream, source: src}, nil
 }
 
-// generateAudioPath creates a unique WAV file path in the system temp directory
-// using os.CreateTemp to avoid any TOCTOU race between path generation and file creation.
-// The caller is responsible for removing the file when done.
-func generateAudioPath(inputPath string) (string, error) {
-	baseFileName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
-	pattern := baseFileName + "_*_audio.wav"
+// decodeCommand returns the FFmpeg arguments that decode src as described
+// by opts to PCM WAV on stdout, with the configured extra arguments before
+// the input and the output.
+func (tc *toolchain) decodeCommand(src *mediaSource, opts prepareOptions) []string {
+	var filters []string
 
-	f, err := os.CreateTemp("", pattern)
-	if err != nil {
-		return "", fmt.Errorf("creating temp audio file: %w", err)
+	if opts.Channel > 0 {
+		filters = append(filters, channelFilter(opts.Channel-1))
 	}
 
-	// Close immediately; the file is only needed as a reserved path for FFmpeg.
-	if err := f.Close(); err != nil {
-		_ = os.Remove(f.Name())
+	if filter := src.rangeFilter(); filter != "" {
+		filters = append(filters, filter)
+	}
+
+	filters = append(filters, opts.Filters...)
 
-		return "", fmt.Errorf("closing temp audio file: %w", err)
+	args := append([]string{"-hide_banner", "-nostats"}, tc.inputArgs...)
+	args = append(args, src.decodeArgs()...)
+	args = append(args, tc.outputArgs...)
+
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
 	}
 
-	return f.Name(), nil
+	args = append(args, formatArgs(opts.Format)...)
+
+	return append(args, uploadCodecs["wav"].outputArgs("pipe:1")...)
 }
 
-// runFFmpegCommand executes FFmpeg and verifies the output was created.
-func runFFmpegCommand(ctx context.Context, ffmpegPath, videoPath, audioPath string, logger *slog.Logger) error {
-	cmd := exec.CommandContext(ctx, ffmpegPath, // #nosec G204 -- ffmpegPath resolved via exec.LookPath
-		"-i", videoPath,
-		"-acodec", "pcm_s16le",
-		"-ar", ffmpegSampleRate,
-		"-ac", ffmpegChannels,
-		"-y",
-		audioPath,
-	)
+// validateInputPath validates and sanitizes any input media path (audio or
+// video): a regular file, or a named pipe such as the /dev/fd path of a
+// shell process substitution.
+func validateInputPath(inputPath string) (string, error) {
+	inputPath = filepath.Clean(inputPath)
 
-	var stderr strings.Builder
+	fileInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("input file error: %w", err)
+	}
 
-	// Always capture stderr; tee to os.Stderr at debug level via slog when verbose.
-	if logger.Enabled(ctx, slog.LevelDebug) {
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
-	} else {
-		cmd.Stderr = &stderr
+	if isPipe(fileInfo.Mode()) {
+		return inputPath, nil
 	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg failed: %w (stderr: %s)", err, stderr.String())
+	if !fileInfo.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file or pipe: %s", inputPath)
 	}
 
-	if _, err := os.Stat(audioPath); err != nil {
-		return fmt.Errorf("ffmpeg did not create output file: %w", err)
+	if fileInfo.Size() > maxFileSize {
+		return "", fmt.Errorf("file too large (>10GB): %s", inputPath)
 	}
 
-	return nil
+	return inputPath, nil
 }
@@ -0,0 +1,233 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func argAfter(args []string, flag string) string {
+	if i := slices.Index(args, flag); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+
+	return ""
+}
+
+// decodeArgs returns the FFmpeg arguments that decode path with the filters,
+// enhancement and format configured in cfg.
+func decodeArgs(path string, cfg *config.Config) ([]string, error) {
+	inputType, mimeType := classifyInputFile(path)
+	src := &mediaSource{Path: path, Type: inputType, MIMEType: mimeType}
+	t := &Transcriber{config: cfg, tools: newToolchain(cfg, slog.Default())}
+
+	filters, err := enhanceFilters(cfg.Enhance, cfg.AudioFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tools.decodeCommand(src, prepareOptions{Filters: filters, Format: t.format()}), nil
+}
+
+func TestDecodeCommandDefaults(t *testing.T) {
+	t.Parallel()
+
+	args, err := decodeArgs("talk.mp4", &config.Config{})
+	if err != nil {
+		t.Fatalf("decodeCommand() error = %v", err)
+	}
+
+	if argAfter(args, "-ar") != "16000" || argAfter(args, "-ac") != "1" {
+		t.Errorf("decodeCommand() = %v; want 16 kHz mono by default", args)
+	}
+
+	if slices.Contains(args, "-af") {
+		t.Errorf("decodeCommand() = %v; want no filters by default", args)
+	}
+}
+
+func TestDecodeCommandEnhancement(t *testing.T) {
+	t.Parallel()
+
+	// Every preset accepted by config validation must resolve to a chain.
+	for _, preset := range config.EnhancePresets {
+		cfg := &config.Config{Enhance: preset, AudioFilter: "volume=2", SampleRate: 24000, Channels: 2}
+
+		args, err := decodeArgs("field.wav", cfg)
+		if err != nil {
+			t.Errorf("decodeCommand(%q) error = %v", preset, err)
+
+			continue
+		}
+
+		chain := argAfter(args, "-af")
+		if !strings.HasPrefix(chain, "highpass=") || !strings.HasSuffix(chain, ",volume=2") {
+			t.Errorf("decodeCommand(%q) -af = %q; want preset chain followed by the custom filter", preset, chain)
+		}
+
+		if argAfter(args, "-ar") != "24000" || argAfter(args, "-ac") != "2" {
+			t.Errorf("decodeCommand(%q) = %v; want configured sample rate and channels", preset, args)
+		}
+
+		// The filter chain must come before the output options.
+		if slices.Index(args, "-af") > slices.Index(args, "-ar") {
+			t.Errorf("decodeCommand(%q) = %v; want -af before -ar", preset, args)
+		}
+	}
+
+	if _, err := decodeArgs("field.wav", &config.Config{Enhance: "studio"}); err == nil {
+		t.Error("decodeCommand() with unknown preset = nil error; want error")
+	}
+}
+
+func TestDecodeCommandExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{FFmpegInputArgs: "-hwaccel auto -analyzeduration 10M", FFmpegOutputArgs: "-threads 2"}
+
+	args, err := decodeArgs("talk.mp4", cfg)
+	if err != nil {
+		t.Fatalf("decodeCommand() error = %v", err)
+	}
+
+	input := slices.Index(args, "-i")
+	if i := slices.Index(args, "-hwaccel"); i < 0 || i > input || argAfter(args, "-analyzeduration") != "10M" {
+		t.Errorf("decodeCommand() = %v; want the input options before -i", args)
+	}
+
+	output := slices.Index(args, "-threads")
+	if output < input || output > slices.Index(args, "-ar") || argAfter(args, "-threads") != "2" {
+		t.Errorf("decodeCommand() = %v; want the output options after -i and before the format", args)
+	}
+}
+
+func TestValidateInputPath(t *testing.T) {
+	t.Parallel()
+
+	t.Run("non-existent file returns error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := validateInputPath("/non/existent/file.mp4")
+		if err == nil {
+			t.Error("expected error for non-existent file, got nil")
+		}
+	})
+
+	t.Run("regular file is accepted", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := os.CreateTemp(t.TempDir(), "test-media-*.mp4")
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("failed to close temp file: %v", err)
+		}
+
+		got, err := validateInputPath(f.Name())
+		if err != nil {
+			t.Errorf("unexpected error for valid file: %v", err)
+		}
+
+		if got == "" {
+			t.Error("expected non-empty cleaned path")
+		}
+	})
+
+	t.Run("directory is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := validateInputPath(t.TempDir())
+		if err == nil {
+			t.Error("expected error for directory input, got nil")
+		}
+	})
+
+	t.Run("path is cleaned", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		f, err := os.CreateTemp(dir, "test-*.mp4")
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("failed to close temp file: %v", err)
+		}
+
+		dirty := filepath.Join(dir, ".", filepath.Base(f.Name()))
+		clean := filepath.Clean(dirty)
+
+		got, err := validateInputPath(dirty)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got != clean {
+			t.Errorf("got %q; want cleaned path %q", got, clean)
+		}
+	})
+}
+
+func TestClassifyInputFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path             string
+		wantType         InputType
+		wantMIMENotEmpty bool
+	}{
+		{path: "recording.wav", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.mp3", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.flac", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.ogg", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.m4a", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.aac", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.webm", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.opus", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.oga", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "recording.aiff", wantType: InputTypeTranscode, wantMIMENotEmpty: false},
+		{path: "recording.amr", wantType: InputTypeTranscode, wantMIMENotEmpty: false},
+		{path: "recording.wma", wantType: InputTypeTranscode, wantMIMENotEmpty: false},
+		{path: "video.mp4", wantType: InputTypeVideo, wantMIMENotEmpty: false},
+		{path: "video.mkv", wantType: InputTypeVideo, wantMIMENotEmpty: false},
+		{path: "video.mov", wantType: InputTypeVideo, wantMIMENotEmpty: false},
+		{path: "video.avi", wantType: InputTypeVideo, wantMIMENotEmpty: false},
+		// Mixed-case extension
+		{path: "recording.WAV", wantType: InputTypeAudio, wantMIMENotEmpty: true},
+		{path: "video.MP4", wantType: InputTypeVideo, wantMIMENotEmpty: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			gotType, gotMIME := classifyInputFile(tc.path)
+
+			if gotType != tc.wantType {
+				t.Errorf("classifyInputFile(%q) type = %v; want %v", tc.path, gotType, tc.wantType)
+			}
+
+			if tc.wantMIMENotEmpty && gotMIME == "" {
+				t.Errorf("classifyInputFile(%q) MIME = empty; want non-empty", tc.path)
+			}
+
+			if !tc.wantMIMENotEmpty && gotMIME != "" {
+				t.Errorf("classifyInputFile(%q) MIME = %q; want empty", tc.path, gotMIME)
+			}
+		})
+	}
+}
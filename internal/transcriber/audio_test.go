@@ -0,0 +1,90 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+func TestShortNativeWAVIsSentInOneRequest(t *testing.T) {
+	t.Parallel()
+
+	wav := synthWAV(3 * time.Second)
+	path := filepath.Join(t.TempDir(), "short.wav")
+
+	if err := os.WriteFile(path, wav, 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{Quiet: true, ChunkDuration: 10 * time.Minute, ChunkOverlap: 2 * time.Second}
+	rec := &requestRecorder{}
+
+	result, err := newTranscriber(t, cfg, rec).TranscribeLocalFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if len(rec.requests) != 1 || result.Chunks != 1 {
+		t.Fatalf("got %d requests, Chunks = %d; want 1", len(rec.requests), result.Chunks)
+	}
+
+	req := rec.requests[0]
+	if req.Timestamps || req.Duration != 3*time.Second || req.MIMEType != "audio/wav" {
+		t.Errorf("request = %+v; want plain 3s audio/wav", req)
+	}
+
+	// The header is re-read after sniffing, so the payload is the full file.
+	if !bytes.Equal(rec.payloads[0], wav) {
+		t.Errorf("payload differs from input (%d vs %d bytes)", len(rec.payloads[0]), len(wav))
+	}
+}
+
+func TestChunkedNativeWAVBoundsRequestSize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "long.wav")
+	if err := os.WriteFile(path, synthWAV(35*time.Second, testGaps...), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{Quiet: true, ChunkDuration: 10 * time.Second, ChunkOverlap: 500 * time.Millisecond}
+	rec := &requestRecorder{}
+
+	result, err := newTranscriber(t, cfg, rec).TranscribeLocalFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	// Window plus overlap on both sides plus the folded tail slack.
+	const maxChunk = 44 + (10*5/4+1)*testSampleRate*2
+
+	var total int64
+
+	for i, p := range rec.payloads {
+		if len(p) > maxChunk {
+			t.Errorf("request %d carried %d bytes; want at most %d", i, len(p), maxChunk)
+		}
+
+		total += int64(len(p))
+	}
+
+	if result.UploadSize != total {
+		t.Errorf("UploadSize = %d; want %d", result.UploadSize, total)
+	}
+
+	n := len(rec.requests)
+	if want := (gemini.Usage{InputTokens: 100 * n, OutputTokens: 7 * n}); result.Usage != want {
+		t.Errorf("Usage = %+v; want %+v, the sum over %d requests", result.Usage, want, n)
+	}
+}
@@ -0,0 +1,321 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// cacheVersion is hashed into every cache key. Changing it retires every
+// entry, for when TranscriptionResult changes meaning.
+const cacheVersion = "voice-transcriber result v1"
+
+// openCache opens the transcription cache configured by cfg, in the user's
+// cache directory unless cfg names another. It returns nil when caching is
+// disabled, or when the cache cannot be used, which is logged: the cache
+// only saves work, so transcription goes ahead without it.
+func openCache(ctx context.Context, cfg *config.Config, logger *slog.Logger) *cache.Cache {
+	if cfg.NoCache {
+		return nil
+	}
+
+	dir := cfg.CacheDir
+	if dir == "" {
+		var err error
+
+		if dir, err = cache.DefaultDir(); err != nil {
+			logger.WarnContext(ctx, "transcription cache disabled", slog.Any("error", err))
+
+			return nil
+		}
+	}
+
+	c, err := cache.Open(dir)
+	if err != nil {
+		logger.WarnContext(ctx, "transcription cache disabled", slog.Any("error", err))
+
+		return nil
+	}
+
+	return c
+}
+
+// cacheSlot is where the transcript of an input is cached. It is keyed
+// by key when that is known before transcription and otherwise by a hash
+// of the audio sent, taken by digest as the audio streams out. link, when
+// set, is a key computed from the input file alone. It leads to the
+// transcript, so later runs find it without preparing the audio.
+type cacheSlot struct {
+	key  string
+	link string
+	// settings is a hash of everything but the audio that the key covers.
+	settings []byte
+	digest   *audioDigest
+}
+
+// lookup returns the key a cached transcript is looked up under.
+func (s *cacheSlot) lookup() string {
+	if s.key != "" {
+		return s.key
+	}
+
+	return s.link
+}
+
+// cachedResult looks the transcription of src, prepared as described by
+// opts, up in the cache. It returns where to cache the result, nil when
+// the cache is off or src cannot be keyed, and the cached result on a hit.
+// With --refresh the slot is returned without looking.
+func (t *Transcriber) cachedResult(
+	ctx context.Context, src *mediaSource, opts prepareOptions, reqOpts requestOptions, offsets *timeMap,
+) (*cacheSlot, *TranscriptionResult) {
+	if t.cache == nil {
+		return nil, nil
+	}
+
+	slot, err := t.cacheSlot(src, opts, reqOpts, offsets)
+
+	switch {
+	case err != nil:
+		t.logger.WarnContext(ctx, "cannot compute cache key; transcribing without the cache", slog.Any("error", err))
+
+		return nil, nil
+	case slot == nil:
+		t.logger.DebugContext(ctx, "input cannot be read twice; transcribing without the cache")
+
+		return nil, nil
+	case t.config.RefreshCache:
+		t.logger.DebugContext(ctx, "refreshing cached transcript", slog.String("key", slot.lookup()))
+
+		return slot, nil
+	}
+
+	var cached TranscriptionResult
+
+	hit, err := t.cache.Get(slot.lookup(), &cached)
+	if err != nil {
+		t.logger.WarnContext(ctx, "cannot read cached transcript", slog.Any("error", err))
+
+		return slot, nil
+	}
+
+	if !hit {
+		t.logger.DebugContext(ctx, "transcript not cached", slog.String("key", slot.lookup()))
+
+		return slot, nil
+	}
+
+	t.logger.InfoContext(ctx, "using cached transcript", slog.String("key", slot.lookup()))
+
+	return slot, &cached
+}
+
+// storeResult caches result in slot, unless slot is nil or the audio sent
+// was not hashed in full. Failures are logged; the transcript itself is
+// still returned.
+func (t *Transcriber) storeResult(ctx context.Context, slot *cacheSlot, src *mediaSource, result *TranscriptionResult) {
+	if slot == nil {
+		return
+	}
+
+	key := slot.key
+	if key == "" {
+		sum, ok := slot.digest.sum()
+		if !ok {
+			t.logger.DebugContext(ctx, "audio was not read to the end; transcript not cached")
+
+			return
+		}
+
+		key = settingsKey(slot.settings, "audio %x\n", sum)
+	}
+
+	entry := cache.Entry{Key: key, Input: InputFileName(src.input), Model: t.config.GeminiModel}
+	if err := t.cache.Put(entry, result); err != nil {
+		t.logger.WarnContext(ctx, "cannot cache transcript", slog.Any("error", err))
+
+		return
+	}
+
+	if slot.link != "" {
+		entry.Key = slot.link
+		if err := t.cache.Link(entry, key); err != nil {
+			t.logger.WarnContext(ctx, "cannot cache transcript", slog.Any("error", err))
+
+			return
+		}
+	}
+
+	t.logger.DebugContext(ctx, "transcript cached", slog.String("key", key))
+}
+
+// cacheSlot returns where the transcript of src, prepared as described by
+// opts, is cached as described by reqOpts and offsets. Every key is a
+// SHA-256 over the settings that shape the model's answer and the audio.
+// Objects read by reference are keyed by the checksum in their metadata.
+// Everything else is keyed by the audio sent, hashed as it is read. That
+// covers every setting that changes it, such as the selected ranges,
+// silence trimming and enhancement. A renamed copy still hits. It is
+// linked from a key over the input file and how its audio is prepared,
+// which a lookup reads but does not decode. The slot is nil for pipes and
+// streamed playlists, which cannot be read twice.
+func (t *Transcriber) cacheSlot(
+	src *mediaSource, opts prepareOptions, reqOpts requestOptions, offsets *timeMap,
+) (*cacheSlot, error) {
+	if src.fileURI != "" && src.fileChecksum == "" || src.fileURI == "" && !t.cacheable(src) {
+		return nil, nil
+	}
+
+	settings, err := t.settingsHash(src, reqOpts, offsets)
+	if err != nil {
+		return nil, err
+	}
+
+	if src.fileURI != "" {
+		return &cacheSlot{key: settingsKey(settings, "object %s %d\n", src.fileChecksum, src.Size)}, nil
+	}
+
+	link, err := t.sourceKey(settings, src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cacheSlot{link: link, settings: settings, digest: newAudioDigest()}, nil
+}
+
+// settingsHash returns a SHA-256 over the settings that shape the model's
+// answer for src, as described by reqOpts and offsets.
+func (t *Transcriber) settingsHash(src *mediaSource, reqOpts requestOptions, offsets *timeMap) ([]byte, error) {
+	language := reqOpts.Language
+	if language == "" {
+		language = t.config.Language
+	}
+
+	fingerprint, err := gemini.Fingerprint(t.config.GeminiModel, language)
+	if err != nil {
+		return nil, fmt.Errorf("computing cache key: %w", err)
+	}
+
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\n%s\n", cacheVersion, fingerprint)
+	fmt.Fprintf(h, "timestamps=%t codec=%s chunks=%s/%s offsets=%v\n", reqOpts.Timestamps, reqOpts.Codec.Name,
+		t.config.ChunkDuration, t.config.ChunkOverlap, offsets)
+	fmt.Fprintf(h, "reference=%q\n", reqOpts.Reference.text(0, 0))
+
+	if t.config.SplitChannels && src.fileURI == "" {
+		speakers, err := t.speakers(src)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(h, "speakers %q\n", speakers)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// settingsKey returns the key over settings and a description of the
+// audio, formatted as by fmt.Sprintf.
+func settingsKey(settings []byte, format string, args ...any) string {
+	h := sha256.New()
+	h.Write(settings)
+	fmt.Fprintf(h, format, args...)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sourceKey returns the key over settings, the bytes of the input file of
+// src and how its audio is prepared as described by opts.
+func (t *Transcriber) sourceKey(settings []byte, src *mediaSource, opts prepareOptions) (string, error) {
+	h := sha256.New()
+	h.Write(settings)
+
+	fmt.Fprintf(h, "source type=%v mime=%q container=%q map=%q window=%v gaps=%v ffmpeg=%q %q\n",
+		src.Type, src.MIMEType, src.Container, src.StreamMap, src.Window, src.Gaps, t.tools.inputArgs,
+		t.tools.outputArgs)
+	fmt.Fprintf(h, "prepare filters=%q channel=%d transcode=%t format=%+v\n",
+		opts.Filters, opts.Channel, opts.Transcode, opts.Format)
+
+	f, err := src.open()
+	if err != nil {
+		return "", fmt.Errorf("reading input for the cache key: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", fmt.Errorf("reading input for the cache key: %w", err)
+	}
+
+	fmt.Fprintf(h, "\n%d bytes\n", n)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// audioDigest hashes the audio sent for an input as it is read, so that
+// the transcript can be cached under it once the last of it has been
+// sent. Streams are hashed in the order they are read, such as one per
+// channel with --split-channels.
+type audioDigest struct {
+	h hash.Hash
+	// streams counts the streams attached, ended those read to the end and
+	// n the bytes read from the current one.
+	streams, ended int
+	n              int64
+}
+
+// newAudioDigest returns an empty digest.
+func newAudioDigest() *audioDigest {
+	return &audioDigest{h: sha256.New()}
+}
+
+// attach hashes the audio read from p from now on. A nil digest attaches
+// nothing.
+func (d *audioDigest) attach(p *PreparedAudio) {
+	if d == nil {
+		return
+	}
+
+	d.streams++
+	d.n = 0
+	fmt.Fprintf(d.h, "stream %d mime=%q native=%t\n", d.streams, p.MIMEType, p.native)
+
+	p.digest = d
+}
+
+// write hashes b, read from the current stream, and its length at the end
+// of the stream, so that consecutive streams cannot run together.
+func (d *audioDigest) write(b []byte, end bool) {
+	d.h.Write(b)
+	d.n += int64(len(b))
+
+	if end {
+		fmt.Fprintf(d.h, "\n%d bytes\n", d.n)
+
+		d.ended++
+	}
+}
+
+// sum returns the digest, or false when a stream was not read to the end
+// and so the digest does not cover all the audio sent.
+func (d *audioDigest) sum() ([]byte, bool) {
+	if d == nil || d.streams == 0 || d.ended != d.streams {
+		return nil, false
+	}
+
+	return d.h.Sum(nil), true
+}
@@ -0,0 +1,59 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestAudioDigest(t *testing.T) {
+	t.Parallel()
+
+	// read attaches a digest to a stream of data and reads n bytes of it,
+	// or all of it when n is negative.
+	read := func(d *audioDigest, data string, n int) {
+		p := &PreparedAudio{MIMEType: "audio/wav", r: io.NopCloser(bytes.NewReader([]byte(data)))}
+		d.attach(p)
+
+		if n < 0 {
+			_, _ = io.Copy(io.Discard, p)
+		} else {
+			_, _ = io.CopyN(io.Discard, p, int64(n))
+		}
+	}
+
+	whole := newAudioDigest()
+	read(whole, "left", -1)
+	read(whole, "right", -1)
+
+	want, ok := whole.sum()
+	if !ok {
+		t.Fatal("sum() after reading every stream = false; want the digest")
+	}
+
+	// The same bytes split differently between streams hash differently.
+	split := newAudioDigest()
+	read(split, "leftr", -1)
+	read(split, "ight", -1)
+
+	if got, _ := split.sum(); bytes.Equal(got, want) {
+		t.Error("streams split at another byte have the same digest")
+	}
+
+	partial := newAudioDigest()
+	read(partial, "left", -1)
+	read(partial, "right", 2)
+
+	if _, ok := partial.sum(); ok {
+		t.Error("sum() with a stream left unfinished = true; want false")
+	}
+
+	if _, ok := (*audioDigest)(nil).sum(); ok {
+		t.Error("sum() of a nil digest = true; want false")
+	}
+}
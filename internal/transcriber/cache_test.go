@@ -0,0 +1,118 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestTranscriptionCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	wav := synthWAV(2 * time.Second)
+	path := filepath.Join(dir, "clip.wav")
+	// The same audio under another name is the same transcription.
+	copyPath := filepath.Join(dir, "copy.wav")
+
+	for _, p := range []string{path, copyPath} {
+		if err := os.WriteFile(p, wav, 0o600); err != nil {
+			t.Fatalf("writing fixture: %v", err)
+		}
+	}
+
+	cacheDir := filepath.Join(dir, "cache")
+	rec := &requestRecorder{}
+
+	transcribe := func(cfg config.Config, input string) *transcriber.TranscriptionResult {
+		t.Helper()
+
+		cfg.Quiet, cfg.CacheDir = true, cacheDir
+
+		result, err := newTranscriber(t, &cfg, rec).TranscribeLocalFile(context.Background(), input)
+		if err != nil {
+			t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+		}
+
+		return result
+	}
+
+	first := transcribe(config.Config{}, path)
+	if first.Cached || len(rec.requests) != 1 {
+		t.Fatalf("first run: Cached = %v after %d requests; want a fresh transcription", first.Cached, len(rec.requests))
+	}
+
+	second := transcribe(config.Config{}, copyPath)
+	if !second.Cached || len(rec.requests) != 1 {
+		t.Fatalf("second run: Cached = %v after %d requests; want a cache hit", second.Cached, len(rec.requests))
+	}
+
+	if second.Text != first.Text || second.UploadSize != 0 || second.Chunks != first.Chunks {
+		t.Errorf("cached result = %+v; want the first transcript with nothing uploaded", second)
+	}
+
+	c, err := cache.Open(cacheDir)
+	if err != nil {
+		t.Fatalf("cache.Open() unexpected error: %v", err)
+	}
+
+	if entries, err := c.List(); err != nil || len(entries) != 1 {
+		t.Errorf("List() = %+v, %v; want one transcript, keyed by the audio sent", entries, err)
+	}
+
+	transcribe(config.Config{Language: "uk"}, path)
+	transcribe(config.Config{RefreshCache: true}, path)
+
+	if len(rec.requests) != 3 {
+		t.Errorf("got %d requests; want a miss for another language and for --refresh", len(rec.requests))
+	}
+
+	transcribe(config.Config{Language: "uk"}, path)
+
+	if len(rec.requests) != 3 {
+		t.Errorf("got %d requests; want the Ukrainian transcript cached", len(rec.requests))
+	}
+
+	// A range changes the prepared audio, and so the key.
+	if transcribe(config.Config{Start: "00:00:01"}, path).Cached || len(rec.requests) != 4 {
+		t.Errorf("got %d requests; want a miss for another range", len(rec.requests))
+	}
+}
+
+func TestTranscriptionCacheSkipsPipes(t *testing.T) {
+	t.Parallel()
+
+	wav := synthWAV(2 * time.Second)
+	cfg := &config.Config{Quiet: true, CacheDir: t.TempDir()}
+	rec := &requestRecorder{}
+
+	for range 2 {
+		tr := newTranscriber(t, cfg, rec)
+		tr.SetStdin(bytes.NewReader(wav))
+
+		result, err := tr.TranscribeLocalFile(context.Background(), transcriber.StdinPath)
+		if err != nil {
+			t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+		}
+
+		if result.Cached {
+			t.Error("piped input was read from the cache")
+		}
+	}
+
+	if len(rec.requests) != 2 {
+		t.Errorf("got %d requests; want every piped run transcribed", len(rec.requests))
+	}
+}
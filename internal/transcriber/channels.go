@@ -0,0 +1,171 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// defaultChannels is assumed for --split-channels when neither ffprobe nor
+// a WAV header reports the real channel count.
+const defaultChannels = 2
+
+// channelFilter returns the FFmpeg filter that keeps only channel ch
+// (zero-based) of the input, as mono.
+func channelFilter(ch int) string {
+	return fmt.Sprintf("pan=mono|c0=c%d", ch)
+}
+
+// channelCount returns the number of channels of the audio stream that
+// will be decoded from s: the selected stream, else the default one.
+func (s *mediaSource) channelCount() int {
+	stream := s.Stream
+
+	if stream == nil && s.Info != nil {
+		streams := s.Info.AudioStreams()
+		if i := slices.IndexFunc(streams, func(s StreamInfo) bool { return s.Default }); i >= 0 {
+			stream = &streams[i]
+		} else if len(streams) > 0 {
+			stream = &streams[0]
+		}
+	}
+
+	if stream == nil || stream.Channels == 0 {
+		return defaultChannels
+	}
+
+	return stream.Channels
+}
+
+// channelSpeakers returns the speaker name of each of n channels: the name
+// from names when given, else Left/Right for stereo and Channel N otherwise.
+func channelSpeakers(names map[int]string, n int) []string {
+	speakers := make([]string, n)
+
+	for ch := range speakers {
+		switch name, ok := names[ch]; {
+		case ok:
+			speakers[ch] = name
+		case n == 2 && ch == 0:
+			speakers[ch] = "Left"
+		case n == 2 && ch == 1:
+			speakers[ch] = "Right"
+		default:
+			speakers[ch] = fmt.Sprintf("Channel %d", ch+1)
+		}
+	}
+
+	return speakers
+}
+
+// transcribeChannels transcribes each channel of src on its own and merges
+// the results into one speaker-attributed transcript. Channels are decoded
+// one at a time with the same filters, so silence trimming keeps them
+// aligned, and hashed with digest in order.
+func (t *Transcriber) transcribeChannels(
+	ctx context.Context, src *mediaSource, opts prepareOptions, reqOpts requestOptions, digest *audioDigest,
+) (*gemini.Transcript, uploadStats, error) {
+	speakers, err := t.speakers(src)
+	if err != nil {
+		return nil, uploadStats{}, err
+	}
+
+	parts := make([]*gemini.Transcript, 0, len(speakers))
+	reqOpts.Timestamps = true
+
+	var stats uploadStats
+
+	for ch, speaker := range speakers {
+		t.logger.InfoContext(ctx, "transcribing channel",
+			slog.Int("channel", ch+1),
+			slog.String("speaker", speaker),
+		)
+
+		transcript, s, err := t.transcribeStream(ctx, src, opts.forChannel(ch), reqOpts, digest)
+		if err != nil {
+			return nil, uploadStats{}, fmt.Errorf("channel %d (%s): %w", ch+1, speaker, err)
+		}
+
+		stats.Requests += s.Requests
+		stats.Bytes += s.Bytes
+		parts = append(parts, transcript)
+	}
+
+	return mergeChannels(parts, speakers), stats, nil
+}
+
+// speakers returns the speaker of each channel of src for --split-channels.
+func (t *Transcriber) speakers(src *mediaSource) ([]string, error) {
+	var names map[int]string
+
+	if t.config.ChannelNames != "" {
+		var err error
+
+		names, err = config.ParseChannelNames(t.config.ChannelNames)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --channel-names: %w", err)
+		}
+	}
+
+	return channelSpeakers(names, src.channelCount()), nil
+}
+
+// forChannel returns opts for decoding the 0-based channel ch alone.
+func (opts prepareOptions) forChannel(ch int) prepareOptions {
+	opts.Channel = ch + 1
+	opts.Format.Channels = 1
+
+	return opts
+}
+
+// mergeChannels interleaves per-channel transcripts, given in channel order,
+// into one transcript ordered by segment start. Each segment is attributed
+// to its channel's speaker, and the text holds one line per speaker turn. A
+// transcript without timing is treated as a single segment at the start.
+func mergeChannels(parts []*gemini.Transcript, speakers []string) *gemini.Transcript {
+	var (
+		merged []gemini.Segment
+		usage  gemini.Usage
+	)
+
+	for ch, part := range parts {
+		usage = usage.Add(part.Usage)
+
+		segments := part.Segments
+		if text := strings.TrimSpace(part.Text); len(segments) == 0 && text != "" {
+			segments = []gemini.Segment{{Text: text}}
+		}
+
+		for _, seg := range segments {
+			seg.Speaker = speakers[ch]
+			merged = append(merged, seg)
+		}
+	}
+
+	slices.SortStableFunc(merged, func(a, b gemini.Segment) int { return cmp.Compare(a.Start, b.Start) })
+
+	var turns []string
+
+	for i, seg := range merged {
+		if i > 0 && seg.Speaker == merged[i-1].Speaker {
+			turns[len(turns)-1] += " " + seg.Text
+
+			continue
+		}
+
+		turns = append(turns, seg.Speaker+": "+seg.Text)
+	}
+
+	return &gemini.Transcript{Text: strings.Join(turns, "\n"), Segments: merged, Usage: usage}
+}
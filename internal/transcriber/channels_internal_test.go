@@ -0,0 +1,107 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+func TestMergeChannels(t *testing.T) {
+	t.Parallel()
+
+	agent := &gemini.Transcript{Segments: []gemini.Segment{
+		{Start: 0, End: 3 * time.Second, Text: "Thank you for calling."},
+		{Start: 3 * time.Second, End: 5 * time.Second, Text: "How can I help?"},
+		{Start: 12 * time.Second, End: 14 * time.Second, Text: "Let me check."},
+	}}
+	customer := &gemini.Transcript{Segments: []gemini.Segment{
+		{Start: 6 * time.Second, End: 11 * time.Second, Text: "My order has not arrived."},
+	}}
+	silent := &gemini.Transcript{}
+
+	got := mergeChannels([]*gemini.Transcript{agent, customer, silent},
+		[]string{"Agent", "Customer", "Channel 3"})
+
+	wantText := "Agent: Thank you for calling. How can I help?\n" +
+		"Customer: My order has not arrived.\n" +
+		"Agent: Let me check."
+	if got.Text != wantText {
+		t.Errorf("Text = %q; want %q", got.Text, wantText)
+	}
+
+	speakers := make([]string, 0, len(got.Segments))
+	for _, seg := range got.Segments {
+		speakers = append(speakers, seg.Speaker)
+	}
+
+	if want := []string{"Agent", "Agent", "Customer", "Agent"}; !slices.Equal(speakers, want) {
+		t.Errorf("segment speakers = %v; want %v", speakers, want)
+	}
+
+	r := &TranscriptionResult{Segments: got.Segments[2:3]}
+	if want := "[00:00:06.000] Customer: My order has not arrived.\n"; r.TimestampedText() != want {
+		t.Errorf("TimestampedText() = %q; want %q", r.TimestampedText(), want)
+	}
+}
+
+func TestMergeChannelsUntimedText(t *testing.T) {
+	t.Parallel()
+
+	got := mergeChannels([]*gemini.Transcript{{Text: " Hello. "}, {Text: "Hi."}},
+		[]string{"Left", "Right"})
+
+	if want := "Left: Hello.\nRight: Hi."; got.Text != want {
+		t.Errorf("Text = %q; want %q", got.Text, want)
+	}
+}
+
+func TestChannelSpeakers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		names map[int]string
+		n     int
+		want  []string
+	}{
+		{n: 2, want: []string{"Left", "Right"}},
+		{names: map[int]string{0: "Agent", 1: "Customer"}, n: 2, want: []string{"Agent", "Customer"}},
+		{names: map[int]string{1: "Customer"}, n: 2, want: []string{"Left", "Customer"}},
+		{names: map[int]string{2: "Interpreter"}, n: 3, want: []string{"Channel 1", "Channel 2", "Interpreter"}},
+		{n: 1, want: []string{"Channel 1"}},
+	}
+
+	for _, tc := range tests {
+		if got := channelSpeakers(tc.names, tc.n); !slices.Equal(got, tc.want) {
+			t.Errorf("channelSpeakers(%v, %d) = %v; want %v", tc.names, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestChannelCount(t *testing.T) {
+	t.Parallel()
+
+	video, err := parseProbeOutput([]byte(probeVideo))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+
+	// The default track of probeVideo is the 5.1 Ukrainian one.
+	if got := (&mediaSource{Info: video}).channelCount(); got != 6 {
+		t.Errorf("channelCount(video) = %d; want 6", got)
+	}
+
+	if got := (&mediaSource{}).channelCount(); got != 2 {
+		t.Errorf("channelCount() without ffprobe = %d; want 2 (stereo assumed)", got)
+	}
+
+	if got := channelFilter(1); got != "pan=mono|c0=c1" {
+		t.Errorf("channelFilter(1) = %q", got)
+	}
+}
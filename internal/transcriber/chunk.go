@@ -0,0 +1,291 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+const (
+	// silenceFrame is the analysis frame used when searching for a split point.
+	silenceFrame = 20 * time.Millisecond
+
+	// silenceSmoothing is the number of consecutive frames averaged when
+	// ranking candidate split points, so a single quiet frame inside a word
+	// does not win over a real pause.
+	silenceSmoothing = 5
+
+	// maxSplitSearch caps how far before the nominal window end the chunker
+	// looks for a pause.
+	maxSplitSearch = time.Minute
+)
+
+// errNotPCM16 is returned when a WAV stream is not 16-bit integer PCM.
+var errNotPCM16 = errors.New("not a 16-bit PCM WAV stream")
+
+// readPCM16Header consumes the WAV header from r and returns the format of
+// its 16-bit integer PCM data and the declared data size. Streams written to
+// a pipe may declare a placeholder size, so callers should treat the size as
+// an upper bound and read until EOF.
+func readPCM16Header(r io.Reader) (audio.Format, int64, error) {
+	h, err := audio.ReadHeader(r)
+
+	switch {
+	case errors.Is(err, audio.ErrNotWAV) || errors.Is(err, audio.ErrUnsupported):
+		return audio.Format{}, 0, errNotPCM16
+	case err != nil:
+		return audio.Format{}, 0, err //nolint:wrapcheck // already describes the read that failed
+	case h.Format.Float || h.Format.BitDepth != 16:
+		return audio.Format{}, 0, errNotPCM16
+	}
+
+	return h.Format, h.DataSize, nil
+}
+
+// chunkOptions controls how audio is split into chunks.
+type chunkOptions struct {
+	// Window is the nominal chunk length.
+	Window time.Duration
+	// Overlap is the audio shared with each neighbouring chunk.
+	Overlap time.Duration
+	// MaxSize bounds the size of a chunk's WAV file in bytes; zero leaves
+	// it unbounded. The window is shortened until a chunk with its overlap
+	// fits, and a short tail is folded into the final chunk only when the
+	// result still fits.
+	MaxSize int
+}
+
+// audioChunk is one window of audio ready to send to the backend.
+type audioChunk struct {
+	Index int
+	// Offset is the position of the first sample of Data in the source.
+	Offset time.Duration
+	// Duration is the length of the audio in Data.
+	Duration time.Duration
+	// Start and End delimit the part of the source this chunk is responsible
+	// for; the rest of Data is overlap shared with neighbouring chunks.
+	// End is zero for the final chunk.
+	Start, End time.Duration
+	// Data is a complete WAV file.
+	Data []byte
+}
+
+// chunker splits a 16-bit PCM WAV stream into overlapping chunks, cutting at
+// the quietest point near each window boundary. It reads the stream
+// incrementally, so memory use is bounded by one window plus overlap.
+type chunker struct {
+	r      *bufio.Reader
+	format audio.Format
+	opts   chunkOptions
+
+	// window is the nominal chunk length in frames, and limit the most
+	// frames a chunk may hold, overlap included, under MaxSize.
+	window, limit int64
+
+	buf      []byte // PCM starting at absolute frame bufStart
+	bufStart int64
+	prevCut  int64 // absolute frame where the next chunk's owned range starts
+	eof      bool
+	index    int
+}
+
+// newChunker reads the WAV header from r and returns a chunker over its data.
+// It fails when the overlap on both sides of a chunk leaves no room for
+// audio of its own under MaxSize.
+func newChunker(r io.Reader, opts chunkOptions) (*chunker, error) {
+	br := bufio.NewReader(r)
+
+	format, _, err := readPCM16Header(br)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &chunker{r: br, format: format, opts: opts}
+	if c.window, c.limit, err = c.windowFrames(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Next returns the next chunk, or io.EOF once the stream is exhausted.
+func (c *chunker) Next() (*audioChunk, error) {
+	if c.eof && c.bufEnd() <= c.prevCut {
+		return nil, io.EOF
+	}
+
+	window, limit := c.window, c.limit
+	overlap := c.format.Frames(c.opts.Overlap)
+
+	// Read enough to cover a full window past the previous cut, plus the
+	// trailing overlap and a quarter window of slack that lets a short tail
+	// be folded into this chunk instead of becoming a chunk of its own.
+	if err := c.fill(c.prevCut + window + max(overlap, window/4) + 1); err != nil {
+		return nil, err
+	}
+
+	from := max(0, c.prevCut-overlap)
+
+	if tail := c.bufEnd() - c.prevCut; c.eof && tail <= window+window/4 && c.prevCut-from+tail <= limit {
+		chunk := c.makeChunk(from, c.bufEnd(), c.prevCut, 0)
+		c.prevCut = c.bufEnd()
+
+		return chunk, nil
+	}
+
+	cut := c.findSplit(c.prevCut + window)
+	chunk := c.makeChunk(from, min(cut+overlap, c.bufEnd()), c.prevCut, cut)
+
+	c.discardBefore(cut - overlap)
+	c.prevCut = cut
+
+	return chunk, nil
+}
+
+// windowFrames returns the nominal chunk length in frames and the most
+// frames a chunk may hold, overlap included, under MaxSize. The window is
+// shortened so that a chunk with its overlap fits.
+func (c *chunker) windowFrames() (window, limit int64, err error) {
+	window = c.format.Frames(c.opts.Window)
+	if c.opts.MaxSize <= 0 {
+		return window, math.MaxInt64, nil
+	}
+
+	limit = int64(c.opts.MaxSize-audio.HeaderSize) / int64(c.format.FrameSize())
+
+	overlap := 2 * c.format.Frames(c.opts.Overlap)
+	if limit <= overlap {
+		return 0, 0, fmt.Errorf("chunk overlap of %v on both sides leaves no room for audio in %d bytes at %v",
+			c.opts.Overlap, c.opts.MaxSize, c.format)
+	}
+
+	return min(window, limit-overlap), limit, nil
+}
+
+// bufEnd returns the absolute frame just past the buffered audio.
+func (c *chunker) bufEnd() int64 {
+	return c.bufStart + int64(len(c.buf)/c.format.FrameSize())
+}
+
+// fill reads from the stream until the buffer reaches frame end or EOF.
+func (c *chunker) fill(end int64) error {
+	frameSize := c.format.FrameSize()
+
+	for !c.eof && c.bufEnd() < end {
+		need := int((end - c.bufEnd()) * int64(frameSize))
+		start := len(c.buf)
+		c.buf = append(c.buf, make([]byte, need)...)
+
+		n, err := io.ReadFull(c.r, c.buf[start:])
+		c.buf = c.buf[:start+n-n%frameSize]
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			c.eof = true
+		} else if err != nil {
+			return fmt.Errorf("reading audio stream: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// discardBefore drops buffered audio before absolute frame f.
+func (c *chunker) discardBefore(f int64) {
+	if f <= c.bufStart {
+		return
+	}
+
+	n := int((f - c.bufStart) * int64(c.format.FrameSize()))
+	c.buf = append(c.buf[:0], c.buf[n:]...)
+	c.bufStart = f
+}
+
+// pcm returns the buffered bytes between absolute frames from and to.
+func (c *chunker) pcm(from, to int64) []byte {
+	frameSize := int64(c.format.FrameSize())
+
+	return c.buf[(from-c.bufStart)*frameSize : (to-c.bufStart)*frameSize]
+}
+
+// makeChunk builds a chunk covering frames [from, to) that owns [start, end).
+func (c *chunker) makeChunk(from, to, start, end int64) *audioChunk {
+	chunk := &audioChunk{
+		Index:    c.index,
+		Offset:   c.format.Duration(from),
+		Duration: c.format.Duration(to - from),
+		Start:    c.format.Duration(start),
+		Data:     audio.Encode(c.format, c.pcm(from, to)),
+	}
+
+	if end > 0 {
+		chunk.End = c.format.Duration(end)
+	}
+
+	c.index++
+
+	return chunk
+}
+
+// findSplit returns the frame of the quietest moment in the search span
+// ending at nominal. Ties favour the candidate closest to nominal.
+func (c *chunker) findSplit(nominal int64) int64 {
+	frameLen := max(1, c.format.Frames(silenceFrame))
+	search := min(c.window/5, c.format.Frames(maxSplitSearch))
+	lo := max(c.prevCut+frameLen, nominal-search)
+
+	energies := make([]float64, 0, (nominal-lo)/frameLen+1)
+	for f := lo; f+frameLen <= nominal; f += frameLen {
+		energies = append(energies, c.rms(f, f+frameLen))
+	}
+
+	if len(energies) == 0 {
+		return nominal
+	}
+
+	best, bestScore := len(energies)-1, math.Inf(1)
+
+	for i := range energies {
+		var sum float64
+
+		n := 0
+		for j := max(0, i-silenceSmoothing/2); j <= min(len(energies)-1, i+silenceSmoothing/2); j++ {
+			sum += energies[j]
+			n++
+		}
+
+		if score := sum / float64(n); score <= bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return lo + int64(best)*frameLen + frameLen/2
+}
+
+// rms returns the root-mean-square amplitude over all channels of frames
+// [from, to), normalised to 0..1.
+func (c *chunker) rms(from, to int64) float64 {
+	data := c.pcm(from, to)
+	if len(data) < 2 {
+		return 0
+	}
+
+	var sum float64
+
+	for i := 0; i+1 < len(data); i += 2 {
+		s := float64(int16(binary.LittleEndian.Uint16(data[i:]))) / math.MaxInt16 // #nosec G115 -- reinterpret sample bits
+		sum += s * s
+	}
+
+	return math.Sqrt(sum / float64(len(data)/2))
+}
@@ -0,0 +1,187 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// toneWAV returns speech-format WAV of length total holding a 440 Hz tone
+// everywhere except the silent spans.
+func toneWAV(total time.Duration, silences ...timeSpan) []byte {
+	rate := audio.Speech.SampleRate
+	pcm := make([]byte, int(total.Seconds()*float64(rate))*2)
+
+	for i := range len(pcm) / 2 {
+		at := time.Duration(i) * time.Second / time.Duration(rate)
+
+		quiet := false
+		for _, s := range silences {
+			quiet = quiet || (at >= s.Start && at < s.End())
+		}
+
+		if !quiet {
+			v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+			binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+		}
+	}
+
+	return audio.Encode(audio.Speech, pcm)
+}
+
+// splitWAV runs the chunker over data and returns its chunks.
+func splitWAV(data []byte, window, overlap time.Duration) ([]*audioChunk, error) {
+	c, err := newChunker(bytes.NewReader(data), chunkOptions{Window: window, Overlap: overlap})
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*audioChunk
+
+	for {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestSplitWAVCutsAtSilence(t *testing.T) {
+	t.Parallel()
+
+	const overlap = 500 * time.Millisecond
+
+	// With a 10-second window each cut must land inside one of the first
+	// three silences.
+	gaps := []timeSpan{
+		{9 * time.Second, 600 * time.Millisecond},
+		{18500 * time.Millisecond, 600 * time.Millisecond},
+		{27500 * time.Millisecond, 600 * time.Millisecond},
+	}
+
+	chunks, err := splitWAV(toneWAV(35*time.Second, gaps...), 10*time.Second, overlap)
+	if err != nil {
+		t.Fatalf("splitWAV() unexpected error: %v", err)
+	}
+
+	if len(chunks) != 4 {
+		t.Fatalf("splitWAV() produced %d chunks; want 4", len(chunks))
+	}
+
+	for i, c := range chunks[:3] {
+		if c.End < gaps[i].Start || c.End > gaps[i].End() {
+			t.Errorf("chunk %d cut at %v; want inside silence %v-%v", i, c.End, gaps[i].Start, gaps[i].End())
+		}
+
+		next := chunks[i+1]
+		if next.Start != c.End {
+			t.Errorf("chunk %d starts at %v; want previous cut %v", i+1, next.Start, c.End)
+		}
+
+		if next.Offset != c.End-overlap {
+			t.Errorf("chunk %d offset = %v; want cut minus overlap %v", i+1, next.Offset, c.End-overlap)
+		}
+
+		if c.Offset+c.Duration != c.End+overlap {
+			t.Errorf("chunk %d audio ends at %v; want cut plus overlap %v", i, c.Offset+c.Duration, c.End+overlap)
+		}
+	}
+
+	last := chunks[len(chunks)-1]
+	if last.End != 0 || last.Offset+last.Duration != 35*time.Second {
+		t.Errorf("last chunk ends at %v with audio to %v; want open end reaching 35s",
+			last.End, last.Offset+last.Duration)
+	}
+}
+
+func TestSplitWAVShortTailIsFolded(t *testing.T) {
+	t.Parallel()
+
+	chunks, err := splitWAV(toneWAV(12*time.Second), 10*time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("splitWAV() unexpected error: %v", err)
+	}
+
+	if len(chunks) != 1 {
+		t.Errorf("splitWAV(12s, window 10s) produced %d chunks; want 1", len(chunks))
+	}
+}
+
+func TestChunkerRejectsOverlapOverSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	// At 48 kHz stereo, the limit holds about 109s: two minutes of overlap
+	// would leave a chunk no audio of its own.
+	hifi := audio.Format{SampleRate: 48000, Channels: 2, BitDepth: 16}
+	data := audio.Encode(hifi, make([]byte, 5*hifi.ByteRate()))
+
+	_, err := newChunker(bytes.NewReader(data), chunkOptions{
+		Window:  10 * time.Minute,
+		Overlap: time.Minute,
+		MaxSize: 20 << 20,
+	})
+	if err == nil {
+		t.Error("newChunker() with 2 x 1m of overlap over a 20 MiB limit succeeded; want an error")
+	}
+}
+
+func TestSplitWAVRejectsNonPCM(t *testing.T) {
+	t.Parallel()
+
+	if _, err := splitWAV([]byte("ID3 not a wav file at all"), time.Second, 0); err == nil {
+		t.Error("splitWAV(mp3 bytes) = nil error; want error")
+	}
+}
+
+func TestMergeChunksDeduplicatesOverlap(t *testing.T) {
+	t.Parallel()
+
+	parts := []chunkTranscript{
+		{
+			chunk: &audioChunk{Index: 0, Duration: 11 * time.Second, End: 10 * time.Second},
+			transcript: &gemini.Transcript{Segments: []gemini.Segment{
+				{Start: 0, End: 5 * time.Second, Text: "перший сегмент тексту"},
+				{Start: 6 * time.Second, End: 10500 * time.Millisecond, Text: "слова на самому стику записів"},
+			}},
+		},
+		{
+			chunk: &audioChunk{Index: 1, Offset: 9 * time.Second, Duration: 10 * time.Second, Start: 10 * time.Second},
+			transcript: &gemini.Transcript{Segments: []gemini.Segment{
+				// Starts in the overlap owned by chunk 0: dropped by timestamp.
+				{Start: 0, End: 800 * time.Millisecond, Text: "на самому"},
+				// Repeats the tail of chunk 0: trimmed by text matching.
+				{Start: time.Second, End: 4 * time.Second, Text: "Самому стику записів, і далі"},
+				{Start: 5 * time.Second, End: 8 * time.Second, Text: "кінець"},
+			}},
+		},
+	}
+
+	got := mergeChunkTranscripts(parts)
+
+	want := "перший сегмент тексту\nслова на самому стику записів\nі далі\nкінець"
+	if got.Text != want {
+		t.Errorf("merged text = %q; want %q", got.Text, want)
+	}
+
+	if last := got.Segments[len(got.Segments)-1]; last.Start != 14*time.Second {
+		t.Errorf("last segment start = %v; want 14s on the source timeline", last.Start)
+	}
+}
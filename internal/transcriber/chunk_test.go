@@ -0,0 +1,244 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+const testSampleRate = 16000
+
+// silence is a [from, to) span of silence in synthetic test audio.
+type silence struct{ from, to time.Duration }
+
+// synthWAV returns a 16 kHz mono WAV of length total containing a 440 Hz
+// tone everywhere except the given silent spans.
+func synthWAV(total time.Duration, gaps ...silence) []byte {
+	n := int(total.Seconds() * testSampleRate)
+	pcm := make([]byte, n*2)
+
+	for i := range n {
+		at := time.Duration(i) * time.Second / testSampleRate
+
+		quiet := false
+		for _, g := range gaps {
+			if at >= g.from && at < g.to {
+				quiet = true
+			}
+		}
+
+		if !quiet {
+			v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/testSampleRate))
+			binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+		}
+	}
+
+	hdr := make([]byte, 44)
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(36+len(pcm)))
+	copy(hdr[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(hdr[16:20], 16)
+	binary.LittleEndian.PutUint16(hdr[20:22], 1)
+	binary.LittleEndian.PutUint16(hdr[22:24], 1)
+	binary.LittleEndian.PutUint32(hdr[24:28], testSampleRate)
+	binary.LittleEndian.PutUint32(hdr[28:32], testSampleRate*2)
+	binary.LittleEndian.PutUint16(hdr[32:34], 2)
+	binary.LittleEndian.PutUint16(hdr[34:36], 16)
+	copy(hdr[36:40], "data")
+	binary.LittleEndian.PutUint32(hdr[40:44], uint32(len(pcm)))
+
+	return append(hdr, pcm...)
+}
+
+// testGaps are the silences in the 35-second chunking fixture. With a
+// 10-second window each cut must land inside one of the first three.
+var testGaps = []silence{
+	{9 * time.Second, 9600 * time.Millisecond},
+	{18500 * time.Millisecond, 19100 * time.Millisecond},
+	{27500 * time.Millisecond, 28100 * time.Millisecond},
+}
+
+// chunkStub answers every request with one segment spanning the audio.
+type chunkStub struct {
+	calls atomic.Int32
+}
+
+func (s *chunkStub) TranscribeAudio(_ context.Context, req *gemini.Request) (*gemini.Transcript, error) {
+	s.calls.Add(1)
+
+	if !req.Timestamps {
+		return &gemini.Transcript{Text: "whole"}, nil
+	}
+
+	return &gemini.Transcript{Segments: []gemini.Segment{
+		{Start: time.Second, End: req.Duration - time.Second, Text: "part"},
+	}, Text: "part"}, nil
+}
+
+func TestTranscribeLocalFileChunksLongAudio(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "long.wav")
+	if err := os.WriteFile(path, synthWAV(35*time.Second, testGaps...), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{
+		Quiet:            true,
+		ChunkDuration:    10 * time.Second,
+		ChunkOverlap:     500 * time.Millisecond,
+		ChunkParallelism: 2,
+	}
+	stub := &chunkStub{}
+
+	result, err := newTranscriber(t, cfg, stub).TranscribeLocalFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if result.Chunks != 4 || stub.calls.Load() != 4 {
+		t.Errorf("Chunks = %d, backend calls = %d; want 4", result.Chunks, stub.calls.Load())
+	}
+
+	if len(result.Segments) != 4 {
+		t.Fatalf("len(Segments) = %d; want 4", len(result.Segments))
+	}
+
+	for i := 1; i < len(result.Segments); i++ {
+		if result.Segments[i].Start <= result.Segments[i-1].Start {
+			t.Errorf("segments not in source order: %+v", result.Segments)
+		}
+	}
+
+	t.Run("disabled chunking sends one request", func(t *testing.T) {
+		t.Parallel()
+
+		single := &chunkStub{}
+		cfg := &config.Config{Quiet: true}
+
+		result, err := newTranscriber(t, cfg, single).TranscribeLocalFile(context.Background(), path)
+		if err != nil {
+			t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+		}
+
+		if result.Text != "whole" || single.calls.Load() != 1 {
+			t.Errorf("got text %q after %d calls; want single request", result.Text, single.calls.Load())
+		}
+	})
+}
+
+// sizeStub records the size of the audio in every request.
+type sizeStub struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (s *sizeStub) TranscribeAudio(_ context.Context, req *gemini.Request) (*gemini.Transcript, error) {
+	data, err := io.ReadAll(req.Audio)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.sizes = append(s.sizes, len(data))
+	s.mu.Unlock()
+
+	return &gemini.Transcript{Segments: []gemini.Segment{{Text: "part"}}, Text: "part"}, nil
+}
+
+func TestChunksFitInlineLimit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// Silence, so that every cut falls on its window boundary. A default
+	// window of 16 kHz mono stays below the limit, but folding the
+	// 2.4-minute tail would make a final chunk of 12.4 minutes.
+	mono := audio.Format{SampleRate: testSampleRate, Channels: 1, BitDepth: 16}
+
+	long := filepath.Join(dir, "long.wav")
+	if err := os.WriteFile(long, audio.Encode(mono, make([]byte, (22*60+24)*mono.ByteRate())), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	// Three minutes of 44.1 kHz stereo is over the limit at its own rate.
+	stereo := audio.Format{SampleRate: 44100, Channels: 2, BitDepth: 16}
+
+	hifi := filepath.Join(dir, "hifi.wav")
+	if err := os.WriteFile(hifi, audio.Encode(stereo, make([]byte, 3*60*stereo.ByteRate())), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		format     audio.Format
+		wantChunks int
+	}{
+		{name: "folded tail", path: long, wantChunks: 3},
+		{name: "native rate", path: hifi, wantChunks: 1},
+		// Decoded at 48 kHz stereo, three minutes need two chunks.
+		{name: "decode format", path: hifi, format: audio.Format{SampleRate: 48000, Channels: 2}, wantChunks: 2},
+	}
+
+	for _, tc := range tests {
+		cfg := &config.Config{
+			Quiet:          true,
+			ChunkDuration:  10 * time.Minute,
+			ChunkOverlap:   2 * time.Second,
+			SampleRate:     tc.format.SampleRate,
+			Channels:       tc.format.Channels,
+			TranscodeAudio: tc.format.SampleRate > 0,
+		}
+		stub := &sizeStub{}
+
+		result, err := newTranscriber(t, cfg, stub).TranscribeLocalFile(context.Background(), tc.path)
+		if err != nil {
+			t.Fatalf("%s: TranscribeLocalFile() error = %v", tc.name, err)
+		}
+
+		if result.Chunks != tc.wantChunks {
+			t.Errorf("%s: Chunks = %d; want %d", tc.name, result.Chunks, tc.wantChunks)
+		}
+
+		for _, size := range stub.sizes {
+			if size > gemini.MaxInlineSize {
+				t.Errorf("%s: sent %d bytes in one request; want at most %d", tc.name, size, gemini.MaxInlineSize)
+			}
+		}
+	}
+}
+
+func TestTimestampedText(t *testing.T) {
+	t.Parallel()
+
+	r := &transcriber.TranscriptionResult{
+		Text: "a\nb",
+		Segments: []gemini.Segment{
+			{Start: 1500 * time.Millisecond, Text: "a"},
+			{Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, Text: "b"},
+		},
+	}
+
+	want := "[00:00:01.500] a\n[01:02:03.004] b\n"
+	if got := r.TimestampedText(); got != want {
+		t.Errorf("TimestampedText() = %q; want %q", got, want)
+	}
+}
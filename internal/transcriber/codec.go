@@ -0,0 +1,101 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// uploadCodec describes how audio is encoded before it is sent to Gemini.
+type uploadCodec struct {
+	Name     string
+	MIMEType string
+	// Format is the FFmpeg muxer name.
+	Format string
+	// Args are the FFmpeg encoder arguments.
+	Args []string
+}
+
+// uploadCodecs lists the supported --upload-codec values. Bitrates are
+// chosen for speech at 16 kHz mono: well above what the model needs to
+// recognise words, and far below the 256 kb/s of raw PCM.
+var uploadCodecs = map[string]uploadCodec{
+	"wav": {
+		Name: "wav", MIMEType: "audio/wav", Format: "wav",
+		Args: []string{"-acodec", "pcm_s16le"},
+	},
+	"flac": {
+		Name: "flac", MIMEType: "audio/flac", Format: "flac",
+		Args: []string{"-acodec", "flac", "-compression_level", "8"},
+	},
+	"opus": {
+		Name: "opus", MIMEType: "audio/ogg", Format: "ogg",
+		Args: []string{"-acodec", "libopus", "-b:a", "32k", "-application", "voip"},
+	},
+	"mp3": {
+		Name: "mp3", MIMEType: "audio/mp3", Format: "mp3",
+		Args: []string{"-acodec", "libmp3lame", "-b:a", "64k"},
+	},
+}
+
+// lookupCodec returns the codec registered under name. An empty name selects
+// WAV, the historical default.
+func lookupCodec(name string) (uploadCodec, error) {
+	if name == "" {
+		name = "wav"
+	}
+
+	codec, ok := uploadCodecs[name]
+	if !ok {
+		return uploadCodec{}, fmt.Errorf("unsupported upload codec %q", name)
+	}
+
+	return codec, nil
+}
+
+// outputArgs returns the FFmpeg arguments that encode audio with the codec,
+// writing to output. Resampling is left to the caller.
+func (c uploadCodec) outputArgs(output string) []string {
+	args := append([]string{"-vn"}, c.Args...)
+
+	return append(args, "-f", c.Format, "-y", output)
+}
+
+// encodeArgs returns the FFmpeg arguments that re-encode a WAV stream on
+// stdin with codec, writing to stdout. The stream was decoded to the
+// configured format already, so it is not resampled again.
+func encodeArgs(codec uploadCodec) []string {
+	return append([]string{"-hide_banner", "-nostats", "-f", "wav", "-i", "pipe:0"}, codec.outputArgs("pipe:1")...)
+}
+
+// encodeStream starts FFmpeg re-encoding the WAV stream r, lasting
+// duration when known, with codec and returns the encoded output as a
+// stream.
+func (tc *toolchain) encodeStream(
+	ctx context.Context, r io.Reader, codec uploadCodec, duration time.Duration,
+) (*ffmpegStream, error) {
+	return tc.start(ctx, ffmpegPass{Stage: "encode", Args: encodeArgs(codec), Duration: duration}, r)
+}
+
+// encodeChunk re-encodes an in-memory WAV chunk lasting duration with
+// codec, piping it through FFmpeg without touching the disk.
+func (tc *toolchain) encodeChunk(
+	ctx context.Context, wav []byte, codec uploadCodec, duration time.Duration,
+) ([]byte, error) {
+	var out bytes.Buffer
+
+	pass := ffmpegPass{Args: encodeArgs(codec), Duration: duration}
+
+	if _, err := tc.run(ctx, pass, bytes.NewReader(wav), &out); err != nil {
+		return nil, fmt.Errorf("encoding chunk as %s: %w", codec.Name, err)
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func TestUploadCodecs(t *testing.T) {
+	t.Parallel()
+
+	wantMIME := map[string]string{
+		"":     "audio/wav",
+		"wav":  "audio/wav",
+		"flac": "audio/flac",
+		"opus": "audio/ogg",
+		"mp3":  "audio/mp3",
+	}
+
+	// Every value accepted by config validation must resolve to a codec.
+	for _, name := range append([]string{""}, config.UploadCodecs...) {
+		codec, err := lookupCodec(name)
+		if err != nil {
+			t.Errorf("lookupCodec(%q) unexpected error: %v", name, err)
+
+			continue
+		}
+
+		if codec.MIMEType != wantMIME[name] {
+			t.Errorf("lookupCodec(%q) MIME = %q; want %q", name, codec.MIMEType, wantMIME[name])
+		}
+
+		if args := codec.outputArgs("out"); args[len(args)-1] != "out" || !slices.Contains(args, "-acodec") {
+			t.Errorf("lookupCodec(%q) args = %v; want encoder and output", name, args)
+		}
+	}
+
+	if _, err := lookupCodec("aiff"); err == nil {
+		t.Error("lookupCodec(\"aiff\") expected error, got nil")
+	}
+}
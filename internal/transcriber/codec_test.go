@@ -0,0 +1,86 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// requestRecorder is a fake AudioTranscriber that consumes and records each
+// request, billing each for recorderUsage.
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []gemini.Request
+	payloads [][]byte
+}
+
+func (r *requestRecorder) TranscribeAudio(_ context.Context, req *gemini.Request) (*gemini.Transcript, error) {
+	var data []byte
+
+	// Requests referencing a Cloud Storage object carry no audio.
+	if req.Audio != nil {
+		var err error
+
+		if data, err = io.ReadAll(req.Audio); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Sent != nil {
+		req.Sent()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, *req)
+	r.payloads = append(r.payloads, data)
+
+	return &gemini.Transcript{Text: "ok", Usage: recorderUsage}, nil
+}
+
+// recorderUsage is the usage a requestRecorder reports for every request.
+var recorderUsage = gemini.Usage{InputTokens: 100, OutputTokens: 7}
+
+func TestNativeAudioIsNotTranscodedByDefault(t *testing.T) {
+	t.Parallel()
+
+	wav := synthWAV(2 * time.Second)
+	path := filepath.Join(t.TempDir(), "clip.wav")
+
+	if err := os.WriteFile(path, wav, 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{Quiet: true, UploadCodec: "opus"}
+	rec := &requestRecorder{}
+
+	result, err := newTranscriber(t, cfg, rec).TranscribeLocalFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if len(rec.requests) != 1 || rec.requests[0].MIMEType != "audio/wav" {
+		t.Fatalf("requests = %+v; want one audio/wav request", rec.requests)
+	}
+
+	if rec.requests[0].Duration != 2*time.Second {
+		t.Errorf("request Duration = %v; want 2s", rec.requests[0].Duration)
+	}
+
+	if result.InputSize != int64(len(wav)) || result.UploadSize != int64(len(wav)) {
+		t.Errorf("InputSize = %d, UploadSize = %d; want both %d", result.InputSize, result.UploadSize, len(wav))
+	}
+}
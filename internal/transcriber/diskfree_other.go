@@ -0,0 +1,14 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build !linux && !darwin
+
+package transcriber
+
+// diskFree reports free space as unknown where Statfs_t differs from the
+// Linux and macOS layout, as on the BSDs, or does not exist.
+func diskFree(string) (uint64, bool) {
+	return 0, false
+}
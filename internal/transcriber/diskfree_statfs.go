@@ -0,0 +1,22 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build linux || darwin
+
+package transcriber
+
+import "syscall"
+
+// diskFree returns the bytes available to this user on the file system
+// holding dir.
+func diskFree(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+
+	return st.Bavail * uint64(st.Bsize), true //nolint:gosec,unconvert // Bsize is int64 on Linux, uint32 on macOS
+}
@@ -0,0 +1,68 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enhancePresets maps each --enhance preset to its FFmpeg filter chain.
+// Filters run on the source before resampling, in order: band-limiting
+// removes rumble, wind and hiss outside the speech band, afftdn removes
+// stationary noise, and dynaudnorm/loudnorm even out and raise the level.
+var enhancePresets = map[string][]string{
+	// voice suits close-miked speech: light cleanup and loudness levelling.
+	"voice": {
+		"highpass=f=80",
+		"afftdn=nr=10:nf=-40",
+		"loudnorm=I=-16:TP=-1.5:LRA=11",
+	},
+	// phone suits narrowband call audio: keep the telephone band and
+	// compensate for level differences between the parties.
+	"phone": {
+		"highpass=f=300",
+		"lowpass=f=3400",
+		"afftdn=nr=12:nf=-35",
+		"dynaudnorm=f=150:g=15",
+	},
+	// noisy-field suits outdoor recordings with wind, traffic and hum.
+	"noisy-field": {
+		"highpass=f=150",
+		"lowpass=f=8000",
+		"afftdn=nr=20:nf=-25:tn=1",
+		"dynaudnorm=f=200:g=21",
+		"loudnorm=I=-16:TP=-1.5:LRA=11",
+	},
+	// lecture-hall suits distant, reverberant speakers with quiet passages.
+	"lecture-hall": {
+		"highpass=f=100",
+		"afftdn=nr=12:nf=-35",
+		"dynaudnorm=f=250:g=25:p=0.9",
+		"loudnorm=I=-16:TP=-1.5:LRA=7",
+	},
+}
+
+// enhanceFilters returns the filter chain for the named preset followed by
+// the custom chain, either of which may be empty.
+func enhanceFilters(preset, custom string) ([]string, error) {
+	var filters []string
+
+	if preset != "" {
+		chain, ok := enhancePresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown enhancement preset %q", preset)
+		}
+
+		filters = append(filters, chain...)
+	}
+
+	if custom = strings.TrimSpace(custom); custom != "" {
+		filters = append(filters, custom)
+	}
+
+	return filters, nil
+}
@@ -0,0 +1,42 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// argAfter returns the argument following flag in args, or "".
+
+func TestEnhancementRoutesNativeAudioThroughFFmpeg(t *testing.T) {
+	// No ffmpeg on PATH: a native WAV must fail to decode rather than be
+	// sent unenhanced.
+	t.Setenv("PATH", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "field.wav")
+	if err := os.WriteFile(path, synthWAV(3*time.Second), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{Quiet: true, Enhance: "noisy-field"}
+	rec := &requestRecorder{}
+
+	_, err := newTranscriber(t, cfg, rec).TranscribeLocalFile(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "ffmpeg") {
+		t.Fatalf("TranscribeLocalFile() error = %v; want ffmpeg required", err)
+	}
+
+	if len(rec.requests) != 0 {
+		t.Errorf("got %d requests; want none", len(rec.requests))
+	}
+}
@@ -8,37 +8,24 @@ package transcriber
 
 import (
 	"context"
-	"log/slog"
-
-	"github.com/idvoretskyi/voice-transcriber/internal/config"
-	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"io"
+	"net/http"
 )
 
-// GenerateAudioPath exposes generateAudioPath for black-box tests.
-func GenerateAudioPath(inputPath string) (string, error) { return generateAudioPath(inputPath) }
-
-// ValidateInputPath exposes validateInputPath for black-box tests.
-func ValidateInputPath(inputPath string) (string, error) {
-	return validateInputPath(inputPath)
+// SetProbeOutput makes t inspect every input as if ffprobe had printed out.
+func (t *Transcriber) SetProbeOutput(out string) {
+	t.probe = func(context.Context, *mediaSource) (*MediaInfo, error) {
+		return parseProbeOutput([]byte(out))
+	}
 }
 
-// ClassifyInputFile exposes classifyInputFile for black-box tests.
-func ClassifyInputFile(inputPath string) (InputType, string) {
-	return classifyInputFile(inputPath)
+// SetStdin makes t read the input path "-" from r.
+func (t *Transcriber) SetStdin(r io.Reader) {
+	t.stdin = r
 }
 
-// NewForTesting constructs a Transcriber with an injected AudioTranscriber
-// backend, bypassing real Gemini and gcloud resolution.
-// For use in unit tests only.
-func NewForTesting(cfg *config.Config, backend gemini.AudioTranscriber, logger *slog.Logger) *Transcriber {
-	if logger == nil {
-		logger = slog.Default()
-	}
-
-	return &Transcriber{
-		config:    cfg,
-		backend:   backend,
-		logger:    logger,
-		resolveID: func(_ context.Context) (string, error) { return "test-project", nil },
-	}
+// SetStorageEndpoint makes t read gs:// inputs from a fake Cloud Storage
+// JSON API at endpoint, without credentials.
+func (t *Transcriber) SetStorageEndpoint(endpoint string) {
+	t.storage = newStorageClientAt(endpoint, http.DefaultClient)
 }
@@ -0,0 +1,516 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+const (
+	// ffmpegBaseTimeout is the time allowed for an FFmpeg pass on top of
+	// the duration of its media: passes run far faster than real time.
+	ffmpegBaseTimeout = 2 * time.Minute
+
+	// ffmpegIdleTimeout bounds how long a streaming pass may keep its reader
+	// waiting for output, e.g. on a network input that stopped sending, so
+	// that a stall is noticed well before the pass times out.
+	ffmpegIdleTimeout = 2 * time.Minute
+
+	// ffmpegDefaultTimeout bounds a pass over media of unknown duration.
+	ffmpegDefaultTimeout = 30 * time.Minute
+
+	// versionTimeout bounds the version check of a binary.
+	versionTimeout = 10 * time.Second
+)
+
+// minFFmpegVersion is the oldest FFmpeg release supported, for ffmpeg and
+// ffprobe alike.
+var minFFmpegVersion = [2]int{4, 2}
+
+// errTooOld is returned for FFmpeg binaries older than minFFmpegVersion.
+var errTooOld = errors.New("too old")
+
+var (
+	// versionRe matches the version in the first line of `ffmpeg -version`,
+	// e.g. "ffmpeg version 6.1.1-3ubuntu5 Copyright ...".
+	versionRe = regexp.MustCompile(`^\S+ version (\S+)`)
+	// releaseRe matches the major and minor number of a release version;
+	// git builds report a revision such as "N-112345-gabc" instead.
+	releaseRe = regexp.MustCompile(`^n?(\d+)\.(\d+)`)
+	// progressRe matches one key=value line of an FFmpeg -progress report.
+	progressRe = regexp.MustCompile(`^([a-z][a-z0-9_]*)=(\S*)$`)
+)
+
+// FFmpegProgress is a progress event of one FFmpeg pass, parsed from its
+// -progress report.
+type FFmpegProgress struct {
+	// Stage names the pass: "decode", "silence" or "encode".
+	Stage string
+	// Processed is how much of the media has been processed, and Total its
+	// duration, or zero when unknown.
+	Processed time.Duration
+	Total     time.Duration
+	// Speed is the processing speed as a multiple of real time.
+	Speed float64
+	// Done is set on the last event of a pass.
+	Done bool
+}
+
+// ffmpegPass is one run of FFmpeg.
+type ffmpegPass struct {
+	// Stage names the pass in progress events; a pass without one reports
+	// no progress.
+	Stage string
+	Args  []string
+	// Duration is the length of the media processed, or zero when unknown.
+	// It scales the timeout and is the total reported in progress events.
+	Duration time.Duration
+}
+
+// toolchain locates and runs the configured FFmpeg and ffprobe binaries.
+type toolchain struct {
+	// ffmpeg and ffprobe resolve and version-check the binaries on first
+	// use and return their paths.
+	ffmpeg  func(context.Context) (string, error)
+	ffprobe func(context.Context) (string, error)
+	// inputArgs go before the input of every pass that reads the source,
+	// outputArgs before the output of every pass that decodes it.
+	inputArgs  []string
+	outputArgs []string
+	// progress receives the progress events of every pass with a stage.
+	progress func(FFmpegProgress)
+	// idle is how long a streaming pass may keep its reader waiting.
+	idle   time.Duration
+	logger *slog.Logger
+}
+
+// newToolchain returns the toolchain configured by cfg, which must have
+// been validated.
+func newToolchain(cfg *config.Config, logger *slog.Logger) *toolchain {
+	inputArgs, outputArgs, _ := cfg.FFmpegArgs()
+
+	tc := &toolchain{inputArgs: inputArgs, outputArgs: outputArgs, idle: ffmpegIdleTimeout, logger: logger}
+
+	tc.ffmpeg = resolveOnce(func(ctx context.Context) (string, error) {
+		path, err := tc.find(ctx, cmp.Or(cfg.FFmpegPath, "ffmpeg"))
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("ffmpeg not found; install ffmpeg first: %w", err)
+		}
+
+		return path, err
+	})
+	tc.ffprobe = resolveOnce(func(ctx context.Context) (string, error) {
+		path, err := tc.find(ctx, cmp.Or(cfg.FFprobePath, "ffprobe"))
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w; install ffmpeg first: %w", errNoFFprobe, err)
+		}
+
+		return path, err
+	})
+	tc.progress = func(p FFmpegProgress) {
+		logger.Debug("ffmpeg progress",
+			slog.String("stage", p.Stage),
+			slog.Duration("processed", p.Processed),
+			slog.Duration("total", p.Total),
+			slog.Float64("speed", p.Speed),
+		)
+	}
+
+	return tc
+}
+
+// resolveOnce returns a function that calls resolve on first use and then
+// returns its result. A call cut short by its context is not remembered, so
+// the next caller resolves again.
+func resolveOnce(resolve func(context.Context) (string, error)) func(context.Context) (string, error) {
+	var (
+		mu   sync.Mutex
+		done bool
+		path string
+		err  error
+	)
+
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !done {
+			path, err = resolve(ctx)
+			done = ctx.Err() == nil
+		}
+
+		return path, err
+	}
+}
+
+// find resolves name, a path or a name on PATH, and checks its version.
+func (tc *toolchain) find(ctx context.Context, name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("looking up %s: %w", name, err)
+	}
+
+	version, err := checkVersion(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	tc.logger.Debug("found FFmpeg binary", slog.String("path", path), slog.String("version", version))
+
+	return path, nil
+}
+
+// checkVersion runs path -version and returns the version it reports, or
+// an error when that is a release older than minFFmpegVersion. Git builds,
+// which report a revision rather than a release, are accepted.
+func checkVersion(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "-version").Output() // #nosec G204 -- path resolved via exec.LookPath
+	if err != nil {
+		return "", fmt.Errorf("checking version of %s: %w", path, err)
+	}
+
+	first, _, _ := strings.Cut(string(out), "\n")
+
+	m := versionRe.FindStringSubmatch(first)
+	if m == nil {
+		return "", fmt.Errorf("checking version of %s: unrecognised output %q", path, first)
+	}
+
+	version := m[1]
+
+	if release := releaseRe.FindStringSubmatch(version); release != nil {
+		major, _ := strconv.Atoi(release[1])
+		minor, _ := strconv.Atoi(release[2])
+
+		if major < minFFmpegVersion[0] || (major == minFFmpegVersion[0] && minor < minFFmpegVersion[1]) {
+			return "", fmt.Errorf("%s is version %s, which is %w: version %d.%d or later is required "+
+				"(use --ffmpeg and --ffprobe to choose other binaries)",
+				path, version, errTooOld, minFFmpegVersion[0], minFFmpegVersion[1])
+		}
+	}
+
+	return version, nil
+}
+
+// passTimeout returns the time allowed for a pass over d of media.
+func passTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return ffmpegDefaultTimeout
+	}
+
+	return ffmpegBaseTimeout + d
+}
+
+// command returns the arguments of pass, asking for a progress report on
+// stderr when the pass has a stage.
+func (p ffmpegPass) command() []string {
+	if p.Stage == "" {
+		return p.Args
+	}
+
+	return append([]string{"-progress", "pipe:2"}, p.Args...)
+}
+
+// stderr returns the writer FFmpeg's stderr goes to: buf, teed to debug
+// log records when debug logging is enabled, behind a filter that turns
+// the progress report of the pass into events for tc.progress and the
+// transcription of ctx. Call flush once FFmpeg exits.
+func (tc *toolchain) stderr(ctx context.Context, pass ffmpegPass, buf *strings.Builder) (w io.Writer, flush func()) {
+	var (
+		log       io.Writer = buf
+		flushLogs           = func() {}
+	)
+
+	if tc.logger.Enabled(ctx, slog.LevelDebug) {
+		lw := &logWriter{ctx: ctx, logger: tc.logger}
+		log, flushLogs = io.MultiWriter(lw, buf), lw.flush
+	}
+
+	if pass.Stage == "" {
+		return log, flushLogs
+	}
+
+	pw := &progressWriter{
+		log: log,
+		emit: func(p FFmpegProgress) {
+			tc.progress(p)
+			reportFFmpeg(ctx, p)
+		},
+		event: FFmpegProgress{Stage: pass.Stage, Total: pass.Duration},
+	}
+
+	return pw, func() {
+		pw.flush()
+		flushLogs()
+	}
+}
+
+// logWriter turns each line written to it into a debug log record, so
+// that FFmpeg's log reaches the same handler as the program's own.
+type logWriter struct {
+	ctx    context.Context
+	logger *slog.Logger
+	// line holds an incomplete line until its newline arrives.
+	line []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.line = append(w.line, b...)
+
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+
+		w.log(w.line[:i])
+		w.line = w.line[i+1:]
+	}
+}
+
+// flush logs an unterminated last line.
+func (w *logWriter) flush() {
+	if len(w.line) > 0 {
+		w.log(w.line)
+		w.line = nil
+	}
+}
+
+func (w *logWriter) log(line []byte) {
+	if line = bytes.TrimRight(line, "\r"); len(line) > 0 {
+		w.logger.DebugContext(w.ctx, "ffmpeg", slog.String("stderr", string(line)))
+	}
+}
+
+// run executes pass to completion, within a timeout scaled by the duration
+// of its media, and returns its captured stderr, which is where FFmpeg
+// writes logs and filter reports. stdin and stdout are connected for use
+// with pipe:0 and pipe:1 arguments; either may be nil.
+func (tc *toolchain) run(ctx context.Context, pass ffmpegPass, stdin io.Reader, stdout io.Writer) (string, error) {
+	ffmpegPath, err := tc.ffmpeg(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, passTimeout(pass.Duration))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, pass.command()...) // #nosec G204 -- ffmpegPath resolved via exec.LookPath
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+
+	var stderr strings.Builder
+
+	w, flush := tc.stderr(ctx, pass, &stderr)
+	cmd.Stderr = w
+
+	err = cmd.Run()
+
+	flush()
+
+	if err != nil {
+		return stderr.String(), fmt.Errorf("ffmpeg failed: %w (stderr: %s)", err, stderr.String())
+	}
+
+	return stderr.String(), nil
+}
+
+// ffmpegStream is a running FFmpeg process whose stdout is read as a stream.
+// A failed process is reported by Read in place of io.EOF. A process that
+// leaves a Read waiting for idle is stopped as stalled, and one that runs
+// past its timeout is stopped as timed out.
+type ffmpegStream struct {
+	cmd     *exec.Cmd
+	stdout  io.ReadCloser
+	stderr  strings.Builder
+	flush   func()
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+
+	idle     time.Duration
+	watchdog *time.Timer
+	stalled  atomic.Bool
+
+	once    sync.Once
+	waitErr error
+}
+
+// start starts pass, which must write to pipe:1, within a timeout scaled by
+// the duration of its media. stdin, when non-nil, is fed to pipe:0.
+func (tc *toolchain) start(ctx context.Context, pass ffmpegPass, stdin io.Reader) (*ffmpegStream, error) {
+	ffmpegPath, err := tc.ffmpeg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := passTimeout(pass.Duration)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	s := &ffmpegStream{ctx: ctx, cancel: cancel, timeout: timeout, idle: tc.idle}
+	s.watchdog = time.AfterFunc(tc.idle, func() {
+		s.stalled.Store(true)
+		cancel()
+	})
+	s.watchdog.Stop()
+	s.cmd = exec.CommandContext(ctx, ffmpegPath, pass.command()...) // #nosec G204 -- ffmpegPath resolved via exec.LookPath
+	s.cmd.Stdin = stdin
+	s.cmd.Stderr, s.flush = tc.stderr(ctx, pass, &s.stderr)
+
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("connecting to ffmpeg output: %w", err)
+	}
+
+	s.stdout = stdout
+
+	if err := s.cmd.Start(); err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+
+	return s, nil
+}
+
+// Read reads FFmpeg's output. At end of output it waits for the process and
+// returns its failure, if any, instead of io.EOF.
+func (s *ffmpegStream) Read(b []byte) (int, error) {
+	s.watchdog.Reset(s.idle)
+	n, err := s.stdout.Read(b)
+	s.watchdog.Stop()
+
+	if errors.Is(err, io.EOF) {
+		if waitErr := s.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err //nolint:wrapcheck // callers compare against io.EOF
+}
+
+// Close stops FFmpeg if it is still running and reaps the process. An
+// early Close is not an error, so the exit status is not reported.
+func (s *ffmpegStream) Close() error {
+	s.cancel()
+	_ = s.wait()
+
+	return nil
+}
+
+// wait reaps the process once and returns its failure, if any.
+func (s *ffmpegStream) wait() error {
+	s.once.Do(func() {
+		err := s.cmd.Wait()
+
+		s.flush()
+
+		switch {
+		case s.stalled.Load():
+			s.waitErr = fmt.Errorf("ffmpeg stalled: no output for %v (stderr: %s)", s.idle, s.stderr.String())
+		case errors.Is(s.ctx.Err(), context.DeadlineExceeded):
+			s.waitErr = fmt.Errorf("ffmpeg timed out after %v (stderr: %s)", s.timeout, s.stderr.String())
+		case err != nil:
+			s.waitErr = fmt.Errorf("ffmpeg failed: %w (stderr: %s)", err, s.stderr.String())
+		}
+
+		s.cancel()
+	})
+
+	return s.waitErr
+}
+
+// progressWriter separates the -progress report FFmpeg interleaves with its
+// log on stderr: each block of key=value lines, ended by a progress line,
+// becomes an event, and every other line is passed on to log.
+type progressWriter struct {
+	log   io.Writer
+	emit  func(FFmpegProgress)
+	event FFmpegProgress
+	// line holds an incomplete line until its newline arrives.
+	line []byte
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n := len(b)
+
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			w.line = append(w.line, b...)
+
+			break
+		}
+
+		w.line = append(w.line, b[:i+1]...)
+		b = b[i+1:]
+
+		if err := w.handle(w.line); err != nil {
+			return n - len(b), err
+		}
+
+		w.line = w.line[:0]
+	}
+
+	return n, nil
+}
+
+// flush passes on an unterminated last line.
+func (w *progressWriter) flush() {
+	if len(w.line) > 0 {
+		_ = w.handle(w.line)
+		w.line = nil
+	}
+}
+
+// handle consumes one line of stderr.
+func (w *progressWriter) handle(line []byte) error {
+	m := progressRe.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+	if m == nil {
+		_, err := w.log.Write(line)
+
+		return err //nolint:wrapcheck // the error goes back to the writing process
+	}
+
+	value := string(m[2])
+
+	switch string(m[1]) {
+	case "out_time_us":
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			w.event.Processed = time.Duration(us) * time.Microsecond
+		}
+	case "speed":
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+			w.event.Speed = speed
+		}
+	case "progress":
+		w.event.Done = value == "end"
+		w.emit(w.event)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shToolchain returns a toolchain that runs sh in place of ffmpeg, so that
+// a pass's arguments are a shell script, and stops streams idle for idle.
+func shToolchain(t *testing.T, idle time.Duration) *toolchain {
+	t.Helper()
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	return &toolchain{
+		ffmpeg: func(context.Context) (string, error) { return sh, nil },
+		idle:   idle,
+		logger: slog.Default(),
+	}
+}
+
+// readStream runs script as a streaming pass of tc and returns everything it
+// wrote to stdout, plus the error that ended the stream.
+func readStream(tc *toolchain, script string) ([]byte, error) {
+	s, err := tc.start(context.Background(), ffmpegPass{Args: []string{"-c", script}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = s.Close() }()
+
+	return io.ReadAll(s)
+}
+
+func TestFFmpegStreamReportsExitStatus(t *testing.T) {
+	t.Parallel()
+
+	tc := shToolchain(t, ffmpegIdleTimeout)
+
+	t.Run("clean exit ends with EOF", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := readStream(tc, "printf audio")
+		if err != nil || string(out) != "audio" {
+			t.Errorf("stream = %q, %v; want \"audio\", nil", out, err)
+		}
+	})
+
+	t.Run("failure replaces EOF", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := readStream(tc, "printf partial; echo broken >&2; exit 3")
+		if string(out) != "partial" {
+			t.Errorf("output = %q; want \"partial\"", out)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), "broken") {
+			t.Errorf("error = %v; want ffmpeg failure with stderr", err)
+		}
+	})
+}
+
+func TestFFmpegStreamStopsWhenStalled(t *testing.T) {
+	t.Parallel()
+
+	tc := shToolchain(t, 100*time.Millisecond)
+
+	start := time.Now()
+
+	// The process writes some output, then hangs like FFmpeg on an input
+	// that stopped sending.
+	out, err := readStream(tc, "printf audio; exec sleep 10")
+	if string(out) != "audio" || err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Errorf("stream = %q, %v; want the output so far and a stall error", out, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stalled stream stopped after %v; want about the idle timeout", elapsed)
+	}
+}
+
+func TestFFmpegStreamLogsStderr(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	tc := shToolchain(t, ffmpegIdleTimeout)
+	tc.logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if _, err := readStream(tc, "echo first >&2; printf last >&2"); err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+
+	for _, line := range []string{"first", "last"} {
+		if !strings.Contains(buf.String(), "stderr="+line) {
+			t.Errorf("log = %q; want a debug record for the stderr line %q", buf.String(), line)
+		}
+	}
+}
+
+func TestResolveOnceRetriesCancelledChecks(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	resolve := resolveOnce(func(ctx context.Context) (string, error) {
+		calls++
+
+		return "ffmpeg", ctx.Err()
+	})
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := resolve(cancelled); err == nil {
+		t.Error("resolving with a cancelled context succeeded; want its error")
+	}
+
+	for range 2 {
+		if path, err := resolve(context.Background()); path != "ffmpeg" || err != nil {
+			t.Errorf("resolve() = %q, %v; want ffmpeg", path, err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("resolver called %d times; want once for the cancelled check and once after", calls)
+	}
+}
+
+func TestParseFFmpegProgress(t *testing.T) {
+	t.Parallel()
+
+	stderr := "Input #0, wav, from 'talk.wav':\n" +
+		"frame=0\nout_time_us=1500000\nout_time=00:00:01.500000\nspeed=N/A\nprogress=continue\n" +
+		"[silencedetect @ 0x5581] silence_start: 2.5\n" +
+		"out_time_us=4000000\nspeed=12.5x\nprogress=end\n" +
+		"size=N/A time=00:00:04.00 bitrate=N/A"
+
+	want := []FFmpegProgress{
+		{Stage: "silence", Processed: 1500 * time.Millisecond, Total: 4 * time.Second},
+		{Stage: "silence", Processed: 4 * time.Second, Total: 4 * time.Second, Speed: 12.5, Done: true},
+	}
+	wantLog := "Input #0, wav, from 'talk.wav':\n[silencedetect @ 0x5581] silence_start: 2.5\n" +
+		"size=N/A time=00:00:04.00 bitrate=N/A"
+
+	// The report must parse the same however FFmpeg's writes split it.
+	for _, n := range []int{1, 7, len(stderr)} {
+		var (
+			events []FFmpegProgress
+			log    strings.Builder
+		)
+
+		w := &progressWriter{
+			log:   &log,
+			emit:  func(p FFmpegProgress) { events = append(events, p) },
+			event: FFmpegProgress{Stage: "silence", Total: 4 * time.Second},
+		}
+
+		for b := []byte(stderr); len(b) > 0; {
+			k := min(n, len(b))
+			_, _ = w.Write(b[:k])
+			b = b[k:]
+		}
+
+		w.flush()
+
+		if !slices.Equal(events, want) {
+			t.Errorf("writes of %d: events = %+v; want %+v", n, events, want)
+		}
+
+		if log.String() != wantLog {
+			t.Errorf("writes of %d: log = %q; want %q", n, log.String(), wantLog)
+		}
+	}
+}
+
+func TestPassTimeout(t *testing.T) {
+	t.Parallel()
+
+	short, long := passTimeout(time.Minute), passTimeout(3*time.Hour)
+	if short >= long || long <= 3*time.Hour {
+		t.Errorf("passTimeout(1m) = %v, passTimeout(3h) = %v; want a timeout growing with the duration", short, long)
+	}
+
+	if unknown := passTimeout(0); unknown < 10*time.Minute {
+		t.Errorf("passTimeout(unknown) = %v; want a generous default", unknown)
+	}
+}
@@ -0,0 +1,76 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build unix
+
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// fakeFFmpeg writes a script to dir that prints the first line of
+// `ffmpeg -version` as a given release would.
+func fakeFFmpeg(t *testing.T, dir, name, version string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\necho '" + name + " version " + version + " Copyright (c) 2000-2025 the FFmpeg developers'\n"
+
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil { // #nosec G306 -- test script must be executable
+		t.Fatalf("writing fake %s: %v", name, err)
+	}
+
+	return path
+}
+
+func TestFFmpegVersionCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		version string
+		wantOld bool
+	}{
+		{version: "6.1.1-3ubuntu5", wantOld: false},
+		{version: "n4.2", wantOld: false},
+		{version: "N-112345-g0123abcd", wantOld: false},
+		{version: "4.1.11", wantOld: true},
+		{version: "3.4.8", wantOld: true},
+	}
+
+	for _, tc := range tests {
+		path := fakeFFmpeg(t, t.TempDir(), "ffmpeg", tc.version)
+
+		got, err := newToolchain(&config.Config{FFmpegPath: path}, slog.Default()).ffmpeg(context.Background())
+		if tc.wantOld {
+			if !errors.Is(err, errTooOld) || !strings.Contains(err.Error(), "4.2 or later") {
+				t.Errorf("version %s: error = %v; want a too-old error naming the minimum", tc.version, err)
+			}
+
+			continue
+		}
+
+		if err != nil || got != path {
+			t.Errorf("version %s: ffmpeg() = %q, %v; want %q", tc.version, got, err, path)
+		}
+	}
+
+	missing := &config.Config{FFmpegPath: filepath.Join(dir, "missing")}
+
+	_, err := newToolchain(missing, slog.Default()).ffmpeg(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "install ffmpeg") {
+		t.Errorf("missing binary: error = %v; want a not-found error", err)
+	}
+}
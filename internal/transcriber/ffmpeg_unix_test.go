@@ -0,0 +1,48 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build unix
+
+package transcriber_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestInspectURL(t *testing.T) {
+	t.Parallel()
+
+	const url = "https://example.com/talk.mp4"
+
+	// The fake ffprobe describes the URL it is given as its last argument
+	// and fails on anything else.
+	path := filepath.Join(t.TempDir(), "ffprobe")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = -version ]; then echo 'ffprobe version 6.1 Copyright (c) the FFmpeg developers'; exit; fi\n" +
+		"for last; do :; done\n" +
+		"[ \"$last\" = '" + url + "' ] || exit 1\n" +
+		"echo '{\"streams\": [], \"format\": {\"format_name\": \"mov,mp4\", \"duration\": \"60.0\"}}'\n"
+
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil { // #nosec G306 -- test script must be executable
+		t.Fatalf("writing fake ffprobe: %v", err)
+	}
+
+	info, err := transcriber.Inspect(context.Background(), &config.Config{FFprobePath: path}, slog.Default(), url)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+
+	if info.Path != url || info.Duration != time.Minute {
+		t.Errorf("Inspect() = %+v; want the URL probed by ffprobe", info)
+	}
+}
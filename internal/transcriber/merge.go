@@ -0,0 +1,203 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+const (
+	// minSeamOverlapWords is the shortest repeated word run treated as
+	// duplicated speech at a chunk seam; shorter matches are too likely to be
+	// coincidence ("і я", "and the").
+	minSeamOverlapWords = 3
+
+	// maxSeamOverlapWords bounds the seam search.
+	maxSeamOverlapWords = 60
+)
+
+// chunkTranscript pairs a chunk with the backend's answer for it.
+type chunkTranscript struct {
+	chunk      *audioChunk
+	transcript *gemini.Transcript
+}
+
+// mergeChunkTranscripts stitches per-chunk transcripts, given in chunk
+// order, into a single transcript on the source timeline.
+//
+// Segment timestamps are shifted by each chunk's offset. A segment is kept
+// by the chunk whose owned range contains its start, which drops most of the
+// speech transcribed twice in the overlap regions; any words still repeated
+// across a seam are then removed by matching the tail of the merged text
+// against the head of the next chunk.
+func mergeChunkTranscripts(parts []chunkTranscript) *gemini.Transcript {
+	var (
+		merged []gemini.Segment
+		usage  gemini.Usage
+	)
+
+	for _, part := range parts {
+		usage = usage.Add(part.transcript.Usage)
+		segments := chunkSegments(part)
+		segments = trimSeamOverlap(merged, segments)
+		merged = append(merged, segments...)
+	}
+
+	texts := make([]string, 0, len(merged))
+	for _, seg := range merged {
+		texts = append(texts, seg.Text)
+	}
+
+	return &gemini.Transcript{Text: strings.Join(texts, "\n"), Segments: merged, Usage: usage}
+}
+
+// chunkSegments returns the segments of one chunk that fall inside its owned
+// range, shifted onto the source timeline. A transcript without timing is
+// treated as a single segment spanning the owned range.
+func chunkSegments(part chunkTranscript) []gemini.Segment {
+	c, t := part.chunk, part.transcript
+
+	if len(t.Segments) == 0 {
+		end := c.End
+		if end == 0 {
+			end = c.Offset + c.Duration
+		}
+
+		return []gemini.Segment{{Start: c.Start, End: end, Text: t.Text}}
+	}
+
+	out := make([]gemini.Segment, 0, len(t.Segments))
+
+	for _, seg := range t.Segments {
+		seg.Start += c.Offset
+		seg.End += c.Offset
+
+		if seg.Start < c.Start {
+			continue
+		}
+
+		if c.End > 0 && seg.Start >= c.End {
+			continue
+		}
+
+		out = append(out, seg)
+	}
+
+	return out
+}
+
+// trimSeamOverlap removes from the head of next any words that repeat the
+// tail of prev, and returns the remaining segments.
+func trimSeamOverlap(prev, next []gemini.Segment) []gemini.Segment {
+	if len(prev) == 0 || len(next) == 0 {
+		return next
+	}
+
+	tail := lastWords(prev, maxSeamOverlapWords)
+	head := firstWords(next, maxSeamOverlapWords)
+
+	n := seamOverlap(tail, head)
+	if n == 0 {
+		return next
+	}
+
+	return dropLeadingWords(next, n)
+}
+
+// seamOverlap returns the length of the longest run of words that ends tail
+// and starts head, or zero when it is shorter than minSeamOverlapWords.
+func seamOverlap(tail, head []string) int {
+	for n := min(len(tail), len(head)); n >= minSeamOverlapWords; n-- {
+		if equalWords(tail[len(tail)-n:], head[:n]) {
+			return n
+		}
+	}
+
+	return 0
+}
+
+// equalWords compares two word slices after normalisation.
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if normalizeWord(a[i]) != normalizeWord(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeWord lowercases w and strips surrounding punctuation.
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
+
+// lastWords returns up to n words from the end of segs.
+func lastWords(segs []gemini.Segment, n int) []string {
+	var words []string
+
+	for i := len(segs) - 1; i >= 0 && len(words) < n; i-- {
+		words = append(strings.Fields(segs[i].Text), words...)
+	}
+
+	if len(words) > n {
+		words = words[len(words)-n:]
+	}
+
+	return words
+}
+
+// firstWords returns up to n words from the start of segs.
+func firstWords(segs []gemini.Segment, n int) []string {
+	var words []string
+
+	for i := 0; i < len(segs) && len(words) < n; i++ {
+		words = append(words, strings.Fields(segs[i].Text)...)
+	}
+
+	if len(words) > n {
+		words = words[:n]
+	}
+
+	return words
+}
+
+// dropLeadingWords removes the first n words from segs, dropping segments
+// that become empty. The start of a partially trimmed segment is moved
+// proportionally to the words removed.
+func dropLeadingWords(segs []gemini.Segment, n int) []gemini.Segment {
+	out := make([]gemini.Segment, 0, len(segs))
+
+	for _, seg := range segs {
+		if n == 0 {
+			out = append(out, seg)
+
+			continue
+		}
+
+		words := strings.Fields(seg.Text)
+		if n >= len(words) {
+			n -= len(words)
+
+			continue
+		}
+
+		span := seg.End - seg.Start
+		seg.Start += time.Duration(int64(span) * int64(n) / int64(len(words)))
+		seg.Text = strings.Join(words[n:], " ")
+		n = 0
+
+		out = append(out, seg)
+	}
+
+	return out
+}
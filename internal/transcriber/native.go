@@ -0,0 +1,253 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// pcmCodecName returns ffprobe's codec name for samples in format f.
+func pcmCodecName(f audio.Format) string {
+	switch {
+	case f.Float:
+		return fmt.Sprintf("pcm_f%dle", f.BitDepth)
+	case f.BitDepth == 8:
+		return "pcm_u8"
+	default:
+		return fmt.Sprintf("pcm_s%dle", f.BitDepth)
+	}
+}
+
+// nativeInfo describes a WAV or raw PCM source from its header alone, so
+// that durations and channel counts are known without ffprobe. It reports
+// false for other inputs and for WAV encodings the audio package cannot
+// read. Pipes are not described, as reading the header would consume them.
+func (s *mediaSource) nativeInfo() (*MediaInfo, bool) {
+	if s.piped() {
+		return nil, false
+	}
+
+	format, data, err := s.openPCM()
+	if err != nil {
+		return nil, false
+	}
+
+	_ = data.Close()
+
+	duration := format.Duration(data.N / int64(format.FrameSize()))
+	info := &MediaInfo{
+		Path:      s.Path,
+		Container: "wav",
+		Duration:  duration,
+		Size:      s.Size,
+		BitRate:   int64(format.ByteRate()) * 8,
+		Streams: []StreamInfo{{
+			Type:       "audio",
+			Codec:      pcmCodecName(format),
+			SampleRate: format.SampleRate,
+			Channels:   format.Channels,
+			Duration:   duration,
+		}},
+	}
+
+	if s.MIMEType == "audio/pcm" {
+		info.Container = pcmCodecName(format)[len("pcm_"):]
+	}
+
+	return info, true
+}
+
+// pcmData is the sample data of an open WAV or raw PCM file or pipe. N is
+// the number of bytes of sample data left to read.
+type pcmData struct {
+	*io.LimitedReader
+	c io.Closer
+}
+
+// Close closes the file. Implements io.Closer.
+func (d *pcmData) Close() error {
+	if err := d.c.Close(); err != nil {
+		return fmt.Errorf("closing audio file: %w", err)
+	}
+
+	return nil
+}
+
+// skip discards the next n bytes of sample data, seeking past them in a
+// file and reading through them in a pipe.
+func (d *pcmData) skip(n int64) error {
+	n = min(n, d.N)
+
+	if f, ok := d.R.(*os.File); ok {
+		if _, err := f.Seek(n, io.SeekCurrent); err != nil {
+			return fmt.Errorf("seeking audio file: %w", err)
+		}
+
+		d.N -= n
+
+		return nil
+	}
+
+	if _, err := io.CopyN(io.Discard, d, n); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("skipping audio: %w", err)
+	}
+
+	return nil
+}
+
+// openPCM opens a WAV or raw PCM source positioned at the start of its
+// sample data. Raw PCM has no header and is read as defaultFormat.
+func (s *mediaSource) openPCM() (audio.Format, *pcmData, error) {
+	if s.MIMEType != "audio/wav" && s.MIMEType != "audio/pcm" {
+		return audio.Format{}, nil, fmt.Errorf("%w: %s", audio.ErrNotWAV, s.MIMEType)
+	}
+
+	if s.piped() && s.MIMEType == "audio/wav" {
+		// Check the header before the pipe is consumed, so that FFmpeg can
+		// still read an encoding the audio package cannot.
+		if _, err := audio.ReadHeader(bytes.NewReader(s.pipe.head)); err != nil {
+			return audio.Format{}, nil, err //nolint:wrapcheck // already describes the read that failed
+		}
+	}
+
+	r, err := s.open()
+	if err != nil {
+		return audio.Format{}, nil, err
+	}
+
+	// A pipe has no size; its data runs until the writer closes it.
+	format, size := defaultFormat, s.Size
+	if size < 0 {
+		size = math.MaxInt64
+	}
+
+	if s.MIMEType == "audio/wav" {
+		h, err := audio.ReadHeader(r)
+		if err != nil {
+			_ = r.Close()
+
+			return audio.Format{}, nil, err //nolint:wrapcheck // already describes the read that failed
+		}
+
+		// A streamed header declares a placeholder size; the data then runs
+		// to the end of the file.
+		format, size = h.Format, min(h.DataSize, size)
+	}
+
+	return format, &pcmData{LimitedReader: &io.LimitedReader{R: r, N: size}, c: r}, nil
+}
+
+// decodesNatively reports whether src can be decoded as described by opts
+// without FFmpeg: a WAV or raw PCM file with no stream mapping and no audio
+// filters beyond range and channel selection.
+func decodesNatively(src *mediaSource, opts prepareOptions) bool {
+	return (src.MIMEType == "audio/wav" || src.MIMEType == "audio/pcm") &&
+		src.StreamMap == "" && len(opts.Filters) == 0
+}
+
+// decodeNative decodes a WAV or raw PCM source in Go: it cuts the selected
+// time ranges, keeps the selected channel and converts the samples to
+// opts.Format, streaming the result as PCM WAV like FFmpeg would.
+func decodeNative(
+	ctx context.Context, src *mediaSource, opts prepareOptions, logger *slog.Logger,
+) (*PreparedAudio, error) {
+	format, data, err := src.openPCM()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "decoding audio without FFmpeg",
+		slog.String("path", src.Path),
+		slog.String("format", format.String()),
+	)
+
+	r, err := nativeStream(data, format, src, opts)
+	if err != nil {
+		_ = data.Close()
+
+		return nil, err
+	}
+
+	return &PreparedAudio{
+		MIMEType: "audio/wav",
+		Size:     -1,
+		r:        decodedStream{Reader: audio.NewStream(opts.Format, r), Closer: data},
+		source:   src,
+	}, nil
+}
+
+// decodedStream is a stream decoded in Go from an open file.
+type decodedStream struct {
+	io.Reader
+	io.Closer
+}
+
+// nativeStream builds the PCM pipeline of decodeNative over data.
+func nativeStream(data *pcmData, format audio.Format, src *mediaSource, opts prepareOptions) (io.Reader, error) {
+	if start := format.Frames(src.Window.Start) * int64(format.FrameSize()); start > 0 {
+		if err := data.skip(start); err != nil {
+			return nil, err
+		}
+	}
+
+	var r io.Reader = data
+	if ranges := src.windowRanges(); ranges != nil {
+		r = audio.Select(r, format, ranges)
+	}
+
+	if opts.Channel > 0 {
+		var err error
+		if r, err = audio.ExtractChannel(r, format, opts.Channel-1); err != nil {
+			return nil, err //nolint:wrapcheck // already names the channel
+		}
+
+		format.Channels = 1
+	}
+
+	r, err := audio.Convert(r, format, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s audio: %w", format, err)
+	}
+
+	return r, nil
+}
+
+// windowRanges returns the selected ranges relative to the window start,
+// for cutting the gaps out in Go, or nil when nothing needs cutting after
+// the window start has been skipped.
+func (s *mediaSource) windowRanges() []audio.Range {
+	if s.Window.Length == 0 && len(s.Gaps) == 0 {
+		return nil
+	}
+
+	var (
+		ranges []audio.Range
+		start  time.Duration
+	)
+
+	for _, gap := range s.Gaps {
+		ranges = append(ranges, audio.Range{Start: start, End: gap.Start})
+		start = gap.End()
+	}
+
+	return append(ranges, audio.Range{Start: start, End: s.Window.Length})
+}
+
+// errNativeUnsupported reports whether err means the audio package cannot
+// read a source, so FFmpeg should be tried instead.
+func errNativeUnsupported(err error) bool {
+	return errors.Is(err, audio.ErrNotWAV) || errors.Is(err, audio.ErrUnsupported)
+}
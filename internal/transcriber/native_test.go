@@ -0,0 +1,159 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// hiFiWAV returns a 48 kHz stereo 24-bit WAV lasting total, whose left
+// channel is silent and right channel carries a full-scale square wave.
+func hiFiWAV(total time.Duration) []byte {
+	format := audio.Format{SampleRate: 48000, Channels: 2, BitDepth: 24}
+	pcm := make([]byte, format.Frames(total)*int64(format.FrameSize()))
+
+	for i := 0; i < len(pcm); i += format.FrameSize() {
+		if (i/format.FrameSize())%96 < 48 {
+			copy(pcm[i+3:], []byte{0xFF, 0xFF, 0x3F})
+		}
+	}
+
+	return audio.Encode(format, pcm)
+}
+
+// transcribeWithoutFFmpeg transcribes data, written to a file called name,
+// with neither ffmpeg nor ffprobe on PATH and returns the uploaded payloads.
+func transcribeWithoutFFmpeg(t *testing.T, name string, data []byte, cfg *config.Config) [][]byte {
+	t.Helper()
+	t.Setenv("PATH", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg.Quiet = true
+	rec := &requestRecorder{}
+
+	if _, err := newTranscriber(t, cfg, rec).TranscribeLocalFile(context.Background(), path); err != nil {
+		t.Fatalf("TranscribeLocalFile() error = %v", err)
+	}
+
+	return rec.payloads
+}
+
+// payloadHeader parses an uploaded WAV and returns its format and duration.
+func payloadHeader(t *testing.T, payload []byte) (audio.Format, time.Duration) {
+	t.Helper()
+
+	h, err := audio.ReadHeader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("uploaded audio is not WAV: %v", err)
+	}
+
+	d, _ := audio.ParseDuration(payload)
+
+	return h.Format, d
+}
+
+func TestNativeDecodeExcerpt(t *testing.T) {
+	payloads := transcribeWithoutFFmpeg(t, "studio.wav", hiFiWAV(10*time.Second),
+		&config.Config{Start: "2", End: "5"})
+
+	if len(payloads) != 1 {
+		t.Fatalf("got %d requests; want 1", len(payloads))
+	}
+
+	format, d := payloadHeader(t, payloads[0])
+	if format != audio.Speech {
+		t.Errorf("uploaded format = %v; want %v", format, audio.Speech)
+	}
+
+	if d != 3*time.Second {
+		t.Errorf("uploaded duration = %v; want 3s", d)
+	}
+}
+
+func TestNativeDecodeRanges(t *testing.T) {
+	ranges := filepath.Join(t.TempDir(), "ranges.txt")
+	if err := os.WriteFile(ranges, []byte("0 1.5\n4 # to the end\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := transcribeWithoutFFmpeg(t, "talk.wav", synthWAV(6*time.Second),
+		&config.Config{RangesFile: ranges, SampleRate: 8000})
+
+	if len(payloads) != 1 {
+		t.Fatalf("got %d requests; want 1", len(payloads))
+	}
+
+	format, d := payloadHeader(t, payloads[0])
+	if format.SampleRate != 8000 || d != 3500*time.Millisecond {
+		t.Errorf("uploaded %v lasting %v; want 8000 Hz lasting 3.5s", format, d)
+	}
+}
+
+func TestNativeDecodeSplitChannels(t *testing.T) {
+	payloads := transcribeWithoutFFmpeg(t, "call.wav", hiFiWAV(2*time.Second),
+		&config.Config{SplitChannels: true})
+
+	if len(payloads) != 2 {
+		t.Fatalf("got %d requests; want one per channel", len(payloads))
+	}
+
+	// The silent left channel must not pick up the right channel's signal.
+	for ch, payload := range payloads {
+		format, _ := payloadHeader(t, payload)
+		if format.Channels != 1 {
+			t.Errorf("channel %d uploaded as %v; want mono", ch+1, format)
+		}
+
+		silent := bytes.Count(payload[audio.HeaderSize:], []byte{0}) == len(payload)-audio.HeaderSize
+		if silent != (ch == 0) {
+			t.Errorf("channel %d silent = %v; want %v", ch+1, silent, ch == 0)
+		}
+	}
+}
+
+func TestNativeDecodeRawPCM(t *testing.T) {
+	pcm := make([]byte, 4*audio.Speech.ByteRate())
+
+	payloads := transcribeWithoutFFmpeg(t, "dump.pcm", pcm, &config.Config{Start: "1"})
+
+	if len(payloads) != 1 {
+		t.Fatalf("got %d requests; want 1", len(payloads))
+	}
+
+	if _, d := payloadHeader(t, payloads[0]); d != 3*time.Second {
+		t.Errorf("uploaded duration = %v; want 3s", d)
+	}
+}
+
+func TestNativeDecodeRejectsRangesPastTheEnd(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "short.wav")
+	if err := os.WriteFile(path, synthWAV(2*time.Second), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The WAV header gives the duration ffprobe would have.
+	cfg := &config.Config{Quiet: true, Start: "5"}
+
+	_, err := newTranscriber(t, cfg, &requestRecorder{}).TranscribeLocalFile(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "after the end of the media") {
+		t.Errorf("TranscribeLocalFile() error = %v; want range past the end", err)
+	}
+}
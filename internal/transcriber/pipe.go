@@ -0,0 +1,173 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdinPath is the input path that reads media from standard input.
+const StdinPath = "-"
+
+// errReadOnce is returned when a piped input is needed a second time.
+var errReadOnce = errors.New("piped input can only be read once")
+
+// pipeInput is media read from standard input or a named pipe. It can be
+// consumed only once, by whichever stage decodes or uploads it; the bytes
+// read ahead to classify it are replayed first.
+type pipeInput struct {
+	r *bufio.Reader
+	// head is the start of the stream, read ahead for sniffing.
+	head []byte
+	// f is the named pipe, closed with the source; nil for standard input.
+	f     *os.File
+	taken bool
+}
+
+// take returns the stream for its one and only reader.
+func (p *pipeInput) take() (io.Reader, error) {
+	if p.taken {
+		return nil, errReadOnce
+	}
+
+	p.taken = true
+
+	return p.r, nil
+}
+
+// isPipe reports whether mode describes a pipe or socket, which is read as
+// a stream rather than as a file.
+func isPipe(mode os.FileMode) bool {
+	return mode&(os.ModeNamedPipe|os.ModeSocket) != 0
+}
+
+// openPipe classifies the media arriving on r, named path, by its content,
+// falling back to the extension of path. f, when non-nil, is the named
+// pipe r reads from and is closed with the source.
+func openPipe(path string, r io.Reader, f *os.File) (*mediaSource, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+
+	head, err := br.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+
+	if len(head) == 0 {
+		return nil, fmt.Errorf("input is empty: %s", path)
+	}
+
+	inputType, mimeType := classifyInputFile(path)
+	src := &mediaSource{
+		Path:     path,
+		Type:     inputType,
+		MIMEType: mimeType,
+		Size:     -1,
+		pipe:     &pipeInput{r: br, head: head, f: f},
+	}
+
+	if sniffed, ok := sniff(bytes.NewReader(head), int64(len(head))); ok {
+		src.classify(sniffed)
+	}
+
+	return src, nil
+}
+
+// openInput opens inputPath with openLocalSource, or stdin when it is
+// StdinPath. URLs, which reach it from Inspect, are left to ffprobe.
+func openInput(inputPath string, stdin io.Reader) (*mediaSource, error) {
+	switch {
+	case inputPath == StdinPath:
+		return openPipe(StdinPath, stdin, nil)
+	case IsURL(inputPath):
+		return urlSource(inputPath), nil
+	default:
+		return openLocalSource(inputPath)
+	}
+}
+
+// checkPiped returns an error when the configuration needs more than one
+// pass over the input, which a pipe does not allow.
+func (t *Transcriber) checkPiped() error {
+	var flag string
+
+	switch {
+	case t.config.TrimSilence:
+		flag = "--trim-silence"
+	case t.config.SplitChannels:
+		flag = "--split-channels"
+	case t.config.AllAudioStreams:
+		flag = "--all-audio-streams"
+	case t.config.Subtitles != "":
+		flag = "--subtitles"
+	case t.config.AnalyzeAudio():
+		flag = "audio analysis"
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("%s reads the input more than once, but %w; save it to a file first", flag, errReadOnce)
+}
+
+// piped reports whether s is read from standard input or a named pipe.
+func (s *mediaSource) piped() bool {
+	return s.pipe != nil
+}
+
+// open returns the media data of s from the start: the file, reopened, or
+// the pipe, which can be opened only once.
+func (s *mediaSource) open() (io.ReadCloser, error) {
+	if s.pipe != nil {
+		r, err := s.pipe.take()
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NopCloser(r), nil
+	}
+
+	f, err := os.Open(s.Path) // #nosec G304 -- s.Path validated by openLocalSource
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+
+	return f, nil
+}
+
+// ffmpegInput returns what FFmpeg reads on stdin for s: the pipe, or nil
+// for a file, which FFmpeg opens itself.
+func (s *mediaSource) ffmpegInput() (io.Reader, error) {
+	if s.pipe == nil {
+		return nil, nil
+	}
+
+	return s.pipe.take()
+}
+
+// Close closes the named pipe behind s and removes a downloaded copy of the
+// input, if any. Files are opened and closed by each stage that reads
+// them. Implements io.Closer.
+func (s *mediaSource) Close() error {
+	if s.tempFile != "" {
+		if err := os.Remove(s.tempFile); err != nil {
+			return fmt.Errorf("removing downloaded input: %w", err)
+		}
+	}
+
+	if s.pipe == nil || s.pipe.f == nil {
+		return nil
+	}
+
+	if err := s.pipe.f.Close(); err != nil {
+		return fmt.Errorf("closing input pipe: %w", err)
+	}
+
+	return nil
+}
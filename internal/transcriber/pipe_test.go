@@ -0,0 +1,120 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// onlyReader hides everything but Read, as a pipe would.
+type onlyReader struct{ r *bytes.Reader }
+
+func (o onlyReader) Read(b []byte) (int, error) { return o.r.Read(b) }
+
+// transcribeStdin transcribes data read from standard input, with neither
+// ffmpeg nor ffprobe on PATH.
+func transcribeStdin(t *testing.T, data []byte, cfg *config.Config) (*requestRecorder, error) {
+	t.Helper()
+	t.Setenv("PATH", t.TempDir())
+
+	cfg.Quiet = true
+	rec := &requestRecorder{}
+
+	tr := newTranscriber(t, cfg, rec)
+	tr.SetStdin(onlyReader{bytes.NewReader(data)})
+
+	_, err := tr.TranscribeLocalFile(context.Background(), transcriber.StdinPath)
+
+	return rec, err
+}
+
+func TestStdinNativeAudioIsSentAsIs(t *testing.T) {
+	wav := synthWAV(2 * time.Second)
+
+	rec, err := transcribeStdin(t, wav, &config.Config{})
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile(-) error = %v", err)
+	}
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("got %d requests; want 1", len(rec.requests))
+	}
+
+	if req := rec.requests[0]; req.MIMEType != "audio/wav" || req.Size != -1 {
+		t.Errorf("request MIME %q size %d; want audio/wav of unknown size", req.MIMEType, req.Size)
+	}
+
+	if !bytes.Equal(rec.payloads[0], wav) {
+		t.Error("piped audio was not uploaded unchanged")
+	}
+}
+
+func TestStdinWAVIsChunkedWithoutFFmpeg(t *testing.T) {
+	cfg := &config.Config{ChunkDuration: 4 * time.Second, ChunkOverlap: time.Second, Start: "1"}
+
+	rec, err := transcribeStdin(t, hiFiWAV(10*time.Second), cfg)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile(-) error = %v", err)
+	}
+
+	if len(rec.payloads) < 2 {
+		t.Fatalf("got %d requests; want the 9s after --start chunked", len(rec.payloads))
+	}
+
+	var total time.Duration
+
+	for _, payload := range rec.payloads {
+		format, d := payloadHeader(t, payload)
+		if format != audio.Speech {
+			t.Errorf("uploaded format = %v; want %v", format, audio.Speech)
+		}
+
+		total += d
+	}
+
+	// Chunks overlap, so together they last at least the 9s selected.
+	if total < 9*time.Second {
+		t.Errorf("chunks last %v in total; want at least 9s", total)
+	}
+}
+
+func TestStdinInputFormat(t *testing.T) {
+	// Silence has no magic bytes; --input-format says what it is.
+	pcm := make([]byte, 2*audio.Speech.ByteRate())
+
+	rec, err := transcribeStdin(t, pcm, &config.Config{InputFormat: "pcm"})
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile(-) error = %v", err)
+	}
+
+	if len(rec.requests) != 1 || rec.requests[0].MIMEType != "audio/pcm" {
+		t.Fatalf("requests = %+v; want one audio/pcm request", rec.requests)
+	}
+}
+
+func TestStdinRejectsMultiplePasses(t *testing.T) {
+	_, err := transcribeStdin(t, synthWAV(time.Second), &config.Config{
+		TrimSilence: true, SilenceThreshold: -45, SilenceMinDuration: 3 * time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), "--trim-silence") {
+		t.Errorf("TranscribeLocalFile(-) error = %v; want --trim-silence rejected", err)
+	}
+}
+
+func TestStdinEmpty(t *testing.T) {
+	_, err := transcribeStdin(t, nil, &config.Config{})
+	if err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("TranscribeLocalFile(-) error = %v; want empty input", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build unix
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func TestNamedPipe(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Skipf("cannot create a named pipe: %v", err)
+	}
+
+	// The pipe has no extension; the writer sends WAV.
+	wav := synthWAV(2 * time.Second)
+
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+
+		_, _ = f.Write(wav)
+		_ = f.Close()
+	}()
+
+	rec := &requestRecorder{}
+
+	_, err := newTranscriber(t, &config.Config{Quiet: true}, rec).
+		TranscribeLocalFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile(fifo) error = %v", err)
+	}
+
+	if len(rec.requests) != 1 || rec.requests[0].MIMEType != "audio/wav" || !bytes.Equal(rec.payloads[0], wav) {
+		t.Errorf("piped WAV was not uploaded unchanged: %d requests", len(rec.requests))
+	}
+}
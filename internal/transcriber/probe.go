@@ -0,0 +1,276 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// probeTimeout bounds a single ffprobe run; probing reads only headers.
+const probeTimeout = time.Minute
+
+// ErrNoAudioStream is returned for inputs that contain no audio to transcribe.
+var ErrNoAudioStream = errors.New("no audio stream found")
+
+// errNoFFprobe is returned when ffprobe is not installed. Callers that can
+// work from the file extension alone fall back to doing so.
+var errNoFFprobe = errors.New("ffprobe not found")
+
+// probeFormatMIME maps ffprobe container names to the Gemini MIME type of
+// audio-only files in that container, so inputs with unfamiliar extensions
+// can still be sent without extraction.
+var probeFormatMIME = map[string]string{
+	"wav":  "audio/wav",
+	"mp3":  "audio/mp3",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+}
+
+// MediaInfo describes a media file as reported by ffprobe.
+type MediaInfo struct {
+	Path string `json:"path"`
+	// Container is ffprobe's format name, e.g. "mov,mp4,m4a,3gp,3g2,mj2".
+	Container     string        `json:"container"`
+	ContainerName string        `json:"container_name,omitempty"`
+	Duration      time.Duration `json:"-"`
+	Size          int64         `json:"size"`
+	BitRate       int64         `json:"bit_rate,omitempty"`
+	Streams       []StreamInfo  `json:"streams"`
+}
+
+// StreamInfo describes one stream of a media file.
+type StreamInfo struct {
+	Index int `json:"index"`
+	// Type is "audio", "video", "subtitle", "data" or "attachment".
+	Type          string        `json:"type"`
+	Codec         string        `json:"codec"`
+	CodecName     string        `json:"codec_name,omitempty"`
+	SampleRate    int           `json:"sample_rate,omitempty"`
+	Channels      int           `json:"channels,omitempty"`
+	ChannelLayout string        `json:"channel_layout,omitempty"`
+	Width         int           `json:"width,omitempty"`
+	Height        int           `json:"height,omitempty"`
+	Duration      time.Duration `json:"-"`
+	Language      string        `json:"language,omitempty"`
+	Title         string        `json:"title,omitempty"`
+	Default       bool          `json:"default,omitempty"`
+	// AttachedPicture marks cover art, which ffprobe lists as a video stream.
+	AttachedPicture bool `json:"attached_picture,omitempty"`
+}
+
+// MarshalJSON encodes the media info with its duration in seconds.
+func (m MediaInfo) MarshalJSON() ([]byte, error) {
+	type plain MediaInfo
+
+	data, err := json.Marshal(struct {
+		plain
+		Duration float64 `json:"duration"`
+	}{plain(m), m.Duration.Seconds()})
+	if err != nil {
+		return nil, fmt.Errorf("encoding media info: %w", err)
+	}
+
+	return data, nil
+}
+
+// MarshalJSON encodes the stream with its duration in seconds.
+func (s StreamInfo) MarshalJSON() ([]byte, error) {
+	type plain StreamInfo
+
+	data, err := json.Marshal(struct {
+		plain
+		Duration float64 `json:"duration,omitempty"`
+	}{plain(s), s.Duration.Seconds()})
+	if err != nil {
+		return nil, fmt.Errorf("encoding stream info: %w", err)
+	}
+
+	return data, nil
+}
+
+// AudioStreams returns the audio streams in index order.
+func (m *MediaInfo) AudioStreams() []StreamInfo {
+	var out []StreamInfo
+
+	for _, s := range m.Streams {
+		if s.Type == "audio" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// HasVideo reports whether the file has a video stream other than cover art.
+func (m *MediaInfo) HasVideo() bool {
+	return slices.ContainsFunc(m.Streams, func(s StreamInfo) bool {
+		return s.Type == "video" && !s.AttachedPicture
+	})
+}
+
+// Inspect runs the ffprobe configured by cfg on path, or on standard input
+// for StdinPath, and returns what it reports.
+func Inspect(ctx context.Context, cfg *config.Config, logger *slog.Logger, path string) (*MediaInfo, error) {
+	src, err := openInput(path, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = src.Close() }()
+
+	return newToolchain(cfg, logger).probe(ctx, src)
+}
+
+// probe runs ffprobe on src.
+func (tc *toolchain) probe(ctx context.Context, src *mediaSource) (*MediaInfo, error) {
+	ffprobePath, err := tc.ffprobe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	args := append([]string{"-v", "error", "-print_format", "json", "-show_format", "-show_streams"},
+		src.inputArgs()...)
+
+	stdin, err := src.ffmpegInput()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, ffprobePath, args...) // #nosec G204 -- ffprobePath resolved via exec.LookPath
+	cmd.Stdin = stdin
+
+	var stdout, stderr strings.Builder
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w (stderr: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := parseProbeOutput([]byte(stdout.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	info.Path = src.Path
+
+	return info, nil
+}
+
+// probeOutput is the subset of `ffprobe -print_format json -show_format
+// -show_streams` output that is used. ffprobe prints most numbers as strings.
+type probeOutput struct {
+	Format struct {
+		FormatName     string `json:"format_name"`
+		FormatLongName string `json:"format_long_name"`
+		Duration       string `json:"duration"`
+		Size           string `json:"size"`
+		BitRate        string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		CodecType     string            `json:"codec_type"`
+		CodecName     string            `json:"codec_name"`
+		CodecLongName string            `json:"codec_long_name"`
+		SampleRate    string            `json:"sample_rate"`
+		Channels      int               `json:"channels"`
+		ChannelLayout string            `json:"channel_layout"`
+		Width         int               `json:"width"`
+		Height        int               `json:"height"`
+		Duration      string            `json:"duration"`
+		Disposition   map[string]int    `json:"disposition"`
+		Tags          map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+// parseProbeOutput decodes ffprobe's JSON report.
+func parseProbeOutput(data []byte) (*MediaInfo, error) {
+	var out probeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("decoding ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{
+		Container:     out.Format.FormatName,
+		ContainerName: out.Format.FormatLongName,
+		Duration:      parseSeconds(out.Format.Duration),
+		Size:          parseInt(out.Format.Size),
+		BitRate:       parseInt(out.Format.BitRate),
+		Streams:       make([]StreamInfo, 0, len(out.Streams)),
+	}
+
+	for _, s := range out.Streams {
+		info.Streams = append(info.Streams, StreamInfo{
+			Index:           s.Index,
+			Type:            s.CodecType,
+			Codec:           s.CodecName,
+			CodecName:       s.CodecLongName,
+			SampleRate:      int(parseInt(s.SampleRate)),
+			Channels:        s.Channels,
+			ChannelLayout:   s.ChannelLayout,
+			Width:           s.Width,
+			Height:          s.Height,
+			Duration:        parseSeconds(s.Duration),
+			Language:        s.Tags["language"],
+			Title:           s.Tags["title"],
+			Default:         s.Disposition["default"] != 0,
+			AttachedPicture: s.Disposition["attached_pic"] != 0,
+		})
+	}
+
+	slices.SortFunc(info.Streams, func(a, b StreamInfo) int { return a.Index - b.Index })
+
+	return info, nil
+}
+
+// parseInt parses a decimal integer, returning 0 for empty or invalid input.
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
+
+// applyInfo refines the extension-based classification of src with what
+// ffprobe found. Files without audio are rejected; native audio extensions
+// that turn out to carry video need extraction; audio-only files in a
+// container Gemini reads natively need none, whatever their extension.
+func (s *mediaSource) applyInfo(info *MediaInfo) error {
+	s.Info = info
+
+	if len(info.AudioStreams()) == 0 {
+		return fmt.Errorf("%s: %w", s.Path, ErrNoAudioStream)
+	}
+
+	switch {
+	case info.HasVideo():
+		s.Type, s.MIMEType = InputTypeVideo, ""
+	case s.Type != InputTypeAudio:
+		if mimeType, ok := probeFormatMIME[info.Container]; ok {
+			s.Type, s.MIMEType = InputTypeAudio, mimeType
+		}
+	}
+
+	return nil
+}
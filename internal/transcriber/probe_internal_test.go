@@ -0,0 +1,195 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// probeVideo is trimmed `ffprobe -print_format json -show_format
+// -show_streams` output for a video with two audio tracks.
+const probeVideo = `{
+  "streams": [
+    {"index": 2, "codec_name": "aac", "codec_long_name": "AAC (Advanced Audio Coding)", "codec_type": "audio",
+     "sample_rate": "44100", "channels": 2, "channel_layout": "stereo", "duration": "3600.000000",
+     "disposition": {"default": 0}, "tags": {"language": "eng", "title": "Commentary"}},
+    {"index": 0, "codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080,
+     "disposition": {"default": 1, "attached_pic": 0}},
+    {"index": 1, "codec_name": "aac", "codec_type": "audio", "sample_rate": "48000", "channels": 6,
+     "channel_layout": "5.1", "disposition": {"default": 1}, "tags": {"language": "ukr"}}
+  ],
+  "format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "format_long_name": "QuickTime / MOV",
+             "duration": "3600.512000", "size": "734003200", "bit_rate": "1630000"}
+}`
+
+// probeMP3 describes an MP3 with embedded cover art.
+const probeMP3 = `{
+  "streams": [
+    {"index": 0, "codec_name": "mp3", "codec_type": "audio", "sample_rate": "44100", "channels": 2},
+    {"index": 1, "codec_name": "mjpeg", "codec_type": "video", "width": 500, "height": 500,
+     "disposition": {"attached_pic": 1}}
+  ],
+  "format": {"format_name": "mp3", "duration": "180.0"}
+}`
+
+// probeOgg describes an audio-only Ogg/Opus file.
+const probeOgg = `{
+  "streams": [{"index": 0, "codec_name": "opus", "codec_type": "audio", "sample_rate": "48000", "channels": 1}],
+  "format": {"format_name": "ogg", "duration": "12.5"}
+}`
+
+// probeSilentVideo describes a screen recording without an audio track.
+const probeSilentVideo = `{
+  "streams": [{"index": 0, "codec_name": "vp9", "codec_type": "video", "width": 1280, "height": 720}],
+  "format": {"format_name": "matroska,webm", "duration": "60.0"}
+}`
+
+// backendFunc adapts a function to gemini.AudioTranscriber.
+type backendFunc func(context.Context, *gemini.Request) (*gemini.Transcript, error)
+
+func (f backendFunc) TranscribeAudio(ctx context.Context, req *gemini.Request) (*gemini.Transcript, error) {
+	return f(ctx, req)
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	t.Parallel()
+
+	info, err := parseProbeOutput([]byte(probeVideo))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() unexpected error: %v", err)
+	}
+
+	if info.Container != "mov,mp4,m4a,3gp,3g2,mj2" || info.Size != 734003200 || info.BitRate != 1630000 {
+		t.Errorf("format = %+v", info)
+	}
+
+	if info.Duration != 3600512*time.Millisecond {
+		t.Errorf("Duration = %v; want 1h0m0.512s", info.Duration)
+	}
+
+	streams := info.AudioStreams()
+	if len(streams) != 2 || streams[0].Index != 1 || streams[1].Index != 2 {
+		t.Fatalf("AudioStreams() = %+v; want streams 1 and 2 in order", streams)
+	}
+
+	if a := streams[0]; a.Language != "ukr" || !a.Default || a.SampleRate != 48000 || a.ChannelLayout != "5.1" {
+		t.Errorf("stream 1 = %+v", a)
+	}
+
+	if a := streams[1]; a.Title != "Commentary" || a.Default || a.Duration != time.Hour {
+		t.Errorf("stream 2 = %+v", a)
+	}
+
+	if !info.HasVideo() {
+		t.Error("HasVideo() = false; want true")
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Duration float64 `json:"duration"`
+		Streams  []struct {
+			Type     string  `json:"type"`
+			Duration float64 `json:"duration"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+
+	if decoded.Duration != 3600.512 || len(decoded.Streams) != 3 || decoded.Streams[2].Duration != 3600 {
+		t.Errorf("JSON = %s; want durations in seconds", data)
+	}
+}
+
+func TestClassifyProbed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		path     string
+		probe    string
+		wantType InputType
+		wantMIME string
+		wantErr  error
+	}{
+		{name: "video container", path: "talk.mp4", probe: probeVideo, wantType: InputTypeVideo},
+		{
+			name: "cover art is not video", path: "song.mp3", probe: probeMP3,
+			wantType: InputTypeAudio, wantMIME: "audio/mp3",
+		},
+		{
+			name: "audio-only file with unknown extension", path: "memo.opus", probe: probeOgg,
+			wantType: InputTypeAudio, wantMIME: "audio/ogg",
+		},
+		{name: "audio extension carrying video", path: "clip.webm", probe: probeVideo, wantType: InputTypeVideo},
+		{name: "no audio stream", path: "screen.mkv", probe: probeSilentVideo, wantErr: ErrNoAudioStream},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := parseProbeOutput([]byte(tc.probe))
+			if err != nil {
+				t.Fatalf("parseProbeOutput() unexpected error: %v", err)
+			}
+
+			inputType, mimeType := classifyInputFile(tc.path)
+			src := &mediaSource{Path: tc.path, Type: inputType, MIMEType: mimeType}
+
+			err = src.applyInfo(info)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("applyInfo() error = %v; want %v", err, tc.wantErr)
+			}
+
+			if err == nil && (src.Type != tc.wantType || src.MIMEType != tc.wantMIME) {
+				t.Errorf("applyInfo() classified %v, %q; want %v, %q", src.Type, src.MIMEType, tc.wantType, tc.wantMIME)
+			}
+		})
+	}
+}
+
+func TestTranscribeLocalFileRejectsInputWithoutAudio(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "screen.mkv")
+	if err := os.WriteFile(path, []byte("not really matroska"), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	calls := 0
+	backend := backendFunc(func(context.Context, *gemini.Request) (*gemini.Transcript, error) {
+		calls++
+
+		return &gemini.Transcript{}, nil
+	})
+
+	tr := NewWithBackend(context.Background(), &config.Config{Quiet: true, NoCache: true}, backend, nil)
+	tr.SetProbeOutput(probeSilentVideo)
+
+	_, err := tr.TranscribeLocalFile(context.Background(), path)
+	if !errors.Is(err, ErrNoAudioStream) {
+		t.Errorf("TranscribeLocalFile() error = %v; want ErrNoAudioStream", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("backend called %d times; want 0", calls)
+	}
+}
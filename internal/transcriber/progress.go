@@ -0,0 +1,246 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// Stage is a step in transcribing one input.
+type Stage string
+
+// The stages an input goes through, roughly in order. Extraction overlaps
+// uploading when audio is streamed to the backend as it is decoded, and the
+// chunks of a long input are sent several at a time, so their events
+// interleave.
+const (
+	// StageProbing: the input is opened, downloaded if remote, and inspected.
+	StageProbing Stage = "probing"
+	// StageExtracting: audio is decoded, scanned or encoded. Events from
+	// FFmpeg carry its report, and with it a percentage.
+	StageExtracting Stage = "extracting"
+	// StageUploading: the audio of a request is read and readied for
+	// sending, including any wait for the rate limiter.
+	StageUploading Stage = "uploading"
+	// StageWaiting: a request has been sent and the model is transcribing it.
+	StageWaiting Stage = "waiting"
+	// StageTranscribed: the model has answered a request.
+	StageTranscribed Stage = "transcribed"
+	// StagePostProcessing: the transcripts of chunks are merged and their
+	// timestamps mapped back to the original media.
+	StagePostProcessing Stage = "post-processing"
+	// StageWriting and StageDone are never reported by the Transcriber,
+	// which writes no files: callers that save transcripts report them
+	// with Report, so that subscribers see an input through to the end.
+	StageWriting Stage = "writing"
+	StageDone    Stage = "done"
+)
+
+// Progress is an event in the transcription of one input.
+type Progress struct {
+	// Input is the input as passed to the Transcriber.
+	Input string
+	Stage Stage
+	// Chunk is the number, from 1, of the chunk of audio an uploading,
+	// waiting or transcribed event is about. Long audio is split into
+	// chunks of --chunk-duration, and every audio stream or channel
+	// transcribed separately has chunks of its own.
+	Chunk int
+	// Chunks is how many requests the input is expected to take over all
+	// its streams, or zero while that is unknown; Transcribed is how many
+	// of them the model has answered.
+	Chunks      int
+	Transcribed int
+	// FFmpeg is the report behind an extracting event from FFmpeg.
+	FFmpeg *FFmpegProgress
+	// Err is set on a StageDone event for an input that failed.
+	Err  error
+	Time time.Time
+}
+
+// Percent returns how much of the FFmpeg pass behind an extracting event
+// is done, from 0 to 100, and false when the event has no FFmpeg report or
+// the length of the media is unknown.
+func (p Progress) Percent() (float64, bool) {
+	switch {
+	case p.FFmpeg == nil:
+		return 0, false
+	case p.FFmpeg.Done:
+		return 100, true
+	case p.FFmpeg.Total <= 0:
+		return 0, false
+	default:
+		return min(100, 100*p.FFmpeg.Processed.Seconds()/p.FFmpeg.Total.Seconds()), true
+	}
+}
+
+// subscribers holds the functions subscribed to the progress of a
+// Transcriber. The list is replaced rather than changed, so events are sent
+// to a snapshot without holding the lock.
+type subscribers struct {
+	mu   sync.Mutex
+	list []*func(Progress)
+}
+
+// add subscribes fn and returns the function that unsubscribes it.
+func (s *subscribers) add(fn func(Progress)) func() {
+	sub := &fn
+
+	s.mu.Lock()
+	s.list = append(slices.Clip(s.list), sub)
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.list = slices.DeleteFunc(slices.Clone(s.list), func(f *func(Progress)) bool { return f == sub })
+	}
+}
+
+// send calls every subscribed function with p.
+func (s *subscribers) send(p Progress) {
+	s.mu.Lock()
+	list := s.list
+	s.mu.Unlock()
+
+	for _, fn := range list {
+		(*fn)(p)
+	}
+}
+
+// Subscribe makes every transcription by t report its progress to fn, and
+// returns a function that cancels the subscription. fn is called from the
+// goroutines doing the work, possibly at once for several inputs or chunks,
+// so it must be safe for concurrent use and return quickly.
+func (t *Transcriber) Subscribe(fn func(Progress)) (unsubscribe func()) {
+	return t.progress.add(fn)
+}
+
+// progressFuncKey is the context key of the function set by WithProgress,
+// and trackerKey that of the tracker of a transcription.
+type (
+	progressFuncKey struct{}
+	trackerKey      struct{}
+)
+
+// WithProgress returns a copy of ctx that makes a transcription started
+// with it report its progress to fn as well as to the subscribers of the
+// Transcriber. fn must be safe for concurrent use. Scoping fn to a context
+// lets inputs transcribed at once by one Transcriber report separately.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressFuncKey{}, fn)
+}
+
+// Report sends p, stamped with the time, to the subscribers of t and to the
+// function set on ctx by WithProgress. Callers use it for stages that
+// happen outside the Transcriber, such as StageWriting and StageDone.
+func (t *Transcriber) Report(ctx context.Context, p Progress) {
+	p.Time = time.Now()
+
+	if fn, ok := ctx.Value(progressFuncKey{}).(func(Progress)); ok {
+		fn(p)
+	}
+
+	t.progress.send(p)
+}
+
+// tracker counts the requests of one transcription for its progress events.
+type tracker struct {
+	t     *Transcriber
+	input string
+
+	mu          sync.Mutex
+	chunks      int
+	unknown     bool
+	transcribed int
+}
+
+// track returns a copy of ctx that reports the progress of transcribing
+// input through the stage functions below.
+func (t *Transcriber) track(ctx context.Context, input string) context.Context {
+	return context.WithValue(ctx, trackerKey{}, &tracker{t: t, input: input})
+}
+
+// report completes p with the input and request counts of the transcription
+// of ctx, if it is tracked, and reports it.
+func report(ctx context.Context, p Progress) {
+	tr, ok := ctx.Value(trackerKey{}).(*tracker)
+	if !ok {
+		return
+	}
+
+	tr.mu.Lock()
+	p.Input, p.Transcribed = tr.input, tr.transcribed
+
+	if !tr.unknown {
+		p.Chunks = tr.chunks
+	}
+	tr.mu.Unlock()
+
+	tr.t.Report(ctx, p)
+}
+
+// reportStage reports that the transcription of ctx has entered stage.
+func reportStage(ctx context.Context, stage Stage) {
+	report(ctx, Progress{Stage: stage})
+}
+
+// reportFFmpeg reports an FFmpeg progress event of the transcription of ctx.
+func reportFFmpeg(ctx context.Context, p FFmpegProgress) {
+	report(ctx, Progress{Stage: StageExtracting, FFmpeg: &p})
+}
+
+// expectRequests adds n requests to those the transcription of ctx is
+// expected to take; n is zero when the number is unknown, which holds for
+// the rest of the transcription.
+func expectRequests(ctx context.Context, n int) {
+	if tr, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+		tr.mu.Lock()
+		tr.chunks += n
+		tr.unknown = tr.unknown || n == 0
+		tr.mu.Unlock()
+	}
+}
+
+// countTranscribed counts a request of the transcription of ctx answered.
+func countTranscribed(ctx context.Context) {
+	if tr, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+		tr.mu.Lock()
+		tr.transcribed++
+		tr.mu.Unlock()
+	}
+}
+
+// send sends req, the audio of chunk number chunk, to the backend,
+// reporting it uploading, waiting for the model and transcribed. A request
+// that is not one of several chunks is number 0 and counts as one.
+func (t *Transcriber) send(ctx context.Context, req *gemini.Request, chunk int) (*gemini.Transcript, error) {
+	if chunk == 0 {
+		expectRequests(ctx, 1)
+
+		chunk = 1
+	}
+
+	report(ctx, Progress{Stage: StageUploading, Chunk: chunk})
+
+	req.Sent = func() { report(ctx, Progress{Stage: StageWaiting, Chunk: chunk}) }
+
+	transcript, err := t.backend.TranscribeAudio(ctx, req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers say what was being transcribed
+	}
+
+	countTranscribed(ctx)
+	report(ctx, Progress{Stage: StageTranscribed, Chunk: chunk})
+
+	return transcript, nil
+}
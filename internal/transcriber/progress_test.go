@@ -0,0 +1,149 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// progressLog collects progress events.
+type progressLog struct {
+	mu     sync.Mutex
+	events []transcriber.Progress
+}
+
+func (l *progressLog) add(p transcriber.Progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, p)
+}
+
+func (l *progressLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.events)
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "long.wav")
+	if err := os.WriteFile(path, synthWAV(35*time.Second, testGaps...), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := &config.Config{Quiet: true, ChunkDuration: 10 * time.Second, ChunkOverlap: 500 * time.Millisecond}
+	tr := newTranscriber(t, cfg, &requestRecorder{})
+
+	var subscribed, scoped progressLog
+
+	unsubscribe := tr.Subscribe(subscribed.add)
+	ctx := transcriber.WithProgress(context.Background(), scoped.add)
+
+	result, err := tr.TranscribeLocalFile(ctx, path)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	events := subscribed.events
+	if len(events) != scoped.len() {
+		t.Errorf("subscriber got %d events, context function %d; want the same", len(events), scoped.len())
+	}
+
+	if len(events) < 2 || events[0].Stage != transcriber.StageProbing ||
+		events[len(events)-1].Stage != transcriber.StagePostProcessing {
+		t.Fatalf("events = %+v; want probing first and post-processing last", events)
+	}
+
+	// Every chunk is uploaded, sent and answered, in that order.
+	stages := make(map[int][]transcriber.Stage)
+
+	for _, p := range events {
+		if p.Input != path || p.Time.IsZero() {
+			t.Errorf("event %+v; want input %s and a time", p, path)
+		}
+
+		if p.Chunk > 0 {
+			stages[p.Chunk] = append(stages[p.Chunk], p.Stage)
+		}
+	}
+
+	want := []transcriber.Stage{transcriber.StageUploading, transcriber.StageWaiting, transcriber.StageTranscribed}
+	if len(stages) != result.Chunks || result.Chunks < 3 {
+		t.Errorf("events are about %d chunks; want all %d", len(stages), result.Chunks)
+	}
+
+	for chunk, got := range stages {
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("chunk %d went through %v; want %v", chunk, got, want)
+		}
+	}
+
+	if last := events[len(events)-1]; last.Chunks != result.Chunks || last.Transcribed != result.Chunks {
+		t.Errorf("last event counts %d of %d chunks transcribed; want %d of %d",
+			last.Transcribed, last.Chunks, result.Chunks, result.Chunks)
+	}
+
+	// Once unsubscribed, and without a function on the context, nothing is
+	// reported.
+	unsubscribe()
+
+	reported := subscribed.len()
+
+	if _, err := tr.TranscribeLocalFile(context.Background(), path); err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if subscribed.len() != reported {
+		t.Errorf("an unsubscribed function got %d more events", subscribed.len()-reported)
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		ffmpeg *transcriber.FFmpegProgress
+		want   float64
+		ok     bool
+	}{
+		{name: "no report"},
+		{name: "unknown length", ffmpeg: &transcriber.FFmpegProgress{Processed: time.Minute}},
+		{
+			name:   "part way",
+			ffmpeg: &transcriber.FFmpegProgress{Processed: time.Minute, Total: 4 * time.Minute},
+			want:   25, ok: true,
+		},
+		{
+			name:   "past the end",
+			ffmpeg: &transcriber.FFmpegProgress{Processed: 5 * time.Minute, Total: 4 * time.Minute},
+			want:   100, ok: true,
+		},
+		{name: "done", ffmpeg: &transcriber.FFmpegProgress{Done: true}, want: 100, ok: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := transcriber.Progress{Stage: transcriber.StageExtracting, FFmpeg: tc.ffmpeg}.Percent()
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("Percent() = %v, %v; want %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// ErrPoorAudio is returned when the audio of an input fails a quality
+// threshold and --quality-action is abort; nothing is uploaded.
+var ErrPoorAudio = errors.New("audio fails the quality thresholds")
+
+// QualityReport is the analysis of the audio of one input before upload.
+type QualityReport struct {
+	audio.Levels
+	// Problems describes each threshold the audio fails.
+	Problems []string
+}
+
+// measure analyses the PCM WAV stream r.
+func (t *Transcriber) measure(r io.Reader) (audio.Levels, error) {
+	h, err := audio.ReadHeader(r)
+	if err != nil {
+		return audio.Levels{}, fmt.Errorf("analysing audio: %w", err)
+	}
+
+	levels, err := audio.Analyze(r, h.Format, t.config.SilenceLevel())
+	if err != nil {
+		return audio.Levels{}, fmt.Errorf("analysing audio: %w", err)
+	}
+
+	return levels, nil
+}
+
+// checkQuality reports on levels, measured from the prepared audio. When
+// the audio fails a configured threshold it logs a warning or, unless the
+// quality action is warn, returns an ErrPoorAudio error.
+func (t *Transcriber) checkQuality(ctx context.Context, levels audio.Levels) (*QualityReport, error) {
+	report := &QualityReport{Levels: levels, Problems: t.qualityProblems(levels)}
+
+	t.logger.InfoContext(ctx, "audio analysed",
+		slog.Duration("duration", levels.Duration),
+		slog.Float64("rms_dbfs", levels.RMS),
+		slog.Float64("peak_dbfs", levels.Peak),
+		slog.Float64("clipping", levels.Clipping),
+		slog.Float64("snr_db", levels.SNR),
+		slog.Float64("speech_ratio", levels.SpeechRatio),
+	)
+
+	if len(report.Problems) == 0 {
+		return report, nil
+	}
+
+	if t.config.QualityAction == config.QualityWarn {
+		for _, problem := range report.Problems {
+			t.logger.WarnContext(ctx, "poor audio quality; transcribing anyway", slog.String("problem", problem))
+		}
+
+		return report, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrPoorAudio, strings.Join(report.Problems, "; "))
+}
+
+// qualityProblems describes each configured threshold that levels fail.
+func (t *Transcriber) qualityProblems(levels audio.Levels) []string {
+	c := t.config
+
+	var problems []string
+
+	if c.MinSpeechRatio > 0 && levels.SpeechRatio < c.MinSpeechRatio {
+		problems = append(problems, fmt.Sprintf("speech makes up %.1f%% of the audio, below --min-speech-ratio %g",
+			100*levels.SpeechRatio, c.MinSpeechRatio))
+	}
+
+	if c.MinSpeech > 0 && levels.Speech < c.MinSpeech {
+		problems = append(problems, fmt.Sprintf("%v of speech is below --min-speech %v",
+			levels.Speech.Round(100*time.Millisecond), c.MinSpeech))
+	}
+
+	if c.MaxClipping > 0 && levels.Clipping > c.MaxClipping {
+		problems = append(problems, fmt.Sprintf("%.2f%% of samples are clipped, above --max-clipping %g",
+			100*levels.Clipping, c.MaxClipping))
+	}
+
+	if c.MinSNR > 0 && levels.SNR < c.MinSNR {
+		problems = append(problems, fmt.Sprintf("estimated signal-to-noise ratio %.1f dB is below --min-snr %g",
+			levels.SNR, c.MinSNR))
+	}
+
+	return problems
+}
@@ -0,0 +1,92 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestQualityCheck(t *testing.T) {
+	t.Parallel()
+
+	// Two seconds of tone in ten seconds of silence.
+	wav := synthWAV(10*time.Second, silence{from: time.Second, to: 9 * time.Second})
+	path := filepath.Join(t.TempDir(), "mostly-silent.wav")
+
+	if err := os.WriteFile(path, wav, 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		wantErr      bool
+		wantProblems int
+	}{
+		{name: "analysis only", cfg: config.Config{Analyze: true}},
+		{name: "enough speech", cfg: config.Config{MinSpeechRatio: 0.1, MinSpeech: time.Second}},
+		{name: "too little speech", cfg: config.Config{MinSpeechRatio: 0.5, MinSpeech: 5 * time.Second}, wantErr: true},
+		{
+			name:         "too little speech, warning",
+			cfg:          config.Config{MinSpeechRatio: 0.5, QualityAction: config.QualityWarn},
+			wantProblems: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := tc.cfg
+			cfg.Quiet, cfg.SilenceThreshold = true, -45
+			rec := &requestRecorder{}
+
+			result, err := newTranscriber(t, &cfg, rec).TranscribeLocalFile(context.Background(), path)
+			if tc.wantErr {
+				if !errors.Is(err, transcriber.ErrPoorAudio) || len(rec.requests) != 0 {
+					t.Fatalf("error = %v after %d requests; want ErrPoorAudio before any", err, len(rec.requests))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+			}
+
+			q := result.Quality
+			if q == nil {
+				t.Fatal("result has no quality report")
+			}
+
+			if q.Duration != 10*time.Second || math.Abs(q.SpeechRatio-0.2) > 0.01 || len(q.Problems) != tc.wantProblems {
+				t.Errorf("quality = %+v; want 10s with 20%% speech and %d problems", q, tc.wantProblems)
+			}
+		})
+	}
+}
+
+func TestQualityCheckRejectsPipes(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Quiet: true, Analyze: true, SilenceThreshold: -45}
+	tr := newTranscriber(t, cfg, &requestRecorder{})
+	tr.SetStdin(bytes.NewReader(synthWAV(time.Second)))
+
+	if _, err := tr.TranscribeLocalFile(context.Background(), transcriber.StdinPath); err == nil {
+		t.Error("analysing piped input succeeded; want an error")
+	}
+}
@@ -0,0 +1,122 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// selectRanges restricts s to the given ranges of its timeline, which must
+// be in media order and not overlap. The span from the first start to the
+// last end is cut with an input seek, so FFmpeg skips the rest of a long
+// input without decoding it; gaps between ranges are removed by a filter.
+func (s *mediaSource) selectRanges(ranges []config.TimeRange) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	first, last := ranges[0], ranges[len(ranges)-1]
+
+	if s.Info != nil && s.Info.Duration > 0 && first.Start >= s.Info.Duration {
+		return fmt.Errorf("range %s starts after the end of the media (%s)",
+			first, FormatTimestamp(s.Info.Duration))
+	}
+
+	s.Window = timeSpan{Start: first.Start}
+	if last.End > 0 {
+		s.Window.Length = last.End - first.Start
+	}
+
+	s.Gaps = nil
+
+	for i := 1; i < len(ranges); i++ {
+		if gap := ranges[i].Start - ranges[i-1].End; gap > 0 {
+			s.Gaps = append(s.Gaps, timeSpan{Start: ranges[i-1].End - first.Start, Length: gap})
+		}
+	}
+
+	return nil
+}
+
+// seekArgs returns the FFmpeg input options that cut the selected window.
+func (s *mediaSource) seekArgs() []string {
+	var args []string
+
+	if s.Window.Start > 0 {
+		args = append(args, "-ss", formatSeconds(s.Window.Start))
+	}
+
+	if s.Window.Length > 0 {
+		args = append(args, "-t", formatSeconds(s.Window.Length))
+	}
+
+	return args
+}
+
+// rangeFilter returns the filter that removes the gaps between selected
+// ranges, or "" when there are none.
+func (s *mediaSource) rangeFilter() string {
+	if len(s.Gaps) == 0 {
+		return ""
+	}
+
+	return removalFilter(s.Gaps)
+}
+
+// rangeMap returns the map from the decoded timeline, after the window cut
+// and gap removal, back to the original media; nil when nothing was cut.
+func (s *mediaSource) rangeMap() *timeMap {
+	var m *timeMap
+
+	if len(s.Gaps) > 0 {
+		m = newRemovalMap(s.Gaps)
+	}
+
+	if s.Window.Start > 0 {
+		m = m.within(newOffsetMap(s.Window.Start))
+	}
+
+	return m
+}
+
+// keptDuration returns how much of an input lasting total remains after the
+// window cut and gap removal. A zero total means the length is unknown.
+func (s *mediaSource) keptDuration(total time.Duration) time.Duration {
+	if total > 0 {
+		total = max(total-s.Window.Start, 0)
+	}
+
+	if s.Window.Length > 0 && (total == 0 || s.Window.Length < total) {
+		total = s.Window.Length
+	}
+
+	kept := total
+	for _, gap := range s.Gaps {
+		kept -= min(gap.Length, max(total-gap.Start, 0))
+	}
+
+	return max(kept, 0)
+}
+
+// duration returns how long the decoded source lasts, or zero when that is
+// unknown.
+func (s *mediaSource) duration() time.Duration {
+	var total time.Duration
+	if s.Info != nil {
+		total = s.Info.Duration
+	}
+
+	return s.keptDuration(total)
+}
+
+// formatSeconds formats d as decimal seconds for FFmpeg options.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
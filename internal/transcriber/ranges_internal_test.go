@@ -0,0 +1,112 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// cutSource returns a video lasting total, or of unknown length when total
+// is zero, cut to ranges.
+func cutSource(ranges []config.TimeRange, total time.Duration) (*mediaSource, error) {
+	src := &mediaSource{Path: "in.mp4", Type: InputTypeVideo}
+	if total > 0 {
+		src.Info = &MediaInfo{Duration: total}
+	}
+
+	return src, src.selectRanges(ranges)
+}
+
+func TestSelectRangesSingleRange(t *testing.T) {
+	t.Parallel()
+
+	src, err := cutSource([]config.TimeRange{{Start: 42 * time.Minute, End: 75 * time.Minute}}, 3*time.Hour)
+	if err != nil {
+		t.Fatalf("selectRanges() error = %v", err)
+	}
+
+	if want := []string{"-ss", "2520.000", "-t", "1980.000"}; !slices.Equal(src.seekArgs(), want) {
+		t.Errorf("seekArgs() = %v; want %v", src.seekArgs(), want)
+	}
+
+	if filter := src.rangeFilter(); filter != "" {
+		t.Errorf("rangeFilter() = %q; want none for a single range", filter)
+	}
+
+	if kept := src.keptDuration(3 * time.Hour); kept != 33*time.Minute {
+		t.Errorf("keptDuration() = %v; want 33m", kept)
+	}
+
+	if got := src.rangeMap().ToOriginal(90 * time.Second); got != 43*time.Minute+30*time.Second {
+		t.Errorf("ToOriginal(1m30s) = %v; want 43m30s", got)
+	}
+}
+
+func TestSelectRangesExcerpts(t *testing.T) {
+	t.Parallel()
+
+	ranges := []config.TimeRange{
+		{Start: 10 * time.Second, End: 20 * time.Second},
+		{Start: 30 * time.Second, End: 40 * time.Second},
+		{Start: 100 * time.Second},
+	}
+
+	// 4s of silence removed from the decoded audio, starting 12s in: inside
+	// the second excerpt (decoded 10s-20s).
+	silences := []timeSpan{{Start: 12 * time.Second, Length: 4 * time.Second}}
+
+	src, err := cutSource(ranges, 120*time.Second)
+	if err != nil {
+		t.Fatalf("selectRanges() error = %v", err)
+	}
+
+	if want := []string{"-ss", "10.000"}; !slices.Equal(src.seekArgs(), want) {
+		t.Errorf("seekArgs() = %v; want %v (last range is open-ended)", src.seekArgs(), want)
+	}
+
+	if filter := src.rangeFilter(); !strings.Contains(filter, "between(t,10.000,20.000)") ||
+		!strings.Contains(filter, "between(t,30.000,90.000)") {
+		t.Errorf("rangeFilter() = %q; want gaps 10-20s and 30-90s of the window", filter)
+	}
+
+	if want := 40 * time.Second; src.keptDuration(120*time.Second) != want {
+		t.Errorf("keptDuration() = %v; want %v", src.keptDuration(120*time.Second), want)
+	}
+
+	offsets := newRemovalMap(silences).within(src.rangeMap())
+
+	tests := []struct {
+		decoded, want time.Duration
+	}{
+		{decoded: 0, want: 10 * time.Second},
+		{decoded: 5 * time.Second, want: 15 * time.Second},
+		{decoded: 11 * time.Second, want: 31 * time.Second},
+		// After the silence cut: decoded 12s resumes at 16s of the window
+		// timeline, which is 36s into the media.
+		{decoded: 12 * time.Second, want: 36 * time.Second},
+		{decoded: 17 * time.Second, want: 101 * time.Second},
+	}
+
+	for _, tc := range tests {
+		if got := offsets.ToOriginal(tc.decoded); got != tc.want {
+			t.Errorf("ToOriginal(%v) = %v; want %v", tc.decoded, got, tc.want)
+		}
+	}
+}
+
+func TestSelectRangesPastEnd(t *testing.T) {
+	t.Parallel()
+
+	_, err := cutSource([]config.TimeRange{{Start: 2 * time.Hour}}, time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "after the end of the media") {
+		t.Fatalf("selectRanges() error = %v; want range past end rejected", err)
+	}
+}
@@ -0,0 +1,374 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// downloadRetries is how many times an interrupted download is resumed.
+	downloadRetries = 3
+
+	// downloadBackoff is the pause before the first resume attempt; each
+	// further attempt waits one more.
+	downloadBackoff = time.Second
+)
+
+// errDownloadTooLarge is returned for downloads over the size limit.
+var errDownloadTooLarge = errors.New("download exceeds the size limit")
+
+// playlistExtensions and playlistTypes identify HLS and DASH manifests by
+// URL path and Content-Type. FFmpeg fetches the segments they list itself.
+var (
+	playlistExtensions = []string{".m3u8", ".mpd"}
+	playlistTypes      = []string{
+		"application/vnd.apple.mpegurl",
+		"application/x-mpegurl",
+		"audio/mpegurl",
+		"audio/x-mpegurl",
+		"application/dash+xml",
+	}
+)
+
+// contentTypeExtensions maps media Content-Types to the file extension a
+// download is saved with, for classifying content that sniffing does not
+// recognise.
+var contentTypeExtensions = map[string]string{
+	"audio/wav":        ".wav",
+	"audio/wave":       ".wav",
+	"audio/x-wav":      ".wav",
+	"audio/vnd.wave":   ".wav",
+	"audio/mpeg":       ".mp3",
+	"audio/mp3":        ".mp3",
+	"audio/flac":       ".flac",
+	"audio/x-flac":     ".flac",
+	"audio/ogg":        ".ogg",
+	"audio/opus":       ".opus",
+	"audio/mp4":        ".m4a",
+	"audio/x-m4a":      ".m4a",
+	"audio/aac":        ".aac",
+	"audio/webm":       ".webm",
+	"audio/aiff":       ".aiff",
+	"audio/x-aiff":     ".aiff",
+	"audio/amr":        ".amr",
+	"audio/x-ms-wma":   ".wma",
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+	"video/webm":       ".webm",
+	"video/x-msvideo":  ".avi",
+	"video/mp2t":       ".ts",
+}
+
+// IsURL reports whether inputPath is an http(s) URL rather than a file path.
+func IsURL(inputPath string) bool {
+	u, err := url.Parse(inputPath)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// InputFileName returns the file name of an input: the base name of a file
+// path, or the last segment of a URL or gs:// object path, falling back to
+// the host or bucket name when the path has none.
+func InputFileName(inputPath string) string {
+	if !IsURL(inputPath) && !IsStorageURI(inputPath) {
+		return filepath.Base(inputPath)
+	}
+
+	u, _ := url.Parse(inputPath)
+
+	if name := path.Base(u.Path); name != "." && name != "/" {
+		return name
+	}
+
+	return u.Hostname()
+}
+
+// isPlaylistURL reports whether rawURL names an HLS or DASH manifest.
+func isPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+
+	return err == nil && slices.Contains(playlistExtensions, strings.ToLower(path.Ext(u.Path)))
+}
+
+// urlSource returns a source FFmpeg reads from rawURL itself.
+func urlSource(rawURL string) *mediaSource {
+	return &mediaSource{Path: rawURL, Type: InputTypeVideo, Size: -1, Container: "url"}
+}
+
+// downloader fetches http(s) inputs to files in a workspace.
+type downloader struct {
+	client    *http.Client
+	workspace *workspace
+	// maxSize is the largest download accepted, in bytes.
+	maxSize int64
+	// backoff is the pause before the first resume attempt.
+	backoff time.Duration
+	logger  *slog.Logger
+}
+
+// newDownloader returns a downloader into ws with the default client and
+// limits.
+func newDownloader(ws *workspace, logger *slog.Logger) *downloader {
+	return &downloader{
+		client:    http.DefaultClient,
+		workspace: ws,
+		maxSize:   maxFileSize,
+		backoff:   downloadBackoff,
+		logger:    logger,
+	}
+}
+
+// open returns a source for rawURL. Playlists are handed to FFmpeg; other
+// media is downloaded to a file in the workspace, removed when the source
+// is closed, and classified like a local file with the Content-Type or file
+// name as its extension.
+func (d *downloader) open(ctx context.Context, rawURL string) (*mediaSource, error) {
+	if isPlaylistURL(rawURL) {
+		d.logger.InfoContext(ctx, "streaming playlist with FFmpeg", slog.String("url", rawURL))
+
+		return urlSource(rawURL), nil
+	}
+
+	resp, err := d.get(ctx, rawURL, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("downloading %s: %s", rawURL, resp.Status)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if slices.Contains(playlistTypes, contentType) {
+		_ = resp.Body.Close()
+
+		d.logger.InfoContext(ctx, "streaming playlist with FFmpeg", slog.String("url", rawURL))
+
+		return urlSource(rawURL), nil
+	}
+
+	if resp.ContentLength > d.maxSize {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("downloading %s: %w (%d > %d bytes)", rawURL, errDownloadTooLarge,
+			resp.ContentLength, d.maxSize)
+	}
+
+	ext := downloadExtension(contentType, responseFileName(resp))
+
+	// The space check can only use a declared length.
+	f, err := d.workspace.createTemp("download-*"+ext, resp.ContentLength)
+	if err != nil {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+
+	d.logger.InfoContext(ctx, "downloading input",
+		slog.String("url", rawURL),
+		slog.String("content_type", contentType),
+		slog.Int64("size", resp.ContentLength),
+	)
+
+	size, err := d.save(ctx, f, resp, rawURL)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing download file: %w", closeErr)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return nil, err
+	}
+
+	d.logger.DebugContext(ctx, "download complete", slog.String("path", f.Name()), slog.Int64("size", size))
+
+	src, err := openLocalSource(f.Name())
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return nil, err
+	}
+
+	src.tempFile = f.Name()
+
+	return src, nil
+}
+
+// get requests rawURL from byte offset on. A non-empty validator (an ETag
+// or Last-Modified date) makes the server send the whole resource instead
+// of the rest if it has changed since.
+func (d *downloader) get(ctx context.Context, rawURL string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+
+	return resp, nil
+}
+
+// save streams the body of resp to f. When the transfer breaks off, the
+// rest is requested with a Range header, up to downloadRetries times; a
+// server that ignores the range sends everything again, which overwrites
+// what was saved. It returns the number of bytes saved.
+func (d *downloader) save(ctx context.Context, f *os.File, resp *http.Response, rawURL string) (int64, error) {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+
+	var written int64
+
+	for attempt := 1; ; attempt++ {
+		// Read one byte past the limit to tell a full-size file from a
+		// larger one.
+		n, err := io.Copy(f, io.LimitReader(resp.Body, d.maxSize-written+1))
+		_ = resp.Body.Close()
+
+		written += n
+
+		switch {
+		case written > d.maxSize:
+			return 0, fmt.Errorf("downloading %s: %w (%d bytes)", rawURL, errDownloadTooLarge, d.maxSize)
+		case err == nil:
+			return written, nil
+		case ctx.Err() != nil || attempt > downloadRetries:
+			return 0, fmt.Errorf("downloading %s: %w", rawURL, err)
+		}
+
+		d.logger.WarnContext(ctx, "download interrupted; resuming",
+			slog.Int64("offset", written),
+			slog.Int("attempt", attempt),
+			slog.Any("error", err),
+		)
+
+		if err := sleepContext(ctx, time.Duration(attempt)*d.backoff); err != nil {
+			return 0, err
+		}
+
+		resp, err = d.get(ctx, rawURL, written, validator)
+		if err != nil {
+			return 0, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != written {
+				_ = resp.Body.Close()
+
+				return 0, fmt.Errorf("downloading %s: server resumed at the wrong offset", rawURL)
+			}
+		case http.StatusOK:
+			// No range support, or the resource changed: start over.
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				_ = resp.Body.Close()
+
+				return 0, fmt.Errorf("rewinding download file: %w", err)
+			}
+
+			if err := f.Truncate(0); err != nil {
+				_ = resp.Body.Close()
+
+				return 0, fmt.Errorf("rewinding download file: %w", err)
+			}
+
+			written = 0
+		default:
+			_ = resp.Body.Close()
+
+			return 0, fmt.Errorf("resuming download of %s: %s", rawURL, resp.Status)
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("download cancelled: %w", context.Cause(ctx))
+	case <-timer.C:
+		return nil
+	}
+}
+
+// contentRangeStart parses the first byte position of a Content-Range
+// header such as "bytes 100-199/200".
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+
+	return start, err == nil
+}
+
+// responseFileName returns the file name of a download: the one suggested
+// by Content-Disposition, else the last segment of the final URL.
+func responseFileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := filepath.Base(params["filename"]); params["filename"] != "" && name != "." {
+			return name
+		}
+	}
+
+	return InputFileName(resp.Request.URL.String())
+}
+
+// downloadExtension returns the extension a download is saved with: the
+// one its Content-Type maps to, else that of its file name.
+func downloadExtension(contentType, fileName string) string {
+	if ext, ok := contentTypeExtensions[contentType]; ok {
+		return ext
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if _, ok := audioExtensions[ext]; ok || transcodeExtensions[ext] {
+		return ext
+	}
+
+	return ""
+}
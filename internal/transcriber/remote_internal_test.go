@@ -0,0 +1,240 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// mediaServer serves body at every path with the given headers.
+func mediaServer(t *testing.T, body []byte, header http.Header) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// testDownloader returns a downloader into a workspace under a temporary
+// directory that resumes without pausing.
+func testDownloader(t *testing.T) *downloader {
+	t.Helper()
+
+	ws := newWorkspace(t.TempDir(), slog.Default())
+	t.Cleanup(func() { _ = ws.Close() })
+
+	d := newDownloader(ws, slog.Default())
+	d.backoff = 0
+
+	return d
+}
+
+// download opens rawURL with d, removing the download when the test ends.
+func download(t *testing.T, d *downloader, rawURL string) (*mediaSource, error) {
+	t.Helper()
+
+	src, err := d.open(context.Background(), rawURL)
+	if err == nil {
+		t.Cleanup(func() { _ = src.Close() })
+	}
+
+	return src, err
+}
+
+func TestDownloadClassification(t *testing.T) {
+	t.Parallel()
+
+	wav := audio.Encode(audio.Speech, make([]byte, audio.Speech.ByteRate()))
+
+	tests := []struct {
+		name     string
+		path     string
+		body     []byte
+		header   http.Header
+		wantType InputType
+		wantMIME string
+	}{
+		{
+			name:     "content sniffed",
+			path:     "/download?id=7",
+			body:     wav,
+			wantType: InputTypeAudio,
+			wantMIME: "audio/wav",
+		},
+		{
+			name:     "content type header",
+			path:     "/download?id=8",
+			body:     []byte("unrecognised"),
+			header:   http.Header{"Content-Type": {"audio/x-aiff"}},
+			wantType: InputTypeTranscode,
+		},
+		{
+			name:     "content disposition file name",
+			path:     "/download?id=9",
+			body:     []byte("unrecognised"),
+			header:   http.Header{"Content-Disposition": {`attachment; filename="memo.opus"`}},
+			wantType: InputTypeAudio,
+			wantMIME: "audio/ogg",
+		},
+		{
+			name:     "URL file name",
+			path:     "/files/notes.wma",
+			body:     []byte("unrecognised"),
+			header:   http.Header{"Content-Type": {"application/octet-stream"}},
+			wantType: InputTypeTranscode,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := mediaServer(t, tc.body, tc.header)
+
+			src, err := download(t, testDownloader(t), srv.URL+tc.path)
+			if err != nil {
+				t.Fatalf("open() error = %v", err)
+			}
+
+			if src.Type != tc.wantType || src.MIMEType != tc.wantMIME {
+				t.Errorf("classified as %v, %q; want %v, %q", src.Type, src.MIMEType, tc.wantType, tc.wantMIME)
+			}
+
+			if src.tempFile == "" || src.Size != int64(len(tc.body)) {
+				t.Errorf("downloaded %d bytes to %q; want %d", src.Size, src.tempFile, len(tc.body))
+			}
+		})
+	}
+}
+
+func TestDownloadSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	body := make([]byte, 1000)
+
+	// Declared too large up front, and too large without a declared length.
+	declared := mediaServer(t, body, nil)
+	streamed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for range 10 {
+			_, _ = w.Write(body[:100])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(streamed.Close)
+
+	for _, url := range []string{declared.URL + "/big.wav", streamed.URL + "/big.wav"} {
+		d := testDownloader(t)
+		d.maxSize = 999
+
+		if _, err := download(t, d, url); err == nil || !strings.Contains(err.Error(), "size limit") {
+			t.Errorf("open(%s) error = %v; want size limit exceeded", url, err)
+		}
+
+		d.maxSize = 1000
+
+		if _, err := download(t, d, url); err != nil {
+			t.Errorf("open(%s) at the limit: error = %v", url, err)
+		}
+	}
+}
+
+func TestDownloadResumes(t *testing.T) {
+	t.Parallel()
+
+	body := audio.Encode(audio.Speech, make([]byte, 2*audio.Speech.ByteRate()))
+
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		// The first transfer breaks off halfway through.
+		if first {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write(body[:len(body)/2])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	d := testDownloader(t)
+	d.maxSize = 1 << 20
+
+	src, err := download(t, d, srv.URL+"/call.wav")
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+
+	if src.MIMEType != "audio/wav" || src.Size != int64(len(body)) {
+		t.Errorf("downloaded %q of %d bytes; want audio/wav of %d", src.MIMEType, src.Size, len(body))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(ranges) != 2 || ranges[1] != "bytes="+strconv.Itoa(len(body)/2)+"-" {
+		t.Errorf("Range headers = %q; want the second request to resume halfway", ranges)
+	}
+}
+
+func TestPlaylistURLsAreLeftToFFmpeg(t *testing.T) {
+	t.Parallel()
+
+	srv := mediaServer(t, []byte("#EXTM3U\n"), http.Header{"Content-Type": {"application/vnd.apple.mpegurl"}})
+
+	var fetched atomic.Bool
+
+	byExtension := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { fetched.Store(true) }))
+	t.Cleanup(byExtension.Close)
+
+	d := testDownloader(t)
+
+	for _, url := range []string{srv.URL + "/live", byExtension.URL + "/vod/master.m3u8"} {
+		src, err := download(t, d, url)
+		if err != nil {
+			t.Fatalf("open(%s) error = %v", url, err)
+		}
+
+		if src.tempFile != "" || src.Type != InputTypeVideo {
+			t.Errorf("open(%s) = %v downloaded to %q; want it left to FFmpeg", url, src.Type, src.tempFile)
+		}
+	}
+
+	if fetched.Load() {
+		t.Error("an .m3u8 URL was requested; want it handed to FFmpeg untouched")
+	}
+}
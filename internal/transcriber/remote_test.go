@@ -0,0 +1,70 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+func TestInputFileName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "input/talk.mp4", want: "talk.mp4"},
+		{path: "https://example.com/rec/weekly%20sync.wav?sig=1", want: "weekly sync.wav"},
+		{path: "http://example.com/", want: "example.com"},
+		{path: "ftp://example.com/talk.mp3", want: "talk.mp3"},
+	}
+
+	for _, tc := range tests {
+		if got := transcriber.InputFileName(tc.path); got != tc.want {
+			t.Errorf("InputFileName(%q) = %q; want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestTranscribeURL(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(synthWAV(2*time.Second)))
+	}))
+	t.Cleanup(srv.Close)
+
+	rec := &requestRecorder{}
+
+	result, err := newTranscriber(t, &config.Config{Quiet: true, TempDir: t.TempDir()}, rec).
+		TranscribeLocalFile(context.Background(), srv.URL+"/talk.wav")
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile(URL) error = %v", err)
+	}
+
+	if len(rec.requests) != 1 || result.InputSize != int64(len(synthWAV(2*time.Second))) {
+		t.Errorf("%d requests for an input of %d bytes; want 1 for the whole file", len(rec.requests), result.InputSize)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missing.Close)
+
+	_, err = newTranscriber(t, &config.Config{Quiet: true, TempDir: t.TempDir()}, rec).
+		TranscribeLocalFile(context.Background(), missing.URL+"/gone.wav")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("TranscribeLocalFile(missing URL) error = %v; want 404", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// silencePadding is the amount of each detected silence kept on either side
+// of the surrounding speech, so word edges are not clipped and the model
+// still hears a pause between sentences.
+const silencePadding = 500 * time.Millisecond
+
+var (
+	// silenceStartRe and silenceEndRe match silencedetect's report lines.
+	silenceStartRe = regexp.MustCompile(`silence_start:\s*(-?[0-9.]+)`)
+	silenceEndRe   = regexp.MustCompile(`silence_end:\s*(-?[0-9.]+)`)
+	// inputDurationRe matches the "Duration:" line FFmpeg prints for its input.
+	inputDurationRe = regexp.MustCompile(`Duration:\s*([0-9]+:[0-9]+:[0-9.]+)`)
+)
+
+// timeSpan is a [Start, Start+Length) interval.
+type timeSpan struct {
+	Start  time.Duration
+	Length time.Duration
+}
+
+// End returns the exclusive end of the span.
+func (s timeSpan) End() time.Duration { return s.Start + s.Length }
+
+// keptSpan maps a contiguous run of processed audio back to the media it
+// was cut from: Length of audio starting at Processed in the processed
+// stream came from Original in the source.
+type keptSpan struct {
+	Processed time.Duration
+	Original  time.Duration
+	Length    time.Duration
+}
+
+// timeMap translates timestamps in processed (cut or trimmed) audio back to
+// the original media timeline. A nil *timeMap is the identity. Maps chain:
+// when outer is set, the result of spans is mapped through it in turn.
+type timeMap struct {
+	spans []keptSpan
+	outer *timeMap
+}
+
+// newRemovalMap returns a timeMap for audio from which the given spans,
+// expressed on the original timeline, have been removed.
+func newRemovalMap(removed []timeSpan) *timeMap {
+	m := &timeMap{}
+
+	var processed, original time.Duration
+
+	for _, r := range removed {
+		if r.Start > original {
+			m.spans = append(m.spans, keptSpan{Processed: processed, Original: original, Length: r.Start - original})
+			processed += r.Start - original
+		}
+
+		original = r.End()
+	}
+
+	// The remainder after the last removal runs to the end of the media.
+	m.spans = append(m.spans, keptSpan{Processed: processed, Original: original})
+
+	return m
+}
+
+// newOffsetMap returns a timeMap for audio that starts offset into the media.
+func newOffsetMap(offset time.Duration) *timeMap {
+	return &timeMap{spans: []keptSpan{{Original: offset}}}
+}
+
+// within returns a map that applies m and then outer, for audio that was
+// processed by m's cut after being cut by outer's. Either may be nil.
+func (m *timeMap) within(outer *timeMap) *timeMap {
+	switch {
+	case m == nil:
+		return outer
+	case outer == nil:
+		return m
+	}
+
+	return &timeMap{spans: m.spans, outer: m.outer.within(outer)}
+}
+
+// String describes the spans of m and of the maps it is within.
+func (m *timeMap) String() string {
+	if m == nil {
+		return "identity"
+	}
+
+	return fmt.Sprintf("%v within %v", m.spans, m.outer)
+}
+
+// ToOriginal maps t on the processed timeline to the original timeline.
+func (m *timeMap) ToOriginal(t time.Duration) time.Duration {
+	if m == nil {
+		return t
+	}
+
+	if len(m.spans) > 0 {
+		// Last span starting at or before t; timestamps falling on a cut map
+		// to the start of the following kept span.
+		i := sort.Search(len(m.spans), func(i int) bool { return m.spans[i].Processed > t }) - 1
+		i = max(i, 0)
+		t = m.spans[i].Original + t - m.spans[i].Processed
+	}
+
+	return m.outer.ToOriginal(t)
+}
+
+// apply rewrites the segment timestamps of tr onto the original timeline.
+func (m *timeMap) apply(tr *gemini.Transcript) {
+	if m == nil {
+		return
+	}
+
+	for i := range tr.Segments {
+		tr.Segments[i].Start = m.ToOriginal(tr.Segments[i].Start)
+		tr.Segments[i].End = max(m.ToOriginal(tr.Segments[i].End), tr.Segments[i].Start)
+	}
+}
+
+// silenceOptions controls silence detection.
+type silenceOptions struct {
+	// ThresholdDB is the level (in dBFS) below which audio counts as silence.
+	ThresholdDB float64
+	// MinDuration is the shortest silence that is removed.
+	MinDuration time.Duration
+}
+
+// silenceDetectFilter returns the silencedetect filter for opts.
+func silenceDetectFilter(opts silenceOptions) string {
+	return fmt.Sprintf("silencedetect=noise=%sdB:d=%s",
+		strconv.FormatFloat(opts.ThresholdDB, 'f', -1, 64),
+		strconv.FormatFloat(opts.MinDuration.Seconds(), 'f', 3, 64))
+}
+
+// parseInputDuration extracts the input duration from FFmpeg's stderr, or
+// returns 0 when it is not reported.
+func parseInputDuration(stderr string) time.Duration {
+	if m := inputDurationRe.FindStringSubmatch(stderr); m != nil {
+		if d, err := config.ParseTimestamp(m[1]); err == nil {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// parseSilenceDetect extracts detected silences from FFmpeg's stderr. A
+// silence still open at end of input runs to total. Each silence is shrunk
+// by padding on both sides; silences that become empty are dropped.
+func parseSilenceDetect(stderr string, total, padding time.Duration) []timeSpan {
+	var (
+		spans []timeSpan
+		open  = time.Duration(-1)
+	)
+
+	addSpan := func(start, end time.Duration) {
+		start, end = max(start+padding, 0), end-padding
+		if end > start {
+			spans = append(spans, timeSpan{Start: start, Length: end - start})
+		}
+	}
+
+	for line := range strings.SplitSeq(stderr, "\n") {
+		if m := silenceStartRe.FindStringSubmatch(line); m != nil {
+			open = parseSeconds(m[1])
+		} else if m := silenceEndRe.FindStringSubmatch(line); m != nil && open >= 0 {
+			addSpan(open, parseSeconds(m[1]))
+			open = -1
+		}
+	}
+
+	if open >= 0 && total > open {
+		// Trailing silence: nothing follows it, so no padding is needed after.
+		addSpan(open, total+padding)
+	}
+
+	return spans
+}
+
+// parseSeconds parses a decimal number of seconds, clamping negatives to 0.
+func parseSeconds(s string) time.Duration {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+
+	return time.Duration(v * float64(time.Second))
+}
+
+// removalFilter returns an aselect filter chain dropping the given spans and
+// re-timing the remaining samples so they play back contiguously.
+func removalFilter(spans []timeSpan) string {
+	terms := make([]string, len(spans))
+	for i, s := range spans {
+		terms[i] = fmt.Sprintf("between(t,%.3f,%.3f)", s.Start.Seconds(), s.End().Seconds())
+	}
+
+	return "aselect='not(" + strings.Join(terms, "+") + ")',asetpts=N/SR/TB"
+}
+
+// trimResult describes the silences found in an input.
+type trimResult struct {
+	// Filter is the FFmpeg filter that removes the silences, or empty when
+	// there is nothing to remove.
+	Filter string
+	// Map translates timestamps in the trimmed audio back to the original.
+	Map     *timeMap
+	Removed time.Duration
+	Total   time.Duration
+}
+
+// detectSilence finds long silences in the selected ranges of src with
+// FFmpeg's silencedetect filter and returns the filter that cuts them out
+// while decoding, along with the map from the trimmed timeline back to the
+// range-cut one.
+func (tc *toolchain) detectSilence(ctx context.Context, src *mediaSource, opts silenceOptions) (*trimResult, error) {
+	tc.logger.InfoContext(ctx, "detecting silence", slog.String("filter", silenceDetectFilter(opts)))
+
+	filters := []string{silenceDetectFilter(opts)}
+	if filter := src.rangeFilter(); filter != "" {
+		filters = append([]string{filter}, filters...)
+	}
+
+	args := append([]string{"-hide_banner", "-nostats"}, tc.inputArgs...)
+	args = append(args, src.decodeArgs()...)
+	args = append(args, "-vn", "-af", strings.Join(filters, ","), "-f", "null", "-")
+
+	pass := ffmpegPass{Stage: "silence", Args: args, Duration: src.duration()}
+
+	stderr, err := tc.run(ctx, pass, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("detecting silence: %w", err)
+	}
+
+	total := src.keptDuration(parseInputDuration(stderr))
+	spans := parseSilenceDetect(stderr, total, silencePadding)
+	if len(spans) == 0 {
+		return &trimResult{Total: total}, nil
+	}
+
+	var removed time.Duration
+	for _, s := range spans {
+		removed += s.Length
+	}
+
+	tc.logger.InfoContext(ctx, "silence detected",
+		slog.Int("spans", len(spans)),
+		slog.Duration("removed", removed),
+		slog.Duration("total", total),
+	)
+
+	return &trimResult{
+		Filter:  removalFilter(spans),
+		Map:     newRemovalMap(spans),
+		Removed: removed,
+		Total:   total,
+	}, nil
+}
@@ -0,0 +1,105 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// silenceDetectOutput is trimmed stderr from
+// `ffmpeg -i in.wav -af silencedetect=noise=-45dB:d=3 -f null -`.
+const silenceDetectOutput = `Input #0, wav, from 'in.wav':
+  Duration: 00:01:40.00, bitrate: 256 kb/s
+  Stream #0:0: Audio: pcm_s16le ([1][0][0][0] / 0x0001), 16000 Hz, mono, s16, 256 kb/s
+[silencedetect @ 0x5581] silence_start: 0
+[silencedetect @ 0x5581] silence_end: 5.2 | silence_duration: 5.2
+[silencedetect @ 0x5581] silence_start: 30.5
+[silencedetect @ 0x5581] silence_end: 70.5 | silence_duration: 40
+[silencedetect @ 0x5581] silence_start: 95
+size=N/A time=00:01:40.00 bitrate=N/A speed= 512x
+`
+
+func TestParseSilenceDetect(t *testing.T) {
+	t.Parallel()
+
+	total := parseInputDuration(silenceDetectOutput)
+	spans := parseSilenceDetect(silenceDetectOutput, total, 500*time.Millisecond)
+
+	if total != 100*time.Second {
+		t.Errorf("total = %v; want 1m40s", total)
+	}
+
+	want := []timeSpan{
+		{Start: 500 * time.Millisecond, Length: 4200 * time.Millisecond},
+		{Start: 31 * time.Second, Length: 39 * time.Second},
+		{Start: 95500 * time.Millisecond, Length: 4500 * time.Millisecond},
+	}
+
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %+v; want %+v", spans, want)
+	}
+
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Errorf("span %d = %+v; want %+v", i, spans[i], want[i])
+		}
+	}
+
+	t.Run("short silences vanish after padding", func(t *testing.T) {
+		t.Parallel()
+
+		out := "silence_start: 10\nsilence_end: 10.8 | silence_duration: 0.8\n"
+		if spans := parseSilenceDetect(out, 0, 500*time.Millisecond); len(spans) != 0 {
+			t.Errorf("spans = %+v; want none", spans)
+		}
+	})
+}
+
+func TestRemovalFilter(t *testing.T) {
+	t.Parallel()
+
+	got := removalFilter([]timeSpan{
+		{Start: time.Second, Length: 2 * time.Second},
+		{Start: 10500 * time.Millisecond, Length: 500 * time.Millisecond},
+	})
+
+	want := "aselect='not(between(t,1.000,3.000)+between(t,10.500,11.000))',asetpts=N/SR/TB"
+	if got != want {
+		t.Errorf("removalFilter() = %q; want %q", got, want)
+	}
+
+	if strings.Count(got, "between") != 2 {
+		t.Errorf("removalFilter() = %q; want one term per span", got)
+	}
+}
+
+func TestRemovalMapToOriginal(t *testing.T) {
+	t.Parallel()
+
+	removed := []timeSpan{
+		{Start: 0, Length: 5 * time.Second},
+		{Start: 30 * time.Second, Length: 40 * time.Second},
+	}
+
+	tests := []struct {
+		processed time.Duration
+		want      time.Duration
+	}{
+		{processed: 0, want: 5 * time.Second},
+		{processed: 10 * time.Second, want: 15 * time.Second},
+		// Processed 25s is exactly the second cut: maps past the removed gap.
+		{processed: 25 * time.Second, want: 70 * time.Second},
+		{processed: 30 * time.Second, want: 75 * time.Second},
+	}
+
+	for _, tc := range tests {
+		if got := newRemovalMap(removed).ToOriginal(tc.processed); got != tc.want {
+			t.Errorf("ToOriginal(%v) = %v; want %v", tc.processed, got, tc.want)
+		}
+	}
+}
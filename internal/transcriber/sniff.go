@@ -0,0 +1,281 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+const (
+	// sniffSize is how much of a file is read to recognise its container.
+	sniffSize = 64 << 10
+
+	// maxMetadataSize bounds the size of an MP4 moov box read to learn what
+	// tracks a file carries.
+	maxMetadataSize = 16 << 20
+)
+
+// asfHeaderGUID starts every ASF (WMA/WMV) file; asfVideoGUID marks a video
+// stream in its header.
+var (
+	asfHeaderGUID = []byte{
+		0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C,
+	}
+	asfVideoGUID = []byte{
+		0xC0, 0xEF, 0x19, 0xBC, 0x4D, 0x5B, 0xCF, 0x11, 0xA8, 0xFD, 0x00, 0x80, 0x5F, 0x5C, 0x44, 0x2B,
+	}
+)
+
+// sniffResult is the classification of a file from its content.
+type sniffResult struct {
+	// Container names the recognised format, e.g. "wav" or "mp4".
+	Container string
+	Type      InputType
+	// MIMEType is the Gemini MIME type of native audio; empty otherwise.
+	MIMEType string
+	// FrameSync marks MPEG audio recognised from frame headers alone, with
+	// no signature or tag; header-less formats can imitate it.
+	FrameSync bool
+}
+
+func nativeAudio(container, mimeType string) (sniffResult, bool) {
+	return sniffResult{Container: container, Type: InputTypeAudio, MIMEType: mimeType}, true
+}
+
+func transcodedAudio(container string) (sniffResult, bool) {
+	return sniffResult{Container: container, Type: InputTypeTranscode}, true
+}
+
+func video(container string) (sniffResult, bool) {
+	return sniffResult{Container: container, Type: InputTypeVideo}, true
+}
+
+// sniffFile recognises the container of the file at path from its magic
+// bytes and, for containers that may hold audio or video, from its track
+// headers. It reports false when the content is not recognised.
+func sniffFile(path string) (sniffResult, bool) {
+	f, err := os.Open(path) // #nosec G304 -- path validated by openLocalSource
+	if err != nil {
+		return sniffResult{}, false
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return sniffResult{}, false
+	}
+
+	return sniff(f, info.Size())
+}
+
+// sniff classifies the size bytes readable from r.
+func sniff(r io.ReaderAt, size int64) (sniffResult, bool) {
+	head := make([]byte, min(size, sniffSize))
+
+	n, err := r.ReadAt(head, 0)
+	if err != nil && n < len(head) {
+		return sniffResult{}, false
+	}
+
+	riff := func(form string) bool {
+		return len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == form
+	}
+
+	switch {
+	case riff("WAVE"):
+		return nativeAudio("wav", "audio/wav")
+	case riff("AVI "):
+		return video("avi")
+	case bytes.HasPrefix(head, []byte("RF64")):
+		return transcodedAudio("rf64")
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return nativeAudio("flac", "audio/flac")
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return sniffOgg(head)
+	case bytes.HasPrefix(head, []byte("FORM")) && len(head) >= 12 &&
+		(string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC"):
+		return transcodedAudio("aiff")
+	case bytes.HasPrefix(head, []byte("#!AMR")):
+		return transcodedAudio("amr")
+	case bytes.HasPrefix(head, asfHeaderGUID):
+		return sniffASF(head)
+	case bytes.HasPrefix(head, ebmlMagic):
+		return sniffMatroska(r, size)
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return sniffMP4(r, size, head)
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return sniffMPEGAudio(head, id3Size(head), true)
+	default:
+		return sniffMPEGAudio(head, 0, false)
+	}
+}
+
+// sniffOgg classifies an Ogg file by the codecs of the logical streams that
+// begin on its first pages.
+func sniffOgg(head []byte) (sniffResult, bool) {
+	native := false
+
+	for off := 0; off+27 <= len(head) && string(head[off:off+4]) == "OggS"; {
+		// Only the beginning-of-stream pages at the start identify codecs.
+		if head[off+5]&0x02 == 0 {
+			break
+		}
+
+		segments := int(head[off+26])
+		body := off + 27 + segments
+
+		if body > len(head) {
+			break
+		}
+
+		length := 0
+		for _, s := range head[off+27 : body] {
+			length += int(s)
+		}
+
+		packet := head[body:min(body+length, len(head))]
+
+		switch {
+		case bytes.HasPrefix(packet, []byte("\x80theora")):
+			return video("ogg")
+		case bytes.HasPrefix(packet, []byte("OpusHead")),
+			bytes.HasPrefix(packet, []byte("\x01vorbis")),
+			bytes.HasPrefix(packet, []byte("\x7fFLAC")):
+			native = true
+		}
+
+		off = body + length
+	}
+
+	if !native {
+		// Speex and other rarer codecs are not read by Gemini.
+		return transcodedAudio("ogg")
+	}
+
+	return nativeAudio("ogg", "audio/ogg")
+}
+
+// sniffASF classifies an ASF file: WMV when its header declares a video
+// stream, WMA otherwise.
+func sniffASF(head []byte) (sniffResult, bool) {
+	header := head
+	if len(head) >= 24 {
+		header = head[:min(binary.LittleEndian.Uint64(head[16:24]), uint64(len(head)))]
+	}
+
+	if bytes.Contains(header, asfVideoGUID) {
+		return video("asf")
+	}
+
+	return transcodedAudio("asf")
+}
+
+// id3Size returns the length of the ID3v2 tag at the start of head.
+func id3Size(head []byte) int {
+	if len(head) < 10 {
+		return len(head)
+	}
+
+	// The tag size is a 28-bit "syncsafe" integer: 7 bits per byte.
+	size := int(head[6])<<21 | int(head[7])<<14 | int(head[8])<<7 | int(head[9])
+	size += 10
+
+	if head[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+
+	return size
+}
+
+// sniffMPEGAudio recognises raw MP3 or ADTS AAC starting at off. Without an
+// ID3 tag to vouch for the content, two consecutive valid frame headers are
+// required, since a lone sync word is easily matched by chance.
+func sniffMPEGAudio(head []byte, off int, tagged bool) (sniffResult, bool) {
+	// Some encoders pad the tag with zeros.
+	for off < len(head) && head[off] == 0 {
+		off++
+	}
+
+	if off+4 > len(head) {
+		if tagged {
+			return nativeAudio("mp3", "audio/mp3")
+		}
+
+		return sniffResult{}, false
+	}
+
+	size, aac := mpegFrame(head[off:])
+	if size == 0 {
+		return sniffResult{}, false
+	}
+
+	if next := off + size; next+4 <= len(head) {
+		if nextSize, nextAAC := mpegFrame(head[next:]); nextSize == 0 || nextAAC != aac {
+			return sniffResult{}, false
+		}
+	} else if !tagged {
+		return sniffResult{}, false
+	}
+
+	result := sniffResult{Container: "mp3", Type: InputTypeAudio, MIMEType: "audio/mp3", FrameSync: !tagged}
+	if aac {
+		result.Container, result.MIMEType = "aac", "audio/aac"
+	}
+
+	return result, true
+}
+
+// MPEG-1 and MPEG-2 Layer III bitrates (kb/s) and sample rates (Hz) by
+// header index.
+var (
+	mp3Bitrates = [2][15]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = [4][3]int{
+		{11025, 12000, 8000},  // MPEG-2.5
+		{},                    // reserved
+		{22050, 24000, 16000}, // MPEG-2
+		{44100, 48000, 32000}, // MPEG-1
+	}
+)
+
+// mpegFrame parses the MP3 (Layer III) or ADTS AAC frame header at the start
+// of b and returns the frame length, or 0 when b does not start a frame.
+func mpegFrame(b []byte) (int, bool) {
+	if len(b) < 7 || b[0] != 0xFF {
+		return 0, false
+	}
+
+	// ADTS: 12-bit sync, layer always 0.
+	if b[1]&0xF6 == 0xF0 {
+		length := int(b[3]&0x03)<<11 | int(b[4])<<3 | int(b[5])>>5
+		if (b[2]>>2)&0x0F >= 13 || length < 7 {
+			return 0, false
+		}
+
+		return length, true
+	}
+
+	version, layer := (b[1]>>3)&0x03, (b[1]>>1)&0x03
+	bitrateIndex, rateIndex := int(b[2]>>4), int((b[2]>>2)&0x03)
+
+	if b[1]&0xE0 != 0xE0 || version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, false
+	}
+
+	rate := mp3SampleRates[version][rateIndex]
+	padding := int(b[2]>>1) & 0x01
+
+	if version == 3 {
+		return 144*mp3Bitrates[0][bitrateIndex]*1000/rate + padding, false
+	}
+
+	return 72*mp3Bitrates[1][bitrateIndex]*1000/rate + padding, false
+}
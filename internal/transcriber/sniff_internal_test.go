@@ -0,0 +1,263 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+)
+
+// mp3Frames returns n silent MPEG-1 Layer III frames at 128 kb/s, 44.1 kHz.
+func mp3Frames(n int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x64})
+
+	return bytes.Repeat(frame, n)
+}
+
+// adtsFrames returns n ADTS AAC frames of 100 bytes each.
+func adtsFrames(n int) []byte {
+	const length = 100
+
+	frame := make([]byte, length)
+	copy(frame, []byte{0xFF, 0xF1, 0x50, 0x80 | length>>11, length >> 3 & 0xFF, length&7<<5 | 0x1F, 0xFC})
+
+	return bytes.Repeat(frame, n)
+}
+
+// oggPage returns a beginning-of-stream Ogg page holding packet.
+func oggPage(serial uint32, packet []byte) []byte {
+	page := []byte("OggS\x00\x02")
+	page = append(page, make([]byte, 8)...) // granule position
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = append(page, make([]byte, 8)...) // sequence number, checksum
+	page = append(page, 1, byte(len(packet)))
+
+	return append(page, packet...)
+}
+
+// box returns an MP4 box of type typ around the concatenated children.
+func box(typ string, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+
+	return append(append(out, typ...), body...)
+}
+
+// mp4File returns an MP4 file with one track per handler/codec pair and its
+// moov box after the media data, as written by recorders.
+func mp4File(brand string, tracks ...[2]string) []byte {
+	var traks [][]byte
+
+	for _, tr := range tracks {
+		hdlr := box("hdlr", make([]byte, 8), []byte(tr[0]), make([]byte, 12))
+		stsd := box("stsd", make([]byte, 8), box(tr[1], make([]byte, 28)))
+		traks = append(traks, box("trak", box("mdia", hdlr, box("minf", box("stbl", stsd)))))
+	}
+
+	ftyp := box("ftyp", []byte(brand), make([]byte, 4), []byte("isom"))
+
+	return bytes.Join([][]byte{ftyp, box("mdat", make([]byte, 1000)), box("moov", traks...)}, nil)
+}
+
+// ebml returns an EBML element with a one-byte size.
+func ebml(id []byte, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+
+	return append(append(append([]byte{}, id...), 0x80|byte(len(body))), body...)
+}
+
+// matroskaFile returns a Matroska file of docType with one track of each
+// type (1 video, 2 audio) inside a segment of unknown size.
+func matroskaFile(docType string, trackTypes ...byte) []byte {
+	var entries [][]byte
+	for _, tt := range trackTypes {
+		entries = append(entries, ebml([]byte{0xAE}, ebml([]byte{0x83}, []byte{tt})))
+	}
+
+	header := ebml([]byte{0x1A, 0x45, 0xDF, 0xA3}, ebml([]byte{0x42, 0x82}, []byte(docType)))
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		ebml([]byte{0x15, 0x49, 0xA9, 0x66})...) // Info
+	segment = append(segment, ebml([]byte{0x16, 0x54, 0xAE, 0x6B}, entries...)...)
+	segment = append(segment, ebml([]byte{0x1F, 0x43, 0xB6, 0x75}, make([]byte, 50))...)
+
+	return append(header, segment...)
+}
+
+// asfFile returns the start of an ASF file whose header declares a stream
+// of the given media type GUID.
+func asfFile(streamType []byte) []byte {
+	header := []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	header = binary.LittleEndian.AppendUint64(header, 30+24+16)
+	header = append(header, make([]byte, 30-24+24)...)
+
+	return append(header, streamType...)
+}
+
+var (
+	asfAudio = []byte{0x40, 0x9E, 0x69, 0xF8, 0x4D, 0x5B, 0xCF, 0x11, 0xA8, 0xFD, 0x00, 0x80, 0x5F, 0x5C, 0x44, 0x2B}
+	asfVideo = []byte{0xC0, 0xEF, 0x19, 0xBC, 0x4D, 0x5B, 0xCF, 0x11, 0xA8, 0xFD, 0x00, 0x80, 0x5F, 0x5C, 0x44, 0x2B}
+)
+
+func TestSniff(t *testing.T) {
+	t.Parallel()
+
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x14"), make([]byte, 20)...)
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantContainer string
+		wantType      InputType
+		wantMIME      string
+	}{
+		{"wav", audio.Encode(audio.Speech, make([]byte, audio.Speech.ByteRate())), "wav", InputTypeAudio, "audio/wav"},
+		{"flac", append([]byte("fLaC"), make([]byte, 60)...), "flac", InputTypeAudio, "audio/flac"},
+		{"mp3 frames", mp3Frames(3), "mp3", InputTypeAudio, "audio/mp3"},
+		{"mp3 with ID3 tag", append(id3, mp3Frames(1)...), "mp3", InputTypeAudio, "audio/mp3"},
+		{"adts aac", adtsFrames(3), "aac", InputTypeAudio, "audio/aac"},
+		{
+			"ogg opus", oggPage(1, append([]byte("OpusHead"), make([]byte, 11)...)),
+			"ogg", InputTypeAudio, "audio/ogg",
+		},
+		{
+			"ogg vorbis with theora",
+			append(oggPage(1, []byte("\x01vorbis\x00\x00")), oggPage(2, []byte("\x80theora\x00"))...),
+			"ogg", InputTypeVideo, "",
+		},
+		{
+			"ogg speex", oggPage(1, []byte("Speex   1.2")),
+			"ogg", InputTypeTranscode, "",
+		},
+		{"m4a brand", box("ftyp", []byte("M4A "), make([]byte, 4)), "mp4", InputTypeAudio, "audio/m4a"},
+		{
+			"audio-only mp4", mp4File("isom", [2]string{"soun", "mp4a"}),
+			"mp4", InputTypeAudio, "audio/m4a",
+		},
+		{
+			"mp4 with video", mp4File("M4A ", [2]string{"soun", "mp4a"}, [2]string{"vide", "avc1"}),
+			"mp4", InputTypeVideo, "",
+		},
+		{"alac in mp4", mp4File("M4A ", [2]string{"soun", "alac"}), "mp4", InputTypeTranscode, ""},
+		{"webm audio", matroskaFile("webm", 2), "webm", InputTypeAudio, "audio/webm"},
+		{"webm video", matroskaFile("webm", 1, 2), "webm", InputTypeVideo, ""},
+		{"matroska audio", matroskaFile("matroska", 2), "matroska", InputTypeTranscode, ""},
+		{"aiff", append([]byte("FORM\x00\x00\x00\x10AIFF"), make([]byte, 16)...), "aiff", InputTypeTranscode, ""},
+		{"amr", []byte("#!AMR\n\x3c\x00"), "amr", InputTypeTranscode, ""},
+		{"wma", asfFile(asfAudio), "asf", InputTypeTranscode, ""},
+		{"wmv", asfFile(asfVideo), "asf", InputTypeVideo, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := sniff(bytes.NewReader(tc.data), int64(len(tc.data)))
+			if !ok {
+				t.Fatal("sniff() did not recognise the content")
+			}
+
+			if got.Container != tc.wantContainer || got.Type != tc.wantType || got.MIMEType != tc.wantMIME {
+				t.Errorf("sniff() = %q, %v, %q; want %q, %v, %q",
+					got.Container, got.Type, got.MIMEType, tc.wantContainer, tc.wantType, tc.wantMIME)
+			}
+		})
+	}
+}
+
+func TestSniffUnrecognised(t *testing.T) {
+	t.Parallel()
+
+	for name, data := range map[string][]byte{
+		"empty":         nil,
+		"text":          []byte("not a media file"),
+		"silent pcm":    make([]byte, 32000),
+		"lone mp3 sync": append([]byte{0xFF, 0xFB, 0x90, 0x64}, bytes.Repeat([]byte{0x55}, 1000)...),
+	} {
+		if got, ok := sniff(bytes.NewReader(data), int64(len(data))); ok {
+			t.Errorf("sniff(%s) = %q; want unrecognised", name, got.Container)
+		}
+	}
+}
+
+func TestClassifyFileByContent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType InputType
+		wantMIME string
+	}{
+		// Content wins over a misleading extension.
+		{"interview.mp4", mp4File("isom", [2]string{"soun", "mp4a"}), InputTypeAudio, "audio/m4a"},
+		{"screen.webm", matroskaFile("webm", 1, 2), InputTypeVideo, ""},
+		{"memo", mp3Frames(4), InputTypeAudio, "audio/mp3"},
+		{"voicemail.dat", []byte("#!AMR\n\x3c\x00"), InputTypeTranscode, ""},
+		// Unrecognised content falls back to the extension.
+		{"notes.wma", []byte("placeholder"), InputTypeTranscode, ""},
+		{"voice.opus", []byte("placeholder"), InputTypeAudio, "audio/ogg"},
+		{"unknown", []byte("placeholder"), InputTypeVideo, ""},
+		// Header-less PCM is not mistaken for MPEG audio.
+		{"dump.pcm", mp3Frames(4), InputTypeAudio, "audio/pcm"},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, tc.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		src, err := openLocalSource(path)
+		if err != nil {
+			t.Errorf("openLocalSource(%s) error = %v", tc.name, err)
+
+			continue
+		}
+
+		if src.Type != tc.wantType || src.MIMEType != tc.wantMIME {
+			t.Errorf("openLocalSource(%s) = %v, %q; want %v, %q", tc.name, src.Type, src.MIMEType, tc.wantType, tc.wantMIME)
+		}
+	}
+}
+
+func TestIsMediaFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"memo", mp3Frames(4), true},
+		{"talk.mkv", []byte("placeholder"), true},
+		{"call.wma", []byte("placeholder"), true},
+		{"voice.opus", []byte("placeholder"), true},
+		{"notes.txt", []byte("placeholder"), false},
+		{"cover.jpg", []byte("placeholder"), false},
+		{"unknown", []byte("placeholder"), false},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, tc.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := IsMediaFile(path); got != tc.want {
+			t.Errorf("IsMediaFile(%s) = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"slices"
+)
+
+// mp4AudioBrands are ftyp brands used only for audio-only MP4 files.
+var mp4AudioBrands = []string{"M4A ", "M4B ", "M4P ", "F4A ", "F4B "}
+
+// sniffMP4 classifies an ISO base media (MP4, M4A, MOV, 3GP) file by the
+// handler of each track in its moov box, wherever in the file that is. A
+// file whose tracks cannot be read is classified by its ftyp brand.
+func sniffMP4(r io.ReaderAt, size int64, head []byte) (sniffResult, bool) {
+	if moov := readMoov(r, size); moov != nil {
+		var handlers, codecs []string
+
+		forEachBox(moov, func(typ string, trak []byte) {
+			if typ != "trak" {
+				return
+			}
+
+			handler, codec := mp4Track(trak)
+			handlers = append(handlers, handler)
+			codecs = append(codecs, codec)
+		})
+
+		switch {
+		case slices.Contains(handlers, "vide"):
+			return video("mp4")
+		case !slices.Contains(handlers, "soun"):
+			// No audio track to speak of; let ffprobe or FFmpeg report it.
+			return video("mp4")
+		case slices.ContainsFunc(codecs, func(c string) bool { return c != "" && c != "mp4a" }):
+			// ALAC, AMR, AC-3 and friends need decoding.
+			return transcodedAudio("mp4")
+		default:
+			return nativeAudio("mp4", "audio/m4a")
+		}
+	}
+
+	if slices.Contains(mp4AudioBrands, string(head[8:12])) {
+		return nativeAudio("mp4", "audio/m4a")
+	}
+
+	return video("mp4")
+}
+
+// readMoov walks the top-level boxes of an MP4 file and returns the body of
+// its moov box, or nil when there is none or it is implausibly large.
+func readMoov(r io.ReaderAt, size int64) []byte {
+	var hdr [16]byte
+
+	for off := int64(0); off+8 <= size; {
+		if _, err := r.ReadAt(hdr[:8], off); err != nil {
+			return nil
+		}
+
+		boxSize, headerSize := int64(binary.BigEndian.Uint32(hdr[0:4])), int64(8)
+
+		switch boxSize {
+		case 0:
+			boxSize = size - off
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], off+8); err != nil {
+				return nil
+			}
+
+			boxSize, headerSize = int64(binary.BigEndian.Uint64(hdr[8:16])), 16 // #nosec G115 -- checked below
+		}
+
+		if boxSize < headerSize {
+			return nil
+		}
+
+		if string(hdr[4:8]) == "moov" {
+			if boxSize-headerSize > maxMetadataSize {
+				return nil
+			}
+
+			moov := make([]byte, boxSize-headerSize)
+			if n, _ := r.ReadAt(moov, off+headerSize); n < len(moov) {
+				return nil
+			}
+
+			return moov
+		}
+
+		off += boxSize
+	}
+
+	return nil
+}
+
+// mp4Track returns the handler type ("soun", "vide", ...) and the codec of
+// the first sample entry ("mp4a", "avc1", ...) of a trak box body.
+func mp4Track(trak []byte) (string, string) {
+	var handler, codec string
+
+	forEachBox(trak, func(typ string, mdia []byte) {
+		if typ != "mdia" {
+			return
+		}
+
+		forEachBox(mdia, func(typ string, body []byte) {
+			switch typ {
+			case "hdlr":
+				// version/flags, pre_defined, then the handler type.
+				if len(body) >= 12 {
+					handler = string(body[8:12])
+				}
+			case "minf":
+				codec = mp4SampleEntry(body)
+			}
+		})
+	})
+
+	return handler, codec
+}
+
+// mp4SampleEntry returns the type of the first sample entry in the stsd box
+// of a minf box body.
+func mp4SampleEntry(minf []byte) string {
+	var codec string
+
+	forEachBox(minf, func(typ string, stbl []byte) {
+		if typ != "stbl" {
+			return
+		}
+
+		forEachBox(stbl, func(typ string, stsd []byte) {
+			// version/flags, entry count, then the first entry's box header.
+			if typ == "stsd" && len(stsd) >= 16 {
+				codec = string(stsd[12:16])
+			}
+		})
+	})
+
+	return codec
+}
+
+// forEachBox calls fn with the type and body of each box in b.
+func forEachBox(b []byte, fn func(typ string, body []byte)) {
+	for len(b) >= 8 {
+		size, headerSize := uint64(binary.BigEndian.Uint32(b[0:4])), uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return
+			}
+
+			size, headerSize = binary.BigEndian.Uint64(b[8:16]), 16
+		}
+
+		if size < headerSize || size > uint64(len(b)) {
+			return
+		}
+
+		fn(string(b[4:8]), b[headerSize:size])
+		b = b[size:]
+	}
+}
+
+// Matroska element IDs, with their length markers.
+const (
+	ebmlDocType    = 0x4282
+	mkvSegment     = 0x18538067
+	mkvCluster     = 0x1F43B675
+	mkvTracks      = 0x1654AE6B
+	mkvTrackEntry  = 0xAE
+	mkvTrackType   = 0x83
+	mkvTrackVideo  = 1
+	mkvTrackAudio  = 2
+	ebmlHeaderID   = 0x1A45DFA3
+	ebmlUnknownLen = -1
+)
+
+// matroskaScanSize is how much of a Matroska file is read looking for its
+// Tracks element, which muxers write near the start.
+const matroskaScanSize = 1 << 20
+
+// ebmlMagic starts every EBML (Matroska, WebM) file.
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// sniffMatroska classifies a Matroska or WebM file by the types of the
+// tracks in its Tracks element. WebM audio is sent as is; audio-only
+// Matroska is decoded, since Gemini reads only the WebM profile.
+func sniffMatroska(r io.ReaderAt, size int64) (sniffResult, bool) {
+	head := make([]byte, min(size, matroskaScanSize))
+	if n, _ := r.ReadAt(head, 0); n < len(head) {
+		return sniffResult{}, false
+	}
+
+	var (
+		docType    string
+		trackTypes []uint64
+	)
+
+	forEachElement(head, func(id uint64, body []byte) bool {
+		switch id {
+		case ebmlHeaderID:
+			forEachElement(body, func(id uint64, body []byte) bool {
+				if id == ebmlDocType {
+					docType = string(body)
+				}
+
+				return true
+			})
+		case mkvSegment:
+			trackTypes = matroskaTrackTypes(body)
+
+			return false
+		}
+
+		return true
+	})
+
+	container := "matroska"
+	if docType == "webm" {
+		container = "webm"
+	}
+
+	switch {
+	case slices.Contains(trackTypes, mkvTrackVideo) || !slices.Contains(trackTypes, mkvTrackAudio):
+		return video(container)
+	case container == "webm":
+		return nativeAudio(container, "audio/webm")
+	default:
+		return transcodedAudio(container)
+	}
+}
+
+// matroskaTrackTypes returns the TrackType of each entry in the Tracks
+// element of a Segment body. Tracks precede the first Cluster.
+func matroskaTrackTypes(segment []byte) []uint64 {
+	var types []uint64
+
+	forEachElement(segment, func(id uint64, body []byte) bool {
+		switch id {
+		case mkvCluster:
+			return false
+		case mkvTracks:
+			forEachElement(body, func(id uint64, entry []byte) bool {
+				if id != mkvTrackEntry {
+					return true
+				}
+
+				forEachElement(entry, func(id uint64, value []byte) bool {
+					if id == mkvTrackType {
+						types = append(types, ebmlUint(value))
+					}
+
+					return true
+				})
+
+				return true
+			})
+
+			return false
+		}
+
+		return true
+	})
+
+	return types
+}
+
+// forEachElement calls fn with the ID and body of each EBML element in b
+// until fn returns false. A body running past the end of b, or of unknown
+// size, is cut short at the end of b.
+func forEachElement(b []byte, fn func(id uint64, body []byte) bool) {
+	for len(b) > 0 {
+		id, idLen := ebmlVint(b, true)
+		if idLen == 0 {
+			return
+		}
+
+		size, sizeLen := ebmlVint(b[idLen:], false)
+		if sizeLen == 0 {
+			return
+		}
+
+		start := idLen + sizeLen
+		end := len(b)
+
+		if size != ebmlUnknownLen && size <= int64(len(b)-start) {
+			end = start + int(size)
+		}
+
+		if !fn(uint64(id), b[start:end]) { // #nosec G115 -- IDs are at most 4 bytes
+			return
+		}
+
+		b = b[end:]
+	}
+}
+
+// ebmlVint decodes the EBML variable-length integer at the start of b and
+// returns it with its length in bytes, or a zero length when b is
+// malformed. IDs keep their length marker; sizes drop it, and a size with
+// every value bit set is returned as ebmlUnknownLen.
+func ebmlVint(b []byte, keepMarker bool) (int64, int) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0
+	}
+
+	n := bits.LeadingZeros8(b[0]) + 1
+	if len(b) < n {
+		return 0, 0
+	}
+
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xFF >> n
+	}
+
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+	}
+
+	if !keepMarker && v == 1<<(7*n)-1 {
+		return ebmlUnknownLen, n
+	}
+
+	return int64(v), n // #nosec G115 -- at most 56 bits
+}
+
+// ebmlUint decodes a big-endian EBML unsigned integer element body.
+func ebmlUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v
+}
@@ -0,0 +1,200 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+// stagedAudio is the prepared audio of an input, read in full before
+// anything is uploaded so that it can be measured.
+type stagedAudio struct {
+	// mimeType is the MIME type of the prepared audio.
+	mimeType string
+	// levels is the analysis of the prepared audio.
+	levels *audio.Levels
+	// file is the decoded audio, written to the workspace as PCM WAV; nil
+	// when the input itself is uploaded.
+	file *mediaSource
+}
+
+// Close removes the staged file. Implements io.Closer.
+func (s *stagedAudio) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
+
+// staging reports whether src is staged before upload: when audio analysis
+// needs its levels first. Audio the backend reads by reference never is.
+// Staging gives up streaming extraction into the first uploads, and decoded
+// audio takes its full PCM size in the workspace, so without analysis src
+// is streamed instead.
+func (t *Transcriber) staging(src *mediaSource) bool {
+	return src.fileURI == "" && t.config.AnalyzeAudio()
+}
+
+// cacheable reports whether transcripts of src are cached: the cache is on
+// and src can be read again to look its transcript up.
+func (t *Transcriber) cacheable(src *mediaSource) bool {
+	return t.cache != nil && !src.piped() && !IsURL(src.Path)
+}
+
+// stage prepares the audio of src as described by opts, measuring it as it
+// is produced. Decoded audio is written to the workspace, after checking
+// there is room for it, and read back for upload, so the input is decoded
+// once. With --split-channels all channels are staged together and split
+// when read back.
+func (t *Transcriber) stage(ctx context.Context, src *mediaSource, opts prepareOptions) (*stagedAudio, error) {
+	if t.config.SplitChannels {
+		opts.Transcode = true
+		opts.Format.Channels = src.channelCount()
+	}
+
+	t.logger.InfoContext(ctx, "analysing audio quality")
+
+	prepared, err := t.tools.prepareAudio(ctx, src, opts)
+	if err != nil {
+		return nil, fmt.Errorf("preparing audio: %w", err)
+	}
+
+	defer t.closeAudio(ctx, prepared)
+
+	if prepared.native {
+		return t.stageNative(ctx, src, opts, prepared)
+	}
+
+	header, err := audio.ReadHeader(prepared)
+	if err != nil {
+		return nil, fmt.Errorf("preparing audio: %w", err)
+	}
+
+	f, err := t.workspace.createTemp("prepared-*.wav", preparedSize(src, header.Format))
+	if err != nil {
+		return nil, err
+	}
+
+	file := &mediaSource{Path: f.Name(), Type: InputTypeAudio, MIMEType: "audio/wav", tempFile: f.Name()}
+	staged := &stagedAudio{mimeType: file.MIMEType, file: file}
+
+	file.Size, err = t.writeStaged(f, prepared, header.Format, staged)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing staged audio: %w", closeErr)
+	}
+
+	if err != nil {
+		_ = staged.Close()
+
+		return nil, err
+	}
+
+	file.Info, _ = file.nativeInfo()
+
+	return staged, nil
+}
+
+// writeStaged writes the PCM read from r, in format, to f as a WAV file,
+// recording its levels in staged. It returns the size of the file.
+func (t *Transcriber) writeStaged(f *os.File, r io.Reader, format audio.Format, staged *stagedAudio) (int64, error) {
+	if err := audio.WriteHeader(f, format, -1); err != nil {
+		return 0, err //nolint:wrapcheck // already describes the write that failed
+	}
+
+	data := &countingReader{r: r}
+	pcm := io.TeeReader(data, f)
+
+	levels, err := audio.Analyze(pcm, format, t.config.SilenceLevel())
+	if err != nil {
+		return 0, fmt.Errorf("analysing audio: %w", err)
+	}
+
+	staged.levels = &levels
+
+	// Whatever analysis leaves unread, such as a partial final frame, is
+	// still uploaded.
+	if _, err := io.Copy(io.Discard, pcm); err != nil {
+		return 0, fmt.Errorf("staging audio: %w", err)
+	}
+
+	// The header is rewritten with the real size, so that the staged file
+	// reads back like any other WAV input.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("staging audio: %w", err)
+	}
+
+	if err := audio.WriteHeader(f, format, data.n); err != nil {
+		return 0, err //nolint:wrapcheck // already describes the write that failed
+	}
+
+	return audio.HeaderSize + data.n, nil
+}
+
+// stageNative stages an input uploaded as-is: it is decoded once to be
+// measured, and uploaded from the input itself.
+func (t *Transcriber) stageNative(
+	ctx context.Context, src *mediaSource, opts prepareOptions, prepared *PreparedAudio,
+) (*stagedAudio, error) {
+	staged := &stagedAudio{mimeType: prepared.MIMEType}
+
+	decoded, err := t.tools.decodeAudio(ctx, src, opts)
+	if err != nil {
+		return nil, fmt.Errorf("analysing audio: %w", err)
+	}
+
+	defer t.closeAudio(ctx, decoded)
+
+	levels, err := t.measure(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	staged.levels = &levels
+
+	return staged, nil
+}
+
+// preparedSize estimates the size in bytes of the audio of src decoded to
+// format: an upper bound, as trimmed silence is not counted. It is zero
+// when the duration of src is unknown.
+func preparedSize(src *mediaSource, format audio.Format) int64 {
+	d := src.duration()
+	if d <= 0 {
+		return 0
+	}
+
+	return audio.HeaderSize + format.Frames(d)*int64(format.FrameSize())
+}
+
+// transcribeStaged transcribes audio staged in the workspace, splitting
+// its channels with --split-channels, and hashes what it sends with digest.
+// The staged file is uploaded in --upload-codec like any decoded audio.
+func (t *Transcriber) transcribeStaged(
+	ctx context.Context, staged *stagedAudio, reqOpts requestOptions, digest *audioDigest,
+) (*gemini.Transcript, uploadStats, error) {
+	if t.config.SplitChannels {
+		return t.transcribeChannels(ctx, staged.file, prepareOptions{Format: t.format()}, reqOpts, digest)
+	}
+
+	r, err := staged.file.open()
+	if err != nil {
+		return nil, uploadStats{}, fmt.Errorf("preparing audio: %w", err)
+	}
+
+	prepared := &PreparedAudio{MIMEType: staged.mimeType, Size: staged.file.Size, r: r, source: staged.file}
+	defer t.closeAudio(ctx, prepared)
+
+	digest.attach(prepared)
+
+	return t.transcribePrepared(ctx, prepared, reqOpts)
+}
@@ -0,0 +1,267 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+)
+
+const (
+	// storageScheme prefixes Cloud Storage object URIs.
+	storageScheme = "gs://"
+
+	// storageEndpoint is the Cloud Storage JSON API.
+	storageEndpoint = "https://storage.googleapis.com"
+
+	// storageReadScope is the OAuth scope for reading objects.
+	storageReadScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+	// storageMetadataLimit bounds the object metadata response read.
+	storageMetadataLimit = 1 << 20
+)
+
+// IsStorageURI reports whether inputPath is a Cloud Storage object URI of
+// the form gs://bucket/object.
+func IsStorageURI(inputPath string) bool {
+	_, _, ok := parseStorageURI(inputPath)
+
+	return ok
+}
+
+// parseStorageURI splits a gs://bucket/object URI.
+func parseStorageURI(uri string) (bucket, object string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, storageScheme)
+	if !ok {
+		return "", "", false
+	}
+
+	bucket, object, ok = strings.Cut(rest, "/")
+
+	return bucket, object, ok && bucket != "" && object != ""
+}
+
+// storageObject is the part of the Cloud Storage object resource used here.
+type storageObject struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size,string"`
+	// MD5Hash and CRC32C are base64 checksums of the content; composite
+	// objects have no MD5 hash.
+	MD5Hash string `json:"md5Hash"`
+	CRC32C  string `json:"crc32c"`
+}
+
+// checksum returns a checksum of the object's content, preferring MD5, or
+// "" when the metadata has none.
+func (o *storageObject) checksum() string {
+	switch {
+	case o.MD5Hash != "":
+		return "md5:" + o.MD5Hash
+	case o.CRC32C != "":
+		return "crc32c:" + o.CRC32C
+	}
+
+	return ""
+}
+
+// storageClient reads Cloud Storage objects through the JSON API.
+type storageClient struct {
+	endpoint string
+	// client returns the HTTP client for endpoint. Credentials are looked up
+	// on first use, so only gs:// inputs need them.
+	client func() (*http.Client, error)
+}
+
+// newStorageClient returns a client for the Cloud Storage endpoint, or for
+// the emulator named by STORAGE_EMULATOR_HOST, which is used without
+// credentials as by Google's own client libraries.
+func newStorageClient() *storageClient {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+
+		return newStorageClientAt(strings.TrimSuffix(host, "/"), http.DefaultClient)
+	}
+
+	return &storageClient{
+		endpoint: storageEndpoint,
+		client: sync.OnceValues(func() (*http.Client, error) {
+			creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{storageReadScope}})
+			if err != nil {
+				return nil, fmt.Errorf("finding Cloud Storage credentials: %w", err)
+			}
+
+			client, err := httptransport.NewClient(&httptransport.Options{Credentials: creds})
+			if err != nil {
+				return nil, fmt.Errorf("creating Cloud Storage client: %w", err)
+			}
+
+			return client, nil
+		}),
+	}
+}
+
+// newStorageClientAt returns a client for endpoint that sends requests with
+// client as they are.
+func newStorageClientAt(endpoint string, client *http.Client) *storageClient {
+	return &storageClient{
+		endpoint: endpoint,
+		client:   func() (*http.Client, error) { return client, nil },
+	}
+}
+
+// objectURL returns the JSON API URL of an object.
+func (c *storageClient) objectURL(bucket, object string) string {
+	return c.endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(object)
+}
+
+// stat fetches the metadata of an object.
+func (c *storageClient) stat(ctx context.Context, uri string) (*storageObject, error) {
+	bucket, object, ok := parseStorageURI(uri)
+	if !ok {
+		return nil, fmt.Errorf("invalid Cloud Storage URI %q: want gs://bucket/object", uri)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(bucket, object), nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata of %s: %w", uri, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata of %s: %w", uri, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading metadata of %s: %s", uri, resp.Status)
+	}
+
+	var obj storageObject
+	if err := json.NewDecoder(io.LimitReader(resp.Body, storageMetadataLimit)).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decoding metadata of %s: %w", uri, err)
+	}
+
+	return &obj, nil
+}
+
+// openStorage returns a source for the Cloud Storage object at uri. Native
+// audio is left in place for the backend to read by reference when
+// byReference is set and it fits in one chunk; anything else, and audio
+// that has to be decoded, is downloaded like an http(s) input and removed
+// when the source is closed.
+// The format comes from format when non-empty, else from the object's
+// Content-Type or, failing that, its name.
+func (t *Transcriber) openStorage(ctx context.Context, uri, format string, byReference bool) (*mediaSource, error) {
+	obj, err := t.storage.stat(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, _, _ := mime.ParseMediaType(obj.ContentType)
+
+	ext := downloadExtension(contentType, path.Base(obj.Name))
+	if format != "" {
+		ext = "." + format
+	}
+
+	inputType, mimeType := classifyInputFile(ext)
+
+	if inputType == InputTypeAudio && byReference && t.overChunk(obj.Size, mimeType) {
+		t.logger.InfoContext(ctx, "Cloud Storage object is longer than a chunk; downloading it to split it",
+			slog.String("uri", uri),
+			slog.Duration("estimated_duration", gemini.EstimateSizeDuration(obj.Size, mimeType)),
+			slog.Duration("chunk_duration", t.config.ChunkDuration),
+		)
+	} else if inputType == InputTypeAudio && byReference {
+		t.logger.InfoContext(ctx, "passing Cloud Storage object by reference",
+			slog.String("uri", uri),
+			slog.String("mime_type", mimeType),
+			slog.Int64("size", obj.Size),
+		)
+
+		return &mediaSource{
+			Path: uri, Type: inputType, MIMEType: mimeType, Size: obj.Size,
+			fileURI: uri, fileChecksum: obj.checksum(),
+		}, nil
+	}
+
+	client, err := t.storage.client()
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, object, _ := parseStorageURI(uri)
+
+	fetch := *t.fetch
+	fetch.client = client
+
+	return fetch.open(ctx, t.storage.objectURL(bucket, object)+"?alt=media")
+}
+
+// byReference reports whether native audio can be handed to the backend as
+// a Cloud Storage reference: nothing in the configuration reads or alters
+// the audio before upload, or needs its subtitle streams. A reference is
+// always a single request, so objects estimated to run longer than one
+// chunk are still downloaded and chunked; see overChunk.
+func (t *Transcriber) byReference() bool {
+	c := t.config
+
+	return !c.TranscodeAudio && !c.TrimSilence && !c.SplitChannels && !c.AllAudioStreams &&
+		c.AudioStream == "" && c.Start == "" && c.End == "" && c.RangesFile == "" &&
+		c.Enhance == "" && c.AudioFilter == "" && c.Subtitles == "" && !c.AnalyzeAudio()
+}
+
+// overChunk reports whether size bytes of audio of the given type are
+// estimated to run longer than a single request of --chunk-duration covers,
+// with the same slack as transcribePrepared allows.
+func (t *Transcriber) overChunk(size int64, mimeType string) bool {
+	window := t.config.ChunkDuration
+
+	return window > 0 && gemini.EstimateSizeDuration(size, mimeType) > window+window/4
+}
+
+// transcribeReference transcribes audio the backend reads from Cloud
+// Storage itself, in a single request. Nothing is uploaded.
+func (t *Transcriber) transcribeReference(
+	ctx context.Context, src *mediaSource, opts requestOptions,
+) (*gemini.Transcript, uploadStats, error) {
+	transcript, err := t.send(ctx, &gemini.Request{
+		Size:       src.Size,
+		MIMEType:   src.MIMEType,
+		Timestamps: opts.Timestamps,
+		Language:   opts.Language,
+		FileURI:    src.fileURI,
+		Reference:  opts.Reference.text(0, 0),
+	}, 0)
+	if err != nil {
+		return nil, uploadStats{}, fmt.Errorf("transcribing audio: %w", err)
+	}
+
+	return transcript, uploadStats{Requests: 1}, nil
+}
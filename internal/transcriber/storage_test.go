@@ -0,0 +1,214 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
+)
+
+// fakeObject is an object served by storageServer.
+type fakeObject struct {
+	contentType string
+	body        []byte
+}
+
+// storageServer fakes the Cloud Storage JSON API for the objects of one
+// bucket, keyed by name. It counts media downloads.
+func storageServer(t *testing.T, bucket string, objects map[string]fakeObject) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var downloads atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.URL.Path, "/storage/v1/b/"+bucket+"/o/")
+		obj, found := objects[name]
+
+		if !ok || !found {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if r.URL.Query().Get("alt") == "media" {
+			downloads.Add(1)
+			w.Header().Set("Content-Type", obj.contentType)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(obj.body))
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"bucket":      bucket,
+			"name":        name,
+			"contentType": obj.contentType,
+			"size":        strconv.Itoa(len(obj.body)),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &downloads
+}
+
+func TestIsStorageURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "gs://archive/2025/talk.mp3", want: true},
+		{path: "gs://archive/", want: false},
+		{path: "gs://archive", want: false},
+		{path: "gs:///talk.mp3", want: false},
+		{path: "https://storage.googleapis.com/archive/talk.mp3", want: false},
+		{path: "input/talk.mp3", want: false},
+	}
+
+	for _, tc := range tests {
+		if got := transcriber.IsStorageURI(tc.path); got != tc.want {
+			t.Errorf("IsStorageURI(%q) = %v; want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if got := transcriber.InputFileName("gs://archive/2025/weekly sync.mp3"); got != "weekly sync.mp3" {
+		t.Errorf("InputFileName(gs URI) = %q; want %q", got, "weekly sync.mp3")
+	}
+}
+
+func TestTranscribeStorageReference(t *testing.T) {
+	t.Parallel()
+
+	srv, downloads := storageServer(t, "archive", map[string]fakeObject{
+		"2025/talk.mp3":  {contentType: "audio/mpeg", body: []byte("ID3 placeholder")},
+		"2025/memo.flac": {contentType: "application/octet-stream", body: []byte("fLaC")},
+	})
+
+	tests := []struct {
+		uri      string
+		wantMIME string
+	}{
+		{uri: "gs://archive/2025/talk.mp3", wantMIME: "audio/mp3"},
+		{uri: "gs://archive/2025/memo.flac", wantMIME: "audio/flac"},
+	}
+
+	for _, tc := range tests {
+		rec := &requestRecorder{}
+		tr := newTranscriber(t, &config.Config{Quiet: true, ChunkDuration: time.Minute}, rec)
+		tr.SetStorageEndpoint(srv.URL)
+
+		result, err := tr.TranscribeLocalFile(context.Background(), tc.uri)
+		if err != nil {
+			t.Fatalf("TranscribeLocalFile(%s) error = %v", tc.uri, err)
+		}
+
+		if len(rec.requests) != 1 {
+			t.Fatalf("%s: %d requests; want 1", tc.uri, len(rec.requests))
+		}
+
+		req := rec.requests[0]
+		if req.FileURI != tc.uri || req.MIMEType != tc.wantMIME || len(rec.payloads[0]) != 0 {
+			t.Errorf("%s: request for %q as %q with %d bytes; want a reference as %s",
+				tc.uri, req.FileURI, req.MIMEType, len(rec.payloads[0]), tc.wantMIME)
+		}
+
+		if result.UploadSize != 0 || result.InputSize <= 0 {
+			t.Errorf("%s: uploaded %d of %d bytes; want nothing uploaded", tc.uri, result.UploadSize, result.InputSize)
+		}
+	}
+
+	if n := downloads.Load(); n != 0 {
+		t.Errorf("%d objects downloaded; want audio passed by reference", n)
+	}
+}
+
+func TestStorageObjectsAreDownloadedWhenNeeded(t *testing.T) {
+	t.Parallel()
+
+	srv, downloads := storageServer(t, "archive", map[string]fakeObject{
+		"talks/keynote.mp4": {contentType: "video/mp4", body: []byte("placeholder")},
+		"calls/support.wav": {contentType: "audio/wav", body: synthWAV(3 * time.Second)},
+	})
+
+	// Video is always extracted locally, never passed by reference.
+	rec := &requestRecorder{}
+	tr := newTranscriber(t, &config.Config{Quiet: true, TempDir: t.TempDir()}, rec)
+	tr.SetStorageEndpoint(srv.URL)
+
+	_, _ = tr.TranscribeLocalFile(context.Background(), "gs://archive/talks/keynote.mp4")
+
+	for _, req := range rec.requests {
+		if req.FileURI != "" {
+			t.Errorf("video sent by reference as %q; want it downloaded", req.FileURI)
+		}
+	}
+
+	// Audio is cut locally when only part of it is wanted.
+	rec = &requestRecorder{}
+	tr = newTranscriber(t, &config.Config{Quiet: true, Start: "1", TempDir: t.TempDir()}, rec)
+	tr.SetStorageEndpoint(srv.URL)
+
+	if _, err := tr.TranscribeLocalFile(context.Background(), "gs://archive/calls/support.wav"); err != nil {
+		t.Fatalf("TranscribeLocalFile(--start) error = %v", err)
+	}
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("%d requests; want 1", len(rec.requests))
+	}
+
+	if rec.requests[0].FileURI != "" || len(rec.payloads[0]) == 0 {
+		t.Errorf("request for %q with %d bytes; want the excerpt sent inline", rec.requests[0].FileURI, len(rec.payloads[0]))
+	}
+
+	if n := downloads.Load(); n != 2 {
+		t.Errorf("%d objects downloaded; want 2", n)
+	}
+
+	_, err := tr.TranscribeLocalFile(context.Background(), "gs://archive/calls/missing.wav")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("TranscribeLocalFile(missing) error = %v; want 404", err)
+	}
+}
+
+func TestLongStorageAudioIsDownloadedToChunk(t *testing.T) {
+	t.Parallel()
+
+	srv, downloads := storageServer(t, "archive", map[string]fakeObject{
+		"calls/long.wav": {contentType: "audio/wav", body: synthWAV(40 * time.Second)},
+	})
+
+	rec := &requestRecorder{}
+	tr := newTranscriber(t, &config.Config{
+		Quiet: true, ChunkDuration: 10 * time.Second, TempDir: t.TempDir(),
+	}, rec)
+	tr.SetStorageEndpoint(srv.URL)
+
+	result, err := tr.TranscribeLocalFile(context.Background(), "gs://archive/calls/long.wav")
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() error = %v", err)
+	}
+
+	if downloads.Load() != 1 || result.Chunks < 2 {
+		t.Errorf("%d downloads, %d chunks; want the object downloaded and split", downloads.Load(), result.Chunks)
+	}
+
+	for _, req := range rec.requests {
+		if req.FileURI != "" {
+			t.Errorf("chunk sent by reference as %q; want it inline", req.FileURI)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// selectStream narrows src to the audio stream chosen by sel. With ffprobe
+// output the selector is checked against the actual streams; without it the
+// choice is passed to FFmpeg as a stream specifier and checked there.
+func (s *mediaSource) selectStream(sel config.AudioStreamSelector) error {
+	if s.Info == nil {
+		if sel.Index >= 0 {
+			s.StreamMap = fmt.Sprintf("0:%d", sel.Index)
+		} else {
+			s.StreamMap = "0:a:m:language:" + sel.Language
+		}
+
+		return nil
+	}
+
+	streams := s.Info.AudioStreams()
+
+	for _, stream := range streams {
+		if streamMatches(stream, sel) {
+			s.useStream(stream)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no audio stream matches %s (available: %s)", describeSelector(sel), listStreams(streams))
+}
+
+// useStream restricts src to stream. A file whose only audio stream is
+// stream needs no stream mapping, so native audio can still be sent as-is.
+func (s *mediaSource) useStream(stream StreamInfo) {
+	s.Stream = &stream
+	s.StreamMap = ""
+
+	if len(s.Info.AudioStreams()) > 1 {
+		s.StreamMap = fmt.Sprintf("0:%d", stream.Index)
+	}
+}
+
+// mapArgs returns the FFmpeg output options that select the chosen stream.
+func (s *mediaSource) mapArgs() []string {
+	if s.StreamMap == "" {
+		return nil
+	}
+
+	return []string{"-map", s.StreamMap}
+}
+
+// languageHint returns the ISO 639-1 code of the selected stream's language
+// tag, or "" when no stream was selected or its tag is unknown.
+func (s *mediaSource) languageHint() string {
+	if s.Stream == nil {
+		return ""
+	}
+
+	code, ok := config.LanguageFromTag(s.Stream.Language)
+	if !ok {
+		return ""
+	}
+
+	return code
+}
+
+// streamMatches reports whether stream is the one sel asks for. Language
+// tags match across ISO 639-1 and 639-2 forms, so "lang:uk" finds "ukr".
+func streamMatches(stream StreamInfo, sel config.AudioStreamSelector) bool {
+	if sel.Index >= 0 {
+		return stream.Index == sel.Index
+	}
+
+	if strings.EqualFold(stream.Language, sel.Language) {
+		return true
+	}
+
+	want, ok := config.LanguageFromTag(sel.Language)
+	if !ok {
+		return false
+	}
+
+	got, ok := config.LanguageFromTag(stream.Language)
+
+	return ok && got == want
+}
+
+// describeSelector renders sel as it was written on the command line.
+func describeSelector(sel config.AudioStreamSelector) string {
+	if sel.Index >= 0 {
+		return fmt.Sprintf("index %d", sel.Index)
+	}
+
+	return "language " + sel.Language
+}
+
+// listStreams renders streams as "1 [ukr], 2 [eng]" for error messages.
+func listStreams(streams []StreamInfo) string {
+	parts := make([]string, 0, len(streams))
+
+	for _, stream := range streams {
+		part := fmt.Sprint(stream.Index)
+		if stream.Language != "" {
+			part += " [" + stream.Language + "]"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,104 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+func TestSelectStream(t *testing.T) {
+	t.Parallel()
+
+	video, err := parseProbeOutput([]byte(probeVideo))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+
+	ogg, err := parseProbeOutput([]byte(probeOgg))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		info     *MediaInfo
+		spec     string
+		wantMap  string
+		wantHint string
+		wantErr  string
+	}{
+		{name: "index", path: "movie.mp4", info: video, spec: "2", wantMap: "0:2", wantHint: "en"},
+		{name: "three-letter tag", path: "movie.mp4", info: video, spec: "lang:ukr", wantMap: "0:1", wantHint: "uk"},
+		{name: "two-letter tag", path: "movie.mp4", info: video, spec: "lang:uk", wantMap: "0:1", wantHint: "uk"},
+		{name: "tag is case-insensitive", path: "movie.mp4", info: video, spec: "lang:ENG", wantMap: "0:2", wantHint: "en"},
+		{name: "video stream index", path: "movie.mp4", info: video, spec: "0", wantErr: "available: 1 [ukr], 2 [eng]"},
+		{name: "missing language", path: "movie.mp4", info: video, spec: "lang:deu", wantErr: "language deu"},
+		{name: "only stream needs no map", path: "voice.ogg", info: ogg, spec: "0"},
+		{name: "index without ffprobe", path: "movie.mp4", spec: "3", wantMap: "0:3"},
+		{name: "language without ffprobe", path: "movie.mp4", spec: "lang:ukr", wantMap: "0:a:m:language:ukr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sel, err := config.ParseAudioStream(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseAudioStream(%q) error = %v", tt.spec, err)
+			}
+
+			inputType, mimeType := classifyInputFile(tt.path)
+			src := &mediaSource{Path: tt.path, Type: inputType, MIMEType: mimeType}
+
+			if tt.info != nil {
+				if err := src.applyInfo(tt.info); err != nil {
+					t.Fatalf("applyInfo() error = %v", err)
+				}
+			}
+
+			err = src.selectStream(sel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectStream() error = %v, want containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("selectStream() error = %v", err)
+			}
+
+			if hint := src.languageHint(); src.StreamMap != tt.wantMap || hint != tt.wantHint {
+				t.Errorf("selectStream() = (%q, %q), want (%q, %q)", src.StreamMap, hint, tt.wantMap, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestTranscribeAllAudioStreamsNeedsFFprobe(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "movie.mp4")
+	if err := os.WriteFile(path, []byte("not really a video"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{NoCache: true, FFprobePath: filepath.Join(t.TempDir(), "ffprobe")}
+	tr := NewWithBackend(context.Background(), cfg, nil, nil)
+
+	_, err := tr.TranscribeAllAudioStreams(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "ffprobe not found") {
+		t.Fatalf("TranscribeAllAudioStreams() error = %v, want ffprobe not found", err)
+	}
+}
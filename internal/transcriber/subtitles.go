@@ -0,0 +1,253 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
+)
+
+// maxReferenceBytes bounds the subtitle text given to the model with one
+// request; a chunk of a long recording gets only the cues it overlaps.
+const maxReferenceBytes = 32 << 10
+
+// ErrNoSubtitleStream is returned when an input has no subtitle stream
+// that can be converted to text.
+var ErrNoSubtitleStream = errors.New("no text subtitle stream found")
+
+// imageSubtitleCodecs are subtitle codecs that store pictures of the text,
+// which FFmpeg cannot convert to SRT.
+var imageSubtitleCodecs = []string{"hdmv_pgs_subtitle", "dvd_subtitle", "dvb_subtitle", "xsub"}
+
+// SubtitleStreams returns the subtitle streams in index order.
+func (m *MediaInfo) SubtitleStreams() []StreamInfo {
+	var out []StreamInfo
+
+	for _, s := range m.Streams {
+		if s.Type == "subtitle" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// SubtitleTrack is an embedded subtitle stream converted to text cues on
+// the timeline of the media.
+type SubtitleTrack struct {
+	Stream StreamInfo
+	Cues   []subtitle.Cue
+}
+
+// ExtractSubtitles converts the subtitle stream of a local file chosen by
+// --subtitle-stream, or its default text subtitle stream, to cues without
+// transcribing anything. Finding the streams requires ffprobe.
+func (t *Transcriber) ExtractSubtitles(ctx context.Context, inputPath string) (*SubtitleTrack, error) {
+	t.logger.InfoContext(ctx, "processing file", slog.String("path", inputPath))
+
+	ctx = t.track(ctx, inputPath)
+
+	src, err := t.openSource(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("preparing input: %w", err)
+	}
+
+	defer t.closeAudio(ctx, src)
+
+	return t.subtitleTrack(ctx, src)
+}
+
+// useSubtitles attaches the subtitle track of src for --subtitles context
+// or diff. A missing track is an error when comparing; as context it is
+// optional.
+func (t *Transcriber) useSubtitles(ctx context.Context, src *mediaSource) error {
+	mode := t.config.Subtitles
+	if mode != config.SubtitlesContext && mode != config.SubtitlesDiff {
+		return nil
+	}
+
+	track, err := t.subtitleTrack(ctx, src)
+
+	switch {
+	case errors.Is(err, ErrNoSubtitleStream) && mode == config.SubtitlesContext:
+		t.logger.WarnContext(ctx, "no subtitles to use as context", slog.Any("error", err))
+	case err != nil:
+		return fmt.Errorf("reading subtitles: %w", err)
+	default:
+		src.subtitles = track
+	}
+
+	return nil
+}
+
+// subtitleTrack selects the configured subtitle stream of src and extracts
+// it.
+func (t *Transcriber) subtitleTrack(ctx context.Context, src *mediaSource) (*SubtitleTrack, error) {
+	if src.Info == nil {
+		return nil, fmt.Errorf("listing subtitle streams: %w", errNoFFprobe)
+	}
+
+	stream, err := selectSubtitleStream(src.Info.SubtitleStreams(), t.config.SubtitleStream)
+	if err != nil {
+		return nil, err
+	}
+
+	cues, err := t.tools.extractSubtitles(ctx, src, stream)
+	if err != nil {
+		return nil, fmt.Errorf("subtitle stream %d: %w", stream.Index, err)
+	}
+
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("subtitle stream %d has no text cues", stream.Index)
+	}
+
+	t.logger.InfoContext(ctx, "subtitles extracted",
+		slog.Int("stream", stream.Index),
+		slog.String("codec", stream.Codec),
+		slog.String("language", stream.Language),
+		slog.Int("cues", len(cues)),
+	)
+
+	return &SubtitleTrack{Stream: stream, Cues: cues}, nil
+}
+
+// selectSubtitleStream returns the stream chosen by selector, as parsed by
+// config.ParseAudioStream, or else the default text stream, or else the
+// first one.
+func selectSubtitleStream(streams []StreamInfo, selector string) (StreamInfo, error) {
+	textual := slices.DeleteFunc(slices.Clone(streams), func(s StreamInfo) bool {
+		return slices.Contains(imageSubtitleCodecs, s.Codec)
+	})
+
+	if selector != "" {
+		sel, err := config.ParseAudioStream(selector)
+		if err != nil {
+			return StreamInfo{}, fmt.Errorf("invalid --subtitle-stream: %w", err)
+		}
+
+		i := slices.IndexFunc(streams, func(s StreamInfo) bool { return streamMatches(s, sel) })
+		if i < 0 {
+			return StreamInfo{}, fmt.Errorf("%w matching %s (available: %s)",
+				ErrNoSubtitleStream, describeSelector(sel), listStreams(streams))
+		}
+
+		if !slices.Contains(textual, streams[i]) {
+			return StreamInfo{}, fmt.Errorf("subtitle stream %d is %s, which holds images rather than text",
+				streams[i].Index, streams[i].Codec)
+		}
+
+		return streams[i], nil
+	}
+
+	if len(textual) == 0 {
+		if len(streams) > 0 {
+			return StreamInfo{}, fmt.Errorf("%w; streams %s hold images rather than text",
+				ErrNoSubtitleStream, listStreams(streams))
+		}
+
+		return StreamInfo{}, ErrNoSubtitleStream
+	}
+
+	if i := slices.IndexFunc(textual, func(s StreamInfo) bool { return s.Default }); i >= 0 {
+		return textual[i], nil
+	}
+
+	return textual[0], nil
+}
+
+// extractSubtitles converts stream of src to cues, with FFmpeg writing the
+// stream as SRT to a pipe.
+func (tc *toolchain) extractSubtitles(
+	ctx context.Context, src *mediaSource, stream StreamInfo,
+) ([]subtitle.Cue, error) {
+	args := append([]string{"-hide_banner", "-nostats"}, tc.inputArgs...)
+	args = append(args, src.inputArgs()...)
+	args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index), "-c:s", "srt", "-f", "srt", "pipe:1")
+
+	stdin, err := src.ffmpegInput()
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+
+	pass := ffmpegPass{Args: args}
+	if src.Info != nil {
+		pass.Duration = src.Info.Duration
+	}
+
+	if _, err := tc.run(ctx, pass, stdin, &out); err != nil {
+		return nil, fmt.Errorf("extracting subtitles: %w", err)
+	}
+
+	cues, err := subtitle.Parse(&out)
+	if err != nil {
+		return nil, fmt.Errorf("extracting subtitles: %w", err)
+	}
+
+	return cues, nil
+}
+
+// subtitleReference is a subtitle track given to the model as context.
+type subtitleReference struct {
+	cues []subtitle.Cue
+	// offsets maps the decoded timeline of requests back to the original
+	// timeline of the cues.
+	offsets *timeMap
+}
+
+// text returns the lines of the cues overlapping the decoded audio from
+// offset lasting duration, or to the end when duration is zero, up to
+// maxReferenceBytes of them.
+func (r *subtitleReference) text(offset, duration time.Duration) string {
+	if r == nil {
+		return ""
+	}
+
+	from, to := r.offsets.ToOriginal(offset), time.Duration(-1)
+	if duration > 0 {
+		to = r.offsets.ToOriginal(offset + duration)
+	}
+
+	var b strings.Builder
+
+	for _, cue := range r.cues {
+		if cue.End < from || (to >= 0 && cue.Start > to) {
+			continue
+		}
+
+		line := cue.PlainText()
+		if b.Len()+len(line)+1 > maxReferenceBytes {
+			break
+		}
+
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// compareSubtitles compares track with the timed segments of a transcript.
+func compareSubtitles(track *SubtitleTrack, segments []gemini.Segment) *subtitle.Report {
+	transcript := make([]subtitle.Cue, len(segments))
+
+	for i, seg := range segments {
+		transcript[i] = subtitle.Cue{Start: seg.Start, End: seg.End, Text: seg.Text}
+	}
+
+	return subtitle.Compare(track.Cues, transcript)
+}
@@ -0,0 +1,99 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
+)
+
+// probeSubtitledVideo describes a film with an English and a Ukrainian text
+// subtitle track, the latter the default, and a Blu-ray picture track.
+const probeSubtitledVideo = `{
+  "streams": [
+    {"index": 0, "codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080},
+    {"index": 1, "codec_name": "aac", "codec_type": "audio", "sample_rate": "48000", "channels": 2},
+    {"index": 2, "codec_name": "subrip", "codec_type": "subtitle", "tags": {"language": "eng"}},
+    {"index": 3, "codec_name": "ass", "codec_type": "subtitle", "tags": {"language": "ukr"},
+     "disposition": {"default": 1}},
+    {"index": 4, "codec_name": "hdmv_pgs_subtitle", "codec_type": "subtitle", "tags": {"language": "fra"}}
+  ],
+  "format": {"format_name": "matroska,webm", "duration": "5400.0"}
+}`
+
+func TestSelectSubtitleStream(t *testing.T) {
+	t.Parallel()
+
+	info, err := parseProbeOutput([]byte(probeSubtitledVideo))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() unexpected error: %v", err)
+	}
+
+	streams := info.SubtitleStreams()
+
+	tests := []struct {
+		selector string
+		want     int
+		wantErr  string
+	}{
+		{selector: "", want: 3},
+		{selector: "lang:eng", want: 2},
+		{selector: "2", want: 2},
+		{selector: "lang:fra", wantErr: "holds images"},
+		{selector: "lang:deu", wantErr: "no text subtitle stream"},
+	}
+
+	for _, tc := range tests {
+		got, err := selectSubtitleStream(streams, tc.selector)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("selector %q: error = %v; want %q", tc.selector, err, tc.wantErr)
+			}
+
+			continue
+		}
+
+		if err != nil || got.Index != tc.want {
+			t.Errorf("selector %q: got stream %d, %v; want %d", tc.selector, got.Index, err, tc.want)
+		}
+	}
+
+	_, err = selectSubtitleStream(streams[2:], "")
+	if !errors.Is(err, ErrNoSubtitleStream) {
+		t.Errorf("picture track only: error = %v; want ErrNoSubtitleStream", err)
+	}
+}
+
+func TestSubtitleReferenceText(t *testing.T) {
+	t.Parallel()
+
+	cues := []subtitle.Cue{
+		{Start: 1 * time.Second, End: 3 * time.Second, Text: "<i>Good evening.</i>"},
+		{Start: 12 * time.Second, End: 15 * time.Second, Text: "Welcome to\nthe news."},
+		{Start: 30 * time.Second, End: 33 * time.Second, Text: "Goodbye."},
+	}
+	// Five seconds of silence at 5s were cut, so decoded 10s is original 15s.
+	removed := []timeSpan{{Start: 5 * time.Second, Length: 5 * time.Second}}
+	ref := &subtitleReference{cues: cues, offsets: newRemovalMap(removed)}
+
+	if got, want := ref.text(0, 0),
+		"Good evening.\nWelcome to the news.\nGoodbye."; got != want {
+		t.Errorf("whole input: %q; want %q", got, want)
+	}
+
+	if got, want := ref.text(6*time.Second, 10*time.Second),
+		"Welcome to the news."; got != want {
+		t.Errorf("chunk at 6s: %q; want %q", got, want)
+	}
+
+	if got := ref.text(40*time.Second, 10*time.Second); got != "" {
+		t.Errorf("chunk past the cues: %q; want empty", got)
+	}
+}
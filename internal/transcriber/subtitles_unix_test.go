@@ -0,0 +1,138 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build unix
+
+package transcriber_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/config"
+)
+
+// probeSubtitledWAV pretends a WAV recording carries a subtitle stream, so
+// the audio is read natively while the subtitles come from FFmpeg.
+const probeSubtitledWAV = `{
+  "streams": [
+    {"index": 0, "codec_name": "pcm_s16le", "codec_type": "audio", "sample_rate": "16000", "channels": 1},
+    {"index": 1, "codec_name": "subrip", "codec_type": "subtitle", "tags": {"language": "eng"}}
+  ],
+  "format": {"format_name": "wav", "duration": "4.0"}
+}`
+
+// subtitledInput writes a four-second WAV and a fake ffmpeg that prints an
+// SRT track with two cues, returning their paths.
+func subtitledInput(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	wav := filepath.Join(dir, "talk.wav")
+
+	if err := os.WriteFile(wav, synthWAV(4*time.Second), 0o600); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	script := `#!/bin/sh
+if [ "$1" = -version ]; then echo 'ffmpeg version 6.1 Copyright (c) 2000-2025 the FFmpeg developers'; exit 0; fi
+printf '1\n00:00:00,500 --> 00:00:02,000\nHello there.\n\n2\n00:00:02,000 --> 00:00:03,500\n<i>Goodbye.</i>\n\n'
+`
+
+	if err := os.WriteFile(ffmpeg, []byte(script), 0o700); err != nil { // #nosec G306 -- test script must be executable
+		t.Fatalf("writing fake ffmpeg: %v", err)
+	}
+
+	return wav, ffmpeg
+}
+
+func TestSubtitlesAsContext(t *testing.T) {
+	t.Parallel()
+
+	wav, ffmpeg := subtitledInput(t)
+	cfg := &config.Config{Quiet: true, FFmpegPath: ffmpeg, Subtitles: config.SubtitlesContext}
+	rec := &requestRecorder{}
+
+	tr := newTranscriber(t, cfg, rec)
+	tr.SetProbeOutput(probeSubtitledWAV)
+
+	result, err := tr.TranscribeLocalFile(context.Background(), wav)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("got %d requests; want 1", len(rec.requests))
+	}
+
+	if got, want := rec.requests[0].Reference, "Hello there.\nGoodbye."; got != want {
+		t.Errorf("request Reference = %q; want %q", got, want)
+	}
+
+	if result.Subtitles == nil || result.Subtitles.Stream.Index != 1 || len(result.Subtitles.Cues) != 2 {
+		t.Errorf("Subtitles = %+v; want the two cues of stream 1", result.Subtitles)
+	}
+
+	if result.SubtitleReport != nil {
+		t.Error("SubtitleReport set without --subtitles diff")
+	}
+}
+
+func TestSubtitlesDiff(t *testing.T) {
+	t.Parallel()
+
+	wav, ffmpeg := subtitledInput(t)
+	cfg := &config.Config{Quiet: true, FFmpegPath: ffmpeg, Subtitles: config.SubtitlesDiff}
+	stub := &chunkStub{}
+
+	tr := newTranscriber(t, cfg, stub)
+	tr.SetProbeOutput(probeSubtitledWAV)
+
+	result, err := tr.TranscribeLocalFile(context.Background(), wav)
+	if err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	// The stub answers "part" from 1s to 3s, as timestamps are requested for
+	// the comparison even though --timestamps is off.
+	report := result.SubtitleReport
+	if report == nil || len(report.Cues) != 2 {
+		t.Fatalf("SubtitleReport = %+v; want a comparison of both cues", report)
+	}
+
+	if report.SubtitleWords != 3 || report.TranscriptWords != 1 || report.MatchedWords != 0 {
+		t.Errorf("words = %d/%d/%d; want 3 subtitle, 1 transcript, 0 matched",
+			report.SubtitleWords, report.TranscriptWords, report.MatchedWords)
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil || !strings.Contains(text.String(), "Hello there.") {
+		t.Errorf("WriteText() = %q, %v; want the differing cue listed", text.String(), err)
+	}
+}
+
+func TestSubtitlesContextWithoutTrack(t *testing.T) {
+	t.Parallel()
+
+	wav, ffmpeg := subtitledInput(t)
+	cfg := &config.Config{Quiet: true, FFmpegPath: ffmpeg, Subtitles: config.SubtitlesContext}
+	rec := &requestRecorder{}
+
+	tr := newTranscriber(t, cfg, rec)
+	tr.SetProbeOutput(strings.Replace(probeSubtitledWAV, `"subtitle"`, `"data"`, 1))
+
+	if _, err := tr.TranscribeLocalFile(context.Background(), wav); err != nil {
+		t.Fatalf("TranscribeLocalFile() unexpected error: %v", err)
+	}
+
+	if len(rec.requests) != 1 || rec.requests[0].Reference != "" {
+		t.Errorf("requests = %+v; want one request without a reference", rec.requests)
+	}
+}
@@ -7,16 +7,25 @@
 package transcriber
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/idvoretskyi/voice-transcriber/internal/audio"
+	"github.com/idvoretskyi/voice-transcriber/internal/cache"
 	"github.com/idvoretskyi/voice-transcriber/internal/config"
 	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
+	"github.com/idvoretskyi/voice-transcriber/internal/subtitle"
 )
 
 const gcloudTimeout = 10 * time.Second
@@ -27,18 +36,98 @@ type TranscriptionResult struct {
 	Text           string
 	ProcessingTime time.Duration
 	WordCount      int
+	// Segments holds timed segments on the source timeline. It is populated
+	// when timestamps were requested or the audio was chunked.
+	Segments []gemini.Segment
+	// Chunks is the number of backend requests the audio was split into.
+	Chunks int
+	// SilenceRemoved is the amount of audio dropped by silence trimming, out
+	// of SourceDuration in total. Both are zero unless trimming was enabled.
+	SilenceRemoved time.Duration
+	SourceDuration time.Duration
+	// InputSize is the size of the input file and UploadSize the total audio
+	// payload sent to the backend, both in bytes.
+	InputSize  int64
+	UploadSize int64
+	// Stream is the audio stream that was transcribed, when one was selected
+	// with --audio-stream or --all-audio-streams and ffprobe described it.
+	Stream *StreamInfo
+	// Subtitles is the embedded subtitle track used with --subtitles
+	// context or diff, and SubtitleReport its comparison with the
+	// transcript in diff mode.
+	Subtitles      *SubtitleTrack
+	SubtitleReport *subtitle.Report
+	// Quality is the analysis of the audio before upload, when --analyze
+	// or a quality threshold asked for one.
+	Quality *QualityReport
+	// Usage is the tokens the backend requests were billed for; zero for
+	// a cached transcript.
+	Usage gemini.Usage
+	// Cached reports that the transcript was read from the cache instead of
+	// the model; nothing was uploaded.
+	Cached bool
+}
+
+// TimestampedText returns the transcript with one segment per line, each
+// prefixed with its start time and, when known, its speaker. Without
+// segments it returns Text unchanged.
+func (r *TranscriptionResult) TimestampedText() string {
+	if len(r.Segments) == 0 {
+		return r.Text
+	}
+
+	var b strings.Builder
+
+	for _, seg := range r.Segments {
+		if seg.Speaker != "" {
+			fmt.Fprintf(&b, "[%s] %s: %s\n", FormatTimestamp(seg.Start), seg.Speaker, seg.Text)
+
+			continue
+		}
+
+		fmt.Fprintf(&b, "[%s] %s\n", FormatTimestamp(seg.Start), seg.Text)
+	}
+
+	return b.String()
+}
+
+// FormatTimestamp formats d as HH:MM:SS.mmm.
+func FormatTimestamp(d time.Duration) string {
+	d = max(d, 0)
+	ms := d.Milliseconds()
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, ms%1000)
 }
 
 // projectIDResolver is the function type used to obtain a GCP project ID
 // at runtime. The default implementation calls gcloud; tests can inject a stub.
 type projectIDResolver func(ctx context.Context) (string, error)
 
+// mediaProber inspects an input with ffprobe. The default implementation is
+// the toolchain's probe; tests can inject a stub.
+type mediaProber func(ctx context.Context, src *mediaSource) (*MediaInfo, error)
+
 // Transcriber handles the main transcription logic.
 type Transcriber struct {
 	config    *config.Config
 	backend   gemini.AudioTranscriber
 	logger    *slog.Logger
 	resolveID projectIDResolver
+	probe     mediaProber
+	// tools runs FFmpeg and ffprobe.
+	tools *toolchain
+	// stdin is read for the input path "-".
+	stdin io.Reader
+	// fetch downloads http(s) inputs.
+	fetch *downloader
+	// storage reads gs:// inputs.
+	storage *storageClient
+	// workspace holds the temporary files of the run until Close.
+	workspace *workspace
+	// cache holds transcription results; nil disables caching.
+	cache *cache.Cache
+	// progress holds the functions subscribed to progress events.
+	progress subscribers
 }
 
 // getProjectIDFromGcloud gets the current project ID from gcloud.
@@ -79,11 +168,7 @@ func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Transcr
 		logger = slog.Default()
 	}
 
-	t := &Transcriber{
-		config:    cfg,
-		logger:    logger,
-		resolveID: getProjectIDFromGcloud,
-	}
+	t := NewWithBackend(ctx, cfg, nil, logger)
 
 	// Prefer GCPProject already on the config (e.g. from FromEnv), then env
 	// var, then gcloud CLI (via the injected resolver).
@@ -116,33 +201,741 @@ func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Transcr
 	return t, nil
 }
 
-// TranscribeLocalFile transcribes a local video or audio file.
+// NewWithBackend creates a Transcriber that sends audio to backend rather
+// than to a Gemini service of its own, and so needs no GCP project. Stale
+// workspaces under the temporary directory are removed as by New.
+// If logger is nil, slog.Default() is used.
+func NewWithBackend(
+	ctx context.Context, cfg *config.Config, backend gemini.AudioTranscriber, logger *slog.Logger,
+) *Transcriber {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ws := newWorkspace(cfg.TempDir, logger)
+	ws.sweep(ctx)
+
+	tools := newToolchain(cfg, logger)
+
+	return &Transcriber{
+		config:    cfg,
+		backend:   backend,
+		logger:    logger,
+		resolveID: getProjectIDFromGcloud,
+		probe:     tools.probe,
+		tools:     tools,
+		stdin:     os.Stdin,
+		fetch:     newDownloader(ws, logger),
+		storage:   newStorageClient(),
+		workspace: ws,
+		cache:     openCache(ctx, cfg, logger),
+	}
+}
+
+// SetFFmpegProgress sets the function that receives progress events from
+// FFmpeg while audio is decoded, scanned for silence or encoded. By default
+// they are logged at debug level.
+func (t *Transcriber) SetFFmpegProgress(fn func(FFmpegProgress)) {
+	t.tools.progress = fn
+}
+
+// Close removes the temporary files of the run. Implements io.Closer.
+func (t *Transcriber) Close() error {
+	return t.workspace.Close()
+}
+
+// TranscribeLocalFile transcribes a local video or audio file, a named pipe,
+// or standard input when inputPath is StdinPath.
 // ctx controls the lifetime of the entire operation.
 // It returns a *TranscriptionResult on success, or a non-nil error on failure.
 func (t *Transcriber) TranscribeLocalFile(ctx context.Context, inputPath string) (*TranscriptionResult, error) {
-	startTime := time.Now()
+	t.logger.InfoContext(ctx, "processing file", slog.String("path", inputPath))
+
+	ctx = t.track(ctx, inputPath)
+
+	src, err := t.openSource(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("preparing audio: %w", err)
+	}
+
+	defer t.closeAudio(ctx, src)
+
+	if t.config.AudioStream != "" {
+		sel, err := config.ParseAudioStream(t.config.AudioStream)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --audio-stream: %w", err)
+		}
+
+		if err := src.selectStream(sel); err != nil {
+			return nil, fmt.Errorf("selecting audio stream: %w", err)
+		}
+	}
+
+	if err := t.useSubtitles(ctx, src); err != nil {
+		return nil, err
+	}
+
+	return t.transcribeSource(ctx, src)
+}
 
+// TranscribeAllAudioStreams transcribes every audio stream of a local file
+// separately, one after another, returning one result per stream in index
+// order. Each stream's language tag is used as its language hint unless
+// --language is set. Enumerating the streams requires ffprobe.
+func (t *Transcriber) TranscribeAllAudioStreams(ctx context.Context, inputPath string) ([]*TranscriptionResult, error) {
 	t.logger.InfoContext(ctx, "processing file", slog.String("path", inputPath))
 
-	prepared, err := prepareAudio(ctx, inputPath, t.logger)
+	ctx = t.track(ctx, inputPath)
+
+	src, err := t.openSource(ctx, inputPath)
 	if err != nil {
 		return nil, fmt.Errorf("preparing audio: %w", err)
 	}
 
-	defer func() {
-		if closeErr := prepared.Close(); closeErr != nil {
-			t.logger.WarnContext(ctx, "failed to remove temp audio file", slog.Any("error", closeErr))
+	defer t.closeAudio(ctx, src)
+
+	if src.Info == nil {
+		return nil, fmt.Errorf("listing audio streams: %w", errNoFFprobe)
+	}
+
+	streams := src.Info.AudioStreams()
+	results := make([]*TranscriptionResult, 0, len(streams))
+
+	for _, stream := range streams {
+		track := *src
+		track.useStream(stream)
+
+		t.logger.InfoContext(ctx, "transcribing audio stream",
+			slog.Int("stream", stream.Index),
+			slog.String("language", stream.Language),
+		)
+
+		result, err := t.transcribeSource(ctx, &track)
+		if err != nil {
+			return nil, fmt.Errorf("audio stream %d: %w", stream.Index, err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// transcribeSource prepares and transcribes an opened input, or returns
+// the cached result of transcribing the same audio the same way.
+func (t *Transcriber) transcribeSource(ctx context.Context, src *mediaSource) (*TranscriptionResult, error) {
+	startTime := time.Now()
+
+	reportStage(ctx, StageExtracting)
+
+	codec, err := lookupCodec(t.config.UploadCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TranscriptionResult{InputSize: src.Size, Stream: src.Stream}
+
+	opts, offsets, err := t.prepareOptions(ctx, src, result)
+	if err != nil {
+		return nil, err
+	}
+
+	reqOpts := t.requestOptions(src, codec)
+	if src.subtitles != nil && t.config.Subtitles == config.SubtitlesContext {
+		reqOpts.Reference = &subtitleReference{cues: src.subtitles.Cues, offsets: offsets}
+	}
+
+	var staged *stagedAudio
+	if t.staging(src) {
+		if staged, err = t.stage(ctx, src, opts); err != nil {
+			return nil, err
+		}
+
+		defer t.closeAudio(ctx, staged)
+	}
+
+	// The quality check runs before the lookup, so a cached transcript is
+	// held to this run's thresholds.
+	var quality *QualityReport
+	if staged != nil {
+		if quality, err = t.checkQuality(ctx, *staged.levels); err != nil {
+			return nil, err
+		}
+	}
+
+	slot, cached := t.cachedResult(ctx, src, opts, reqOpts, offsets)
+	if cached != nil {
+		// The cached result describes the transcription that made it; this
+		// run uploaded nothing.
+		result = cached
+		result.InputSize, result.Stream, result.Cached = src.Size, src.Stream, true
+		result.UploadSize, result.Usage = 0, gemini.Usage{}
+	} else {
+		var (
+			transcript *gemini.Transcript
+			stats      uploadStats
+			digest     *audioDigest
+		)
+
+		if slot != nil {
+			digest = slot.digest
+		}
+
+		switch {
+		case src.fileURI != "":
+			transcript, stats, err = t.transcribeReference(ctx, src, reqOpts)
+		case staged != nil && staged.file != nil:
+			transcript, stats, err = t.transcribeStaged(ctx, staged, reqOpts, digest)
+		case t.config.SplitChannels:
+			transcript, stats, err = t.transcribeChannels(ctx, src, opts, reqOpts, digest)
+		default:
+			transcript, stats, err = t.transcribeStream(ctx, src, opts, reqOpts, digest)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		reportStage(ctx, StagePostProcessing)
+
+		offsets.apply(transcript)
+
+		result.Text = transcript.Text
+		result.WordCount = len(strings.Fields(transcript.Text))
+		result.Segments = transcript.Segments
+		result.Chunks = stats.Requests
+		result.UploadSize = stats.Bytes
+		result.Usage = transcript.Usage
+
+		t.storeResult(ctx, slot, src, result)
+	}
+
+	// Set after the lookup so that a cached result reports this run's time
+	// and analysis.
+	result.ProcessingTime, result.Quality = time.Since(startTime), quality
+
+	if result.Subtitles = src.subtitles; src.subtitles != nil && t.config.Subtitles == config.SubtitlesDiff {
+		result.SubtitleReport = compareSubtitles(src.subtitles, result.Segments)
+	}
+
+	return result, nil
+}
+
+// prepareOptions returns how src is decoded for transcription: cut to the
+// selected ranges, trimmed of silence and enhanced as configured. It
+// records the silence removed in result and returns the map from the
+// decoded timeline back to the original one.
+func (t *Transcriber) prepareOptions(
+	ctx context.Context, src *mediaSource, result *TranscriptionResult,
+) (prepareOptions, *timeMap, error) {
+	enhance, err := enhanceFilters(t.config.Enhance, t.config.AudioFilter)
+	if err != nil {
+		return prepareOptions{}, nil, err
+	}
+
+	// A pipe cannot be read again once its length is known, so piped audio
+	// that may need chunking is decoded as it arrives.
+	opts := prepareOptions{
+		Transcode: t.config.TranscodeAudio || (src.piped() && t.config.ChunkDuration > 0),
+		Format:    t.format(),
+	}
+	offsets := src.rangeMap()
+
+	if t.config.TrimSilence {
+		trimmed, err := t.tools.detectSilence(ctx, src, silenceOptions{
+			ThresholdDB: t.config.SilenceLevel(),
+			MinDuration: t.config.MinSilence(),
+		})
+		if err != nil {
+			return prepareOptions{}, nil, fmt.Errorf("trimming silence: %w", err)
 		}
-	}()
 
-	transcript, err := t.backend.TranscribeAudio(ctx, prepared.Data, prepared.MIMEType)
+		if trimmed.Filter != "" {
+			opts.Filters = append(opts.Filters, trimmed.Filter)
+		}
+
+		offsets = trimmed.Map.within(offsets)
+		result.SilenceRemoved, result.SourceDuration = trimmed.Removed, trimmed.Total
+	}
+
+	// Enhancement runs after trimming: levelling would lift the silences
+	// above the detection threshold.
+	opts.Filters = append(opts.Filters, enhance...)
+
+	return opts, offsets, nil
+}
+
+// transcribeStream decodes src as described by opts and transcribes it,
+// hashing what it sends with digest.
+func (t *Transcriber) transcribeStream(
+	ctx context.Context, src *mediaSource, opts prepareOptions, reqOpts requestOptions, digest *audioDigest,
+) (*gemini.Transcript, uploadStats, error) {
+	prepared, err := t.tools.prepareAudio(ctx, src, opts)
 	if err != nil {
-		return nil, fmt.Errorf("transcribing audio: %w", err)
+		return nil, uploadStats{}, fmt.Errorf("preparing audio: %w", err)
+	}
+
+	defer t.closeAudio(ctx, prepared)
+
+	digest.attach(prepared)
+
+	return t.transcribePrepared(ctx, prepared, reqOpts)
+}
+
+// format returns the PCM format audio is decoded to.
+func (t *Transcriber) format() audio.Format {
+	format := defaultFormat
+
+	if t.config.SampleRate > 0 {
+		format.SampleRate = t.config.SampleRate
+	}
+
+	if t.config.Channels > 0 {
+		format.Channels = t.config.Channels
+	}
+
+	return format
+}
+
+// requestOptions holds the per-input settings of every backend request.
+type requestOptions struct {
+	Codec uploadCodec
+	// Language overrides --language for this input; empty keeps it.
+	Language string
+	// Timestamps asks for timed segments.
+	Timestamps bool
+	// Reference is the subtitle track given to the model as context, or
+	// nil.
+	Reference *subtitleReference
+}
+
+// requestOptions returns the request settings for src. With automatic
+// language detection, a selected stream's language tag becomes the hint.
+// Comparing with subtitles needs timed segments to align them.
+func (t *Transcriber) requestOptions(src *mediaSource, codec uploadCodec) requestOptions {
+	opts := requestOptions{
+		Codec:      codec,
+		Timestamps: t.config.Timestamps || t.config.Subtitles == config.SubtitlesDiff,
+	}
+
+	if _, auto := config.NormalizeLanguage(t.config.Language); auto {
+		opts.Language = src.languageHint()
+	}
+
+	return opts
+}
+
+// openSource validates and classifies inputPath, refining the content-based
+// guess with ffprobe when it is installed, and applies the configured input
+// format and time ranges. StdinPath opens standard input, http(s) URLs are
+// downloaded, and gs:// objects are referenced or downloaded.
+func (t *Transcriber) openSource(ctx context.Context, inputPath string) (*mediaSource, error) {
+	reportStage(ctx, StageProbing)
+
+	var (
+		src *mediaSource
+		err error
+	)
+
+	switch {
+	case IsURL(inputPath):
+		src, err = t.fetch.open(ctx, inputPath)
+	case IsStorageURI(inputPath):
+		src, err = t.openStorage(ctx, inputPath, t.config.InputFormat, t.byReference())
+	default:
+		src, err = openInput(inputPath, t.stdin)
 	}
 
-	return &TranscriptionResult{
-		Text:           transcript,
-		WordCount:      len(strings.Fields(transcript)),
-		ProcessingTime: time.Since(startTime),
+	if err != nil {
+		return nil, err
+	}
+
+	src.input = inputPath
+
+	if err := t.configureSource(ctx, src); err != nil {
+		_ = src.Close()
+
+		return nil, err
+	}
+
+	return src, nil
+}
+
+// configureSource inspects an opened src and applies the configuration to it.
+func (t *Transcriber) configureSource(ctx context.Context, src *mediaSource) error {
+	// Referenced objects are never read here, and byReference rules out
+	// any configuration that would need them to be.
+	if src.fileURI != "" {
+		return nil
+	}
+
+	if t.config.InputFormat != "" {
+		src.setFormat(t.config.InputFormat)
+	}
+
+	if src.piped() {
+		if err := t.checkPiped(); err != nil {
+			return err
+		}
+
+		// ffprobe would consume the pipe, so its content has to do.
+		t.logger.DebugContext(ctx, "reading from a pipe; classifying input by content",
+			slog.String("container", src.Container),
+			slog.Bool("native", src.Type == InputTypeAudio),
+		)
+	} else if err := t.inspect(ctx, src); err != nil {
+		return err
+	}
+
+	ranges, err := t.config.TimeRanges()
+	if err != nil {
+		return err
+	}
+
+	return src.selectRanges(ranges)
+}
+
+// inspect describes src with ffprobe, when it is installed, or from its WAV
+// header otherwise.
+func (t *Transcriber) inspect(ctx context.Context, src *mediaSource) error {
+	info, err := t.probe(ctx, src)
+
+	switch {
+	case errors.Is(err, errNoFFprobe):
+		t.logger.DebugContext(ctx, "ffprobe not available; classifying input by content",
+			slog.String("container", src.Container),
+			slog.Bool("native", src.Type == InputTypeAudio),
+		)
+
+		if info, ok := src.nativeInfo(); ok {
+			src.Info = info
+		}
+	case err != nil:
+		return fmt.Errorf("inspecting media: %w", err)
+	default:
+		if err := src.applyInfo(info); err != nil {
+			return err
+		}
+
+		t.logger.DebugContext(ctx, "media inspected",
+			slog.String("container", info.Container),
+			slog.Duration("duration", info.Duration),
+			slog.Int("audio_streams", len(info.AudioStreams())),
+			slog.Bool("native", src.Type == InputTypeAudio),
+		)
+	}
+
+	return nil
+}
+
+// closeAudio releases p, logging rather than returning any cleanup error.
+func (t *Transcriber) closeAudio(ctx context.Context, p io.Closer) {
+	if err := p.Close(); err != nil {
+		t.logger.WarnContext(ctx, "failed to release audio stream", slog.Any("error", err))
+	}
+}
+
+// uploadStats counts the backend requests made for one input and the audio
+// bytes they carried.
+type uploadStats struct {
+	Requests int
+	Bytes    int64
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+
+	return n, err //nolint:wrapcheck // callers compare against io.EOF
+}
+
+// transcribePrepared sends prepared audio to the backend as described by
+// opts, splitting it into chunks when it is longer than the configured chunk
+// window. PCM WAV streams are always read through the chunker, so at most
+// one window of audio per worker is held in memory; a stream that fits in a
+// single window becomes a single request. Chunks are kept within the inline
+// request limit.
+func (t *Transcriber) transcribePrepared(
+	ctx context.Context, prepared *PreparedAudio, opts requestOptions,
+) (*gemini.Transcript, uploadStats, error) {
+	window := t.config.ChunkDuration
+
+	pcm, format, duration, err := prepared.pcmWAV()
+	if err != nil {
+		return nil, uploadStats{}, err
+	}
+
+	if !pcm {
+		duration = gemini.EstimateSizeDuration(prepared.Size, prepared.MIMEType)
+		if info := prepared.source.Info; info != nil && info.Duration > 0 {
+			duration = info.Duration
+		}
+
+		if window <= 0 || duration <= window+window/4 {
+			return t.transcribeWhole(ctx, prepared, duration, opts)
+		}
+	}
+
+	// Chunking needs 16-bit PCM; decode anything else with FFmpeg. Native
+	// WAV at a higher rate than the decode format would be split into more
+	// and larger chunks, so WAV too long for one request is resampled.
+	resample := pcm && prepared.native && window > 0 && format.ByteRate() > t.format().ByteRate() &&
+		(duration > window || prepared.Size > gemini.MaxInlineSize)
+
+	if !pcm || resample {
+		decoded, err := t.tools.decodeAudio(ctx, prepared.source, prepareOptions{Format: t.format()})
+
+		switch {
+		case err == nil:
+			defer t.closeAudio(ctx, decoded)
+
+			// The digest covers the audio sent, which is now decoded.
+			decoded.digest, prepared.digest = prepared.digest, nil
+			prepared = decoded
+		case resample:
+			t.logger.WarnContext(ctx, "cannot resample audio for chunking; splitting it at its own rate",
+				slog.Any("error", err))
+		default:
+			t.logger.WarnContext(ctx, "cannot decode audio for chunking; sending as a single request",
+				slog.Any("error", err))
+
+			return t.transcribeWhole(ctx, prepared, duration, opts)
+		}
+	}
+
+	if window <= 0 {
+		return t.transcribeWhole(ctx, prepared, duration, opts)
+	}
+
+	c, err := newChunker(prepared, chunkOptions{
+		Window:  window,
+		Overlap: t.config.ChunkOverlap,
+		MaxSize: gemini.MaxInlineSize,
+	})
+	if err != nil {
+		return nil, uploadStats{}, fmt.Errorf("preparing chunks: %w", err)
+	}
+
+	first, err := c.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, uploadStats{}, fmt.Errorf("no audio to transcribe")
+	} else if err != nil {
+		return nil, uploadStats{}, fmt.Errorf("splitting audio: %w", err)
+	}
+
+	if first.End == 0 {
+		// The whole stream fits in one window. Native audio keeps its
+		// encoding, as it would have without chunking.
+		req, err := t.chunkRequest(ctx, first, opts, !prepared.native)
+		if err != nil {
+			return nil, uploadStats{}, err
+		}
+
+		transcript, err := t.send(ctx, req, 0)
+		if err != nil {
+			return nil, uploadStats{}, fmt.Errorf("transcribing audio: %w", err)
+		}
+
+		return transcript, uploadStats{Requests: 1, Bytes: req.Size}, nil
+	}
+
+	// Chunks are cut at pauses before the end of their window, so there may
+	// be one more than the duration suggests; transcribeChunks counts it.
+	expected := 0
+	if duration > 0 {
+		expected = int((duration + window - 1) / window)
+	}
+
+	expectRequests(ctx, expected)
+
+	parts, uploaded, err := t.transcribeChunks(ctx, first, c, opts, expected)
+	if err != nil {
+		return nil, uploadStats{}, err
+	}
+
+	return mergeChunkTranscripts(parts), uploadStats{Requests: len(parts), Bytes: uploaded}, nil
+}
+
+// transcribeWhole streams prepared audio to the backend in a single request,
+// re-encoding it on the fly when it was produced by FFmpeg and the upload
+// codec differs. Native audio is sent as-is.
+func (t *Transcriber) transcribeWhole(
+	ctx context.Context, prepared *PreparedAudio, duration time.Duration, opts requestOptions,
+) (*gemini.Transcript, uploadStats, error) {
+	var (
+		audio    io.Reader = prepared
+		mimeType           = prepared.MIMEType
+		size               = prepared.Size
+	)
+
+	if !prepared.native && mimeType != opts.Codec.MIMEType {
+		encoded, err := t.tools.encodeStream(ctx, prepared, opts.Codec, duration)
+		if err != nil {
+			return nil, uploadStats{}, fmt.Errorf("preparing upload: %w", err)
+		}
+
+		defer t.closeAudio(ctx, encoded)
+
+		audio, mimeType, size = encoded, opts.Codec.MIMEType, -1
+	}
+
+	counted := &countingReader{r: audio}
+
+	transcript, err := t.send(ctx, &gemini.Request{
+		Audio:      counted,
+		Size:       size,
+		MIMEType:   mimeType,
+		Duration:   duration,
+		Timestamps: opts.Timestamps,
+		Language:   opts.Language,
+		Reference:  opts.Reference.text(0, duration),
+	}, 0)
+	if err != nil {
+		return nil, uploadStats{}, fmt.Errorf("transcribing audio: %w", err)
+	}
+
+	return transcript, uploadStats{Requests: 1, Bytes: counted.n}, nil
+}
+
+// chunkRequest builds the backend request for one chunk, encoding it with
+// the upload codec first when encode is set.
+func (t *Transcriber) chunkRequest(
+	ctx context.Context, chunk *audioChunk, opts requestOptions, encode bool,
+) (*gemini.Request, error) {
+	audio, mimeType := chunk.Data, "audio/wav"
+
+	if encode && opts.Codec.MIMEType != mimeType {
+		encoded, err := t.tools.encodeChunk(ctx, chunk.Data, opts.Codec, chunk.Duration)
+		if err != nil {
+			return nil, err
+		}
+
+		audio, mimeType = encoded, opts.Codec.MIMEType
+	}
+
+	return &gemini.Request{
+		Audio:      bytes.NewReader(audio),
+		Size:       int64(len(audio)),
+		MIMEType:   mimeType,
+		Duration:   chunk.Duration,
+		Timestamps: opts.Timestamps,
+		Language:   opts.Language,
+		Reference:  opts.Reference.text(chunk.Offset, chunk.Duration),
 	}, nil
 }
+
+// transcribeChunk transcribes one chunk of a multi-chunk stream, always
+// encoded with the upload codec and with timed segments for merging. It
+// returns the transcript and the number of bytes uploaded.
+func (t *Transcriber) transcribeChunk(
+	ctx context.Context, chunk *audioChunk, opts requestOptions,
+) (*gemini.Transcript, int64, error) {
+	where := fmt.Sprintf("chunk %d at %s", chunk.Index+1, FormatTimestamp(chunk.Offset))
+
+	opts.Timestamps = true
+
+	req, err := t.chunkRequest(ctx, chunk, opts, true)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", where, err)
+	}
+
+	transcript, err := t.send(ctx, req, chunk.Index+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", where, err)
+	}
+
+	return transcript, req.Size, nil
+}
+
+// transcribeChunks transcribes first and the remaining chunks from c, up to
+// ChunkParallelism of them concurrently, encoding each with the upload
+// codec. A new chunk is only read once a worker is free, so at most
+// ChunkParallelism chunks are held in memory. The first failure cancels
+// the remaining work. Chunks beyond the expected number are added to the
+// requests counted in progress events. It returns the transcribed chunks
+// and the number of audio bytes uploaded.
+func (t *Transcriber) transcribeChunks(
+	ctx context.Context, first *audioChunk, c *chunker, opts requestOptions, expected int,
+) ([]chunkTranscript, int64, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	sem := make(chan struct{}, max(1, t.config.ChunkParallelism))
+
+	var (
+		mu       sync.Mutex
+		parts    []chunkTranscript
+		wg       sync.WaitGroup
+		uploaded atomic.Int64
+	)
+
+	for ctx.Err() == nil {
+		sem <- struct{}{}
+
+		chunk, err := first, error(nil)
+		if chunk == nil {
+			chunk, err = c.Next()
+		}
+
+		first = nil
+
+		if err != nil {
+			<-sem
+
+			if !errors.Is(err, io.EOF) {
+				cancel(fmt.Errorf("splitting audio: %w", err))
+			}
+
+			break
+		}
+
+		if chunk.Index >= expected {
+			expectRequests(ctx, 1)
+		}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			t.logger.InfoContext(ctx, "transcribing chunk",
+				slog.Int("chunk", chunk.Index+1),
+				slog.String("offset", FormatTimestamp(chunk.Offset)),
+				slog.Duration("duration", chunk.Duration),
+			)
+
+			transcript, size, err := t.transcribeChunk(ctx, chunk, opts)
+			if err != nil {
+				cancel(err)
+
+				return
+			}
+
+			uploaded.Add(size)
+
+			chunk.Data = nil
+
+			mu.Lock()
+			parts = append(parts, chunkTranscript{chunk: chunk, transcript: transcript})
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, 0, fmt.Errorf("transcribing audio: %w", err)
+	}
+
+	if len(parts) == 0 {
+		return nil, 0, fmt.Errorf("no audio to transcribe")
+	}
+
+	slices.SortFunc(parts, func(a, b chunkTranscript) int { return a.chunk.Index - b.chunk.Index })
+
+	return parts, uploaded.Load(), nil
+}
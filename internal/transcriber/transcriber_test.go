@@ -14,172 +14,25 @@ import (
 	"testing"
 
 	"github.com/idvoretskyi/voice-transcriber/internal/config"
+	"github.com/idvoretskyi/voice-transcriber/internal/gemini"
 	"github.com/idvoretskyi/voice-transcriber/internal/transcriber"
 )
 
-func TestGenerateAudioPath(t *testing.T) {
-	t.Parallel()
-
-	inputPath := "/some/dir/my video file.mp4"
-
-	audioPath, err := transcriber.GenerateAudioPath(inputPath)
-	if err != nil {
-		t.Fatalf("GenerateAudioPath() unexpected error: %v", err)
-	}
-
-	defer func() {
-		if err := os.Remove(audioPath); err != nil && !os.IsNotExist(err) {
-			t.Errorf("failed to remove temp file %q: %v", audioPath, err)
-		}
-	}()
+// newTranscriber returns a Transcriber for cfg that sends audio to
+// backend. Inputs are described from their headers, as without ffprobe,
+// unless the test sets the probe output or names ffprobe, and the cache is
+// only used in a directory the test names.
+func newTranscriber(t *testing.T, cfg *config.Config, backend gemini.AudioTranscriber) *transcriber.Transcriber {
+	t.Helper()
 
-	// Must be in the system temp dir
-	if !strings.HasPrefix(audioPath, os.TempDir()) {
-		t.Errorf("GenerateAudioPath() = %q; want prefix %q", audioPath, os.TempDir())
+	c := *cfg
+	if c.FFprobePath == "" {
+		c.FFprobePath = filepath.Join(t.TempDir(), "ffprobe")
 	}
 
-	// Must end with .wav
-	if filepath.Ext(audioPath) != ".wav" {
-		t.Errorf("GenerateAudioPath() = %q; want .wav extension", audioPath)
-	}
+	c.NoCache = c.NoCache || c.CacheDir == ""
 
-	// Must contain the base name (without extension) of the input
-	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
-	if !strings.Contains(filepath.Base(audioPath), base) {
-		t.Errorf("GenerateAudioPath() = %q; want base name %q in output", audioPath, base)
-	}
-
-	// Two calls must produce different paths (os.CreateTemp uniqueness)
-	audioPath2, err := transcriber.GenerateAudioPath(inputPath)
-	if err != nil {
-		t.Fatalf("GenerateAudioPath() second call unexpected error: %v", err)
-	}
-
-	defer func() {
-		if err := os.Remove(audioPath2); err != nil && !os.IsNotExist(err) {
-			t.Errorf("failed to remove temp file %q: %v", audioPath2, err)
-		}
-	}()
-
-	if audioPath == audioPath2 {
-		t.Errorf("GenerateAudioPath() returned identical paths on two calls: %q", audioPath)
-	}
-}
-
-func TestValidateInputPath(t *testing.T) {
-	t.Parallel()
-
-	t.Run("non-existent file returns error", func(t *testing.T) {
-		t.Parallel()
-
-		_, err := transcriber.ValidateInputPath("/non/existent/file.mp4")
-		if err == nil {
-			t.Error("expected error for non-existent file, got nil")
-		}
-	})
-
-	t.Run("regular file is accepted", func(t *testing.T) {
-		t.Parallel()
-
-		f, err := os.CreateTemp(t.TempDir(), "test-media-*.mp4")
-		if err != nil {
-			t.Fatalf("failed to create temp file: %v", err)
-		}
-
-		if err := f.Close(); err != nil {
-			t.Fatalf("failed to close temp file: %v", err)
-		}
-
-		got, err := transcriber.ValidateInputPath(f.Name())
-		if err != nil {
-			t.Errorf("unexpected error for valid file: %v", err)
-		}
-
-		if got == "" {
-			t.Error("expected non-empty cleaned path")
-		}
-	})
-
-	t.Run("directory is rejected", func(t *testing.T) {
-		t.Parallel()
-
-		_, err := transcriber.ValidateInputPath(t.TempDir())
-		if err == nil {
-			t.Error("expected error for directory input, got nil")
-		}
-	})
-
-	t.Run("path is cleaned", func(t *testing.T) {
-		t.Parallel()
-
-		dir := t.TempDir()
-
-		f, err := os.CreateTemp(dir, "test-*.mp4")
-		if err != nil {
-			t.Fatalf("failed to create temp file: %v", err)
-		}
-
-		if err := f.Close(); err != nil {
-			t.Fatalf("failed to close temp file: %v", err)
-		}
-
-		dirty := filepath.Join(dir, ".", filepath.Base(f.Name()))
-		clean := filepath.Clean(dirty)
-
-		got, err := transcriber.ValidateInputPath(dirty)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-
-		if got != clean {
-			t.Errorf("got %q; want cleaned path %q", got, clean)
-		}
-	})
-}
-
-func TestClassifyInputFile(t *testing.T) {
-	t.Parallel()
-
-	tests := []struct {
-		path             string
-		wantType         transcriber.InputType
-		wantMIMENotEmpty bool
-	}{
-		{path: "recording.wav", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.mp3", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.flac", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.ogg", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.m4a", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.aac", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "recording.webm", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "video.mp4", wantType: transcriber.InputTypeVideo, wantMIMENotEmpty: false},
-		{path: "video.mkv", wantType: transcriber.InputTypeVideo, wantMIMENotEmpty: false},
-		{path: "video.mov", wantType: transcriber.InputTypeVideo, wantMIMENotEmpty: false},
-		{path: "video.avi", wantType: transcriber.InputTypeVideo, wantMIMENotEmpty: false},
-		// Mixed-case extension
-		{path: "recording.WAV", wantType: transcriber.InputTypeAudio, wantMIMENotEmpty: true},
-		{path: "video.MP4", wantType: transcriber.InputTypeVideo, wantMIMENotEmpty: false},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.path, func(t *testing.T) {
-			t.Parallel()
-
-			gotType, gotMIME := transcriber.ClassifyInputFile(tc.path)
-
-			if gotType != tc.wantType {
-				t.Errorf("ClassifyInputFile(%q) type = %v; want %v", tc.path, gotType, tc.wantType)
-			}
-
-			if tc.wantMIMENotEmpty && gotMIME == "" {
-				t.Errorf("ClassifyInputFile(%q) MIME = empty; want non-empty", tc.path)
-			}
-
-			if !tc.wantMIMENotEmpty && gotMIME != "" {
-				t.Errorf("ClassifyInputFile(%q) MIME = %q; want empty", tc.path, gotMIME)
-			}
-		})
-	}
+	return transcriber.NewWithBackend(context.Background(), &c, backend, nil)
 }
 
 // stubBackend is a fake AudioTranscriber for unit testing TranscribeLocalFile.
@@ -188,8 +41,12 @@ type stubBackend struct {
 	err        error
 }
 
-func (s *stubBackend) TranscribeAudio(_ context.Context, _ []byte, _ string) (string, error) {
-	return s.transcript, s.err
+func (s *stubBackend) TranscribeAudio(_ context.Context, _ *gemini.Request) (*gemini.Transcript, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &gemini.Transcript{Text: s.transcript}, nil
 }
 
 // TestTranscribeLocalFileWithFakeBackend exercises TranscribeLocalFile end-to-end
@@ -212,7 +69,7 @@ func TestTranscribeLocalFileWithFakeBackend(t *testing.T) {
 
 		cfg := &config.Config{Quiet: true}
 		stub := &stubBackend{transcript: "hello world test"}
-		tr := transcriber.NewForTesting(cfg, stub, nil)
+		tr := newTranscriber(t, cfg, stub)
 
 		result, err := tr.TranscribeLocalFile(context.Background(), f.Name())
 		if err != nil {
@@ -246,7 +103,7 @@ func TestTranscribeLocalFileWithFakeBackend(t *testing.T) {
 
 		cfg := &config.Config{Quiet: true}
 		stub := &stubBackend{err: errors.New("gemini unavailable")}
-		tr := transcriber.NewForTesting(cfg, stub, nil)
+		tr := newTranscriber(t, cfg, stub)
 
 		_, err = tr.TranscribeLocalFile(context.Background(), f.Name())
 		if err == nil {
@@ -263,7 +120,7 @@ func TestTranscribeLocalFileWithFakeBackend(t *testing.T) {
 
 		cfg := &config.Config{Quiet: true}
 		stub := &stubBackend{transcript: "should not be reached"}
-		tr := transcriber.NewForTesting(cfg, stub, nil)
+		tr := newTranscriber(t, cfg, stub)
 
 		_, err := tr.TranscribeLocalFile(context.Background(), "/nonexistent/audio.wav")
 		if err == nil {
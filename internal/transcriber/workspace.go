@@ -0,0 +1,183 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// workspacePrefix starts the name of every workspace directory, which is
+// followed by the PID of the process that owns it.
+const workspacePrefix = "voice-transcriber-"
+
+// errInsufficientSpace is returned when a temporary file would not fit.
+var errInsufficientSpace = errors.New("not enough free disk space")
+
+// liveWorkspaces holds the directories of this process's open workspaces,
+// which share its PID with any stale ones left by an earlier process.
+var liveWorkspaces sync.Map
+
+// workspace is the per-run directory holding temporary files: downloaded
+// inputs and audio staged for quality analysis. Decoded audio that is not
+// staged is streamed through pipes and never lands here. The directory is
+// created under parent on first use and removed by Close.
+type workspace struct {
+	parent string
+	logger *slog.Logger
+
+	mu  sync.Mutex
+	dir string
+}
+
+// newWorkspace returns a workspace under parent, or under the system
+// temporary directory when parent is empty.
+func newWorkspace(parent string, logger *slog.Logger) *workspace {
+	if parent == "" {
+		parent = os.TempDir()
+	}
+
+	return &workspace{parent: parent, logger: logger}
+}
+
+// workspacePID returns the PID in a workspace directory name.
+func workspacePID(name string) (int, bool) {
+	rest, ok := strings.CutPrefix(name, workspacePrefix)
+	if !ok {
+		return 0, false
+	}
+
+	digits, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(digits)
+
+	return pid, err == nil && pid > 0
+}
+
+// sweep removes the workspaces under the parent directory whose process is
+// no longer running, left behind by runs that were killed. Failures are
+// logged: a stale workspace wastes space but breaks nothing.
+func (w *workspace) sweep(ctx context.Context) {
+	entries, err := os.ReadDir(w.parent)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		pid, ok := workspacePID(entry.Name())
+		if !ok || !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(w.parent, entry.Name())
+
+		if _, live := liveWorkspaces.Load(path); live || (pid != os.Getpid() && processAlive(pid)) {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			w.logger.WarnContext(ctx, "failed to remove stale workspace", slog.String("path", path), slog.Any("error", err))
+
+			continue
+		}
+
+		w.logger.InfoContext(ctx, "removed stale workspace", slog.String("path", path), slog.Int("pid", pid))
+	}
+}
+
+// path returns the workspace directory, creating it on first use.
+func (w *workspace) path() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.dir != "" {
+		return w.dir, nil
+	}
+
+	if err := os.MkdirAll(w.parent, 0o700); err != nil {
+		return "", fmt.Errorf("creating temporary directory: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(w.parent, workspacePrefix+strconv.Itoa(os.Getpid())+"-*")
+	if err != nil {
+		return "", fmt.Errorf("creating workspace: %w", err)
+	}
+
+	liveWorkspaces.Store(dir, struct{}{})
+
+	w.dir = dir
+
+	return dir, nil
+}
+
+// createTemp creates a temporary file named after pattern, as for
+// os.CreateTemp, in the workspace. When size is positive, the file system
+// must have that many bytes free for it.
+func (w *workspace) createTemp(pattern string, size int64) (*os.File, error) {
+	dir, err := w.path()
+	if err != nil {
+		return nil, err
+	}
+
+	if size > 0 {
+		if err := checkFreeSpace(dir, size); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	return f, nil
+}
+
+// Close removes the workspace and everything in it. Implements io.Closer.
+func (w *workspace) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.dir == "" {
+		return nil
+	}
+
+	dir := w.dir
+	w.dir = ""
+
+	liveWorkspaces.Delete(dir)
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing workspace: %w", err)
+	}
+
+	return nil
+}
+
+// checkFreeSpace returns an error when the file system holding dir has
+// fewer than need bytes available. It passes when free space cannot be
+// determined.
+func checkFreeSpace(dir string, need int64) error {
+	free, ok := diskFree(dir)
+	if !ok || free >= uint64(need) {
+		return nil
+	}
+
+	const mib = 1 << 20
+
+	return fmt.Errorf("%w in %s: %d MiB needed, %d MiB available (use --tmp-dir to pick another location)",
+		errInsufficientSpace, dir, (need+mib-1)/mib, free/mib)
+}
@@ -0,0 +1,44 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package transcriber
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceRemovedOnClose(t *testing.T) {
+	t.Parallel()
+
+	parent := filepath.Join(t.TempDir(), "scratch")
+	ws := newWorkspace(parent, slog.Default())
+
+	if _, err := os.Stat(parent); !os.IsNotExist(err) {
+		t.Fatalf("workspace parent exists before first use: %v", err)
+	}
+
+	dir, err := ws.path()
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+
+	prefix := "voice-transcriber-" + strconv.Itoa(os.Getpid()) + "-"
+	if filepath.Dir(dir) != parent || !strings.HasPrefix(filepath.Base(dir), prefix) {
+		t.Errorf("workspace = %s; want %s* in %s", dir, prefix, parent)
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("workspace %s still exists after Close: %v", dir, err)
+	}
+}
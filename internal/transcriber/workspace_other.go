@@ -0,0 +1,14 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build !unix
+
+package transcriber
+
+// processAlive reports every process as alive where liveness cannot be
+// checked, so workspaces are never swept from under a running process.
+func processAlive(int) bool {
+	return true
+}
@@ -0,0 +1,86 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build unix
+
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestSweepWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	// A process that has exited leaves its PID unused.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running short-lived process: %v", err)
+	}
+
+	parent := t.TempDir()
+	own := strconv.Itoa(os.Getpid())
+
+	ws := newWorkspace(parent, slog.Default())
+	t.Cleanup(func() { _ = ws.Close() })
+
+	live, err := ws.path()
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+
+	stale := []string{
+		"voice-transcriber-" + strconv.Itoa(cmd.Process.Pid) + "-123",
+		// Left by an earlier process that had the same PID, as in a container.
+		"voice-transcriber-" + own + "-456",
+	}
+	kept := []string{"voice-transcriber-1-789", "voice-transcriber-notes", "unrelated"}
+
+	for _, name := range append(append([]string{}, stale...), kept...) {
+		if err := os.MkdirAll(filepath.Join(parent, name, "sub"), 0o700); err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+	}
+
+	newWorkspace(parent, slog.Default()).sweep(context.Background())
+
+	for _, name := range stale {
+		if _, err := os.Stat(filepath.Join(parent, name)); !os.IsNotExist(err) {
+			t.Errorf("stale workspace %s not removed: %v", name, err)
+		}
+	}
+
+	for _, path := range append([]string{live}, kept...) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(parent, path)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s removed; want it kept: %v", path, err)
+		}
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := checkFreeSpace(dir, 1); err != nil {
+		t.Errorf("checkFreeSpace(1 byte) error = %v", err)
+	}
+
+	err := checkFreeSpace(dir, 1<<62)
+	if !errors.Is(err, errInsufficientSpace) {
+		t.Errorf("checkFreeSpace(4 EiB) error = %v; want errInsufficientSpace", err)
+	}
+}
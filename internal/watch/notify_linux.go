@@ -0,0 +1,80 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build linux
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// inotifyMask selects the events that may make a file ready: files created
+// in or moved into a directory, and files closed after writing. Writes
+// themselves are not watched; a file still being written is rescanned when
+// it would have settled.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_CLOSE_WRITE
+
+// inotify is a notifier backed by an inotify instance.
+type inotify struct {
+	f    *os.File
+	wake chan struct{}
+}
+
+// newNotifier returns a notifier of changes to dirs.
+func newNotifier(dirs []string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initialising inotify: %w", err)
+	}
+
+	// A non-blocking descriptor is read through the runtime poller, so
+	// closing the file interrupts a pending read.
+	n := &inotify{f: os.NewFile(uintptr(fd), "inotify"), wake: make(chan struct{}, 1)}
+
+	for _, dir := range dirs {
+		if _, err := syscall.InotifyAddWatch(fd, dir, inotifyMask); err != nil {
+			_ = n.f.Close()
+
+			return nil, fmt.Errorf("watching %s with inotify: %w", dir, err)
+		}
+	}
+
+	go n.read()
+
+	return n, nil
+}
+
+// read drains events until the instance is closed, waking the watcher
+// after each batch. The events are not decoded: any of them calls for a
+// scan.
+func (n *inotify) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		if _, err := n.f.Read(buf); err != nil {
+			return
+		}
+
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inotify) Wake() <-chan struct{} {
+	return n.wake
+}
+
+func (n *inotify) Close() error {
+	if err := n.f.Close(); err != nil {
+		return fmt.Errorf("closing inotify: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+//go:build !linux
+
+package watch
+
+// newNotifier reports that change notifications are not available, so
+// directories are polled.
+func newNotifier([]string) (notifier, error) {
+	return nil, errNoNotify
+}
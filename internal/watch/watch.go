@@ -0,0 +1,262 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+// Package watch reports the files that appear in directories once they
+// have finished being written.
+//
+// Directories are scanned on start, whenever the operating system reports a
+// change (inotify on Linux) and otherwise at a fixed interval. A file is
+// ready when its size and modification time have not changed for a settle
+// period, so files still being copied or recorded are left alone.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults for Options left zero.
+const (
+	DefaultSettle   = 5 * time.Second
+	DefaultInterval = 2 * time.Second
+)
+
+// rescanInterval is how often directories are scanned when change
+// notifications are available, in case one was missed.
+const rescanInterval = time.Minute
+
+// errNoNotify is returned by newNotifier where change notifications are
+// not available.
+var errNoNotify = errors.New("change notifications are not supported on this platform")
+
+// Options configures a Watcher.
+type Options struct {
+	// Settle is how long a file must keep its size and modification time
+	// before it is ready.
+	Settle time.Duration
+	// Interval is how often directories are scanned when polling.
+	Interval time.Duration
+	// Poll scans at Interval even where change notifications are
+	// available, e.g. for network file systems that do not send them.
+	Poll bool
+}
+
+// notifier wakes a Watcher when the watched directories change.
+type notifier interface {
+	// Wake receives a value after one or more changes.
+	Wake() <-chan struct{}
+	Close() error
+}
+
+// file is what a Watcher last saw of a file.
+type file struct {
+	size    int64
+	modTime time.Time
+	// since is when the file was last seen to change, and reported whether
+	// it has been reported ready since.
+	since    time.Time
+	reported bool
+}
+
+// Watcher reports the files of a set of directories once they are ready.
+// Subdirectories and hidden files are ignored.
+type Watcher struct {
+	dirs     []string
+	opts     Options
+	logger   *slog.Logger
+	notifier notifier
+	files    map[string]*file
+}
+
+// New returns a Watcher of dirs, which must exist. Unless opts.Poll is set
+// it uses change notifications where available, falling back to polling.
+func New(dirs []string, opts Options, logger *slog.Logger) (*Watcher, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no directories to watch")
+	}
+
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("watching %s: %w", dir, err)
+		}
+
+		if !info.IsDir() {
+			return nil, fmt.Errorf("watching %s: not a directory", dir)
+		}
+	}
+
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultSettle
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	w := &Watcher{dirs: dirs, opts: opts, logger: logger, files: make(map[string]*file)}
+
+	if !opts.Poll {
+		n, err := newNotifier(dirs)
+
+		// Polling is the only backend where notifications are not
+		// supported, so it is only worth a warning when they failed.
+		switch {
+		case errors.Is(err, errNoNotify):
+			logger.Debug("polling for changes", slog.Duration("interval", opts.Interval))
+		case err != nil:
+			logger.Warn("cannot watch for changes; polling instead",
+				slog.Duration("interval", opts.Interval), slog.Any("error", err))
+		}
+
+		w.notifier = n
+	}
+
+	return w, nil
+}
+
+// Notifying reports whether the Watcher is woken by change notifications
+// rather than polling.
+func (w *Watcher) Notifying() bool {
+	return w.notifier != nil
+}
+
+// Close releases the change notifications of the Watcher.
+func (w *Watcher) Close() error {
+	if w.notifier == nil {
+		return nil
+	}
+
+	return w.notifier.Close() //nolint:wrapcheck // notifiers wrap their own errors
+}
+
+// Run watches the directories until ctx is done, calling ready with the
+// path of every file that becomes ready. A file is reported again only
+// after it changes. ready is called from Run's goroutine, so scanning waits
+// for it to return.
+func (w *Watcher) Run(ctx context.Context, ready func(path string)) {
+	var wake <-chan struct{}
+	if w.notifier != nil {
+		wake = w.notifier.Wake()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-timer.C:
+		}
+
+		for _, path := range w.Scan() {
+			if ctx.Err() != nil {
+				return
+			}
+
+			ready(path)
+		}
+
+		timer.Reset(w.nextScan())
+	}
+}
+
+// Scan looks at the directories once and returns the files that have
+// become ready since the last scan.
+func (w *Watcher) Scan() []string {
+	now := time.Now()
+	present := make(map[string]bool, len(w.files))
+
+	var ready []string
+
+	for _, dir := range w.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			w.logger.Warn("cannot read watched directory", slog.String("dir", dir), slog.Any("error", err))
+
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+
+			// Symbolic links are followed to the files they name.
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			present[path] = true
+
+			if w.observe(path, info, now) {
+				ready = append(ready, path)
+			}
+		}
+	}
+
+	for path := range w.files {
+		if !present[path] {
+			delete(w.files, path)
+		}
+	}
+
+	return ready
+}
+
+// observe records what a scan at now found of the file at path and
+// reports whether it has just become ready.
+func (w *Watcher) observe(path string, info os.FileInfo, now time.Time) bool {
+	f, ok := w.files[path]
+
+	switch {
+	case !ok:
+		f = &file{since: now}
+		w.files[path] = f
+	case f.size != info.Size() || !f.modTime.Equal(info.ModTime()):
+		f.since, f.reported = now, false
+	}
+
+	f.size, f.modTime = info.Size(), info.ModTime()
+
+	if f.reported || now.Sub(f.since) < w.opts.Settle {
+		return false
+	}
+
+	f.reported = true
+
+	return true
+}
+
+// nextScan returns how long to wait before the next scan: until the next
+// file may settle, and no longer than the polling interval, or the rescan
+// interval with change notifications.
+func (w *Watcher) nextScan() time.Duration {
+	wait := w.opts.Interval
+	if w.notifier != nil {
+		wait = rescanInterval
+	}
+
+	now := time.Now()
+
+	for _, f := range w.files {
+		if !f.reported {
+			wait = min(wait, max(0, f.since.Add(w.opts.Settle).Sub(now)))
+		}
+	}
+
+	return wait
+}
@@ -0,0 +1,149 @@
+// Voice Transcriber
+// Copyright (c) 2025 Ihor Dvoretskyi
+//
+// Licensed under MIT License
+
+package watch_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idvoretskyi/voice-transcriber/internal/watch"
+)
+
+// settle is the settle period of the tests: long enough for a scan to see
+// a file before it settles, short enough to keep the tests quick.
+const settle = 200 * time.Millisecond
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanWaitsForFilesToSettle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	talk := filepath.Join(dir, "talk.mp3")
+
+	writeFile(t, talk, "first part")
+	writeFile(t, filepath.Join(dir, ".talk.mp3.part"), "hidden")
+
+	if err := os.Mkdir(filepath.Join(dir, "done"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := watch.New([]string{dir}, watch.Options{Settle: settle, Poll: true}, discard)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if ready := w.Scan(); len(ready) != 0 {
+		t.Fatalf("first scan reported %v; want nothing before the files settle", ready)
+	}
+
+	time.Sleep(settle / 2)
+
+	// The file grows: its settle period starts again.
+	writeFile(t, talk, "first part, second part")
+
+	if ready := w.Scan(); len(ready) != 0 {
+		t.Fatalf("scan reported %v while the file was growing", ready)
+	}
+
+	time.Sleep(settle + settle/2)
+
+	if ready := w.Scan(); !slices.Equal(ready, []string{talk}) {
+		t.Fatalf("scan after settling reported %v; want %s alone", ready, talk)
+	}
+
+	if ready := w.Scan(); len(ready) != 0 {
+		t.Errorf("a settled file was reported again: %v", ready)
+	}
+
+	// Replacing the file makes it ready again once it settles.
+	writeFile(t, talk, "another recording with the same name")
+	w.Scan()
+	time.Sleep(settle + settle/2)
+
+	if ready := w.Scan(); !slices.Equal(ready, []string{talk}) {
+		t.Errorf("scan after replacing the file reported %v; want %s", ready, talk)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	for _, poll := range []bool{false, true} {
+		t.Run(map[bool]string{false: "notify", true: "poll"}[poll], func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			opts := watch.Options{Settle: settle, Interval: 50 * time.Millisecond, Poll: poll}
+
+			w, err := watch.New([]string{dir}, opts, discard)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			// With inotify, only a notification makes Run scan again before
+			// the file would be reported.
+			if want := !poll && runtime.GOOS == "linux"; w.Notifying() != want {
+				t.Errorf("Notifying() = %v; want %v", w.Notifying(), want)
+			}
+
+			defer w.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			found := make(chan string, 1)
+
+			var wg sync.WaitGroup
+
+			wg.Go(func() { w.Run(ctx, func(path string) { found <- path }) })
+
+			path := filepath.Join(dir, "memo.wav")
+			writeFile(t, path, "recording")
+
+			select {
+			case got := <-found:
+				if got != path {
+					t.Errorf("Run reported %s; want %s", got, path)
+				}
+			case <-time.After(10 * time.Second):
+				t.Error("Run did not report the new file")
+			}
+
+			cancel()
+			wg.Wait()
+		})
+	}
+}
+
+func TestNewRejectsMissingDirectories(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	writeFile(t, notDir, "x")
+
+	for _, dirs := range [][]string{nil, {filepath.Join(dir, "missing")}, {notDir}} {
+		if _, err := watch.New(dirs, watch.Options{Poll: true}, discard); err == nil {
+			t.Errorf("New(%v) succeeded; want an error", dirs)
+		}
+	}
+}
@@ -152,7 +152,13 @@ func TestHelp(t *testing.T) {
 		{
 			name:     "transcribe --help",
 			args:     []string{"transcribe", "--help"},
-			wantOut:  []string{"Transcribe a video or audio file", "--output"},
+			wantOut:  []string{"Transcribe one or more video or audio files", "--output"},
+			exitCode: 0,
+		},
+		{
+			name:     "info --help",
+			args:     []string{"info", "--help"},
+			wantOut:  []string{"Inspect a media file with ffprobe", "--json"},
 			exitCode: 0,
 		},
 		{
@@ -204,12 +210,62 @@ func TestArgValidation(t *testing.T) {
 		{
 			name:    "transcribe missing argument",
 			args:    []string{"transcribe"},
+			wantErr: "requires at least 1 arg(s), only received 0",
+		},
+		{
+			name:    "transcribe standard input with other inputs",
+			args:    []string{"transcribe", "-", "b"},
+			wantErr: "standard input cannot be transcribed with other inputs",
+		},
+		{
+			name:    "transcribe retry-failed without resume",
+			args:    []string{"transcribe", "a.mp4", "--retry-failed"},
+			wantErr: "--retry-failed needs --resume",
+		},
+		{
+			name:    "transcribe resume with inputs",
+			args:    []string{"transcribe", "a.mp4", "--resume", "manifest.json"},
+			wantErr: "--resume takes its inputs from the manifest",
+		},
+		{
+			name:    "info missing argument",
+			args:    []string{"info"},
 			wantErr: "accepts 1 arg(s), received 0",
 		},
 		{
-			name:    "transcribe too many arguments",
-			args:    []string{"transcribe", "a", "b"},
-			wantErr: "accepts 1 arg(s), received 2",
+			name:    "info nonexistent file",
+			args:    []string{"info", "nonexistent.mp4"},
+			wantErr: "inspecting nonexistent.mp4",
+		},
+		{
+			name:    "conflicting audio stream flags",
+			args:    []string{"transcribe", "a.mp4", "--audio-stream", "1", "--all-audio-streams"},
+			wantErr: "--audio-stream and --all-audio-streams are mutually exclusive",
+		},
+		{
+			name:    "invalid audio stream",
+			args:    []string{"transcribe", "a.mp4", "--audio-stream", "lang:english"},
+			wantErr: "invalid --audio-stream",
+		},
+		{
+			name:    "channel names without split channels",
+			args:    []string{"transcribe", "call.wav", "--channel-names", "left=Agent"},
+			wantErr: "--channel-names requires --split-channels",
+		},
+		{
+			name:    "end before start",
+			args:    []string{"transcribe", "a.mp4", "--start", "1:00", "--end", "0:30"},
+			wantErr: "--end 0:30 must be after --start 1:00",
+		},
+		{
+			name:    "missing ranges file",
+			args:    []string{"transcribe", "a.mp4", "--ranges", "nonexistent-ranges.txt"},
+			wantErr: "opening ranges file",
+		},
+		{
+			name:    "unknown enhancement preset",
+			args:    []string{"transcribe", "a.wav", "--enhance", "studio"},
+			wantErr: "invalid --enhance",
 		},
 		{
 			name:    "transcribe unknown flag",